@@ -0,0 +1,203 @@
+// Package accessloghec writes access logs of net/http servers as HEC events.
+package accessloghec
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+)
+
+const (
+	defaultQueueSize     = 1000
+	defaultBatchSize     = 100
+	defaultFlushInterval = time.Second
+)
+
+// Options configures a Logger
+type Options struct {
+	// Metadata of events, e.g. the index and sourcetype (optional)
+	Metadata *hec.EventMetadata
+
+	// Max number of events waiting to be written; further events are dropped
+	// (default: 1000)
+	QueueSize int
+
+	// Max number of events per batch (default: 100)
+	BatchSize int
+
+	// Max time an event waits for its batch (default: 1s)
+	FlushInterval time.Duration
+
+	// Called with errors of writes, from the goroutine of the logger (optional)
+	OnError func(err error)
+}
+
+// Logger is a middleware writing an event for every request, with its
+// method, path, status, duration in milliseconds, response size and remote
+// address. Events are queued and written in batches by a goroutine, so
+// requests never wait for HEC.
+type Logger struct {
+	client  hec.HEC
+	options Options
+
+	// Guards sends to queue against Close
+	mtx     sync.RWMutex
+	closed  bool
+	queue   chan *hec.Event
+	done    chan struct{}
+	dropped atomic.Int64
+}
+
+// New creates a logger writing with client and starts its goroutine
+func New(client hec.HEC, options *Options) *Logger {
+	l := &Logger{client: client}
+	if options != nil {
+		l.options = *options
+	}
+	if l.options.QueueSize <= 0 {
+		l.options.QueueSize = defaultQueueSize
+	}
+	if l.options.BatchSize <= 0 {
+		l.options.BatchSize = defaultBatchSize
+	}
+	if l.options.FlushInterval <= 0 {
+		l.options.FlushInterval = defaultFlushInterval
+	}
+	l.queue = make(chan *hec.Event, l.options.QueueSize)
+	l.done = make(chan struct{})
+	go l.run()
+	return l
+}
+
+// Handler wraps next, logging its requests
+func (l *Logger) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+		l.log(r, recorder, start, time.Since(start))
+	})
+}
+
+func (l *Logger) log(r *http.Request, recorder *responseRecorder, start time.Time, duration time.Duration) {
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	event := hec.NewEvent(map[string]interface{}{
+		"method":      r.Method,
+		"path":        r.URL.Path,
+		"query":       r.URL.RawQuery,
+		"proto":       r.Proto,
+		"host":        r.Host,
+		"status":      recorder.status,
+		"duration_ms": float64(duration.Microseconds()) / 1000,
+		"bytes":       recorder.bytes,
+		"remote_addr": remote,
+		"user_agent":  r.UserAgent(),
+		"referer":     r.Referer(),
+	})
+	if m := l.options.Metadata; m != nil {
+		event.Host, event.Index, event.Source, event.SourceType = m.Host, m.Index, m.Source, m.SourceType
+	}
+	event.SetTime(start)
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+	if l.closed {
+		l.dropped.Add(1)
+		return
+	}
+	select {
+	case l.queue <- event:
+	default:
+		l.dropped.Add(1)
+	}
+}
+
+// Dropped returns the number of events dropped because the queue was full or
+// the logger was closed
+func (l *Logger) Dropped() int64 {
+	return l.dropped.Load()
+}
+
+// Close writes the queued events and stops the goroutine. Requests logged
+// afterwards are dropped.
+func (l *Logger) Close() {
+	l.mtx.Lock()
+	if !l.closed {
+		l.closed = true
+		close(l.queue)
+	}
+	l.mtx.Unlock()
+	<-l.done
+}
+
+func (l *Logger) run() {
+	defer close(l.done)
+	ticker := time.NewTicker(l.options.FlushInterval)
+	defer ticker.Stop()
+	var batch []*hec.Event
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if _, err := l.client.WriteBatchWithResponses(context.Background(), batch); err != nil && l.options.OnError != nil {
+			l.options.OnError(err)
+		}
+		batch = nil
+	}
+	for {
+		select {
+		case event, ok := <-l.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, event)
+			if len(batch) >= l.options.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// responseRecorder records the status and size of a response
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(data)
+	r.bytes += int64(n)
+	return n, err
+}
+
+func (r *responseRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap gives http.ResponseController access to the wrapped writer
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
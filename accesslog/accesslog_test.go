@@ -0,0 +1,70 @@
+package accessloghec
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+	"github.com/fuyufjh/splunk-hec-go/hectest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testToken = "00000000-0000-0000-0000-000000000000"
+
+func TestLogger(t *testing.T) {
+	server := hectest.NewServer(testToken)
+	defer server.Close()
+
+	logger := New(hec.NewClient(server.URL, testToken), &Options{
+		Metadata:      &hec.EventMetadata{SourceType: hec.String("access_combined_json")},
+		FlushInterval: time.Hour,
+	})
+	app := httptest.NewServer(logger.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("hello"))
+	})))
+	defer app.Close()
+
+	for _, path := range []string{"/hello?name=x", "/missing"} {
+		response, err := http.Get(app.URL + path)
+		require.NoError(t, err)
+		io.Copy(io.Discard, response.Body)
+		response.Body.Close()
+	}
+	logger.Close()
+
+	events := server.Events()
+	require.Len(t, events, 2)
+	assert.Equal(t, "access_combined_json", *events[0].SourceType)
+	hello := events[0].Event.Event.(map[string]interface{})
+	assert.Equal(t, "GET", hello["method"])
+	assert.Equal(t, "/hello", hello["path"])
+	assert.Equal(t, "name=x", hello["query"])
+	assert.Equal(t, 200.0, hello["status"])
+	assert.Equal(t, 5.0, hello["bytes"])
+	assert.Equal(t, "127.0.0.1", hello["remote_addr"])
+	assert.Contains(t, hello, "duration_ms")
+	assert.Equal(t, 404.0, events[1].Event.Event.(map[string]interface{})["status"])
+}
+
+func TestBatchesAndDrops(t *testing.T) {
+	server := hectest.NewServer(testToken)
+	defer server.Close()
+	server.SetLatency(50 * time.Millisecond)
+
+	logger := New(hec.NewClient(server.URL, testToken), &Options{QueueSize: 2, BatchSize: 1})
+	handler := logger.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for i := 0; i < 10; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	logger.Close()
+	assert.Greater(t, logger.Dropped(), int64(0))
+	assert.Equal(t, 10-int(logger.Dropped()), len(server.Events()))
+}
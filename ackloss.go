@@ -0,0 +1,202 @@
+package hec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// WithAckLossTimeout makes WaitForAcknowledgement give up on acknowledgement
+// IDs still reported as not indexed timeout after their request was sent,
+// returning them as an AckLostError so that their data can be sent again.
+// Without it, unacknowledged IDs are polled until the context is done.
+func WithAckLossTimeout(timeout time.Duration) Option {
+	return func(client *Client) {
+		client.ackLossTimeout = timeout
+	}
+}
+
+var ErrAckLost = errors.New("Acknowledgement lost")
+
+// LostAck describes data whose indexing was never acknowledged
+type LostAck struct {
+	// Acknowledgement ID and channel of the request
+	AckID   int
+	Channel string
+
+	// Time the request was sent
+	Sent time.Time
+
+	// Payload of the request
+	Payload PayloadInfo
+
+	// Whether a later ID of the channel was acknowledged before, a strong
+	// hint that the data was lost rather than slowly indexed
+	Gap bool
+}
+
+// AckLostError is returned by WaitForAcknowledgement for acknowledgements
+// given up on, see WithAckLossTimeout. The data of the other requests was
+// acknowledged. It matches ErrAckLost with errors.Is.
+type AckLostError struct {
+	Lost []LostAck
+}
+
+func (e *AckLostError) Error() string {
+	ids := make([]string, len(e.Lost))
+	for i, lost := range e.Lost {
+		ids[i] = fmt.Sprint(lost.AckID)
+	}
+	return fmt.Sprintf("Acknowledgement lost (ack IDs: %s)", strings.Join(ids, ", "))
+}
+
+func (e *AckLostError) Is(target error) bool {
+	return target == ErrAckLost
+}
+
+// pendingAck is the request of an acknowledgement ID waited for
+type pendingAck struct {
+	sent    time.Time
+	payload PayloadInfo
+}
+
+// trackAck records the request of an acknowledgement ID, with ackMux held
+func (hec *Client) trackAck(ackID int, endpoint string, data []byte) {
+	if hec.ackLossTimeout <= 0 {
+		return
+	}
+	if hec.ackPending == nil {
+		hec.ackPending = make(map[int]pendingAck)
+	}
+	payload := PayloadInfo{Endpoint: endpoint, Size: len(data)}
+	if path, _, _ := strings.Cut(endpoint, "?"); path != "/services/collector/raw" {
+		payload.Events = countEvents(endpoint, data)
+	}
+	hec.ackPending[ackID] = pendingAck{sent: hec.clock.Now(), payload: payload}
+}
+
+// lostAcks removes the IDs of ackIDs waited for longer than the loss timeout
+// and returns them, with the highest acknowledged ID so far
+func (hec *Client) lostAcks(ackIDs []int, highestAcked int) ([]int, []LostAck) {
+	if hec.ackLossTimeout <= 0 {
+		return ackIDs, nil
+	}
+	now := hec.clock.Now()
+	hec.ackMux.Lock()
+	defer hec.ackMux.Unlock()
+	var remaining []int
+	var lost []LostAck
+	for _, ackID := range ackIDs {
+		pending, ok := hec.ackPending[ackID]
+		if !ok || now.Sub(pending.sent) < hec.ackLossTimeout {
+			remaining = append(remaining, ackID)
+			continue
+		}
+		ack := LostAck{
+			AckID:   ackID,
+			Channel: hec.channel,
+			Sent:    pending.sent,
+			Payload: pending.payload,
+			Gap:     ackID < highestAcked,
+		}
+		lost = append(lost, ack)
+		delete(hec.ackPending, ackID)
+		hec.finishPoll(ackID, false, &ack)
+	}
+	return remaining, lost
+}
+
+// ackDone forgets the request of an acknowledged ID
+func (hec *Client) ackDone(ackID int) {
+	hec.ackMux.Lock()
+	delete(hec.ackPending, ackID)
+	hec.finishPoll(ackID, true, nil)
+	hec.ackMux.Unlock()
+}
+
+// ackPoll is the outcome of the poll for an acknowledgement ID, set before
+// done is closed: acknowledged, given up on, or neither if the ID was put
+// back into the IDs of the client
+type ackPoll struct {
+	done  chan struct{}
+	acked bool
+	lost  *LostAck
+}
+
+// startPoll marks an ID taken out of the IDs of the client as being polled
+// for, with ackMux held
+func (hec *Client) startPoll(ackID int) {
+	if hec.ackPolls == nil {
+		hec.ackPolls = make(map[int]*ackPoll)
+	}
+	hec.ackPolls[ackID] = &ackPoll{done: make(chan struct{})}
+}
+
+// finishPoll unblocks the waits for an ID being polled for, with ackMux held
+func (hec *Client) finishPoll(ackID int, acked bool, lost *LostAck) {
+	if poll, ok := hec.ackPolls[ackID]; ok {
+		poll.acked, poll.lost = acked, lost
+		close(poll.done)
+		delete(hec.ackPolls, ackID)
+	}
+}
+
+// putBackAcks puts IDs still unacknowledged back into the IDs of the client
+func (hec *Client) putBackAcks(ackIDs []int) {
+	hec.ackMux.Lock()
+	defer hec.ackMux.Unlock()
+	hec.ackIDs = append(hec.ackIDs, ackIDs...)
+	for _, ackID := range ackIDs {
+		hec.finishPoll(ackID, false, nil)
+	}
+}
+
+// Ack is the acknowledgement of a request sent with indexer acknowledgement
+// enabled. It keeps the client the request was sent with, so its
+// acknowledgement can be waited for after a Cluster failed over as well.
+type Ack struct {
+	ID int
+
+	// Redacted URL of the server and channel the request was sent to
+	Server  string
+	Channel string
+
+	client *Client
+}
+
+// Ack returns the acknowledgement of a successful request, or nil if HEC
+// returned no acknowledgement ID
+func (res *Response) Ack() *Ack {
+	return res.ack
+}
+
+// Wait blocks until the indexer acknowledges the data of the request, like
+// WaitForAcknowledgementWithContext does for all requests sent. A nil Ack
+// has nothing to wait for.
+func (a *Ack) Wait(ctx context.Context) error {
+	return WaitForAcks(ctx, a)
+}
+
+// WaitForAcks blocks until the data of all acks is acknowledged, polling each
+// client once for its acks. It returns the first error; the acks of the
+// other clients are still waited for.
+func WaitForAcks(ctx context.Context, acks ...*Ack) error {
+	var clients []*Client
+	ackIDs := make(map[*Client][]int)
+	for _, ack := range acks {
+		if ack == nil {
+			continue
+		}
+		if _, ok := ackIDs[ack.client]; !ok {
+			clients = append(clients, ack.client)
+		}
+		ackIDs[ack.client] = append(ackIDs[ack.client], ack.ID)
+	}
+	var err error
+	for _, client := range clients {
+		err = firstError(err, client.waitForAcks(ctx, ackIDs[client]))
+	}
+	return err
+}
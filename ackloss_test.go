@@ -0,0 +1,186 @@
+package hec
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithAckLossTimeout(t *testing.T) {
+	// The server acknowledges every ID except 1
+	var mtx sync.Mutex
+	nextAckID := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		if r.URL.Path == "/services/collector/ack" {
+			var request acknowledgementRequest
+			json.NewDecoder(r.Body).Decode(&request)
+			acks := make(map[string]bool)
+			for _, ackID := range request.Acks {
+				acks[fmt.Sprint(ackID)] = ackID != 1
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"acks": acks})
+			return
+		}
+		fmt.Fprintf(w, `{"text":"Success","code":0,"ackId":%d}`, nextAckID)
+		nextAckID++
+	}))
+	defer ts.Close()
+
+	var handled []PayloadInfo
+	clock := NewManualClock(time.Unix(1485237827, 0))
+	c := NewClient(ts.URL, testSplunkToken, WithAckLossTimeout(time.Minute))
+	c.SetClock(clock)
+	c.SetErrorHandler(func(err error, payload PayloadInfo) {
+		assert.ErrorIs(t, err, ErrAckLost)
+		handled = append(handled, payload)
+	})
+	for _, events := range [][]*Event{{NewEvent("a")}, {NewEvent("b"), NewEvent("c")}, {NewEvent("d")}} {
+		assert.NoError(t, c.WriteBatch(events))
+	}
+
+	err := c.WaitForAcknowledgementWithContext(context.Background())
+	var lostErr *AckLostError
+	if assert.ErrorAs(t, err, &lostErr) && assert.Len(t, lostErr.Lost, 1) {
+		lost := lostErr.Lost[0]
+		assert.Equal(t, 1, lost.AckID)
+		assert.Equal(t, c.(*Client).channel, lost.Channel)
+		assert.Equal(t, time.Unix(1485237827, 0), lost.Sent)
+		assert.Equal(t, 2, lost.Payload.Events)
+		assert.True(t, lost.Gap)
+	}
+	assert.Len(t, handled, 1)
+	assert.Empty(t, c.(*Client).ackIDs)
+	assert.Empty(t, c.(*Client).ackPending)
+	assert.NoError(t, c.WaitForAcknowledgementWithContext(context.Background()))
+}
+
+func TestAck_Wait(t *testing.T) {
+	// Every server acknowledges the IDs it was asked for, and busy fails the writes
+	var mtx sync.Mutex
+	var polled []string
+	newServer := func(name string, busy bool) *httptest.Server {
+		nextAckID := 0
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			if r.URL.Path == "/services/collector/ack" {
+				var request acknowledgementRequest
+				json.NewDecoder(r.Body).Decode(&request)
+				polled = append(polled, fmt.Sprint(name, request.Acks))
+				acks := make(map[string]bool)
+				for _, ackID := range request.Acks {
+					acks[fmt.Sprint(ackID)] = true
+				}
+				json.NewEncoder(w).Encode(map[string]interface{}{"acks": acks})
+				return
+			}
+			if busy {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				w.Write([]byte(`{"text":"Server is busy","code":9}`))
+				return
+			}
+			fmt.Fprintf(w, `{"text":"Success","code":0,"ackId":%d}`, nextAckID)
+			nextAckID++
+		}))
+	}
+	busy := newServer("busy", true)
+	defer busy.Close()
+	ts := newServer("ts", false)
+	defer ts.Close()
+
+	c := NewCluster([]string{busy.URL, ts.URL}, testSplunkToken)
+	ctx := context.Background()
+	first, err := c.WriteEventWithResponse(ctx, NewEvent("first"))
+	assert.NoError(t, err)
+	second, err := c.WriteEventWithResponse(ctx, NewEvent("second"))
+	assert.NoError(t, err)
+
+	// The acks are polled from the node the events were written to after failing over
+	ack := first.Ack()
+	if assert.NotNil(t, ack) {
+		assert.Equal(t, 0, ack.ID)
+		assert.Equal(t, ts.URL, ack.Server)
+		assert.NoError(t, ack.Wait(ctx))
+	}
+	assert.Equal(t, []string{"ts[0]"}, polled)
+	assert.NoError(t, WaitForAcks(ctx, first.Ack(), second.Ack(), nil))
+	assert.Equal(t, []string{"ts[0]", "ts[1]"}, polled)
+
+	// Acks waited for on their own are not polled again
+	assert.NoError(t, c.WaitForAcknowledgementWithContext(ctx))
+	assert.Len(t, polled, 2)
+	assert.Nil(t, (&Response{}).Ack())
+}
+
+func TestAck_WaitWhilePolled(t *testing.T) {
+	// The first poll blocks until released
+	polling, release := make(chan struct{}), make(chan struct{})
+	var polls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/services/collector/ack" {
+			w.Write([]byte(`{"text":"Success","code":0,"ackId":0}`))
+			return
+		}
+		if atomic.AddInt32(&polls, 1) == 1 {
+			close(polling)
+			<-release
+		}
+		w.Write([]byte(`{"acks":{"0":true}}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+	ctx := context.Background()
+	res, err := c.WriteEventWithResponse(ctx, NewEvent("polled"))
+	assert.NoError(t, err)
+
+	waited := make(chan error, 2)
+	go func() { waited <- c.WaitForAcknowledgementWithContext(ctx) }()
+	<-polling
+	go func() { waited <- res.Ack().Wait(ctx) }()
+	// The ack being polled for is not acknowledged yet
+	select {
+	case err := <-waited:
+		t.Fatalf("wait returned before the acknowledgement: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	assert.NoError(t, <-waited)
+	assert.NoError(t, <-waited)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&polls))
+}
+
+func TestWaitForAcknowledgement_InvalidAckID(t *testing.T) {
+	invalid := true
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/services/collector/ack" {
+			w.Write([]byte(`{"text":"Success","code":0,"ackId":0}`))
+		} else if invalid {
+			w.Write([]byte(`{"acks":{"zero":true}}`))
+		} else {
+			w.Write([]byte(`{"acks":{"0":true}}`))
+		}
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+	assert.NoError(t, c.WriteEvent(NewEvent("hello")))
+	assert.Error(t, c.WaitForAcknowledgement())
+	// The ID is kept for the next wait
+	invalid = false
+	assert.Equal(t, []int{0}, c.(*Client).ackIDs)
+	assert.NoError(t, c.WaitForAcknowledgement())
+	assert.Empty(t, c.(*Client).ackIDs)
+}
@@ -0,0 +1,82 @@
+package hec
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// AdaptiveBatching adapts the max number of events per request of batches to
+// the latency and errors of recent requests, AIMD style: the limit grows by
+// the min after every request faster than the target latency, and is halved
+// after a slower one or a failure caused by the load of HEC (a timeout, a
+// network error or a retriable status). It is safe to share between the
+// clients of a Cluster, to adapt to the load of all nodes.
+type AdaptiveBatching struct {
+	min           int
+	max           int
+	targetLatency time.Duration
+
+	mtx   sync.Mutex
+	limit int
+}
+
+// NewAdaptiveBatching creates an adaptive limit between minEvents and
+// maxEvents, starting at minEvents
+func NewAdaptiveBatching(minEvents int, maxEvents int, targetLatency time.Duration) *AdaptiveBatching {
+	minEvents = max(minEvents, 1)
+	maxEvents = max(maxEvents, minEvents)
+	return &AdaptiveBatching{min: minEvents, max: maxEvents, targetLatency: targetLatency, limit: minEvents}
+}
+
+// WithAdaptiveBatching makes a client limit the events per request of batches
+// by batching, on top of SetMaxBatchEvents and the max content length
+func WithAdaptiveBatching(batching *AdaptiveBatching) Option {
+	return func(client *Client) {
+		client.adaptive = batching
+	}
+}
+
+// Limit returns the current max number of events per request
+func (a *AdaptiveBatching) Limit() int {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	return a.limit
+}
+
+// observe adapts the limit to a request of a batch
+func (a *AdaptiveBatching) observe(latency time.Duration, err error) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	if overloaded(err) || latency > a.targetLatency {
+		a.limit = max(a.min, a.limit/2)
+	} else if err == nil {
+		a.limit = min(a.max, a.limit+a.min)
+	}
+}
+
+// overloaded tells whether a request failed because of the load of HEC
+func overloaded(err error) bool {
+	if err == nil {
+		return false
+	}
+	var res *Response
+	if errors.As(err, &res) {
+		return retriable(res.Code) || serverTimeout(res.StatusCode)
+	}
+	var requestErr *RequestError
+	return errors.As(err, &requestErr) && !errors.Is(err, ErrCanceled) && !errors.Is(err, ErrDeadlineExceeded)
+}
+
+// maxBatchEvents returns the max number of events per request of a batch, 0
+// for no limit
+func (hec *Client) maxBatchEvents(settings *Settings) int {
+	if hec.adaptive == nil {
+		return settings.MaxBatchEvents
+	}
+	limit := hec.adaptive.Limit()
+	if settings.MaxBatchEvents > 0 && settings.MaxBatchEvents < limit {
+		return settings.MaxBatchEvents
+	}
+	return limit
+}
@@ -0,0 +1,53 @@
+package hec
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithAdaptiveBatching(t *testing.T) {
+	var requests, busy atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if busy.Load() > 0 {
+			busy.Add(-1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"text":"Server is busy","code":9}`))
+			return
+		}
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	batching := NewAdaptiveBatching(2, 7, time.Minute)
+	c := NewClient(ts.URL, testSplunkToken, WithAdaptiveBatching(batching))
+	c.SetMaxRetry(0)
+	events := NewBatch(nil, "a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m")
+
+	// 2, 4, 6 and then at most 7 events per request
+	assert.NoError(t, c.WriteBatch(events))
+	assert.EqualValues(t, 4, requests.Load())
+	assert.Equal(t, 7, batching.Limit())
+
+	busy.Store(1)
+	assert.Error(t, c.WriteBatch(events))
+	assert.Equal(t, 3, batching.Limit())
+
+	// The max events of the client still apply
+	c.SetMaxBatchEvents(1)
+	requests.Store(0)
+	assert.NoError(t, c.WriteBatch(events[:3]))
+	assert.EqualValues(t, 3, requests.Load())
+	assert.Equal(t, 7, batching.Limit())
+
+	// Slow requests shrink batches
+	slow := NewAdaptiveBatching(4, 100, 0)
+	c = NewClient(ts.URL, testSplunkToken, WithAdaptiveBatching(slow))
+	assert.NoError(t, c.WriteBatch(events))
+	assert.Equal(t, 4, slow.Limit())
+}
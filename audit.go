@@ -0,0 +1,142 @@
+package hec
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Reasons of dropped events in audit records
+const (
+	DropTooLong          = "too-long"
+	DropInvalid          = "invalid"
+	DropOverQuota        = "over-quota"
+	DropRetriesExhausted = "retries-exhausted"
+	DropRejected         = "rejected"
+)
+
+// Max bytes of the sample of an audit record
+const maxAuditSample = 256
+
+// AuditRecord records events dropped at once
+type AuditRecord struct {
+	// Time the events were dropped
+	Time time.Time `json:"time"`
+
+	// Why the events were dropped, one of the Drop constants
+	Reason string `json:"reason"`
+
+	// Number of events dropped, or lines for raw data
+	Count int `json:"count"`
+
+	// Bytes of the dropped data before compression
+	Bytes int `json:"bytes"`
+
+	// Earliest and latest times of the dropped events, if they set it
+	FirstEvent *time.Time `json:"first_event,omitempty"`
+	LastEvent  *time.Time `json:"last_event,omitempty"`
+
+	// Beginning of the first dropped event
+	Sample string `json:"sample"`
+}
+
+// WithDropAudit makes a client write an audit record as a line of JSON to
+// writer whenever events are dropped, because they are rejected before or by
+// HEC. For a Cluster, events failed on a node are audited even if another
+// node accepts them later, like errors are reported to the error handler.
+func WithDropAudit(writer io.Writer) Option {
+	return func(client *Client) {
+		client.audit = &auditTrail{writer: writer}
+	}
+}
+
+// auditTrail writes audit records, one at a time
+type auditTrail struct {
+	mtx    sync.Mutex
+	writer io.Writer
+}
+
+func (a *auditTrail) write(record AuditRecord) {
+	line, _ := json.Marshal(record)
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.writer.Write(append(line, '\n'))
+}
+
+// dropReason returns the reason of events dropped because of err
+func dropReason(err error) string {
+	switch {
+	case err == errQuotaDropped || errors.Is(err, ErrQuotaExceeded):
+		return DropOverQuota
+	case errors.Is(err, ErrEventTooLong) || errors.Is(err, ErrLineTooLong):
+		return DropTooLong
+	case errors.Is(err, ErrInvalidEvent):
+		return DropInvalid
+	case errors.Is(err, ErrRetriesExhausted):
+		return DropRetriesExhausted
+	}
+	return DropRejected
+}
+
+// auditEvents records events dropped because of err
+func (hec *Client) auditEvents(err error, events []*Event) {
+	if hec.audit == nil || len(events) == 0 {
+		return
+	}
+	record := AuditRecord{Time: hec.clock.Now(), Reason: dropReason(err), Count: len(events)}
+	for i, event := range events {
+		data, _ := hec.marshal(hec.withDefaults(event))
+		record.Bytes += len(data)
+		if i == 0 {
+			record.Sample = sample(data)
+		}
+		if event.Time == nil {
+			continue
+		}
+		seconds, err := strconv.ParseFloat(*event.Time, 64)
+		if err != nil {
+			continue
+		}
+		t := time.Unix(0, int64(seconds*float64(time.Second))).UTC()
+		if record.FirstEvent == nil || t.Before(*record.FirstEvent) {
+			record.FirstEvent = &t
+		}
+		if record.LastEvent == nil || t.After(*record.LastEvent) {
+			record.LastEvent = &t
+		}
+	}
+	hec.audit.write(record)
+}
+
+// auditRaw records a chunk of raw data dropped because of err
+func (hec *Client) auditRaw(err error, chunk []byte) {
+	if hec.audit == nil {
+		return
+	}
+	hec.audit.write(AuditRecord{
+		Time:   hec.clock.Now(),
+		Reason: dropReason(err),
+		Count:  hec.rawSplitter.countLines(chunk),
+		Bytes:  len(chunk),
+		Sample: sample(chunk),
+	})
+}
+
+func sample(data []byte) string {
+	if len(data) > maxAuditSample {
+		data = data[:maxAuditSample]
+	}
+	return string(data)
+}
+
+// eventsAt returns the events at indexes
+func eventsAt(events []*Event, indexes []int) []*Event {
+	result := make([]*Event, len(indexes))
+	for i, index := range indexes {
+		result[i] = events[index]
+	}
+	return result
+}
@@ -0,0 +1,85 @@
+package hec
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readAudit(t *testing.T, output *bytes.Buffer) []AuditRecord {
+	var records []AuditRecord
+	for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+		var record AuditRecord
+		assert.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	output.Reset()
+	return records
+}
+
+func TestWithDropAudit(t *testing.T) {
+	busy := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if busy {
+			w.WriteHeader(503)
+			w.Write([]byte(`{"text":"Server is busy","code":9}`))
+			return
+		}
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	var output bytes.Buffer
+	recorder := NewTestRecorder()
+	c := NewClient(ts.URL, testSplunkToken, WithTestMode(recorder), WithDropAudit(&output))
+	c.SetHTTPClient(testHttpClient)
+	c.SetMaxContentLength(100)
+	c.SetQuotas([]Quota{{Index: "debug", Events: 1}})
+	c.SetMaxRetry(1)
+
+	first := NewEvent("first")
+	first.SetTime(time.Unix(100, 0))
+	last := NewEvent("last")
+	last.SetTime(time.Unix(200, 0))
+	debug := NewEvent("debug")
+	debug.SetIndex("debug")
+	long := NewEvent(strings.Repeat("x", 100))
+	assert.Error(t, c.WriteBatch([]*Event{long, debug, debug}))
+
+	records := readAudit(t, &output)
+	if assert.Len(t, records, 2) {
+		assert.Equal(t, DropOverQuota, records[0].Reason)
+		assert.Equal(t, 1, records[0].Count)
+		assert.Equal(t, `{"index":"debug","event":"debug"}`, records[0].Sample)
+		assert.Equal(t, DropTooLong, records[1].Reason)
+		assert.Equal(t, 1, records[1].Count)
+		assert.Len(t, records[1].Sample, 112)
+	}
+
+	busy = true
+	assert.Error(t, c.WriteBatch([]*Event{last, first}))
+	records = readAudit(t, &output)
+	if assert.Len(t, records, 1) {
+		assert.Equal(t, DropRetriesExhausted, records[0].Reason)
+		assert.Equal(t, 2, records[0].Count)
+		assert.Equal(t, time.Unix(100, 0).UTC(), *records[0].FirstEvent)
+		assert.Equal(t, time.Unix(200, 0).UTC(), *records[0].LastEvent)
+		assert.Equal(t, recorder.Clock.Now().UTC(), records[0].Time.UTC())
+	}
+
+	c.SetMaxRetry(0)
+	assert.Error(t, c.WriteRawString("one\ntwo\n", nil))
+	records = readAudit(t, &output)
+	if assert.Len(t, records, 1) {
+		assert.Equal(t, DropRejected, records[0].Reason)
+		assert.Equal(t, 2, records[0].Count)
+		assert.Equal(t, 8, records[0].Bytes)
+		assert.Nil(t, records[0].FirstEvent)
+	}
+}
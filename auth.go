@@ -0,0 +1,30 @@
+package hec
+
+import "encoding/base64"
+
+// AuthScheme is how the token is sent in the Authorization header
+type AuthScheme int
+
+const (
+	// Send "Splunk <token>"
+	AuthSplunk AuthScheme = iota
+
+	// Send "Bearer <token>", for gateways in front of HEC authenticating
+	// with OAuth
+	AuthBearer
+
+	// Send the token as the password of basic authentication, with the
+	// username "x", which HEC also accepts
+	AuthBasic
+)
+
+// header returns the Authorization header of token
+func (scheme AuthScheme) header(token string) string {
+	switch scheme {
+	case AuthBearer:
+		return "Bearer " + token
+	case AuthBasic:
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte("x:"+token))
+	}
+	return "Splunk " + token
+}
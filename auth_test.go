@@ -0,0 +1,37 @@
+package hec
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetAuthScheme(t *testing.T) {
+	var authorization string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL, testSplunkToken)
+	for scheme, expected := range map[AuthScheme]string{
+		AuthSplunk: "Splunk " + testSplunkToken,
+		AuthBearer: "Bearer " + testSplunkToken,
+		AuthBasic:  "Basic eDowMDAwMDAwMC0wMDAwLTAwMDAtMDAwMC0wMDAwMDAwMDAwMDA=",
+	} {
+		c.SetAuthScheme(scheme)
+		assert.NoError(t, c.WriteEvent(NewEvent("hello")))
+		assert.Equal(t, expected, authorization)
+	}
+
+	c, err := NewFromConfig(Config{URLs: []string{ts.URL, ts.URL}, Token: testSplunkToken, AuthScheme: "bearer"})
+	if assert.NoError(t, err) {
+		assert.NoError(t, c.WriteEvent(NewEvent("cluster")))
+		assert.Equal(t, "Bearer "+testSplunkToken, authorization)
+	}
+	_, err = NewFromConfig(Config{URLs: []string{ts.URL}, Token: testSplunkToken, AuthScheme: "digest"})
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+}
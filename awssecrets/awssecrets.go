@@ -0,0 +1,48 @@
+// Package awssecrets provides HEC tokens stored in AWS Secrets Manager.
+package awssecrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	hec "github.com/fuyufjh/splunk-hec-go"
+)
+
+// API gets secret values, e.g. a *secretsmanager.Client
+type API interface {
+	GetSecretValue(ctx context.Context, input *secretsmanager.GetSecretValueInput, options ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+}
+
+// NewTokenProvider returns a provider reading the token from the current
+// version of the secret secretID (a name or ARN) with api, cached for ttl or
+// until HEC rejects it (0 to cache until rejected). The secret string is the
+// token, or if key is set, a JSON object with the token as key, like the
+// key/value secrets of the console.
+func NewTokenProvider(api API, secretID string, key string, ttl time.Duration) hec.TokenProvider {
+	return hec.CachedToken(func(ctx context.Context) (string, error) {
+		output, err := api.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: aws.String(secretID)})
+		if err != nil {
+			return "", err
+		}
+		if output.SecretString == nil {
+			return "", errors.New("secret has no string value")
+		}
+		if key == "" {
+			return *output.SecretString, nil
+		}
+		var values map[string]interface{}
+		if err := json.Unmarshal([]byte(*output.SecretString), &values); err != nil {
+			return "", fmt.Errorf("secret is not a JSON object: %w", err)
+		}
+		token, ok := values[key].(string)
+		if !ok || token == "" {
+			return "", fmt.Errorf("secret has no %q", key)
+		}
+		return token, nil
+	}, ttl)
+}
@@ -0,0 +1,57 @@
+package awssecrets
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	hec "github.com/fuyufjh/splunk-hec-go"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeAPI struct {
+	secrets map[string]string
+	calls   int
+}
+
+func (f *fakeAPI) GetSecretValue(ctx context.Context, input *secretsmanager.GetSecretValueInput, options ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+	f.calls++
+	secret, ok := f.secrets[*input.SecretId]
+	if !ok {
+		return nil, errors.New("ResourceNotFoundException")
+	}
+	return &secretsmanager.GetSecretValueOutput{SecretString: aws.String(secret)}, nil
+}
+
+func TestNewTokenProvider(t *testing.T) {
+	api := &fakeAPI{secrets: map[string]string{
+		"plain": "plain-token",
+		"json":  `{"token":"json-token","other":1}`,
+	}}
+	ctx := context.Background()
+
+	provider := NewTokenProvider(api, "plain", "", 0)
+	token, err := provider.Token(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "plain-token", token)
+	provider.Token(ctx)
+	assert.Equal(t, 1, api.calls)
+	provider.(hec.TokenInvalidator).InvalidateToken(token)
+	provider.Token(ctx)
+	assert.Equal(t, 2, api.calls)
+
+	token, err = NewTokenProvider(api, "json", "token", 0).Token(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "json-token", token)
+
+	for _, provider := range []hec.TokenProvider{
+		NewTokenProvider(api, "json", "other", 0),
+		NewTokenProvider(api, "plain", "token", 0),
+		NewTokenProvider(api, "missing", "", 0),
+	} {
+		_, err = provider.Token(ctx)
+		assert.Error(t, err)
+	}
+}
@@ -0,0 +1,146 @@
+package hec
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Backfill writes historical events at a limited pace, so replaying days of
+// logs leaves capacity of the indexers for live traffic. The events are
+// split into chunks as by BatchIterator, and chunks are sent no faster than
+// the rates allow:
+//
+//	backfill := &hec.Backfill{EventsPerSecond: 5000, MaxConcurrency: 2}
+//	err := backfill.Write(ctx, client, events)
+//
+// The first failed chunk stops the backfill; chunks already sent are not
+// sent again.
+type Backfill struct {
+	// Max events and bytes of JSON sent per second (default: 0, no limit)
+	EventsPerSecond float64
+	BytesPerSecond  float64
+
+	// Max chunks being written at the same time (default: 1)
+	MaxConcurrency int
+
+	// Max bytes of JSON and events per chunk, see BatchIterator
+	MaxSize   int
+	MaxEvents int
+
+	// Time given to events that don't set their own, counted from Start by
+	// Interval per event, e.g. to spread records without timestamps over the
+	// period they were collected in (optional)
+	Start    time.Time
+	Interval time.Duration
+
+	// Source of time for pacing (default: SystemClock)
+	Clock Clock
+}
+
+// Write writes events to hec, see Backfill
+func (b *Backfill) Write(ctx context.Context, hec HEC, events []*Event) error {
+	i := 0
+	return b.WriteFunc(ctx, hec, func() (*Event, bool) {
+		if i == len(events) {
+			return nil, false
+		}
+		i++
+		return events[i-1], true
+	})
+}
+
+// WriteFunc writes the events returned by next to hec until it returns
+// false, see Backfill
+func (b *Backfill) WriteFunc(ctx context.Context, hec HEC, next func() (*Event, bool)) error {
+	clock := b.Clock
+	if clock == nil {
+		clock = SystemClock
+	}
+	concurrency := b.MaxConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	n := 0
+	it := NewBatchIteratorFunc(func() (*Event, bool) {
+		event, ok := next()
+		if ok && event != nil && event.Time == nil && !b.Start.IsZero() {
+			copied := *event
+			copied.SetTime(b.Start.Add(time.Duration(n) * b.Interval))
+			event = &copied
+		}
+		n++
+		return event, ok
+	})
+	it.MaxSize, it.MaxEvents = b.MaxSize, b.MaxEvents
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var wg sync.WaitGroup
+	var mtx sync.Mutex
+	var firstErr error
+	fail := func(err error) {
+		mtx.Lock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		mtx.Unlock()
+		cancel()
+	}
+	slots := make(chan struct{}, concurrency)
+
+	ready := clock.Now()
+	for ctx.Err() == nil && it.Next() {
+		if wait := ready.Sub(clock.Now()); wait > 0 {
+			select {
+			case <-clock.After(wait):
+			case <-ctx.Done():
+			}
+		}
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		if now := clock.Now(); now.After(ready) {
+			ready = now
+		}
+		ready = ready.Add(b.pace(len(it.Batch()), it.size))
+
+		wg.Add(1)
+		go func(batch []*Event) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if err := hec.WriteBatchWithContext(ctx, batch); err != nil {
+				fail(err)
+			}
+		}(it.Batch())
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return contextError(ctx)
+	}
+	return nil
+}
+
+// pace returns the time a chunk of events and size bytes takes up at the rates
+func (b *Backfill) pace(events int, size int) time.Duration {
+	var d time.Duration
+	if b.EventsPerSecond > 0 {
+		d = time.Duration(float64(events) / b.EventsPerSecond * float64(time.Second))
+	}
+	if b.BytesPerSecond > 0 {
+		d = max(d, time.Duration(float64(size)/b.BytesPerSecond*float64(time.Second)))
+	}
+	return d
+}
@@ -0,0 +1,107 @@
+package hec
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackfill(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	recorder := NewTestRecorder()
+	c := NewClient(ts.URL, testSplunkToken, WithTestMode(recorder))
+	c.SetChannelMode(ChannelNone)
+
+	start := time.Unix(1600000000, 0)
+	events := make([]*Event, 25)
+	for i := range events {
+		events[i] = NewEvent("historical")
+	}
+	events[1].SetTime(start.Add(-time.Hour))
+
+	clock := NewManualClock(start)
+	backfill := &Backfill{EventsPerSecond: 10, MaxEvents: 10, Start: start, Interval: 100 * time.Millisecond, Clock: clock}
+	require.NoError(t, backfill.Write(context.Background(), c, events))
+
+	requests := recorder.Requests()
+	require.Len(t, requests, 3)
+	assert.True(t, strings.HasPrefix(string(requests[0].Payload),
+		`{"time":"1600000000.000","event":"historical"}{"time":"1599996400.000","event":"historical"}{"time":"1600000000.200"`))
+	assert.Contains(t, string(requests[2].Payload), `{"time":"1600000002.400","event":"historical"}`)
+	// 10 events per second
+	assert.Equal(t, []time.Duration{time.Second, time.Second}, clock.Slept())
+	// The events of the caller are unchanged
+	assert.Nil(t, events[0].Time)
+
+	// Paced by bytes as well
+	recorder.Reset()
+	clock = NewManualClock(start)
+	backfill = &Backfill{BytesPerSecond: 100, MaxEvents: 10, Clock: clock}
+	require.NoError(t, backfill.Write(context.Background(), c, events[:20]))
+	assert.Len(t, recorder.Requests(), 2)
+	assert.Len(t, clock.Slept(), 1)
+	assert.Greater(t, clock.Slept()[0], time.Second)
+}
+
+func TestBackfill_Concurrency(t *testing.T) {
+	var inFlight, maxInFlight int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			m := atomic.LoadInt32(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+	events := make([]*Event, 8)
+	for i := range events {
+		events[i] = NewEvent("historical")
+	}
+	backfill := &Backfill{MaxConcurrency: 2, MaxEvents: 1}
+	require.NoError(t, backfill.Write(context.Background(), c, events))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&maxInFlight))
+}
+
+func TestBackfill_Error(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"text":"Invalid token","code":4}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+	backfill := &Backfill{MaxEvents: 1}
+	err := backfill.Write(context.Background(), c, []*Event{NewEvent("one"), NewEvent("two")})
+	var res *Response
+	if assert.ErrorAs(t, err, &res) {
+		assert.Equal(t, StatusInvalidToken, res.Code)
+	}
+	// The backfill stops at the first failed chunk
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, backfill.Write(ctx, c, []*Event{NewEvent("one")}), ErrCanceled)
+}
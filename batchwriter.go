@@ -0,0 +1,261 @@
+package hec
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWriterQueueSize = 1000
+	defaultWriterBatchSize = 100
+)
+
+// ErrWriterClosed is returned by writes to a closed BatchWriter
+var ErrWriterClosed = errors.New("Batch writer closed")
+
+// BatchWriterOptions configures a BatchWriter
+type BatchWriterOptions struct {
+	// Max number of events per batch (default: 100)
+	MaxBatchSize int
+
+	// Max bytes of JSON per batch (default: the default max content length
+	// of clients). A larger event makes a batch on its own.
+	MaxBatchBytes int
+
+	// Max time an event waits for its batch (default: 1s)
+	FlushInterval time.Duration
+
+	// Max number of events waiting for the goroutine of the writer; Write
+	// blocks while it is full (default: 1000)
+	QueueSize int
+
+	// Called with the error and the events of every failed batch, from the
+	// goroutine of the writer. It must not write to the writer, which may wait
+	// for the goroutine (optional).
+	OnError func(err error, events []*Event)
+
+	// Source of time for flushes (default: SystemClock)
+	Clock Clock
+}
+
+// BatchWriter buffers events and writes them with WriteBatch from a
+// goroutine, once MaxBatchSize events or MaxBatchBytes bytes are buffered,
+// and every FlushInterval:
+//
+//	writer := hec.NewBatchWriter(client, nil)
+//	defer writer.Close()
+//	writer.Write(hec.NewEvent("hello"))
+type BatchWriter struct {
+	hec     HEC
+	options BatchWriterOptions
+
+	// Guards closed against writes starting, which are counted by writers
+	mtx     sync.RWMutex
+	closed  bool
+	writers sync.WaitGroup
+
+	queue   chan *Event
+	flushes chan chan error
+
+	// Closed by Close, once all writes returned, and once the goroutine stopped
+	closing     chan struct{}
+	writersDone chan struct{}
+	done        chan struct{}
+
+	// Error of the last flush, set before done is closed
+	closeErr error
+
+	// Buffered events, used by the goroutine only
+	batch []*Event
+	size  int
+}
+
+// NewBatchWriter creates a writer writing to hec and starts its goroutine.
+// Options may be nil for the defaults.
+func NewBatchWriter(hec HEC, options *BatchWriterOptions) *BatchWriter {
+	w := &BatchWriter{hec: hec}
+	if options != nil {
+		w.options = *options
+	}
+	if w.options.MaxBatchSize <= 0 {
+		w.options.MaxBatchSize = defaultWriterBatchSize
+	}
+	if w.options.MaxBatchBytes <= 0 {
+		w.options.MaxBatchBytes = defaultMaxContentLength
+	}
+	if w.options.FlushInterval <= 0 {
+		w.options.FlushInterval = defaultFlushInterval
+	}
+	if w.options.QueueSize <= 0 {
+		w.options.QueueSize = defaultWriterQueueSize
+	}
+	if w.options.Clock == nil {
+		w.options.Clock = SystemClock
+	}
+	w.queue = make(chan *Event, w.options.QueueSize)
+	w.flushes = make(chan chan error)
+	w.closing = make(chan struct{})
+	w.writersDone = make(chan struct{})
+	w.done = make(chan struct{})
+	// Started here, so the first flush is FlushInterval after the writer is created
+	go w.run(w.options.Clock.NewTicker(w.options.FlushInterval))
+	return w
+}
+
+// Write adds event to the next batch. It blocks while the queue is full,
+// and fails with ErrWriterClosed once the writer is closed.
+func (w *BatchWriter) Write(event *Event) error {
+	return w.WriteWithContext(context.Background(), event)
+}
+
+// WriteWithContext is Write with a context for cancellation while the queue is full
+func (w *BatchWriter) WriteWithContext(ctx context.Context, event *Event) error {
+	w.mtx.RLock()
+	if w.closed {
+		w.mtx.RUnlock()
+		return ErrWriterClosed
+	}
+	w.writers.Add(1)
+	w.mtx.RUnlock()
+	defer w.writers.Done()
+
+	select {
+	case w.queue <- event:
+		return nil
+	case <-w.closing:
+		return ErrWriterClosed
+	case <-ctx.Done():
+		return contextError(ctx)
+	}
+}
+
+// WriteChannel adds the events received from events until it is closed or
+// the context is cancelled
+func (w *BatchWriter) WriteChannel(ctx context.Context, events <-chan *Event) error {
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if err := w.WriteWithContext(ctx, event); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return contextError(ctx)
+		}
+	}
+}
+
+// Flush writes the events added so far, and returns the first error of the
+// batches it writes. Batches written before on their own report their errors
+// to OnError only.
+func (w *BatchWriter) Flush() error {
+	reply := make(chan error)
+	select {
+	case w.flushes <- reply:
+		return <-reply
+	case <-w.done:
+		return nil // flushed by Close
+	}
+}
+
+// Close writes the buffered events, stops the goroutine and returns the error
+// of the last batch. Writes waiting for the queue and writes afterwards fail
+// with ErrWriterClosed.
+func (w *BatchWriter) Close() error {
+	w.mtx.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.closing)
+		go func() {
+			w.writers.Wait()
+			close(w.writersDone)
+		}()
+	}
+	w.mtx.Unlock()
+	<-w.done
+	return w.closeErr
+}
+
+func (w *BatchWriter) run(ticker Ticker) {
+	defer close(w.done)
+	defer ticker.Stop()
+	for {
+		select {
+		case event := <-w.queue:
+			w.add(event)
+		case <-w.writersDone:
+			// No more events can be queued
+			err := w.drain()
+			w.closeErr = firstError(err, w.flush())
+			return
+		case reply := <-w.flushes:
+			err := w.drain()
+			reply <- firstError(err, w.flush())
+		case <-ticker.C():
+			w.drain()
+			w.flush()
+		}
+	}
+}
+
+// drain adds the events queued so far, so a flush takes those written before it
+func (w *BatchWriter) drain() error {
+	var err error
+	for n := len(w.queue); n > 0; n-- {
+		err = firstError(err, w.add(<-w.queue))
+	}
+	return err
+}
+
+// add buffers event, flushing the batch before if event doesn't fit into it
+// and after if it is full
+func (w *BatchWriter) add(event *Event) error {
+	if event == nil || event.empty() {
+		return nil
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		w.fail(err, []*Event{event})
+		return err
+	}
+	if len(w.batch) > 0 && w.size+len(data) > w.options.MaxBatchBytes {
+		err = w.flush()
+	}
+	w.batch = append(w.batch, event)
+	w.size += len(data)
+	if len(w.batch) >= w.options.MaxBatchSize {
+		err = firstError(err, w.flush())
+	}
+	return err
+}
+
+func (w *BatchWriter) flush() error {
+	if len(w.batch) == 0 {
+		return nil
+	}
+	batch := w.batch
+	w.batch, w.size = nil, 0
+	err := w.hec.WriteBatch(batch)
+	if err != nil {
+		w.fail(err, batch)
+	}
+	return err
+}
+
+func (w *BatchWriter) fail(err error, events []*Event) {
+	if w.options.OnError != nil {
+		w.options.OnError(err, events)
+	}
+}
+
+func firstError(err error, other error) error {
+	if err != nil {
+		return err
+	}
+	return other
+}
@@ -0,0 +1,188 @@
+package hec
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchWriter(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	recorder := NewTestRecorder()
+	c := NewClient(ts.URL, testSplunkToken, WithTestMode(recorder))
+	c.SetChannelMode(ChannelNone)
+
+	// The ticker of a ManualClock only ticks when the clock is advanced
+	w := NewBatchWriter(c, &BatchWriterOptions{MaxBatchSize: 3, MaxBatchBytes: 60, Clock: NewManualClock(time.Now())})
+	for _, data := range []string{"one", "two", "three", "four"} {
+		require.NoError(t, w.Write(NewEvent(data)))
+	}
+	require.NoError(t, w.Flush())
+	payloads := func() []string {
+		var payloads []string
+		for _, request := range recorder.Requests() {
+			payloads = append(payloads, string(request.Payload))
+		}
+		return payloads
+	}
+	assert.Equal(t, []string{`{"event":"one"}{"event":"two"}{"event":"three"}`, `{"event":"four"}`}, payloads())
+
+	// Flushed before the batch would exceed MaxBatchBytes
+	recorder.Reset()
+	require.NoError(t, w.Write(NewEvent("a long event of many bytes")))
+	require.NoError(t, w.Write(NewEvent("another long event of many bytes")))
+	require.NoError(t, w.Flush())
+	assert.Len(t, recorder.Requests(), 2)
+
+	// From a channel, with the rest written by Close
+	recorder.Reset()
+	events := make(chan *Event, 2)
+	events <- NewEvent("five")
+	events <- NewEvent("six")
+	close(events)
+	require.NoError(t, w.WriteChannel(context.Background(), events))
+	require.NoError(t, w.Close())
+	assert.Equal(t, []string{`{"event":"five"}{"event":"six"}`}, payloads())
+	assert.ErrorIs(t, w.Write(NewEvent("closed")), ErrWriterClosed)
+	assert.NoError(t, w.Flush())
+	assert.NoError(t, w.Close())
+}
+
+func TestBatchWriter_FlushInterval(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	recorder := NewTestRecorder()
+	c := NewClient(ts.URL, testSplunkToken, WithTestMode(recorder))
+	w := NewBatchWriter(c, &BatchWriterOptions{FlushInterval: 10 * time.Millisecond})
+	defer w.Close()
+	require.NoError(t, w.Write(NewEvent("timed")))
+	assert.Eventually(t, func() bool { return len(recorder.Requests()) == 1 }, time.Second, 5*time.Millisecond)
+}
+
+func TestBatchWriter_OnError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"text":"Invalid token","code":4}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+	var failed []*Event
+	w := NewBatchWriter(c, &BatchWriterOptions{
+		Clock:   NewManualClock(time.Now()),
+		OnError: func(err error, events []*Event) { failed = append(failed, events...) },
+	})
+	event := NewEvent("rejected")
+	require.NoError(t, w.Write(event))
+	var res *Response
+	if assert.ErrorAs(t, w.Flush(), &res) {
+		assert.Equal(t, StatusInvalidToken, res.Code)
+	}
+	require.NoError(t, w.Write(NewEvent("last")))
+	if assert.ErrorAs(t, w.Close(), &res) {
+		assert.Equal(t, StatusInvalidToken, res.Code)
+	}
+	if assert.Len(t, failed, 2) {
+		assert.Same(t, event, failed[0])
+	}
+}
+
+func TestBatchWriter_ManualClock(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	recorder := NewTestRecorder()
+	c := NewClient(ts.URL, testSplunkToken, WithTestMode(recorder))
+	clock := NewManualClock(time.Now())
+	w := NewBatchWriter(c, &BatchWriterOptions{FlushInterval: time.Minute, Clock: clock})
+	defer w.Close()
+	require.NoError(t, w.Write(NewEvent("timed")))
+	clock.Advance(30 * time.Second)
+	require.NoError(t, w.Write(NewEvent("not yet")))
+	assert.Empty(t, recorder.Requests())
+	clock.Advance(30 * time.Second)
+	assert.Eventually(t, func() bool { return len(recorder.Requests()) == 1 }, time.Second, time.Millisecond)
+}
+
+// newBlockedWriter returns a writer with a full queue, whose goroutine is
+// blocked writing a batch until unblock is called
+func newBlockedWriter(t *testing.T) (w *BatchWriter, recorder *TestRecorder, unblock func()) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	t.Cleanup(ts.Close)
+
+	recorder = NewTestRecorder()
+	c := NewClient(ts.URL, testSplunkToken, WithTestMode(recorder))
+	w = NewBatchWriter(c, &BatchWriterOptions{MaxBatchSize: 1, QueueSize: 1, Clock: NewManualClock(time.Now())})
+	require.NoError(t, w.Write(NewEvent("blocking")))
+	<-started
+	require.NoError(t, w.Write(NewEvent("queued")))
+	var once sync.Once
+	return w, recorder, func() { once.Do(func() { close(release) }) }
+}
+
+func TestBatchWriter_CancelFullQueue(t *testing.T) {
+	w, _, unblock := newBlockedWriter(t)
+	defer w.Close()
+	defer unblock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, w.WriteWithContext(ctx, NewEvent("waiting")), ErrDeadlineExceeded)
+
+	events := make(chan *Event, 1)
+	events <- NewEvent("waiting")
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	assert.ErrorIs(t, w.WriteChannel(ctx, events), ErrCanceled)
+}
+
+func TestBatchWriter_CloseBlockedWrite(t *testing.T) {
+	w, recorder, unblock := newBlockedWriter(t)
+
+	written := make(chan error)
+	go func() { written <- w.Write(NewEvent("waiting")) }()
+	closed := make(chan error)
+	go func() { closed <- w.Close() }()
+	err := <-written
+	if err != nil {
+		assert.ErrorIs(t, err, ErrWriterClosed)
+	}
+	unblock()
+	assert.NoError(t, <-closed)
+
+	// Every accepted event is written
+	expected := 2
+	if err == nil {
+		expected = 3
+	}
+	assert.Len(t, recorder.Requests(), expected)
+	assert.ErrorIs(t, w.Write(NewEvent("closed")), ErrWriterClosed)
+}
@@ -0,0 +1,130 @@
+package hec
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// WithCAFile makes a client verify servers against the PEM certificates of
+// path instead of the system roots. The file is checked at every request and
+// loaded again when its modification time changes, so new connections trust
+// a rotated CA without restarting; if it cannot be loaded, the previous
+// certificates are kept. Requests fail until path is loaded.
+func WithCAFile(path string) Option {
+	roots := &caFile{path: path}
+	return func(client *Client) {
+		if client.httpClient == nil {
+			client.httpClient = http.DefaultClient
+		}
+		base, ok := client.httpClient.Transport.(*http.Transport)
+		if !ok {
+			base = http.DefaultTransport.(*http.Transport)
+		}
+		copied := *client.httpClient
+		copied.Transport = &caTransport{roots: roots, base: base.Clone()}
+		client.httpClient = &copied
+	}
+}
+
+// caTransport sends requests with a clone of base trusting the current roots
+// of a file as RootCAs, cloned again whenever the roots change. The TLS
+// options applied after WithCAFile update base, see withTLSConfig.
+type caTransport struct {
+	roots *caFile
+	base  *http.Transport
+
+	mtx       sync.Mutex
+	pool      *x509.CertPool
+	transport *http.Transport
+}
+
+func (t *caTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport, err := t.current()
+	if err != nil {
+		return nil, err
+	}
+	return transport.RoundTrip(req)
+}
+
+// CloseIdleConnections closes the idle connections of the current transport
+func (t *caTransport) CloseIdleConnections() {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if t.transport != nil {
+		t.transport.CloseIdleConnections()
+	}
+}
+
+// current returns the transport trusting the current roots
+func (t *caTransport) current() (*http.Transport, error) {
+	pool, err := t.roots.current()
+	if err != nil {
+		return nil, err
+	}
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if pool != t.pool {
+		// Connections verified against the previous roots are not reused
+		if t.transport != nil {
+			t.transport.CloseIdleConnections()
+		}
+		transport := t.base.Clone()
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{}
+		}
+		transport.TLSClientConfig.RootCAs = pool
+		t.pool, t.transport = pool, transport
+	}
+	return t.transport, nil
+}
+
+// withBase returns a transport trusting the same file with the TLS config of
+// base returned by update
+func (t *caTransport) withBase(update func(config *tls.Config) *tls.Config) *caTransport {
+	base := t.base.Clone()
+	base.TLSClientConfig = update(base.TLSClientConfig)
+	return &caTransport{roots: t.roots, base: base}
+}
+
+// caFile is a pool of root certificates loaded from a file
+type caFile struct {
+	path string
+
+	mtx     sync.Mutex
+	pool    *x509.CertPool
+	modTime time.Time
+}
+
+// current returns the pool, loaded again if the file changed
+func (f *caFile) current() (*x509.CertPool, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	info, err := os.Stat(f.path)
+	if err == nil && (f.pool == nil || !info.ModTime().Equal(f.modTime)) {
+		var pool *x509.CertPool
+		if pool, err = loadCAFile(f.path); err == nil {
+			f.pool, f.modTime = pool, info.ModTime()
+		}
+	}
+	if f.pool == nil {
+		return nil, err
+	}
+	return f.pool, nil
+}
+
+func loadCAFile(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%s has no PEM certificates", path)
+	}
+	return pool, nil
+}
@@ -0,0 +1,74 @@
+package hec
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithCAFile(t *testing.T) {
+	ca, rotated := newTestCA(t), newTestCA(t)
+	ts := ca.newServer(t)
+	defer ts.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	c := NewClient(ts.URL, testSplunkToken, WithCAFile(caFile))
+	c.SetMaxRetry(0)
+	c.SetKeepAlive(false)
+	assert.Error(t, c.WriteEvent(NewEvent("missing")))
+
+	require.NoError(t, os.WriteFile(caFile, rotated.pem(), 0o600))
+	assert.Error(t, c.WriteEvent(NewEvent("untrusted")))
+
+	// Make sure the modification time changes on coarse file systems
+	require.NoError(t, os.WriteFile(caFile, append(rotated.pem(), ca.pem()...), 0o600))
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(caFile, later, later)
+	assert.NoError(t, c.WriteEvent(NewEvent("rotated")))
+
+	// A broken file keeps the previous certificates
+	require.NoError(t, os.WriteFile(caFile, []byte("broken"), 0o600))
+	later = later.Add(time.Minute)
+	os.Chtimes(caFile, later, later)
+	assert.NoError(t, c.WriteEvent(NewEvent("broken")))
+
+	// The host name is still verified
+	require.NoError(t, os.WriteFile(caFile, ca.pem(), 0o600))
+	c = NewClient(ts.URL, testSplunkToken, WithCAFile(caFile))
+	assert.NoError(t, c.WriteEvent(NewEvent("trusted")))
+	c = NewClient(strings.Replace(ts.URL, "127.0.0.1", "localhost", 1), testSplunkToken, WithCAFile(caFile))
+	c.SetMaxRetry(0)
+	assert.Error(t, c.WriteEvent(NewEvent("wrong host")))
+}
+
+func TestWithCAFile_IPAddress(t *testing.T) {
+	ca := newTestCA(t)
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, ca.pem(), 0o600))
+
+	// Signed by the CA, but for another server than 127.0.0.1
+	cert, err := tls.X509KeyPair(ca.issueFor(t, "other", nil))
+	require.NoError(t, err)
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	ts.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	ts.StartTLS()
+	defer ts.Close()
+
+	c := NewClient(ts.URL, testSplunkToken, WithCAFile(caFile))
+	c.SetMaxRetry(0)
+	assert.Error(t, c.WriteEvent(NewEvent("wrong address")))
+
+	// Options applied afterwards keep the roots of the file
+	c = NewClient(ts.URL, testSplunkToken, WithCAFile(caFile), WithServerName("other"))
+	assert.NoError(t, c.WriteEvent(NewEvent("server name")))
+}
@@ -0,0 +1,137 @@
+package hec
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// WireCapture writes a sampled fraction of the requests to HEC and their
+// responses to a local file, with headers redacted like in errors. Once the
+// file would exceed MaxSize, it is rotated to Path + ".1", Path + ".1" to
+// Path + ".2" and so on, keeping at most MaxBackups old files.
+type WireCapture struct {
+	// Path of the capture file
+	Path string
+
+	// Fraction of requests captured, from 0 to 1
+	SampleRate float64
+
+	// Max bytes of a capture file, 0 for no limit
+	MaxSize int64
+
+	// Max number of rotated files kept
+	MaxBackups int
+
+	mtx  sync.Mutex
+	file *os.File
+	size int64
+}
+
+// WithWireCapture makes a client capture requests to capture. The file is
+// opened at the first captured request and closed by Close.
+func WithWireCapture(capture *WireCapture) Option {
+	return func(client *Client) {
+		client.capture = capture
+	}
+}
+
+// Close closes the capture file
+func (c *WireCapture) Close() error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.file == nil {
+		return nil
+	}
+	err := c.file.Close()
+	c.file = nil
+	return err
+}
+
+func (c *WireCapture) sampled() bool {
+	return c != nil && rand.Float64() < c.SampleRate
+}
+
+// captureRequest writes a request and its response, which is nil if it
+// failed. Errors of the capture file are only logged.
+func (hec *Client) captureRequest(req *http.Request, token string, payload []byte, res *http.Response, body []byte, err error) {
+	var record bytes.Buffer
+	fmt.Fprintf(&record, "=== %s\n", hec.clock.Now().UTC().Format(time.RFC3339Nano))
+	fmt.Fprintf(&record, "%s %s\n", req.Method, redactURL(req.URL.String()))
+	writeHeader(&record, redactHeader(req.Header, hec.redactor, token))
+	record.WriteString("\n")
+	record.Write(payload)
+	record.WriteString("\n\n")
+	if res == nil {
+		fmt.Fprintf(&record, "error: %v\n\n", err)
+	} else {
+		fmt.Fprintf(&record, "%s\n", res.Status)
+		writeHeader(&record, redactHeader(res.Header, hec.redactor, token))
+		record.WriteString("\n")
+		record.Write(body)
+		record.WriteString("\n\n")
+	}
+	if err := hec.capture.write(record.Bytes()); err != nil && hec.logger != nil {
+		hec.logger.Warn("Failed to capture HEC request", "error", err)
+	}
+}
+
+func writeHeader(buffer *bytes.Buffer, header http.Header) {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range header[name] {
+			fmt.Fprintf(buffer, "%s: %s\n", name, value)
+		}
+	}
+}
+
+func (c *WireCapture) write(record []byte) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.file != nil && c.MaxSize > 0 && c.size+int64(len(record)) > c.MaxSize {
+		if err := c.rotate(); err != nil {
+			return err
+		}
+	}
+	if c.file == nil {
+		file, err := os.OpenFile(c.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return err
+		}
+		info, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return err
+		}
+		c.file = file
+		c.size = info.Size()
+	}
+	n, err := c.file.Write(record)
+	c.size += int64(n)
+	return err
+}
+
+// rotate closes the capture file and shifts it and its backups by one
+func (c *WireCapture) rotate() error {
+	if err := c.file.Close(); err != nil {
+		return err
+	}
+	c.file = nil
+	if c.MaxBackups <= 0 {
+		return os.Remove(c.Path)
+	}
+	os.Remove(fmt.Sprintf("%s.%d", c.Path, c.MaxBackups))
+	for i := c.MaxBackups - 1; i > 0; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", c.Path, i), fmt.Sprintf("%s.%d", c.Path, i+1))
+	}
+	return os.Rename(c.Path, c.Path+".1")
+}
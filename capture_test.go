@@ -0,0 +1,92 @@
+package hec
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithWireCapture(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.WriteHeader(400)
+		w.Write([]byte(`{"text":"Invalid data format","code":6}`))
+	}))
+	defer ts.Close()
+
+	path := filepath.Join(t.TempDir(), "capture.log")
+	capture := &WireCapture{Path: path, SampleRate: 1}
+	defer capture.Close()
+	c := NewClient(ts.URL, testSplunkToken, WithWireCapture(capture))
+	c.SetHTTPClient(testHttpClient)
+	assert.Error(t, c.WriteEvent(NewEvent("bad")))
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	captured := string(content)
+	assert.Contains(t, captured, "POST "+ts.URL+"/services/collector?channel=")
+	assert.Contains(t, captured, "Authorization: <redacted>")
+	assert.NotContains(t, captured, testSplunkToken)
+	assert.Contains(t, captured, `{"event":"bad"}`)
+	assert.Contains(t, captured, "400 Bad Request")
+	assert.Contains(t, captured, "Set-Cookie: <redacted>")
+	assert.Contains(t, captured, `{"text":"Invalid data format","code":6}`)
+
+	// Nothing is captured with a sample rate of 0
+	capture.SampleRate = 0
+	assert.Error(t, c.WriteEvent(NewEvent("bad")))
+	again, _ := os.ReadFile(path)
+	assert.Equal(t, content, again)
+}
+
+func TestWireCaptureRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.log")
+	capture := &WireCapture{Path: path, MaxSize: 10, MaxBackups: 2}
+	defer capture.Close()
+	for _, record := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		assert.NoError(t, capture.write([]byte(record)))
+	}
+
+	contents := make([]string, 3)
+	for i, name := range []string{path, path + ".1", path + ".2"} {
+		content, err := os.ReadFile(name)
+		assert.NoError(t, err)
+		contents[i] = string(content)
+	}
+	assert.Equal(t, []string{"fourth\n", "third\n", "second\n"}, contents)
+	_, err := os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestWithWireCapture_Redactor(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Echo", r.Header.Get("Authorization"))
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	path := filepath.Join(t.TempDir(), "capture.log")
+	capture := &WireCapture{Path: path, SampleRate: 1}
+	defer capture.Close()
+	c := NewClient(ts.URL, testSplunkToken, WithWireCapture(capture))
+	c.SetHTTPClient(testHttpClient)
+	// Exposes every header it is given
+	var seen []string
+	c.SetRedactor(func(name string, value string) string {
+		seen = append(seen, value)
+		return value
+	})
+	assert.NoError(t, c.WriteEvent(NewEvent("hello")))
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "Authorization: <redacted>")
+	assert.Contains(t, string(content), "X-Echo: <redacted>")
+	assert.NotContains(t, string(content), testSplunkToken)
+	assert.NotContains(t, strings.Join(seen, "\n"), testSplunkToken)
+}
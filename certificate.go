@@ -0,0 +1,129 @@
+package hec
+
+import (
+	"crypto/tls"
+	"os"
+	"sync"
+	"time"
+)
+
+// WithClientCertificate makes a client authenticate with cert, for
+// deployments requiring mutual TLS. It keeps the rest of the TLS config.
+func WithClientCertificate(cert tls.Certificate) Option {
+	return func(client *Client) {
+		client.httpClient = withTLSConfig(client.httpClient, func(config *tls.Config) *tls.Config {
+			if config == nil {
+				config = &tls.Config{}
+			}
+			config.Certificates = []tls.Certificate{cert}
+			config.GetClientCertificate = nil
+			return config
+		})
+	}
+}
+
+// WithClientCertificateLoader makes a client authenticate with the
+// certificate of loader, for deployments requiring mutual TLS. It keeps the
+// rest of the TLS config.
+func WithClientCertificateLoader(loader *CertificateLoader) Option {
+	return func(client *Client) {
+		client.httpClient = withTLSConfig(client.httpClient, func(config *tls.Config) *tls.Config {
+			if config == nil {
+				config = &tls.Config{}
+			}
+			config.Certificates = nil
+			config.GetClientCertificate = loader.GetClientCertificate
+			return config
+		})
+	}
+}
+
+// ForNode applies options only to the client of a Cluster with serverURL,
+// e.g. to give every node its own client certificate
+func ForNode(serverURL string, options ...Option) Option {
+	return func(client *Client) {
+		if client.serverURL != serverURL {
+			return
+		}
+		for _, option := range options {
+			option(client)
+		}
+	}
+}
+
+// CertificateLoader loads a client certificate and key from PEM files, and
+// loads them again when they change. Files are checked for changes at the
+// first handshake after the reload interval; no goroutine is started.
+type CertificateLoader struct {
+	certFile       string
+	keyFile        string
+	reloadInterval time.Duration
+
+	mtx       sync.Mutex
+	cert      *tls.Certificate
+	checked   time.Time
+	modTimes  [2]time.Time
+	lastError error
+}
+
+// NewCertificateLoader loads the certificate of certFile and keyFile, to be
+// reloaded if changed after reloadInterval (0 to never reload)
+func NewCertificateLoader(certFile string, keyFile string, reloadInterval time.Duration) (*CertificateLoader, error) {
+	l := &CertificateLoader{certFile: certFile, keyFile: keyFile, reloadInterval: reloadInterval}
+	if err := l.load(time.Now()); err != nil {
+		return nil, &ConfigError{Param: "client certificate", Reason: err.Error()}
+	}
+	return l, nil
+}
+
+func (l *CertificateLoader) load(now time.Time) error {
+	modTimes, err := l.stat()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(l.certFile, l.keyFile)
+	if err != nil {
+		return err
+	}
+	l.cert, l.modTimes, l.checked = &cert, modTimes, now
+	return nil
+}
+
+func (l *CertificateLoader) stat() ([2]time.Time, error) {
+	var modTimes [2]time.Time
+	for i, path := range []string{l.certFile, l.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return modTimes, err
+		}
+		modTimes[i] = info.ModTime()
+	}
+	return modTimes, nil
+}
+
+// GetClientCertificate returns the current certificate, for
+// tls.Config.GetClientCertificate. If reloading fails, the previous
+// certificate is kept and the error is returned by Err.
+func (l *CertificateLoader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	now := time.Now()
+	if l.reloadInterval > 0 && now.Sub(l.checked) >= l.reloadInterval {
+		l.checked = now
+		// Certificates are rotated by updating both files, one at a time: a
+		// pair that doesn't match is reported and retried at the next check
+		if modTimes, err := l.stat(); err != nil {
+			l.lastError = err
+		} else if modTimes != l.modTimes {
+			l.lastError = l.load(now)
+		}
+	}
+	return l.cert, nil
+}
+
+// Err returns the error of the last reload, or nil if it succeeded
+func (l *CertificateLoader) Err() error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.lastError
+}
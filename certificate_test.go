@@ -0,0 +1,212 @@
+package hec
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCA issues client certificates for tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{cert: cert, key: key, pool: pool}
+}
+
+// pem returns the PEM certificate of the CA
+func (ca *testCA) pem() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw})
+}
+
+// issue returns the PEM certificate and key of a certificate for clients, or
+// servers on 127.0.0.1 or named name
+func (ca *testCA) issue(t *testing.T, name string) ([]byte, []byte) {
+	return ca.issueFor(t, name, []net.IP{net.IPv4(127, 0, 0, 1)})
+}
+
+// issueFor is issue for servers on ips or named name
+func (ca *testCA) issueFor(t *testing.T, name string, ips []net.IP) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		IPAddresses:  ips,
+		DNSNames:     []string{name},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+}
+
+// newServer starts a server on 127.0.0.1 with a certificate of ca
+func (ca *testCA) newServer(t *testing.T) *httptest.Server {
+	cert, err := tls.X509KeyPair(ca.issue(t, "server"))
+	require.NoError(t, err)
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	ts.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	ts.StartTLS()
+	return ts
+}
+
+// mutualTLSServer requires client certificates of a CA, recording the
+// common name of the last client
+type mutualTLSServer struct {
+	*httptest.Server
+
+	mtx    sync.Mutex
+	client string
+}
+
+func newMutualTLSServer(ca *testCA) *mutualTLSServer {
+	s := &mutualTLSServer{}
+	s.Server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mtx.Lock()
+		s.client = r.TLS.PeerCertificates[0].Subject.CommonName
+		s.mtx.Unlock()
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	s.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: ca.pool}
+	s.StartTLS()
+	return s
+}
+
+func (s *mutualTLSServer) lastClient() string {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.client
+}
+
+func serverPool(ts *mutualTLSServer) *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+	return pool
+}
+
+func TestWithClientCertificate(t *testing.T) {
+	ca := newTestCA(t)
+	ts := newMutualTLSServer(ca)
+	defer ts.Close()
+	certPEM, keyPEM := ca.issue(t, "client")
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+
+	c := NewClient(ts.URL, testSplunkToken, WithTLS(&tls.Config{RootCAs: serverPool(ts)}))
+	c.SetMaxRetry(0)
+	assert.Error(t, c.WriteEvent(NewEvent("no certificate")))
+
+	// The certificate is added to the TLS config, in either order
+	c = NewClient(ts.URL, testSplunkToken, WithTLS(&tls.Config{RootCAs: serverPool(ts)}), WithClientCertificate(cert))
+	require.NoError(t, c.WriteEvent(NewEvent("certificate")))
+	assert.Equal(t, "client", ts.lastClient())
+}
+
+func TestForNode(t *testing.T) {
+	ca := newTestCA(t)
+	a, b := newMutualTLSServer(ca), newMutualTLSServer(ca)
+	defer a.Close()
+	defer b.Close()
+	pool := serverPool(a)
+	pool.AddCert(b.Certificate())
+	certA, keyA := ca.issue(t, "a")
+	certB, keyB := ca.issue(t, "b")
+	pairA, _ := tls.X509KeyPair(certA, keyA)
+	pairB, _ := tls.X509KeyPair(certB, keyB)
+
+	cluster := NewCluster([]string{a.URL, b.URL}, testSplunkToken,
+		WithTLS(&tls.Config{RootCAs: pool}),
+		ForNode(a.URL, WithClientCertificate(pairA)),
+		ForNode(b.URL, WithClientCertificate(pairB)),
+	).(*Cluster)
+	for i, server := range []*mutualTLSServer{a, b} {
+		require.NoError(t, cluster.clients[i].WriteEvent(NewEvent("hello")))
+		assert.Equal(t, []string{"a", "b"}[i], server.lastClient())
+	}
+}
+
+func TestCertificateLoader(t *testing.T) {
+	ca := newTestCA(t)
+	ts := newMutualTLSServer(ca)
+	defer ts.Close()
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	write := func(name string) {
+		certPEM, keyPEM := ca.issue(t, name)
+		require.NoError(t, os.WriteFile(certFile, certPEM, 0o600))
+		require.NoError(t, os.WriteFile(keyFile, keyPEM, 0o600))
+	}
+	write("first")
+
+	_, err := NewCertificateLoader(certFile, filepath.Join(dir, "missing.key"), 0)
+	assert.IsType(t, &ConfigError{}, err)
+
+	loader, err := NewCertificateLoader(certFile, keyFile, time.Nanosecond)
+	require.NoError(t, err)
+	c := NewClient(ts.URL, testSplunkToken, WithTLS(&tls.Config{RootCAs: serverPool(ts)}), WithClientCertificateLoader(loader))
+	c.SetKeepAlive(false)
+	require.NoError(t, c.WriteEvent(NewEvent("hello")))
+	assert.Equal(t, "first", ts.lastClient())
+
+	// Make sure the modification times change on coarse file systems
+	write("second")
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, later, later)
+	c.(*Client).httpClient.CloseIdleConnections()
+	require.NoError(t, c.WriteEvent(NewEvent("hello")))
+	assert.Equal(t, "second", ts.lastClient())
+	assert.NoError(t, loader.Err())
+
+	// A broken key keeps the previous certificate
+	require.NoError(t, os.WriteFile(keyFile, []byte("broken"), 0o600))
+	os.Chtimes(keyFile, later, later)
+	c.(*Client).httpClient.CloseIdleConnections()
+	require.NoError(t, c.WriteEvent(NewEvent("hello")))
+	assert.Equal(t, "second", ts.lastClient())
+	assert.Error(t, loader.Err())
+}
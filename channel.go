@@ -0,0 +1,35 @@
+package hec
+
+import "net/url"
+
+// ChannelMode is how the channel is sent to HEC
+type ChannelMode int
+
+const (
+	// Send the channel as the channel parameter of the query string
+	ChannelInQuery ChannelMode = iota
+
+	// Send the channel as the X-Splunk-Request-Channel header, keeping it out
+	// of logged query strings
+	ChannelInHeader
+
+	// Don't send the channel, for tokens without indexer acknowledgement. Raw
+	// mode and acknowledgement require a channel.
+	ChannelNone
+)
+
+// queryChannel returns the channel to send in the query string, or ""
+func (hec *Client) queryChannel() string {
+	if hec.channelMode == ChannelInQuery {
+		return hec.channel
+	}
+	return ""
+}
+
+// endpoint returns path with the channel in the query string if needed
+func (hec *Client) endpoint(path string) string {
+	if channel := hec.queryChannel(); channel != "" {
+		return path + "?channel=" + url.QueryEscape(channel)
+	}
+	return path
+}
@@ -0,0 +1,146 @@
+// Package cimhec renames the fields of events to the field names of the
+// Splunk Common Information Model (CIM), so events are found by CIM data
+// models and the dashboards built on them.
+package cimhec
+
+import (
+	"strings"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+)
+
+// Mapping renames and normalizes fields
+type Mapping struct {
+	// CIM field names by the names of fields in events
+	Fields map[string]string
+
+	// Normalizers of the values of CIM fields, applied after renaming
+	Values map[string]func(value interface{}) interface{}
+}
+
+// WebMapping maps the fields of access logs, e.g. of the accesslog package,
+// to the Web data model
+var WebMapping = Mapping{
+	Fields: map[string]string{
+		"method":      "http_method",
+		"path":        "uri_path",
+		"query":       "uri_query",
+		"host":        "site",
+		"bytes":       "bytes_out",
+		"remote_addr": "src",
+		"user_agent":  "http_user_agent",
+		"referer":     "http_referrer",
+		"duration_ms": "duration",
+	},
+	Values: map[string]func(interface{}) interface{}{
+		"http_method": Upper,
+	},
+}
+
+// AuthenticationMapping maps common fields of login events to the
+// Authentication data model
+var AuthenticationMapping = Mapping{
+	Fields: map[string]string{
+		"username":    "user",
+		"remote_addr": "src",
+		"result":      "action",
+		"method":      "authentication_method",
+	},
+	Values: map[string]func(interface{}) interface{}{
+		"action": Values(map[string]string{
+			"ok":      "success",
+			"success": "success",
+			"allowed": "success",
+			"fail":    "failure",
+			"failed":  "failure",
+			"failure": "failure",
+			"denied":  "failure",
+		}),
+	},
+}
+
+// Merge returns a mapping with the fields and values of mappings, later ones
+// taking precedence
+func Merge(mappings ...Mapping) Mapping {
+	merged := Mapping{Fields: map[string]string{}, Values: map[string]func(interface{}) interface{}{}}
+	for _, m := range mappings {
+		for from, to := range m.Fields {
+			merged.Fields[from] = to
+		}
+		for field, normalize := range m.Values {
+			merged.Values[field] = normalize
+		}
+	}
+	return merged
+}
+
+// Lower normalizes strings to lower case
+func Lower(value interface{}) interface{} {
+	if s, ok := value.(string); ok {
+		return strings.ToLower(s)
+	}
+	return value
+}
+
+// Upper normalizes strings to upper case
+func Upper(value interface{}) interface{} {
+	if s, ok := value.(string); ok {
+		return strings.ToUpper(s)
+	}
+	return value
+}
+
+// Values returns a normalizer replacing strings by their values in
+// replacements, ignoring case. Other strings are kept.
+func Values(replacements map[string]string) func(interface{}) interface{} {
+	lower := make(map[string]string, len(replacements))
+	for from, to := range replacements {
+		lower[strings.ToLower(from)] = to
+	}
+	return func(value interface{}) interface{} {
+		if s, ok := value.(string); ok {
+			if replacement, ok := lower[strings.ToLower(s)]; ok {
+				return replacement
+			}
+		}
+		return value
+	}
+}
+
+// Apply renames and normalizes the fields of fields in place. A field is not
+// renamed if the CIM field is set already.
+func (m Mapping) Apply(fields map[string]interface{}) {
+	for from, to := range m.Fields {
+		value, ok := fields[from]
+		if !ok || from == to {
+			continue
+		}
+		if _, exists := fields[to]; exists {
+			continue
+		}
+		delete(fields, from)
+		fields[to] = value
+	}
+	for field, normalize := range m.Values {
+		if value, ok := fields[field]; ok {
+			fields[field] = normalize(value)
+		}
+	}
+}
+
+// Enricher returns an enricher for hec.WithEnrichers applying the mapping to
+// the indexed fields of events, and to their data if it is a
+// map[string]interface{}. The data is copied rather than changed.
+func (m Mapping) Enricher() hec.Enricher {
+	return func(event *hec.Event) {
+		m.Apply(event.Fields)
+		if data, ok := event.Event.(map[string]interface{}); ok {
+			copied := make(map[string]interface{}, len(data))
+			for key, value := range data {
+				copied[key] = value
+			}
+			m.Apply(copied)
+			event.Event = copied
+		}
+	}
+}
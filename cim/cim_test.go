@@ -0,0 +1,55 @@
+package cimhec
+
+import (
+	"testing"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+	"github.com/fuyufjh/splunk-hec-go/hectest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testToken = "00000000-0000-0000-0000-000000000000"
+
+func TestApply(t *testing.T) {
+	fields := map[string]interface{}{"method": "get", "path": "/", "src": "10.0.0.1", "remote_addr": "127.0.0.1", "status": 200}
+	WebMapping.Apply(fields)
+	assert.Equal(t, map[string]interface{}{
+		"http_method": "GET",
+		"uri_path":    "/",
+		"src":         "10.0.0.1",
+		"remote_addr": "127.0.0.1",
+		"status":      200,
+	}, fields)
+
+	fields = map[string]interface{}{"username": "alice", "result": "Denied"}
+	AuthenticationMapping.Apply(fields)
+	assert.Equal(t, map[string]interface{}{"user": "alice", "action": "failure"}, fields)
+}
+
+func TestMerge(t *testing.T) {
+	m := Merge(WebMapping, Mapping{Fields: map[string]string{"bytes": "bytes_in"}, Values: map[string]func(interface{}) interface{}{"http_method": Lower}})
+	fields := map[string]interface{}{"bytes": 10, "method": "GET"}
+	m.Apply(fields)
+	assert.Equal(t, map[string]interface{}{"bytes_in": 10, "http_method": "get"}, fields)
+	assert.Equal(t, "bytes_out", WebMapping.Fields["bytes"])
+}
+
+func TestEnricher(t *testing.T) {
+	server := hectest.NewServer(testToken)
+	defer server.Close()
+
+	client := hec.NewClient(server.URL, testToken, hec.WithEnrichers(WebMapping.Enricher()))
+	data := map[string]interface{}{"method": "post", "user_agent": "curl"}
+	event := hec.NewEvent(data)
+	event.SetField("host", "shop")
+	require.NoError(t, client.WriteEvent(event))
+
+	events := server.Events()
+	require.Len(t, events, 1)
+	assert.Equal(t, map[string]interface{}{"http_method": "POST", "http_user_agent": "curl"}, events[0].Event.Event)
+	assert.Equal(t, map[string]interface{}{"site": "shop"}, events[0].Fields)
+	// The event of the caller is unchanged
+	assert.Equal(t, map[string]interface{}{"method": "post", "user_agent": "curl"}, data)
+	assert.Equal(t, map[string]interface{}{"host": "shop"}, event.Fields)
+}
@@ -5,12 +5,19 @@ import (
 	"compress/gzip"
 	"context"
 	"encoding/json"
+	"errors"
+	"expvar"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"regexp"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -22,11 +29,13 @@ const (
 	defaultMaxContentLength = 1000000
 
 	defaultAcknowledgementTimeout = 90 * time.Second
+
+	defaultFlushInterval = 1 * time.Second
+
+	maxBodySnippet = 256
 )
 
 type Client struct {
-	HEC
-
 	// HTTP Client for communication with (optional)
 	httpClient *http.Client
 
@@ -42,11 +51,9 @@ type Client struct {
 	// Channel (required for Raw mode)
 	channel string
 
-	// Max retrying times (optional, default: 2)
-	retries int
-
-	// Max content length (optional, default: 1000000)
-	maxLength int
+	// Current *Settings, replaced as a whole when updated
+	settings    atomic.Value
+	settingsMtx sync.Mutex
 
 	// List of acknowledgement IDs provided by Splunk
 	ackIDs []int
@@ -54,22 +61,145 @@ type Client struct {
 	// Mutex to allow threadsafe acknowledgement checking
 	ackMux sync.Mutex
 
-	// Compression type, "" and "gzip" are supported
-	compression string
+	// Acknowledgement IDs taken out of ackIDs by the wait polling for them,
+	// which other waits for the same IDs block on
+	ackPolls map[int]*ackPoll
+
+	// Requests of acknowledgement IDs given up on after ackLossTimeout (optional)
+	ackLossTimeout time.Duration
+	ackPending     map[int]pendingAck
+
+	// Marshal event keys in canonical (sorted) order (optional, default: false)
+	canonical bool
+
+	// Record boundaries of raw data (optional, default: newline-delimited)
+	rawSplitter splitter
+
+	// Called after each chunk of raw data is sent (optional)
+	rawProgress func(progress RawProgress)
+
+	// Bytes of every raw request reserved for headers (optional, default: 0)
+	rawOverhead int
+
+	// Called for every failed write (optional)
+	errorHandler func(err error, payload PayloadInfo)
+
+	// Called for warnings of responses (optional)
+	warningHandler func(warning Warning)
+
+	// Redacts response headers exposed in errors (optional, default: DefaultRedactor)
+	redactor Redactor
+
+	// Metadata of events and raw data that don't set their own (optional)
+	defaults *EventMetadata
+
+	// Provides the token instead of the static one (optional)
+	tokenProvider TokenProvider
+
+	// How the token is sent (optional, default: AuthSplunk)
+	authScheme AuthScheme
+
+	// Source of time for retries, flushes and acknowledgement polling (optional, default: SystemClock)
+	clock Clock
+
+	// Quotas of events (optional)
+	quotas *quotaTracker
+
+	// How the channel is sent (optional, default: ChannelInQuery)
+	channelMode ChannelMode
+
+	// Limits events are checked against before sending (optional)
+	profile *ValidationProfile
+
+	// Compression buffers and slots of concurrent requests shared by the
+	// clients of a ClientFactory (optional)
+	buffers *sync.Pool
+	workers chan struct{}
+	gauge   *slotGauge
+
+	// Records the payloads of requests in test mode (optional)
+	recorder *TestRecorder
+
+	// Traces requests (optional)
+	tracer RequestTracer
+
+	// Signs requests after their headers are set (optional)
+	signer RequestSigner
+
+	// Receives metrics of requests (optional)
+	metrics MetricsHook
+
+	// Counters published with expvar (optional)
+	vars *expvar.Map
+
+	// Logs requests at debug level (optional)
+	logger *slog.Logger
+
+	// Writes payloads rejected for their data format (optional)
+	dump *payloadDump
+
+	// Latencies of recent requests per endpoint
+	latencies latencyTracker
+
+	// Requests taking longer are logged with a warning (optional, default: 0, disabled)
+	slowThreshold time.Duration
+
+	// Data written successfully by index and sourcetype
+	usage accounting
+
+	// Results of recent writes
+	health health
+
+	// Send the trace context and request ID of callers (optional, default: false)
+	traceContext    bool
+	requestIDHeader string
+
+	// Records dropped events (optional)
+	audit *auditTrail
+
+	// Receives the metadata of every request (optional)
+	requestAudit func(record RequestAuditRecord)
+
+	// Captures sampled requests and responses (optional)
+	capture *WireCapture
+
+	// Add fields or metadata to events before they are sent (optional)
+	enrichers []Enricher
+
+	// Transform events before they are validated, e.g. to redact them (optional)
+	transformers []Transformer
+
+	// Adapts the max events per request of batches (optional)
+	adaptive *AdaptiveBatching
+
+	// Field of events set to a GUID kept across retries (optional)
+	idempotencyField string
 }
 
-func NewClient(serverURL string, token string) HEC {
+// Option configures a client when it is created
+type Option func(client *Client)
+
+func NewClient(serverURL string, token string, options ...Option) HEC {
 	id := uuid.New()
 
-	return &Client{
+	hec := &Client{
 		httpClient: http.DefaultClient,
 		serverURL:  serverURL,
 		token:      token,
 		keepAlive:  true,
 		channel:    id.String(),
-		retries:    2,
-		maxLength:  defaultMaxContentLength,
+		redactor:   DefaultRedactor,
+		clock:      SystemClock,
 	}
+	hec.settings.Store(&Settings{
+		MaxRetries:       2,
+		MaxContentLength: defaultMaxContentLength,
+		FlushInterval:    defaultFlushInterval,
+	})
+	for _, option := range options {
+		option(hec)
+	}
+	return hec
 }
 
 func (hec *Client) SetHTTPClient(client *http.Client) {
@@ -84,30 +214,152 @@ func (hec *Client) SetChannel(channel string) {
 	hec.channel = channel
 }
 
+func (hec *Client) SetChannelMode(mode ChannelMode) {
+	hec.channelMode = mode
+}
+
 func (hec *Client) SetMaxRetry(retries int) {
-	hec.retries = retries
+	hec.updateSettings(func(settings *Settings) { settings.MaxRetries = retries })
 }
 
 func (hec *Client) SetMaxContentLength(size int) {
-	hec.maxLength = size
+	hec.updateSettings(func(settings *Settings) { settings.MaxContentLength = size })
+}
+
+func (hec *Client) SetMaxBatchEvents(n int) {
+	hec.updateSettings(func(settings *Settings) { settings.MaxBatchEvents = n })
 }
 
 func (hec *Client) SetCompression(compression string) {
-	hec.compression = compression
+	hec.updateSettings(func(settings *Settings) { settings.Compression = compression })
+}
+
+func (hec *Client) SetCanonicalJSON(enable bool) {
+	hec.canonical = enable
+}
+
+func (hec *Client) SetRawDelimiter(delimiter string) {
+	hec.rawSplitter.delimiter = []byte(delimiter)
+}
+
+func (hec *Client) SetRawRecordPattern(pattern *regexp.Regexp) {
+	hec.rawSplitter.pattern = pattern
+}
+
+func (hec *Client) SetRawContinuationPattern(pattern *regexp.Regexp) {
+	hec.rawSplitter.continuation = pattern
+}
+
+func (hec *Client) SetMaxLineSize(size int) {
+	hec.rawSplitter.maxLine = size
+}
+
+func (hec *Client) SetRawProgressHandler(handler func(progress RawProgress)) {
+	hec.rawProgress = handler
+}
+
+func (hec *Client) SetRawOverhead(size int) {
+	hec.rawOverhead = size
+}
+
+func (hec *Client) SetFlushInterval(interval time.Duration) {
+	hec.updateSettings(func(settings *Settings) { settings.FlushInterval = interval })
+}
+
+func (hec *Client) SetErrorHandler(handler func(err error, payload PayloadInfo)) {
+	hec.errorHandler = handler
+}
+
+func (hec *Client) SetRedactor(redactor Redactor) {
+	hec.redactor = redactor
+}
+
+func (hec *Client) SetValidationProfile(profile *ValidationProfile) {
+	hec.profile = profile
+}
+
+func (hec *Client) SetQuotas(quotas []Quota) {
+	hec.quotas = newQuotaTracker(quotas)
+}
+
+func (hec *Client) SetClock(clock Clock) {
+	hec.clock = clock
+}
+
+func (hec *Client) SetTokenProvider(provider TokenProvider) {
+	hec.tokenProvider = provider
+}
+
+func (hec *Client) SetAuthScheme(scheme AuthScheme) {
+	hec.authScheme = scheme
+}
+
+func (hec *Client) SetDefaultMetadata(metadata *EventMetadata) {
+	hec.defaults = metadata
 }
 
 func (hec *Client) WriteEventWithContext(ctx context.Context, event *Event) error {
+	_, err := hec.WriteEventWithResponse(ctx, event)
+	return err
+}
+
+func (hec *Client) WriteEventWithResponse(ctx context.Context, event *Event) (*Response, error) {
+	endpoint := hec.endpoint("/services/collector")
+	if len(hec.transformers) > 0 {
+		transformed, err := hec.transform(event)
+		if err != nil {
+			err = &TransformError{Indexes: []int{0}, Errs: []error{err}}
+			hec.reportError(err, PayloadInfo{Endpoint: endpoint, Events: 1})
+			return nil, err
+		}
+		if transformed == nil {
+			return nil, nil // dropped by a transformer
+		}
+		event = transformed
+	}
+	event = hec.withIdempotencyKey(event)
 	if event.empty() {
-		return nil // skip empty events
+		return nil, nil // skip empty events
 	}
 
-	endpoint := "/services/collector?channel=" + hec.channel
-	data, _ := json.Marshal(event)
-
-	if len(data) > hec.maxLength {
-		return ErrEventTooLong
+	// Enriched once, so the event validated, admitted and accounted is the one sent
+	accounted := hec.withDefaults(event)
+	data, _ := hec.marshal(accounted)
+
+	maxLength := hec.current().MaxContentLength
+	var response *Response
+	var err error
+	if len(data) > maxLength {
+		err = &EventTooLongError{
+			Indexes: []int{0},
+			Sizes:   []int{len(data)},
+			Events:  []*Event{event},
+			Limit:   maxLength,
+		}
+	} else if reason := hec.validate(accounted, len(data)); reason != "" {
+		err = &InvalidEventError{Profile: hec.profile.Name, Indexes: []int{0}, Reasons: []string{reason}}
+	} else {
+		switch err = hec.quotas.admit(ctx, hec.clock, accounted, len(data)); err {
+		case nil:
+			response, err = hec.send(ctx, endpoint, data)
+			if err == nil {
+				usage := make(usageSet)
+				usage.add(accounted.Index, accounted.SourceType, 1, int64(len(data)))
+				hec.usage.merge(usage)
+			}
+		case errQuotaDropped:
+			hec.count("dropped")
+			hec.auditEvents(err, []*Event{event})
+			return nil, nil
+		case ErrQuotaExceeded:
+			err = &QuotaExceededError{Indexes: []int{0}}
+		}
 	}
-	return hec.write(ctx, endpoint, data)
+	if err != nil {
+		hec.reportError(err, PayloadInfo{Endpoint: endpoint, Size: len(data), Events: 1})
+		hec.auditEvents(err, []*Event{event})
+	}
+	return response, err
 }
 
 func (hec *Client) WriteEvent(event *Event) error {
@@ -115,49 +367,246 @@ func (hec *Client) WriteEvent(event *Event) error {
 }
 
 func (hec *Client) WriteBatchWithContext(ctx context.Context, events []*Event) error {
+	_, err := hec.WriteBatchWithResponses(ctx, events)
+	return err
+}
+
+func (hec *Client) WriteBatchWithResponses(ctx context.Context, events []*Event) ([]*Response, error) {
 	if len(events) == 0 {
-		return nil
+		return nil, nil
 	}
 
-	endpoint := "/services/collector?channel=" + hec.channel
+	endpoint := hec.endpoint("/services/collector")
 	var buffer bytes.Buffer
-	var tooLongs []int
+	settings := hec.current()
+	maxLength := settings.MaxContentLength
+	maxEvents := hec.maxBatchEvents(settings)
+	tooLongs := &EventTooLongError{Limit: maxLength}
+	overQuota := &QuotaExceededError{}
+	var invalid *InvalidEventError
+	if hec.profile != nil {
+		invalid = &InvalidEventError{Profile: hec.profile.Name}
+	}
+	// Indexes in events of the events in buffer, and their usage
+	var buffered []int
+	usage := make(usageSet)
+	var responses []*Response
+	var failed *TransformError
+	if len(hec.transformers) > 0 {
+		events, failed = hec.transformAll(events)
+	}
+	events = hec.withIdempotencyKeys(events)
 
 	for index, event := range events {
-		if event.empty() {
-			continue // skip empty events
+		if event == nil || event.empty() {
+			continue // skip dropped and empty events
 		}
 
-		data, _ := json.Marshal(event)
-		if len(data) > hec.maxLength {
-			tooLongs = append(tooLongs, index)
+		accounted := hec.withDefaults(event)
+		data, _ := hec.marshal(accounted)
+		if len(data) > maxLength {
+			tooLongs.add(index, len(data), event)
+			continue
+		}
+		if reason := hec.validate(accounted, len(data)); reason != "" {
+			invalid.add(index, reason)
 			continue
 		}
-		// Send out bytes in buffer immediately if the limit exceeded after adding this event
-		if buffer.Len()+len(data) > hec.maxLength {
-			if err := hec.write(ctx, endpoint, buffer.Bytes()); err != nil {
-				return err
+		switch err := hec.quotas.admit(ctx, hec.clock, accounted, len(data)); err {
+		case nil:
+		case errQuotaDropped:
+			hec.count("dropped")
+			hec.auditEvents(err, []*Event{event})
+			continue
+		case ErrQuotaExceeded:
+			overQuota.Indexes = append(overQuota.Indexes, index)
+			continue
+		default:
+			return responses, err
+		}
+		// Send out bytes in buffer immediately if a limit exceeded after adding this event
+		if buffer.Len()+len(data) > maxLength || maxEvents > 0 && len(buffered) >= maxEvents {
+			response, err := hec.writeBatchChunk(ctx, endpoint, buffer.Bytes(), buffered)
+			if err != nil {
+				hec.auditEvents(err, eventsAt(events, buffered))
+				return responses, err
 			}
+			responses = append(responses, response)
+			hec.usage.merge(usage)
 			buffer.Reset()
+			buffered = buffered[:0]
+			usage = make(usageSet)
+			maxEvents = hec.maxBatchEvents(settings)
 		}
 		buffer.Write(data)
+		buffered = append(buffered, index)
+		usage.add(accounted.Index, accounted.SourceType, 1, int64(len(data)))
 	}
 
 	if buffer.Len() > 0 {
-		if err := hec.write(ctx, endpoint, buffer.Bytes()); err != nil {
-			return err
+		response, err := hec.writeBatchChunk(ctx, endpoint, buffer.Bytes(), buffered)
+		if err != nil {
+			hec.auditEvents(err, eventsAt(events, buffered))
+			return responses, err
 		}
+		responses = append(responses, response)
+		hec.usage.merge(usage)
 	}
-	if len(tooLongs) > 0 {
-		return ErrEventTooLong
+	// Report all skipped events, but return only the first kind of error
+	var err error
+	if failed != nil && len(failed.Indexes) > 0 {
+		hec.reportError(failed, PayloadInfo{Endpoint: endpoint, Events: len(failed.Indexes)})
+		err = failed
 	}
-	return nil
+	if invalid != nil && len(invalid.Indexes) > 0 {
+		hec.reportError(invalid, PayloadInfo{Endpoint: endpoint, Events: len(invalid.Indexes)})
+		hec.auditEvents(invalid, eventsAt(events, invalid.Indexes))
+		err = invalid
+	}
+	if len(overQuota.Indexes) > 0 {
+		hec.reportError(overQuota, PayloadInfo{Endpoint: endpoint, Events: len(overQuota.Indexes)})
+		hec.auditEvents(overQuota, eventsAt(events, overQuota.Indexes))
+		err = overQuota
+	}
+	if len(tooLongs.Indexes) > 0 {
+		size := 0
+		for _, s := range tooLongs.Sizes {
+			size += s
+		}
+		hec.reportError(tooLongs, PayloadInfo{Endpoint: endpoint, Size: size, Events: len(tooLongs.Indexes)})
+		hec.auditEvents(tooLongs, tooLongs.Events)
+		err = tooLongs
+	}
+	return responses, err
+}
+
+// writeBatchChunk writes a chunk of a batch. If HEC rejects one of its events,
+// the invalid event number of the returned response is translated from the
+// position in the chunk into the index of the event in the batch.
+func (hec *Client) writeBatchChunk(ctx context.Context, endpoint string, chunk []byte, indexes []int) (*Response, error) {
+	startTime := hec.clock.Now()
+	response, err := hec.send(ctx, endpoint, chunk)
+	if hec.adaptive != nil {
+		hec.adaptive.observe(hec.clock.Now().Sub(startTime), err)
+	}
+	if res, ok := err.(*Response); ok && res.InvalidEventNumber != nil {
+		if n := *res.InvalidEventNumber; n >= 0 && n < len(indexes) {
+			res.InvalidEventNumber = Int(indexes[n])
+		}
+	}
+	if err != nil {
+		hec.reportError(err, PayloadInfo{Endpoint: endpoint, Size: len(chunk), Events: len(indexes)})
+	}
+	return response, err
 }
 
 func (hec *Client) WriteBatch(events []*Event) error {
 	return hec.WriteBatchWithContext(context.Background(), events)
 }
 
+func (hec *Client) WriteBatchWithMetadata(events []*Event, defaults *EventMetadata) error {
+	return hec.WriteBatchWithMetadataContext(context.Background(), events, defaults)
+}
+
+func (hec *Client) WriteBatchWithMetadataContext(ctx context.Context, events []*Event, defaults *EventMetadata) error {
+	return hec.WriteBatchWithContext(ctx, withMetadata(events, defaults))
+}
+
+// withMetadata returns events with the missing metadata set to defaults,
+// copying the events of the caller rather than modifying them
+func withMetadata(events []*Event, defaults *EventMetadata) []*Event {
+	if defaults == nil {
+		return events
+	}
+	result := make([]*Event, len(events))
+	for i, event := range events {
+		if event == nil {
+			continue
+		}
+		copied := *event
+		copied.Host = firstNonNil(event.Host, defaults.Host)
+		copied.Index = firstNonNil(event.Index, defaults.Index)
+		copied.Source = firstNonNil(event.Source, defaults.Source)
+		copied.SourceType = firstNonNil(event.SourceType, defaults.SourceType)
+		copied.Time = defaultTime(event.Time, defaults.Time)
+		result[i] = &copied
+	}
+	return result
+}
+
+// marshal serializes an event with the defaults applied by withDefaults. In
+// canonical mode, the keys of every object (including the event envelope and
+// structs inside the event data) are sorted, which gives deterministic
+// payloads and better gzip ratios.
+func (hec *Client) marshal(event *Event) ([]byte, error) {
+	data, err := json.Marshal(event)
+	if err != nil || !hec.canonical {
+		return data, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var generic interface{}
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return json.Marshal(generic)
+}
+
+// withDefaults returns event, or a copy of it with the missing metadata set to
+// the defaults and passed through the enrichers
+func (hec *Client) withDefaults(event *Event) *Event {
+	d := hec.defaults
+	if d == nil && len(hec.enrichers) == 0 {
+		return event
+	}
+	copied := *event
+	if d != nil {
+		copied.Host = firstNonNil(event.Host, d.Host)
+		copied.Index = firstNonNil(event.Index, d.Index)
+		copied.Source = firstNonNil(event.Source, d.Source)
+		copied.SourceType = firstNonNil(event.SourceType, d.SourceType)
+		copied.Time = defaultTime(event.Time, d.Time)
+	}
+	if len(hec.enrichers) > 0 {
+		hec.enrich(&copied)
+	}
+	return &copied
+}
+
+// rawEndpoint returns the raw endpoint for metadata, with the missing metadata set to the defaults
+func (hec *Client) rawEndpoint(metadata *EventMetadata) string {
+	if hec.defaults != nil {
+		merged := EventMetadata{}
+		if metadata != nil {
+			merged = *metadata
+		}
+		merged.Host = firstNonNil(merged.Host, hec.defaults.Host)
+		merged.Index = firstNonNil(merged.Index, hec.defaults.Index)
+		merged.Source = firstNonNil(merged.Source, hec.defaults.Source)
+		merged.SourceType = firstNonNil(merged.SourceType, hec.defaults.SourceType)
+		if merged.Time == nil {
+			merged.Time = hec.defaults.Time
+		}
+		metadata = &merged
+	}
+	return rawHecEndpoint(hec.queryChannel(), metadata)
+}
+
+func firstNonNil(value *string, fallback *string) *string {
+	if value != nil {
+		return value
+	}
+	return fallback
+}
+
+// defaultTime returns the time of an event, or fallback in epoch format if it has none
+func defaultTime(value *string, fallback *time.Time) *string {
+	if value != nil || fallback == nil {
+		return value
+	}
+	return String(epochTime(fallback))
+}
+
 type EventMetadata struct {
 	Host       *string
 	Index      *string
@@ -167,14 +616,122 @@ type EventMetadata struct {
 }
 
 func (hec *Client) WriteRawWithContext(ctx context.Context, reader io.ReadSeeker, metadata *EventMetadata) error {
-	endpoint := rawHecEndpoint(hec.channel, metadata)
+	endpoint := hec.rawEndpoint(metadata)
+	return hec.writeRawStream(ctx, reader, endpoint, func(int, []byte) string {
+		return endpoint
+	}, nil)
+}
 
-	return breakStream(reader, hec.maxLength, func(chunk []byte) error {
-		if err := hec.write(ctx, endpoint, chunk); err != nil {
-			// Ignore NoData error (e.g. "\n\n" will cause NoData error)
-			if res, ok := err.(*Response); !ok || res.Code != StatusNoData {
-				return err
+// WriteRawWithMetadataFunc writes raw data stream via HEC raw mode with the
+// metadata of every chunk given by metadataFunc. The size of its query string
+// is not known before chunking, so leave room for it with SetRawOverhead.
+func (hec *Client) WriteRawWithMetadataFunc(ctx context.Context, reader io.ReadSeeker, metadataFunc func(index int, chunk []byte) *EventMetadata) error {
+	return hec.writeRawStream(ctx, reader, hec.rawEndpoint(nil), func(index int, chunk []byte) string {
+		return hec.rawEndpoint(metadataFunc(index, chunk))
+	}, nil)
+}
+
+// WriteRawFromOffset writes raw data stream from offset via HEC raw mode, and
+// calls checkpoint with the offset after every chunk accepted by HEC. An
+// interrupted upload can be resumed from the last checkpointed offset. To only
+// checkpoint indexed data, call WaitForAcknowledgement in checkpoint.
+func (hec *Client) WriteRawFromOffset(ctx context.Context, reader io.ReadSeeker, metadata *EventMetadata, offset int64, checkpoint func(offset int64) error) error {
+	size, err := reader.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if _, err := reader.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	endpoint := hec.rawEndpoint(metadata)
+	return hec.writeRawStream(ctx, reader, endpoint, func(int, []byte) string {
+		return endpoint
+	}, func(chunk []byte) error {
+		// The last chunk may end with a terminator which is not in the stream
+		if offset += int64(len(chunk)); offset > size {
+			offset = size
+		}
+		return checkpoint(offset)
+	})
+}
+
+// WriteRawWithTimeFunc writes raw data stream via HEC raw mode with the time of
+// every line extracted by timeFunc. Consecutive lines with the same time are
+// sent together with the time parameter. Lines without time (timeFunc returns
+// false) keep the time of the previous line, or the time in metadata.
+func (hec *Client) WriteRawWithTimeFunc(ctx context.Context, reader io.ReadSeeker, metadata *EventMetadata, timeFunc func(line []byte) (time.Time, bool)) error {
+	var groupMetadata EventMetadata
+	if metadata != nil {
+		groupMetadata = *metadata
+	}
+	groupMetadata.Time = &time.Time{}
+	limit, err := hec.rawChunkLimit(hec.rawEndpoint(&groupMetadata))
+	if err != nil {
+		return err
+	}
+	groupMetadata.Time = nil
+	if metadata != nil {
+		groupMetadata.Time = metadata.Time
+	}
+
+	terminator := hec.rawSplitter.terminator()
+	var group bytes.Buffer
+	flush := func() error {
+		if group.Len() == 0 {
+			return nil
+		}
+		err := hec.writeRaw(ctx, hec.rawEndpoint(&groupMetadata), group.Bytes())
+		group.Reset()
+		return err
+	}
+
+	err = breakStream(reader, limit, hec.rawSplitter, func(chunk []byte) error {
+		return hec.rawSplitter.forEachLine(chunk, func(line []byte) error {
+			if t, ok := timeFunc(line); ok && (groupMetadata.Time == nil || epochTime(&t) != epochTime(groupMetadata.Time)) {
+				if err := flush(); err != nil {
+					return err
+				}
+				groupMetadata.Time = &t
+			}
+			if group.Len()+len(line)+len(terminator) > limit {
+				if err := flush(); err != nil {
+					return err
+				}
 			}
+			group.Write(line)
+			group.Write(terminator)
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+	return flush()
+}
+
+// writeRawStream writes raw data stream to the endpoint of every chunk, and
+// calls onSent (if not nil) after each of them. The chunk size is limited
+// according to the length of limitEndpoint.
+func (hec *Client) writeRawStream(ctx context.Context, reader io.Reader, limitEndpoint string, endpointFunc func(index int, chunk []byte) string, onSent func(chunk []byte) error) error {
+	limit, err := hec.rawChunkLimit(limitEndpoint)
+	if err != nil {
+		return err
+	}
+
+	var progress RawProgress
+	index := 0
+	return breakStream(reader, limit, hec.rawSplitter, func(chunk []byte) error {
+		if err := hec.writeRaw(ctx, endpointFunc(index, chunk), chunk); err != nil {
+			return err
+		}
+		index++
+		if hec.rawProgress != nil {
+			progress.add(chunk, hec.rawSplitter)
+			hec.rawProgress(progress)
+		}
+		if onSent != nil {
+			return onSent(chunk)
 		}
 		return nil
 	})
@@ -184,6 +741,41 @@ func (hec *Client) WriteRaw(reader io.ReadSeeker, metadata *EventMetadata) error
 	return hec.WriteRawWithContext(context.Background(), reader, metadata)
 }
 
+func (hec *Client) WriteRawString(data string, metadata *EventMetadata) error {
+	return hec.WriteRaw(strings.NewReader(data), metadata)
+}
+
+func (hec *Client) WriteRawLine(line string, metadata *EventMetadata) error {
+	return hec.WriteRawLineWithContext(context.Background(), line, metadata)
+}
+
+func (hec *Client) WriteRawLineWithContext(ctx context.Context, line string, metadata *EventMetadata) error {
+	data := []byte(line)
+	if terminator := hec.rawSplitter.terminator(); !bytes.HasSuffix(data, terminator) {
+		data = append(data, terminator...)
+	}
+	endpoint := hec.rawEndpoint(metadata)
+	limit, err := hec.rawChunkLimit(endpoint)
+	if err != nil {
+		return err
+	}
+	if len(data) > limit {
+		return newLineTooLongError(1, data, limit)
+	}
+	return hec.writeRaw(ctx, endpoint, data)
+}
+
+func (hec *Client) WriteRawChannel(ctx context.Context, records <-chan []byte, metadata *EventMetadata) error {
+	endpoint := hec.rawEndpoint(metadata)
+	limit, err := hec.rawChunkLimit(endpoint)
+	if err != nil {
+		return err
+	}
+	return streamRaw(ctx, records, limit, hec.current().FlushInterval, hec.clock, hec.rawSplitter, func(chunk []byte) error {
+		return hec.writeRaw(ctx, endpoint, chunk)
+	})
+}
+
 type acknowledgementRequest struct {
 	Acks []int `json:"acks"`
 }
@@ -193,28 +785,84 @@ type acknowledgementRequest struct {
 // indexed or if the provided context is cancelled. This requires the HEC token
 // configuration in Splunk to have indexer acknowledgement enabled.
 func (hec *Client) WaitForAcknowledgementWithContext(ctx context.Context) error {
-	// Make our own copy of the list of acknowledgement IDs and remove them
-	// from the client while we check them.
+	// IDs being polled for by other waits are waited for as well
 	hec.ackMux.Lock()
-	ackIDs := hec.ackIDs
-	hec.ackIDs = nil
+	ackIDs := append([]int(nil), hec.ackIDs...)
+	for ackID := range hec.ackPolls {
+		ackIDs = append(ackIDs, ackID)
+	}
 	hec.ackMux.Unlock()
+	return hec.waitForAcks(ctx, ackIDs)
+}
 
+// waitForAcks waits for ackIDs only. It polls for the IDs still pending,
+// taking them out of the IDs of the client while it checks them, and blocks
+// until other waits polling for the rest are done with them. IDs neither
+// pending nor being polled for were acknowledged or given up on already.
+func (hec *Client) waitForAcks(ctx context.Context, ackIDs []int) error {
+	var lost []LostAck
+	for len(ackIDs) > 0 {
+		hec.ackMux.Lock()
+		var taken []int
+		polls := make(map[int]*ackPoll)
+		for _, ackID := range ackIDs {
+			if remaining := remove(hec.ackIDs, ackID); len(remaining) < len(hec.ackIDs) {
+				hec.ackIDs = remaining
+				hec.startPoll(ackID)
+				taken = append(taken, ackID)
+			} else if poll, ok := hec.ackPolls[ackID]; ok {
+				polls[ackID] = poll
+			}
+		}
+		hec.ackMux.Unlock()
+
+		err := hec.pollAcks(ctx, taken)
+		var lostErr *AckLostError
+		if errors.As(err, &lostErr) {
+			lost = append(lost, lostErr.Lost...)
+		} else if err != nil {
+			return err
+		}
+
+		// IDs put back by a failed wait are polled for again
+		ackIDs = nil
+		for ackID, poll := range polls {
+			select {
+			case <-poll.done:
+			case <-ctx.Done():
+				return contextError(ctx)
+			}
+			if poll.lost != nil {
+				lost = append(lost, *poll.lost)
+			} else if !poll.acked {
+				ackIDs = append(ackIDs, ackID)
+			}
+		}
+	}
+	if len(lost) > 0 {
+		return &AckLostError{Lost: lost}
+	}
+	return nil
+}
+
+// pollAcks polls the indexer until ackIDs, taken out of the IDs of the client
+// with startPoll, are acknowledged or given up on. The IDs left on failure
+// are put back into the IDs of the client.
+func (hec *Client) pollAcks(ctx context.Context, ackIDs []int) error {
 	if len(ackIDs) == 0 {
 		return nil
 	}
 
-	endpoint := "/services/collector/ack?channel=" + hec.channel
+	endpoint := hec.endpoint("/services/collector/ack")
+	var lost []LostAck
+	highestAcked := -1
 
 	for {
 		ackRequestData, _ := json.Marshal(acknowledgementRequest{Acks: ackIDs})
 
 		response, err := hec.makeRequest(ctx, endpoint, ackRequestData)
 		if err != nil {
-			// Put the remaining unacknowledged IDs back
-			hec.ackMux.Lock()
-			hec.ackIDs = append(hec.ackIDs, ackIDs...)
-			hec.ackMux.Unlock()
+			hec.putBackAcks(ackIDs)
 			return err
 		}
 
@@ -222,13 +870,24 @@ func (hec *Client) WaitForAcknowledgementWithContext(ctx context.Context) error
 			if status {
 				ackID, err := strconv.Atoi(ackIDString)
 				if err != nil {
+					hec.putBackAcks(ackIDs)
 					return fmt.Errorf("could not convert ack ID to int: %v", err)
 				}
 
 				ackIDs = remove(ackIDs, ackID)
+				hec.ackDone(ackID)
+				highestAcked = max(highestAcked, ackID)
 			}
 		}
 
+		// IDs missing from the response are not acknowledged either
+		var newlyLost []LostAck
+		ackIDs, newlyLost = hec.lostAcks(ackIDs, highestAcked)
+		for _, ack := range newlyLost {
+			hec.reportError(&AckLostError{Lost: []LostAck{ack}}, ack.Payload)
+		}
+		lost = append(lost, newlyLost...)
+
 		if len(ackIDs) == 0 {
 			break
 		}
@@ -236,17 +895,17 @@ func (hec *Client) WaitForAcknowledgementWithContext(ctx context.Context) error
 		// If the server did not indicate that all acknowledgements have been
 		// made, check again after a short delay.
 		select {
-		case <-time.After(retryWaitTime):
+		case <-hec.clock.After(retryWaitTime):
 			continue
 		case <-ctx.Done():
-			// Put the remaining unacknowledged IDs back
-			hec.ackMux.Lock()
-			hec.ackIDs = append(hec.ackIDs, ackIDs...)
-			hec.ackMux.Unlock()
-			return ctx.Err()
+			hec.putBackAcks(ackIDs)
+			return contextError(ctx)
 		}
 	}
 
+	if len(lost) > 0 {
+		return &AckLostError{Lost: lost}
+	}
 	return nil
 }
 
@@ -260,54 +919,15 @@ func (hec *Client) WaitForAcknowledgement() error {
 	return hec.WaitForAcknowledgementWithContext(ctx)
 }
 
-// breakStream breaks text from reader into chunks, with every chunk less than max.
-// Unless a single line is longer than max, it always cut at end of lines ("\n")
-func breakStream(reader io.ReadSeeker, max int, callback func(chunk []byte) error) error {
-
-	var buf []byte = make([]byte, max+1)
-	var writeAt int
-	for {
-		n, err := reader.Read(buf[writeAt:max])
-		if n == 0 && err == io.EOF {
-			break
-		}
-
-		// If last line does not end with LF, add one for it
-		if err == io.EOF && buf[writeAt+n-1] != '\n' {
-			n++
-			buf[writeAt+n-1] = '\n'
-		}
-
-		data := buf[0 : writeAt+n]
-
-		// Cut after the last LF character
-		cut := bytes.LastIndexByte(data, '\n') + 1
-		if cut == 0 {
-			// This line is too long, but just let it break here
-			cut = len(data)
-		}
-		if err := callback(buf[:cut]); err != nil {
-			return err
-		}
-
-		writeAt = copy(buf, data[cut:])
-
-		if err != nil && err != io.EOF {
-			return err
+func responseFrom(body []byte, statusCode int) (*Response, error) {
+	var res Response
+	if err := json.Unmarshal(body, &res); err != nil {
+		if len(body) > maxBodySnippet {
+			body = body[:maxBodySnippet]
 		}
+		return nil, &BadResponseError{StatusCode: statusCode, Body: string(body), Err: err}
 	}
-
-	if writeAt != 0 {
-		return callback(buf[:writeAt])
-	}
-
-	return nil
-}
-
-func responseFrom(body []byte) *Response {
-	var res Response
-	json.Unmarshal(body, &res)
-	return &res
+	return &res, nil
 }
 
 func (res *Response) Error() string {
@@ -320,68 +940,182 @@ func (res *Response) String() string {
 }
 
 func (hec *Client) makeRequest(ctx context.Context, endpoint string, data []byte) (*Response, error) {
+	settings := hec.current()
 	retries := 0
+	var attempts []Attempt
+	refreshed := false
 RETRY:
+	token, err := hec.currentToken(ctx)
+	if err != nil {
+		return nil, err
+	}
 	var reader io.Reader
-	if hec.compression == "gzip" {
-		var buffer bytes.Buffer
-		gzipWriter := gzip.NewWriter(&buffer)
+	var compressed *bytes.Buffer
+	if settings.Compression == "gzip" {
+		compressed = hec.getBuffer()
+		gzipWriter := gzip.NewWriter(compressed)
 		_, err := gzipWriter.Write(data)
 		gzipWriter.Close()
 		if err != nil {
+			hec.putBuffer(compressed)
 			return nil, err
 		}
-		reader = &buffer
+		reader = compressed
 	} else {
 		reader = bytes.NewReader(data)
 	}
+	wireSize := len(data)
+	if compressed != nil {
+		wireSize = compressed.Len()
+	}
+	entry := requestLog{endpoint: endpoint, attempt: retries + 1, size: len(data), wireSize: wireSize}
 
 	req, err := http.NewRequest(http.MethodPost, hec.serverURL+endpoint, reader)
 	if err != nil {
 		return nil, err
 	}
-	req = req.WithContext(ctx)
+	// Set before tracing, so the trace context of a tracer takes precedence
+	hec.injectTraceContext(ctx, req.Header)
+	reqCtx, finish := hec.startRequest(ctx, RequestInfo{
+		Server:   redactURL(hec.serverURL),
+		Endpoint: endpoint,
+		Size:     len(data),
+		Attempt:  retries + 1,
+		Header:   req.Header,
+	})
+	req = req.WithContext(reqCtx)
 	if hec.keepAlive {
 		req.Header.Set("Connection", "keep-alive")
 	}
-	req.Header.Set("Authorization", "Splunk "+hec.token)
-	if hec.compression == "gzip" {
+	if hec.channelMode == ChannelInHeader {
+		req.Header.Set("X-Splunk-Request-Channel", hec.channel)
+	}
+	req.Header.Set("Authorization", hec.authScheme.header(token))
+	if settings.Compression == "gzip" {
 		req.Header.Set("Content-Encoding", "gzip")
 	}
-	res, err := hec.httpClient.Do(req)
+	if hec.signer != nil {
+		if err := hec.signer.SignRequest(req); err != nil {
+			hec.putBuffer(compressed)
+			err = fmt.Errorf("Failed to sign request: %w", err)
+			finish(RequestResult{Err: err})
+			return nil, err
+		}
+	}
+	hec.recorder.record(endpoint, data)
+	startTime := hec.clock.Now()
+	entry.startTime = startTime
+	res, body, err := hec.do(reqCtx, req)
+	hec.putBuffer(compressed)
+	if hec.capture.sampled() {
+		hec.captureRequest(req, token, data, res, body, err)
+	}
+	entry.duration = hec.clock.Now().Sub(startTime)
 	if err != nil {
+		err = hec.requestError(ctx, endpoint, retries, data, startTime, err)
+		finish(RequestResult{Err: err})
+		entry.err = err
+		hec.logRequest(ctx, data, entry)
 		return nil, err
 	}
 
-	body, err := ioutil.ReadAll(res.Body)
-	res.Body.Close()
+	entry.statusCode = res.StatusCode
+	response, err := responseFrom(body, res.StatusCode)
 	if err != nil {
+		hec.checkWarnings(endpoint, res, nil)
+		finish(RequestResult{StatusCode: res.StatusCode, Err: err})
+		entry.err = err
+		hec.logRequest(ctx, data, entry)
 		return nil, err
 	}
+	finish(RequestResult{StatusCode: res.StatusCode, Code: response.Code})
+	hec.checkWarnings(endpoint, res, response)
+	entry.response = response
+	hec.logRequest(ctx, data, entry)
+	response.StatusCode = res.StatusCode
+	response.Body = body
+	response.Header = redactHeader(res.Header, hec.redactor, token)
+
+	if hec.tokenProvider != nil && !refreshed && tokenRejected(res.StatusCode, response.Code) {
+		// Retry once with a new token, the old one may have been rotated
+		if invalidator, ok := hec.tokenProvider.(TokenInvalidator); ok {
+			invalidator.InvalidateToken(token)
+		}
+		refreshed = true
+		goto RETRY
+	}
 
-	response := responseFrom(body)
-
-	if res.StatusCode != http.StatusOK {
-		if retriable(response.Code) && retries < hec.retries {
+	if res.StatusCode != http.StatusOK && retriable(response.Code) {
+		attempts = append(attempts, attemptOf(redactURL(hec.serverURL), startTime, response))
+		if retries < settings.MaxRetries {
 			retries++
-			time.Sleep(retryWaitTime)
+			hec.count("retried")
+			hec.clock.Sleep(retryWaitTime)
 			goto RETRY
 		}
+		if retries > 0 {
+			return nil, &RetriesExhaustedError{Attempts: attempts, Last: response}
+		}
 	}
 
 	return response, nil
 }
 
+// do sends a request and reads the body of its response
+func (hec *Client) do(ctx context.Context, req *http.Request) (*http.Response, []byte, error) {
+	if hec.workers != nil {
+		select {
+		case hec.workers <- struct{}{}:
+			hec.gauge.acquire()
+			defer func() {
+				hec.gauge.release()
+				<-hec.workers
+			}()
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+	res, err := hec.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	return res, body, err
+}
+
+func (hec *Client) requestError(ctx context.Context, endpoint string, retries int, data []byte, startTime time.Time, err error) error {
+	return &RequestError{
+		Reason:  failureReason(ctx, err),
+		URL:     redactURL(hec.serverURL + endpoint),
+		Attempt: retries + 1,
+		Size:    len(data),
+		Elapsed: hec.clock.Now().Sub(startTime),
+		Err:     err,
+	}
+}
+
 func (hec *Client) write(ctx context.Context, endpoint string, data []byte) error {
+	_, err := hec.send(ctx, endpoint, data)
+	return err
+}
+
+// send writes data to endpoint and returns the response of a successful write
+func (hec *Client) send(ctx context.Context, endpoint string, data []byte) (*Response, error) {
 	response, err := hec.makeRequest(ctx, endpoint, data)
 	if err != nil {
-		return err
+		hec.health.failed(hec.clock.Now(), err)
+		return nil, err
 	}
 
 	// TODO: find out the correct code
 	if response.Text != "Success" {
-		return response
+		if !errors.Is(response, ErrNoData) {
+			hec.health.failed(hec.clock.Now(), response)
+		}
+		return nil, response
 	}
+	hec.health.succeeded(hec.clock.Now())
 
 	// Check for acknowledgement IDs and store them if provided
 	if response.AckID != nil {
@@ -389,31 +1123,80 @@ func (hec *Client) write(ctx context.Context, endpoint string, data []byte) erro
 		defer hec.ackMux.Unlock()
 
 		hec.ackIDs = append(hec.ackIDs, *response.AckID)
+		hec.trackAck(*response.AckID, endpoint, data)
+		response.ack = &Ack{ID: *response.AckID, Server: redactURL(hec.serverURL), Channel: hec.channel, client: hec}
 	}
 
+	hec.count("sent")
+	return response, nil
+}
+
+// rawChunkLimit returns the max size of raw data sent to endpoint in one request.
+// Once an overhead is configured, the metadata query string is accounted as well.
+func (hec *Client) rawChunkLimit(endpoint string) (int, error) {
+	maxLength := hec.current().MaxContentLength
+	limit := maxLength
+	if hec.rawOverhead > 0 {
+		limit -= hec.rawOverhead + len(endpoint)
+	}
+	if limit <= 0 {
+		return 0, fmt.Errorf("Max content length %d leaves no room for raw data", maxLength)
+	}
+	return limit, nil
+}
+
+// writeRaw writes a chunk of raw data
+func (hec *Client) writeRaw(ctx context.Context, endpoint string, chunk []byte) error {
+	if err := hec.write(ctx, endpoint, chunk); err != nil {
+		// Ignore NoData error (e.g. "\n\n" will cause NoData error)
+		if !errors.Is(err, ErrNoData) {
+			hec.reportError(err, PayloadInfo{Endpoint: endpoint, Size: len(chunk)})
+			hec.auditRaw(err, chunk)
+			return err
+		}
+		return nil
+	}
+	hec.accountRaw(endpoint, chunk)
 	return nil
 }
 
+func (hec *Client) reportError(err error, payload PayloadInfo) {
+	hec.count("failed")
+	if hec.errorHandler != nil {
+		hec.errorHandler(err, payload)
+	}
+}
+
+// rawHecEndpoint returns the raw endpoint with metadata, escaped in the query
+// string. The channel is not added to the query string if it is empty.
 func rawHecEndpoint(channel string, metadata *EventMetadata) string {
 	var buffer bytes.Buffer
-	buffer.WriteString("/services/collector/raw?channel=" + channel)
+	buffer.WriteString("/services/collector/raw")
+	separator := "?"
+	param := func(name string, value string) {
+		buffer.WriteString(separator + name + "=" + url.QueryEscape(value))
+		separator = "&"
+	}
+	if channel != "" {
+		param("channel", channel)
+	}
 	if metadata == nil {
 		return buffer.String()
 	}
 	if metadata.Host != nil {
-		buffer.WriteString("&host=" + *metadata.Host)
+		param("host", *metadata.Host)
 	}
 	if metadata.Index != nil {
-		buffer.WriteString("&index=" + *metadata.Index)
+		param("index", *metadata.Index)
 	}
 	if metadata.Source != nil {
-		buffer.WriteString("&source=" + *metadata.Source)
+		param("source", *metadata.Source)
 	}
 	if metadata.SourceType != nil {
-		buffer.WriteString("&sourcetype=" + *metadata.SourceType)
+		param("sourcetype", *metadata.SourceType)
 	}
 	if metadata.Time != nil {
-		buffer.WriteString("&time=" + epochTime(metadata.Time))
+		param("time", epochTime(metadata.Time))
 	}
 	return buffer.String()
 }
@@ -2,10 +2,14 @@ package hec
 
 import (
 	"compress/gzip"
+	"context"
 	"crypto/tls"
 	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
 	"time"
@@ -249,25 +253,590 @@ func TestHEC_WriteRawFailure(t *testing.T) {
 	assert.Error(t, err)
 }
 
-func TestBreakStream(t *testing.T) {
-	text := "This is line A\nThis is line B" // length of every line is 14
+func TestHEC_CanonicalJSON(t *testing.T) {
+	event := &Event{
+		Host:       String("localhost"),
+		SourceType: String("manual"),
+		Event: struct {
+			Zebra int    `json:"zebra"`
+			Alpha string `json:"alpha"`
+		}{1, "a"},
+	}
 
-	getCountFunc := func(counter *int) func(chunk []byte) error {
-		// returned function adds count of all character except "\n"
-		return func(chunk []byte) error {
-			for _, b := range chunk {
-				if b != '\n' {
-					*counter++
-				}
-			}
-			return nil
+	c := NewClient(testSplunkURL, testSplunkToken).(*Client)
+	data, err := c.marshal(event)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"host":"localhost","sourcetype":"manual","event":{"zebra":1,"alpha":"a"}}`, string(data))
+
+	c.SetCanonicalJSON(true)
+	data, err = c.marshal(event)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"event":{"alpha":"a","zebra":1},"host":"localhost","sourcetype":"manual"}`, string(data))
+}
+
+func TestHEC_WriteRawLineTooLong(t *testing.T) {
+	events := `2017-01-24T06:07:10.488Z Raw event one
+2017-01-24T06:07:12.434Z Raw event two with a much longer line`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	c := NewClient(ts.URL, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+	c.SetMaxLineSize(50)
+	err := c.WriteRaw(strings.NewReader(events), nil)
+	assert.ErrorIs(t, err, ErrLineTooLong)
+
+	var lineErr *LineTooLongError
+	if assert.ErrorAs(t, err, &lineErr) {
+		assert.Equal(t, 2, lineErr.Line)
+		assert.Equal(t, 62, lineErr.Length)
+		assert.Equal(t, 50, lineErr.Limit)
+		assert.Equal(t, "2017-01-24T06:07:12.434Z Raw event two with a much longer line", lineErr.Preview)
+	}
+}
+
+func TestHEC_WriteRawProgress(t *testing.T) {
+	events := `2017-01-24T06:07:10.488Z Raw event one
+2017-01-24T06:07:12.434Z Raw event two
+2017-01-24T06:07:14.434Z Raw event three`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	c := NewClient(ts.URL, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+	c.SetMaxContentLength(80)
+
+	var reports []RawProgress
+	c.SetRawProgressHandler(func(progress RawProgress) {
+		reports = append(reports, progress)
+	})
+	err := c.WriteRaw(strings.NewReader(events), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []RawProgress{
+		{Bytes: 78, Chunks: 1, Lines: 2},
+		{Bytes: 119, Chunks: 2, Lines: 3},
+	}, reports)
+}
+
+func TestHEC_WriteRawLineAndString(t *testing.T) {
+	var bodies []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	c := NewClient(ts.URL, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+	metadata := &EventMetadata{Source: String("test-hec-raw")}
+
+	assert.NoError(t, c.WriteRawLine("single line", metadata))
+	assert.NoError(t, c.WriteRawString("line one\nline two", metadata))
+	assert.Equal(t, []string{"single line\n", "line one\nline two\n"}, bodies)
+
+	c.SetMaxContentLength(5)
+	assert.ErrorIs(t, c.WriteRawLine("single line", metadata), ErrLineTooLong)
+}
+
+func TestHEC_WriteRaw_EscapedMetadata(t *testing.T) {
+	var query url.Values
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+	c := NewClient(ts.URL, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+
+	source := "/var/log/a&sourcetype=evil/größe 1.log"
+	assert.NoError(t, c.WriteRawLine("line", &EventMetadata{Source: String(source), SourceType: String("app")}))
+	assert.Equal(t, []string{source}, query["source"])
+	assert.Equal(t, []string{"app"}, query["sourcetype"])
+}
+
+func TestHEC_WriteRawLineWithContext(t *testing.T) {
+	var bodies []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+	c := NewClient(ts.URL, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+	c.SetRawDelimiter("\x00")
+
+	// Terminated by the raw delimiter, as by WriteRaw
+	assert.NoError(t, c.WriteRawLineWithContext(context.Background(), "single line", nil))
+	assert.NoError(t, c.WriteRawLine("terminated\x00", nil))
+	assert.Equal(t, []string{"single line\x00", "terminated\x00"}, bodies)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, c.WriteRawLineWithContext(ctx, "cancelled", nil), ErrCanceled)
+	assert.Len(t, bodies, 2)
+}
+
+func TestHEC_WriteRawChannel(t *testing.T) {
+	bodies := make(chan string, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies <- string(body)
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	c := NewClient(ts.URL, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+	c.SetMaxContentLength(20)
+	c.SetFlushInterval(10 * time.Millisecond)
+
+	records := make(chan []byte)
+	done := make(chan error)
+	go func() {
+		done <- c.WriteRawChannel(context.Background(), records, nil)
+	}()
+
+	records <- []byte("record one")
+	assert.Equal(t, "record one\n", <-bodies) // flushed by time
+	records <- []byte("record two\n")
+	records <- []byte("record three")
+	assert.Equal(t, "record two\n", <-bodies) // flushed by size
+	close(records)
+	assert.NoError(t, <-done)
+	assert.Equal(t, "record three\n", <-bodies)
+}
+
+func TestHEC_WriteRawChannel_ManualClock(t *testing.T) {
+	bodies := make(chan string, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies <- string(body)
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+	clock := NewManualClock(time.Unix(1485237827, 0))
+	c := NewClient(ts.URL, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+	c.SetClock(clock)
+	c.SetFlushInterval(time.Minute)
+
+	records := make(chan []byte)
+	done := make(chan error)
+	go func() {
+		done <- c.WriteRawChannel(context.Background(), records, nil)
+	}()
+
+	records <- []byte("record one")
+	records <- []byte("record two")
+	clock.Advance(59 * time.Second)
+	clock.Advance(time.Second)
+	assert.Equal(t, "record one\nrecord two\n", <-bodies) // flushed by time
+	records <- []byte("record three")
+	close(records)
+	assert.NoError(t, <-done)
+	assert.Equal(t, "record three\n", <-bodies)
+	assert.Empty(t, bodies)
+}
+
+func TestHEC_WriteRawOverhead(t *testing.T) {
+	var lengths []int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lengths = append(lengths, int(r.ContentLength)+len(r.URL.RequestURI())-len(r.URL.Path))
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	c := NewClient(ts.URL, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+	c.SetMaxContentLength(200)
+	c.SetRawOverhead(50)
+
+	events := strings.Repeat("2017-01-24T06:07:10.488Z Raw event\n", 10)
+	err := c.WriteRaw(strings.NewReader(events), &EventMetadata{Source: String("test-hec-raw")})
+	assert.NoError(t, err)
+	assert.Greater(t, len(lengths), 1)
+	for _, length := range lengths {
+		assert.LessOrEqual(t, length, 150)
+	}
+
+	c.SetRawOverhead(150)
+	err = c.WriteRaw(strings.NewReader(events), nil)
+	assert.Error(t, err)
+}
+
+func TestHEC_WriteRawWithMetadataFunc(t *testing.T) {
+	var sources []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sources = append(sources, r.URL.Query().Get("source"))
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	c := NewClient(ts.URL, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+	c.SetMaxContentLength(40)
+
+	events := `2017-01-24T06:07:10.488Z Raw event one
+2017-01-24T06:07:12.434Z Raw event two`
+	err := c.WriteRawWithMetadataFunc(context.Background(), strings.NewReader(events), func(index int, chunk []byte) *EventMetadata {
+		return &EventMetadata{Source: String(fmt.Sprintf("shard-%d", index))}
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"shard-0", "shard-1"}, sources)
+}
+
+func TestHEC_WriteRawFromOffset(t *testing.T) {
+	var bodies []string
+	failAt := 2
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(bodies) == failAt {
+			w.WriteHeader(400)
+			w.Write([]byte(`{"text":"Oh no","code":90}`))
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	c := NewClient(ts.URL, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+	c.SetMaxContentLength(10)
+
+	events := "line one\nline two\nline 3\nline 4"
+	var committed int64
+	checkpoint := func(offset int64) error {
+		committed = offset
+		return nil
+	}
+	err := c.WriteRawFromOffset(context.Background(), strings.NewReader(events), nil, 0, checkpoint)
+	assert.Error(t, err)
+	assert.Equal(t, int64(18), committed)
+
+	failAt = -1
+	err = c.WriteRawFromOffset(context.Background(), strings.NewReader(events), nil, committed, checkpoint)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(events)), committed)
+	assert.Equal(t, []string{"line one\n", "line two\n", "line 3\n", "line 4\n"}, bodies)
+}
+
+func TestHEC_WriteRawWithTimeFunc(t *testing.T) {
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r.URL.Query().Get("time")+" "+string(body))
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	c := NewClient(ts.URL, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+
+	events := `1485237827 event one
+1485237827 event two
+  continued
+1485237900 event three`
+	timeFunc := func(line []byte) (time.Time, bool) {
+		var sec int64
+		if _, err := fmt.Sscanf(string(line), "%d", &sec); err != nil {
+			return time.Time{}, false
 		}
+		return time.Unix(sec, 0), true
 	}
+	err := c.WriteRawWithTimeFunc(context.Background(), strings.NewReader(events), nil, timeFunc)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"1485237827.000 1485237827 event one\n1485237827 event two\n  continued\n",
+		"1485237900.000 1485237900 event three\n",
+	}, requests)
+}
 
-	for _, max := range []int{13, 14, 15, 28, 5, 30} {
-		var counter int = 0
-		err := breakStream(strings.NewReader(text), max, getCountFunc(&counter))
-		assert.NoError(t, err)
-		assert.Equal(t, 28, counter)
+func TestHEC_ErrorsIs(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(403)
+		w.Write([]byte(`{"text":"Invalid token","code":4}`))
+	}))
+	c := NewClient(ts.URL, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+	err := c.WriteEvent(NewEvent("hello, world"))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	assert.NotErrorIs(t, err, ErrServerBusy)
+
+	var res *Response
+	if assert.ErrorAs(t, err, &res) {
+		assert.Equal(t, StatusInvalidToken, res.Code)
+	}
+}
+
+func TestHEC_WriteBatchInvalidEventNumber(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Write([]byte(`{"text":"Success","code":0}`))
+			return
+		}
+		w.Header().Set("X-Splunk-Test", "yes")
+		w.WriteHeader(400)
+		w.Write([]byte(`{"text":"Invalid data format","code":6,"invalid-event-number":1}`))
+	}))
+	c := NewClient(ts.URL, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+	c.SetMaxContentLength(50)
+
+	events := []*Event{
+		{Event: "event one"},
+		{Event: "event two"},
+		{Event: ""}, // skipped
+		{Event: "event three"},
+		{Event: "event four"},
+	}
+	err := c.WriteBatch(events)
+	var res *Response
+	if assert.ErrorAs(t, err, &res) {
+		assert.Equal(t, 4, *res.InvalidEventNumber)
+		assert.Equal(t, 400, res.StatusCode)
+		assert.Equal(t, "yes", res.Header.Get("X-Splunk-Test"))
+	}
+}
+
+func TestHEC_RequestError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond) // longer than client timeout
+	}))
+	c := NewClient(ts.URL, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+	err := c.WriteEvent(NewEvent("hello, world"))
+
+	var reqErr *RequestError
+	if assert.ErrorAs(t, err, &reqErr) {
+		assert.Equal(t, ts.URL+"/services/collector?channel="+c.(*Client).channel, reqErr.URL)
+		assert.Equal(t, 1, reqErr.Attempt)
+		assert.Equal(t, 24, reqErr.Size)
+		assert.GreaterOrEqual(t, reqErr.Elapsed, 100*time.Millisecond)
+	}
+	assert.NotContains(t, err.Error(), testSplunkToken)
+}
+
+func TestHEC_EventTooLongError(t *testing.T) {
+	ts := httptest.NewServer(jsonEndpoint(t, ""))
+	c := NewClient(ts.URL, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+	c.SetMaxContentLength(25)
+
+	events := []*Event{
+		{Event: "event one"},
+		{Event: "a much longer event"},
+		{Event: "event two"},
+		{Event: "another long event"},
+	}
+	err := c.WriteBatch(events)
+	assert.ErrorIs(t, err, ErrEventTooLong)
+
+	var tooLong *EventTooLongError
+	if assert.ErrorAs(t, err, &tooLong) {
+		assert.Equal(t, []int{1, 3}, tooLong.Indexes)
+		assert.Equal(t, []int{31, 30}, tooLong.Sizes)
+		assert.Equal(t, []*Event{events[1], events[3]}, tooLong.Events)
+		assert.Equal(t, 25, tooLong.Limit)
+	}
+}
+
+func TestHEC_BadResponse(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(502)
+		w.Write([]byte(`<html><body>` + strings.Repeat("Bad Gateway ", 50) + `</body></html>`))
+	}))
+	c := NewClient(ts.URL, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+	err := c.WriteEvent(NewEvent("hello, world"))
+	assert.ErrorIs(t, err, ErrBadResponse)
+
+	var badErr *BadResponseError
+	if assert.ErrorAs(t, err, &badErr) {
+		assert.Equal(t, 502, badErr.StatusCode)
+		assert.Len(t, badErr.Body, 256)
+		assert.True(t, strings.HasPrefix(badErr.Body, "<html>"))
+	}
+}
+
+func TestHEC_ErrorHandler(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(400)
+		w.Write([]byte(`{"text":"Invalid data format","code":6}`))
+	}))
+	c := NewClient(ts.URL, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+
+	var errs []error
+	var payloads []PayloadInfo
+	c.SetErrorHandler(func(err error, payload PayloadInfo) {
+		errs = append(errs, err)
+		payloads = append(payloads, payload)
+	})
+
+	c.WriteBatch([]*Event{{Event: "event one"}, {Event: "event two"}})
+	c.WriteRaw(strings.NewReader("raw event\n"), nil)
+	if assert.Len(t, errs, 2) {
+		assert.ErrorIs(t, errs[0], ErrInvalidDataFormat)
+		assert.Equal(t, 2, payloads[0].Events)
+		assert.Equal(t, 42, payloads[0].Size)
+		assert.Equal(t, 0, payloads[1].Events)
+		assert.Equal(t, 10, payloads[1].Size)
+		assert.True(t, strings.HasPrefix(payloads[1].Endpoint, "/services/collector/raw"))
+	}
+}
+
+func TestHEC_RetriesExhausted(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(503)
+		w.Write([]byte(`{"text":"Server is busy","code":9}`))
+	}))
+	clock := NewManualClock(time.Unix(1485237827, 0))
+	c := NewClient(ts.URL, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+	c.SetClock(clock)
+	c.SetMaxRetry(1)
+	err := c.WriteEvent(NewEvent("hello, world"))
+	assert.ErrorIs(t, err, ErrRetriesExhausted)
+	assert.ErrorIs(t, err, ErrServerBusy)
+	assert.Equal(t, []time.Duration{retryWaitTime}, clock.Slept())
+
+	var exhausted *RetriesExhaustedError
+	if assert.ErrorAs(t, err, &exhausted) {
+		assert.Len(t, exhausted.Attempts, 2)
+		assert.Equal(t, 503, exhausted.Attempts[1].StatusCode)
+		assert.Equal(t, StatusServerBusy, exhausted.Attempts[1].Code)
 	}
 }
+
+func TestHEC_WriteWithResponse(t *testing.T) {
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprintf(w, `{"text":"Success","code":0,"ackId":%d,"extra":"kept"}`, requests)
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+
+	res, err := c.WriteEventWithResponse(context.Background(), NewEvent("hello, world"))
+	if assert.NoError(t, err) && assert.NotNil(t, res) {
+		assert.Equal(t, 1, *res.AckID)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Contains(t, string(res.Body), `"extra":"kept"`)
+	}
+
+	res, err = c.WriteEventWithResponse(context.Background(), NewEvent(""))
+	assert.NoError(t, err)
+	assert.Nil(t, res)
+
+	c.SetMaxContentLength(30)
+	events := []*Event{NewEvent("event one"), NewEvent("event two")}
+	responses, err := c.WriteBatchWithResponses(context.Background(), events)
+	if assert.NoError(t, err) && assert.Len(t, responses, 2) {
+		assert.Equal(t, 2, *responses[0].AckID)
+		assert.Equal(t, 3, *responses[1].AckID)
+	}
+}
+
+func TestHEC_DefaultMetadata(t *testing.T) {
+	var bodies, queries []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		queries = append(queries, r.URL.RawQuery)
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+	c.SetDefaultMetadata(&EventMetadata{Index: String("main"), SourceType: String("app")})
+
+	event := NewEvent("hello")
+	event.SetIndex("audit")
+	assert.NoError(t, c.WriteEvent(event))
+	assert.Nil(t, event.SourceType)
+	assert.NoError(t, c.WriteRawString("raw\n", &EventMetadata{Source: String("stdin")}))
+
+	assert.Equal(t, `{"index":"audit","sourcetype":"app","event":"hello"}`, bodies[0])
+	assert.Contains(t, queries[1], "index=main&source=stdin&sourcetype=app")
+}
+
+func TestHEC_WriteBatchWithMetadata(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	recorder := NewTestRecorder()
+	c := NewClient(ts.URL, testSplunkToken, WithTestMode(recorder))
+	c.SetDefaultMetadata(&EventMetadata{Index: String("main"), SourceType: String("app")})
+
+	event := NewEvent("hello")
+	event.SetSource("stdin")
+	other := NewEvent("world")
+	other.SetIndex("audit")
+	events := []*Event{event, other}
+	assert.NoError(t, c.WriteBatchWithMetadata(events, &EventMetadata{Index: String("backfill"), Source: String("replay")}))
+	assert.Equal(t, `{"index":"backfill","source":"stdin","sourcetype":"app","event":"hello"}`+
+		`{"index":"audit","source":"replay","sourcetype":"app","event":"world"}`, string(recorder.Requests()[0].Payload))
+	// The events of the caller are unchanged
+	assert.Nil(t, event.Index)
+	assert.Nil(t, other.Source)
+	assert.Equal(t, []*Event{event, other}, events)
+
+	cluster := NewCluster([]string{ts.URL, ts.URL}, testSplunkToken, WithTestMode(recorder))
+	assert.NoError(t, cluster.WriteBatchWithMetadata([]*Event{NewEvent("cluster")}, &EventMetadata{Index: String("backfill")}))
+	assert.Equal(t, `{"index":"backfill","event":"cluster"}`, string(recorder.Requests()[1].Payload))
+
+	// Time is taken from the defaults as well
+	recorder.Reset()
+	at := time.Unix(1485237827, 0)
+	timed := NewEvent("timed")
+	timed.SetTime(at.Add(time.Second))
+	defaults := &EventMetadata{Time: &at}
+	assert.NoError(t, c.WriteBatchWithMetadataContext(context.Background(), []*Event{NewEvent("untimed"), timed}, defaults))
+	assert.Equal(t, `{"index":"main","sourcetype":"app","time":"1485237827.000","event":"untimed"}`+
+		`{"index":"main","sourcetype":"app","time":"1485237828.000","event":"timed"}`, string(recorder.Requests()[0].Payload))
+	c.SetDefaultMetadata(defaults)
+	assert.NoError(t, c.WriteEvent(NewEvent("default")))
+	assert.Equal(t, `{"time":"1485237827.000","event":"default"}`, string(recorder.Requests()[1].Payload))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, cluster.WriteBatchWithMetadataContext(ctx, []*Event{NewEvent("cancelled")}, defaults), ErrCanceled)
+}
+
+func TestHEC_ChannelMode(t *testing.T) {
+	var queries, headers []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		headers = append(headers, r.Header.Get("X-Splunk-Request-Channel"))
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+	c.SetChannel(testSplunkToken)
+
+	c.SetChannelMode(ChannelInHeader)
+	assert.NoError(t, c.WriteEvent(NewEvent("header")))
+	assert.NoError(t, c.WriteRawString("header\n", &EventMetadata{Index: String("main")}))
+	c.SetChannelMode(ChannelNone)
+	assert.NoError(t, c.WriteEvent(NewEvent("none")))
+
+	assert.Equal(t, []string{"", "index=main", ""}, queries)
+	assert.Equal(t, []string{testSplunkToken, testSplunkToken, ""}, headers)
+}
+
+func TestHEC_MaxBatchEvents(t *testing.T) {
+	var requests []int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, strings.Count(string(body), `"event"`))
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+	c.SetMaxBatchEvents(2)
+	events := []*Event{NewEvent("one"), NewEvent("two"), NewEvent("three"), NewEvent("four"), NewEvent("five")}
+	assert.NoError(t, c.WriteBatch(events))
+	assert.Equal(t, []int{2, 2, 1}, requests)
+}
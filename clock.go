@@ -0,0 +1,132 @@
+package hec
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the source of time of a client, used by retries, flush timers and
+// acknowledgement polling. Tests can replace it to avoid waiting out delays.
+type Clock interface {
+	Now() time.Time
+
+	// Sleep pauses the current goroutine for d
+	Sleep(d time.Duration)
+
+	// After waits for d and then sends the current time on the returned channel
+	After(d time.Duration) <-chan time.Time
+
+	// NewTicker returns a ticker sending the time every d
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker is a ticker created by a Clock
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// SystemClock is the default Clock, backed by package time
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (systemClock) NewTicker(d time.Duration) Ticker       { return systemTicker{time.NewTicker(d)} }
+
+type systemTicker struct {
+	ticker *time.Ticker
+}
+
+func (t systemTicker) C() <-chan time.Time { return t.ticker.C }
+func (t systemTicker) Stop()               { t.ticker.Stop() }
+
+// ManualClock is a Clock for tests. Sleep and After advance its time without
+// waiting, and its tickers only tick when the time is advanced past their
+// next tick, so tests decide when streaming writes flush. Like those of
+// package time, tickers drop ticks their receiver is not ready for.
+type ManualClock struct {
+	mtx     sync.Mutex
+	now     time.Time
+	slept   []time.Duration
+	tickers []*manualTicker
+}
+
+// NewManualClock creates a ManualClock starting at start
+func NewManualClock(start time.Time) *ManualClock {
+	return &ManualClock{now: start}
+}
+
+func (c *ManualClock) Now() time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.now
+}
+
+func (c *ManualClock) Sleep(d time.Duration) {
+	c.Advance(d)
+}
+
+func (c *ManualClock) After(d time.Duration) <-chan time.Time {
+	c.Advance(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.Now()
+	return ch
+}
+
+func (c *ManualClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for ManualClock.NewTicker")
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	ticker := &manualTicker{clock: c, c: make(chan time.Time, 1), period: d, next: c.now.Add(d)}
+	c.tickers = append(c.tickers, ticker)
+	return ticker
+}
+
+// Advance moves the time forward by d, ticking the tickers due by then
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.now = c.now.Add(d)
+	c.slept = append(c.slept, d)
+	for _, ticker := range c.tickers {
+		for !ticker.next.After(c.now) {
+			select {
+			case ticker.c <- ticker.next:
+			default:
+			}
+			ticker.next = ticker.next.Add(ticker.period)
+		}
+	}
+}
+
+// Slept returns the durations the clock advanced by, in order
+func (c *ManualClock) Slept() []time.Duration {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return append([]time.Duration(nil), c.slept...)
+}
+
+type manualTicker struct {
+	clock  *ManualClock
+	c      chan time.Time
+	period time.Duration
+	next   time.Time
+}
+
+func (t *manualTicker) C() <-chan time.Time { return t.c }
+
+func (t *manualTicker) Stop() {
+	t.clock.mtx.Lock()
+	defer t.clock.mtx.Unlock()
+	for i, ticker := range t.clock.tickers {
+		if ticker == t {
+			t.clock.tickers = append(t.clock.tickers[:i], t.clock.tickers[i+1:]...)
+			break
+		}
+	}
+}
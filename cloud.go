@@ -0,0 +1,45 @@
+package hec
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// SplunkCloudURL returns the HEC URL of a Splunk Cloud stack. The stack name
+// may also be given as a host name, e.g. "example.splunkcloud.com".
+func SplunkCloudURL(stackName string) string {
+	stack := strings.ToLower(strings.TrimSpace(stackName))
+	stack = strings.TrimPrefix(stack, "https://")
+	stack = strings.TrimSuffix(stack, "/")
+	stack = strings.TrimSuffix(stack, ".splunkcloud.com")
+	stack = strings.TrimPrefix(stack, "http-inputs-")
+	return "https://http-inputs-" + stack + ".splunkcloud.com:443"
+}
+
+// NewSplunkCloudClient creates a client for a Splunk Cloud stack, applying
+// options to it. It meets the requirements of tokens with indexer
+// acknowledgement enabled:
+//   - A channel is always sent, a random GUID unless one is set, even if an
+//     option sets ChannelNone
+//   - The HTTP client keeps cookies, so the load balancer of the stack sends
+//     acknowledgement polls to the indexer which received the data
+//
+// Data is only known to be indexed once WaitForAcknowledgement returns, or
+// the Ack of its response is waited for.
+func NewSplunkCloudClient(stackName string, token string, options ...Option) HEC {
+	jar, _ := cookiejar.New(nil) // never fails without options
+	cloudOptions := append([]Option{func(client *Client) {
+		client.httpClient = &http.Client{Jar: jar}
+	}}, options...)
+	client := NewClient(SplunkCloudURL(stackName), token, cloudOptions...).(*Client)
+	if client.channel == "" {
+		client.channel = uuid.New().String()
+	}
+	if client.channelMode == ChannelNone {
+		client.channelMode = ChannelInQuery
+	}
+	return client
+}
@@ -0,0 +1,47 @@
+package hec
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplunkCloudURL(t *testing.T) {
+	for _, stack := range []string{"acme", "ACME", "acme.splunkcloud.com", "https://acme.splunkcloud.com/", "http-inputs-acme.splunkcloud.com"} {
+		assert.Equal(t, "https://http-inputs-acme.splunkcloud.com:443", SplunkCloudURL(stack), stack)
+	}
+
+	c := NewSplunkCloudClient("acme", testSplunkToken).(*Client)
+	assert.Equal(t, "https://http-inputs-acme.splunkcloud.com:443", c.serverURL)
+	assert.NotEmpty(t, c.channel)
+}
+
+func TestNewSplunkCloudClient(t *testing.T) {
+	// The stack pins acknowledgement polls to an indexer with a cookie
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, _ := r.Cookie("AWSELB")
+		sticky := cookie != nil && cookie.Value == "indexer-1"
+		requests = append(requests, fmt.Sprintf("%s %v %v", r.URL.Path, r.URL.Query().Get("channel") != "", sticky))
+		if r.URL.Path == "/services/collector/ack" {
+			w.Write([]byte(`{"acks":{"0":true}}`))
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "AWSELB", Value: "indexer-1", Path: "/"})
+		w.Write([]byte(`{"text":"Success","code":0,"ackId":0}`))
+	}))
+	defer ts.Close()
+
+	c := NewSplunkCloudClient("acme", testSplunkToken, func(client *Client) {
+		client.serverURL = ts.URL
+		client.SetChannel("")
+		client.SetChannelMode(ChannelNone)
+	})
+	assert.NotEmpty(t, c.(*Client).channel)
+	assert.NoError(t, c.WriteEvent(NewEvent("hello")))
+	assert.NoError(t, c.WaitForAcknowledgement())
+	assert.Equal(t, []string{"/services/collector true false", "/services/collector/ack true true"}, requests)
+}
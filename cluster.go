@@ -1,17 +1,20 @@
 package hec
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"io"
 	"math/rand"
 	"net/http"
+	"regexp"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 type Cluster struct {
-	HEC
-
 	// Inner clients
 	clients []*Client
 
@@ -20,7 +23,8 @@ type Cluster struct {
 	maxRetries int
 }
 
-func NewCluster(serverURLs []string, token string) HEC {
+// NewCluster creates a cluster of clients, applying options to each of them
+func NewCluster(serverURLs []string, token string, options ...Option) HEC {
 	id := uuid.New()
 
 	channel := id.String()
@@ -32,8 +36,16 @@ func NewCluster(serverURLs []string, token string) HEC {
 			token:      token,
 			keepAlive:  true,
 			channel:    channel,
-			retries:    0, // try only once for each client
-			maxLength:  defaultMaxContentLength,
+			redactor:   DefaultRedactor,
+			clock:      SystemClock,
+		}
+		clients[i].settings.Store(&Settings{
+			MaxRetries:       0, // try only once for each client
+			MaxContentLength: defaultMaxContentLength,
+			FlushInterval:    defaultFlushInterval,
+		})
+		for _, option := range options {
+			option(clients[i])
 		}
 	}
 	return &Cluster{
@@ -50,6 +62,14 @@ func (c *Cluster) SetHTTPClient(httpClient *http.Client) {
 	c.mtx.Unlock()
 }
 
+func (c *Cluster) SetTLSConfig(config *tls.Config) {
+	c.mtx.Lock()
+	for _, client := range c.clients {
+		client.SetTLSConfig(config)
+	}
+	c.mtx.Unlock()
+}
+
 func (c *Cluster) SetKeepAlive(enable bool) {
 	c.mtx.Lock()
 	for _, client := range c.clients {
@@ -66,8 +86,18 @@ func (c *Cluster) SetChannel(channel string) {
 	c.mtx.Unlock()
 }
 
+func (c *Cluster) SetChannelMode(mode ChannelMode) {
+	c.mtx.Lock()
+	for _, client := range c.clients {
+		client.SetChannelMode(mode)
+	}
+	c.mtx.Unlock()
+}
+
 func (c *Cluster) SetMaxRetry(retries int) {
+	c.mtx.Lock()
 	c.maxRetries = retries
+	c.mtx.Unlock()
 }
 
 func (c *Cluster) SetMaxContentLength(size int) {
@@ -78,6 +108,14 @@ func (c *Cluster) SetMaxContentLength(size int) {
 	c.mtx.Unlock()
 }
 
+func (c *Cluster) SetMaxBatchEvents(n int) {
+	c.mtx.Lock()
+	for _, client := range c.clients {
+		client.SetMaxBatchEvents(n)
+	}
+	c.mtx.Unlock()
+}
+
 func (c *Cluster) SetCompression(compression string) {
 	c.mtx.Lock()
 	for _, client := range c.clients {
@@ -86,35 +124,309 @@ func (c *Cluster) SetCompression(compression string) {
 	c.mtx.Unlock()
 }
 
+func (c *Cluster) SetCanonicalJSON(enable bool) {
+	c.mtx.Lock()
+	for _, client := range c.clients {
+		client.SetCanonicalJSON(enable)
+	}
+	c.mtx.Unlock()
+}
+
+func (c *Cluster) SetRawDelimiter(delimiter string) {
+	c.mtx.Lock()
+	for _, client := range c.clients {
+		client.SetRawDelimiter(delimiter)
+	}
+	c.mtx.Unlock()
+}
+
+func (c *Cluster) SetRawRecordPattern(pattern *regexp.Regexp) {
+	c.mtx.Lock()
+	for _, client := range c.clients {
+		client.SetRawRecordPattern(pattern)
+	}
+	c.mtx.Unlock()
+}
+
+func (c *Cluster) SetRawContinuationPattern(pattern *regexp.Regexp) {
+	c.mtx.Lock()
+	for _, client := range c.clients {
+		client.SetRawContinuationPattern(pattern)
+	}
+	c.mtx.Unlock()
+}
+
+func (c *Cluster) SetMaxLineSize(size int) {
+	c.mtx.Lock()
+	for _, client := range c.clients {
+		client.SetMaxLineSize(size)
+	}
+	c.mtx.Unlock()
+}
+
+func (c *Cluster) SetRawProgressHandler(handler func(progress RawProgress)) {
+	c.mtx.Lock()
+	for _, client := range c.clients {
+		client.SetRawProgressHandler(handler)
+	}
+	c.mtx.Unlock()
+}
+
+func (c *Cluster) SetFlushInterval(interval time.Duration) {
+	c.mtx.Lock()
+	for _, client := range c.clients {
+		client.SetFlushInterval(interval)
+	}
+	c.mtx.Unlock()
+}
+
+func (c *Cluster) SetRawOverhead(size int) {
+	c.mtx.Lock()
+	for _, client := range c.clients {
+		client.SetRawOverhead(size)
+	}
+	c.mtx.Unlock()
+}
+
+func (c *Cluster) SetErrorHandler(handler func(err error, payload PayloadInfo)) {
+	c.mtx.Lock()
+	for _, client := range c.clients {
+		client.SetErrorHandler(handler)
+	}
+	c.mtx.Unlock()
+}
+
+func (c *Cluster) SetRedactor(redactor Redactor) {
+	c.mtx.Lock()
+	for _, client := range c.clients {
+		client.SetRedactor(redactor)
+	}
+	c.mtx.Unlock()
+}
+
+func (c *Cluster) SetValidationProfile(profile *ValidationProfile) {
+	c.mtx.Lock()
+	for _, client := range c.clients {
+		client.SetValidationProfile(profile)
+	}
+	c.mtx.Unlock()
+}
+
+// SetQuotas sets quotas shared by all clients. Events retried on another
+// client count against the quotas again.
+func (c *Cluster) SetQuotas(quotas []Quota) {
+	tracker := newQuotaTracker(quotas)
+	c.mtx.Lock()
+	for _, client := range c.clients {
+		client.quotas = tracker
+	}
+	c.mtx.Unlock()
+}
+
+func (c *Cluster) SetClock(clock Clock) {
+	c.mtx.Lock()
+	for _, client := range c.clients {
+		client.SetClock(clock)
+	}
+	c.mtx.Unlock()
+}
+
+func (c *Cluster) SetTokenProvider(provider TokenProvider) {
+	c.mtx.Lock()
+	for _, client := range c.clients {
+		client.SetTokenProvider(provider)
+	}
+	c.mtx.Unlock()
+}
+
+func (c *Cluster) SetAuthScheme(scheme AuthScheme) {
+	c.mtx.Lock()
+	for _, client := range c.clients {
+		client.SetAuthScheme(scheme)
+	}
+	c.mtx.Unlock()
+}
+
+func (c *Cluster) SetDefaultMetadata(metadata *EventMetadata) {
+	c.mtx.Lock()
+	for _, client := range c.clients {
+		client.SetDefaultMetadata(metadata)
+	}
+	c.mtx.Unlock()
+}
+
 func (c *Cluster) WriteEvent(event *Event) error {
+	return c.WriteEventWithContext(context.Background(), event)
+}
+
+func (c *Cluster) WriteEventWithContext(ctx context.Context, event *Event) error {
+	// Set before failing over, so every attempt sends the same key
+	event = c.clients[0].withIdempotencyKey(event)
 	return c.retry(func(client *Client) error {
-		return client.WriteEvent(event)
+		return client.WriteEventWithContext(ctx, event)
 	})
 }
 
 func (c *Cluster) WriteBatch(events []*Event) error {
+	return c.WriteBatchWithContext(context.Background(), events)
+}
+
+func (c *Cluster) WriteBatchWithContext(ctx context.Context, events []*Event) error {
+	events = c.clients[0].withIdempotencyKeys(events)
+	return c.retry(func(client *Client) error {
+		return client.WriteBatchWithContext(ctx, events)
+	})
+}
+
+func (c *Cluster) WriteBatchWithMetadata(events []*Event, defaults *EventMetadata) error {
+	events = c.clients[0].withIdempotencyKeys(events)
 	return c.retry(func(client *Client) error {
-		return client.WriteBatch(events)
+		return client.WriteBatchWithMetadata(events, defaults)
 	})
 }
 
+func (c *Cluster) WriteBatchWithMetadataContext(ctx context.Context, events []*Event, defaults *EventMetadata) error {
+	events = c.clients[0].withIdempotencyKeys(events)
+	return c.retry(func(client *Client) error {
+		return client.WriteBatchWithMetadataContext(ctx, events, defaults)
+	})
+}
+
+func (c *Cluster) WriteEventWithResponse(ctx context.Context, event *Event) (*Response, error) {
+	event = c.clients[0].withIdempotencyKey(event)
+	var response *Response
+	err := c.retry(func(client *Client) error {
+		var err error
+		response, err = client.WriteEventWithResponse(ctx, event)
+		return err
+	})
+	return response, err
+}
+
+// WriteBatchWithResponses returns the responses of the client the batch was
+// finally written to
+func (c *Cluster) WriteBatchWithResponses(ctx context.Context, events []*Event) ([]*Response, error) {
+	events = c.clients[0].withIdempotencyKeys(events)
+	var responses []*Response
+	err := c.retry(func(client *Client) error {
+		var err error
+		responses, err = client.WriteBatchWithResponses(ctx, events)
+		return err
+	})
+	return responses, err
+}
+
 func (c *Cluster) WriteRaw(reader io.ReadSeeker, metadata *EventMetadata) error {
+	return c.WriteRawWithContext(context.Background(), reader, metadata)
+}
+
+func (c *Cluster) WriteRawWithContext(ctx context.Context, reader io.ReadSeeker, metadata *EventMetadata) error {
 	startAt, _ := reader.Seek(0, io.SeekCurrent)
 	return c.retry(func(client *Client) error {
 		reader.Seek(startAt, io.SeekStart)
-		return client.WriteRaw(reader, metadata)
+		return client.WriteRawWithContext(ctx, reader, metadata)
 	})
 }
 
+func (c *Cluster) WriteRawWithMetadataFunc(ctx context.Context, reader io.ReadSeeker, metadataFunc func(index int, chunk []byte) *EventMetadata) error {
+	startAt, _ := reader.Seek(0, io.SeekCurrent)
+	return c.retry(func(client *Client) error {
+		reader.Seek(startAt, io.SeekStart)
+		return client.WriteRawWithMetadataFunc(ctx, reader, metadataFunc)
+	})
+}
+
+func (c *Cluster) WriteRawFromOffset(ctx context.Context, reader io.ReadSeeker, metadata *EventMetadata, offset int64, checkpoint func(offset int64) error) error {
+	// Resume from the last checkpoint when failing over to another client
+	return c.retry(func(client *Client) error {
+		return client.WriteRawFromOffset(ctx, reader, metadata, offset, func(committed int64) error {
+			offset = committed
+			return checkpoint(committed)
+		})
+	})
+}
+
+func (c *Cluster) WriteRawWithTimeFunc(ctx context.Context, reader io.ReadSeeker, metadata *EventMetadata, timeFunc func(line []byte) (time.Time, bool)) error {
+	startAt, _ := reader.Seek(0, io.SeekCurrent)
+	return c.retry(func(client *Client) error {
+		reader.Seek(startAt, io.SeekStart)
+		return client.WriteRawWithTimeFunc(ctx, reader, metadata, timeFunc)
+	})
+}
+
+func (c *Cluster) WriteRawString(data string, metadata *EventMetadata) error {
+	return c.retry(func(client *Client) error {
+		return client.WriteRawString(data, metadata)
+	})
+}
+
+func (c *Cluster) WriteRawLine(line string, metadata *EventMetadata) error {
+	return c.retry(func(client *Client) error {
+		return client.WriteRawLine(line, metadata)
+	})
+}
+
+func (c *Cluster) WriteRawLineWithContext(ctx context.Context, line string, metadata *EventMetadata) error {
+	return c.retry(func(client *Client) error {
+		return client.WriteRawLineWithContext(ctx, line, metadata)
+	})
+}
+
+func (c *Cluster) WriteRawChannel(ctx context.Context, records <-chan []byte, metadata *EventMetadata) error {
+	// Records consumed from the channel cannot be read again, so retry every chunk on its own
+	first := c.clients[0]
+	limit, err := first.rawChunkLimit(first.rawEndpoint(metadata))
+	if err != nil {
+		return err
+	}
+	return streamRaw(ctx, records, limit, first.current().FlushInterval, first.clock, first.rawSplitter, func(chunk []byte) error {
+		return c.retry(func(client *Client) error {
+			return client.writeRaw(ctx, client.rawEndpoint(metadata), chunk)
+		})
+	})
+}
+
+func (c *Cluster) WaitForAcknowledgement() error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultAcknowledgementTimeout)
+	defer cancel()
+	return c.WaitForAcknowledgementWithContext(ctx)
+}
+
+// WaitForAcknowledgementWithContext waits for the acknowledgements of every
+// client in turn, and returns the first error. The acknowledgements the
+// clients didn't get to are kept for the next call.
+func (c *Cluster) WaitForAcknowledgementWithContext(ctx context.Context) error {
+	for _, client := range c.clients {
+		if err := client.WaitForAcknowledgementWithContext(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (c *Cluster) retry(writeFunc func(*Client) error) error {
 	exclude := make([]*Client, 0)
+	var attempts []Attempt
 	var err error
-	for t := 0; t < len(c.clients) && t != c.maxRetries; t++ {
+	c.mtx.Lock()
+	maxRetries := c.maxRetries
+	c.mtx.Unlock()
+	for t := 0; t < len(c.clients) && t != maxRetries; t++ {
 		client := pick(c.clients, exclude)
+		startTime := client.clock.Now()
 		if err = writeFunc(client); err != nil {
-			if err == ErrEventTooLong {
+			if errors.Is(err, ErrEventTooLong) || errors.Is(err, ErrLineTooLong) {
 				return err
-			} else if res, ok := err.(*Response); !ok || retriable(res.Code) {
+			}
+			if errors.Is(err, ErrQuotaExceeded) || errors.Is(err, ErrInvalidEvent) || errors.Is(err, ErrTransformFailed) {
+				return err // the other events were written already
+			}
+			if errors.Is(err, ErrCanceled) || errors.Is(err, ErrDeadlineExceeded) {
+				return err // other clients would fail the same way
+			}
+			attempts = append(attempts, attemptOf(redactURL(client.serverURL), startTime, err))
+			var res *Response
+			if !errors.As(err, &res) || retriable(res.Code) {
 				// If failed to write into this client, exclude it and try others
 				exclude = append(exclude, client)
 				continue
@@ -123,6 +435,9 @@ func (c *Cluster) retry(writeFunc func(*Client) error) error {
 			return nil
 		}
 	}
+	if len(attempts) > 1 {
+		return &RetriesExhaustedError{Attempts: attempts, Last: err}
+	}
 	return err
 }
 
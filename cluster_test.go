@@ -1,9 +1,12 @@
 package hec
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -98,3 +101,132 @@ func TestCluster_Retrying(t *testing.T) {
 		assert.NoError(t, err)
 	}
 }
+
+func TestCluster_WriteRawChannel(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	c := NewCluster([]string{ts.URL, "http://example.com:88"}, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+
+	records := make(chan []byte, 2)
+	records <- []byte("2017-01-24T06:07:10.488Z Raw event one")
+	records <- []byte("2017-01-24T06:07:12.434Z Raw event two")
+	close(records)
+	err := c.WriteRawChannel(context.Background(), records, nil)
+	assert.NoError(t, err)
+}
+
+func TestCluster_RetriesExhausted(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(503)
+		w.Write([]byte(`{"text":"Server is busy","code":9}`))
+	})
+	ts1 := httptest.NewServer(handler)
+	ts2 := httptest.NewServer(handler)
+	c := NewCluster([]string{ts1.URL, ts2.URL}, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+	err := c.WriteEvent(&Event{Event: "test retrying"})
+	assert.ErrorIs(t, err, ErrServerBusy)
+
+	var exhausted *RetriesExhaustedError
+	if assert.ErrorAs(t, err, &exhausted) {
+		assert.Len(t, exhausted.Attempts, 2)
+		assert.ElementsMatch(t, []string{ts1.URL, ts2.URL}, []string{exhausted.Attempts[0].Server, exhausted.Attempts[1].Server})
+	}
+}
+
+func TestCluster_WithContext(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if strings.HasSuffix(r.URL.Path, "/ack") {
+			w.Write([]byte(`{"acks":{"1":true}}`))
+			return
+		}
+		w.Write([]byte(`{"text":"Success","code":0,"ackId":1}`))
+	}))
+	defer ts.Close()
+
+	c := NewCluster([]string{ts.URL, ts.URL}, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+	ctx := context.Background()
+	assert.NoError(t, c.WriteEventWithContext(ctx, NewEvent("event")))
+	assert.NoError(t, c.WriteBatchWithContext(ctx, []*Event{NewEvent("batch")}))
+	assert.NoError(t, c.WriteRawWithContext(ctx, strings.NewReader("raw\n"), nil))
+	assert.NoError(t, c.WaitForAcknowledgementWithContext(ctx))
+	assert.NoError(t, c.WaitForAcknowledgement())
+
+	// A cancelled write is not retried on the other node
+	atomic.StoreInt32(&requests, 0)
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, c.WriteEventWithContext(ctx, NewEvent("cancelled")), ErrCanceled)
+	assert.ErrorIs(t, c.WriteBatchWithContext(ctx, []*Event{NewEvent("cancelled")}), ErrCanceled)
+	assert.ErrorIs(t, c.WriteRawWithContext(ctx, strings.NewReader("cancelled\n"), nil), ErrCanceled)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&requests))
+}
+
+func TestCluster_QuotaExceeded(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	c := NewCluster([]string{ts.URL, ts.URL, ts.URL}, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+	c.SetQuotas([]Quota{{Events: 1, Policy: QuotaError}})
+	err := c.WriteBatch([]*Event{NewEvent("one"), NewEvent("two"), NewEvent("three")})
+	var quotaErr *QuotaExceededError
+	if assert.ErrorAs(t, err, &quotaErr) {
+		assert.Equal(t, []int{1, 2}, quotaErr.Indexes)
+	}
+	// The admitted event is not written again by other nodes
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestCluster_InvalidEvent(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	c := NewCluster([]string{ts.URL, ts.URL, ts.URL}, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+	c.SetValidationProfile(&ValidationProfile{Name: "small", MaxEventSize: 30})
+	err := c.WriteBatch([]*Event{NewEvent("valid"), NewEvent("too large to be valid at all")})
+	var invalid *InvalidEventError
+	if assert.ErrorAs(t, err, &invalid) {
+		assert.Equal(t, []int{1}, invalid.Indexes)
+	}
+	// The valid event is not written again by other nodes
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestCluster_TransformFailed(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	c := NewCluster([]string{ts.URL, ts.URL, ts.URL}, testSplunkToken, WithTransformers(func(event *Event) (*Event, error) {
+		if event.Event == "broken" {
+			return nil, errors.New("cannot transform")
+		}
+		return event, nil
+	}))
+	c.SetHTTPClient(testHttpClient)
+	err := c.WriteBatch([]*Event{NewEvent("fine"), NewEvent("broken")})
+	var transformErr *TransformError
+	if assert.ErrorAs(t, err, &transformErr) {
+		assert.Equal(t, []int{1}, transformErr.Indexes)
+	}
+	// The transformed event is not written again by other nodes
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
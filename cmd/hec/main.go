@@ -0,0 +1,192 @@
+// Command hec sends lines or NDJSON from stdin or files to Splunk HEC.
+//
+// Usage:
+//
+//	hec [flags] [file ...]
+//
+// The URL and token default to $SPLUNK_HEC_URL and $SPLUNK_HEC_TOKEN. With
+// -follow, files are tailed until the command is interrupted.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+	"github.com/fuyufjh/splunk-hec-go/tail"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := run(ctx, os.Args[1:], os.Stdin); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, "hec:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+type options struct {
+	mode     string
+	format   string
+	batch    int
+	follow   bool
+	metadata hec.EventMetadata
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader) error {
+	flags := flag.NewFlagSet("hec", flag.ContinueOnError)
+	config := hec.Config{}
+	var opts options
+	var serverURL, host, source, sourceType string
+	flags.StringVar(&serverURL, "url", os.Getenv(hec.EnvURL), "URL of HEC, comma-separated for a cluster")
+	flags.StringVar(&config.Token, "token", os.Getenv(hec.EnvToken), "HEC token")
+	flags.StringVar(&config.Index, "index", os.Getenv(hec.EnvIndex), "index of the data")
+	flags.StringVar(&sourceType, "sourcetype", "", "sourcetype of the data")
+	flags.StringVar(&source, "source", "", "source of the data (default: the file name)")
+	flags.StringVar(&host, "host", "", "host of the data")
+	flags.StringVar(&config.Compression, "compression", os.Getenv(hec.EnvCompression), `compression of requests, "" or "gzip"`)
+	flags.BoolVar(&config.TLS.InsecureSkipVerify, "insecure", false, "skip verification of server certificates")
+	flags.StringVar(&opts.mode, "mode", "event", `"event" to send every line as an event, "raw" to send raw data`)
+	flags.StringVar(&opts.format, "format", "lines", `format of input in event mode, "lines" or "ndjson"`)
+	flags.IntVar(&opts.batch, "batch", 1000, "max events of a batch in event mode")
+	flags.BoolVar(&opts.follow, "follow", false, "keep sending lines appended to files")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if serverURL == "" {
+		return fmt.Errorf("-url or $%s is required", hec.EnvURL)
+	}
+	if opts.mode != "event" && opts.mode != "raw" {
+		return fmt.Errorf("Unknown mode %q", opts.mode)
+	}
+	if opts.format != "lines" && opts.format != "ndjson" {
+		return fmt.Errorf("Unknown format %q", opts.format)
+	}
+	if opts.follow && flags.NArg() == 0 {
+		return errors.New("-follow requires files")
+	}
+	config.URLs = strings.Split(serverURL, ",")
+	client, err := hec.NewFromConfig(config)
+	if err != nil {
+		return err
+	}
+	opts.metadata = hec.EventMetadata{Host: optional(host), Source: optional(source), SourceType: optional(sourceType)}
+
+	if flags.NArg() == 0 {
+		return send(ctx, client, stdin, opts)
+	}
+	if opts.follow {
+		return follow(ctx, client, flags.Args(), opts)
+	}
+	for _, path := range flags.Args() {
+		fileOpts := opts
+		if fileOpts.metadata.Source == nil {
+			fileOpts.metadata.Source = hec.String(path)
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		err = send(ctx, client, file, fileOpts)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	return nil
+}
+
+// send writes all of input
+func send(ctx context.Context, client hec.HEC, input io.Reader, opts options) error {
+	metadata := &opts.metadata
+	maxLength := client.Settings().MaxContentLength
+	if opts.mode == "raw" {
+		// Lines of any length are broken into chunks as by WriteRaw
+		return hec.ChunkLines(input, maxLength, func(chunk []byte) error {
+			return client.WriteRawWithContext(ctx, bytes.NewReader(chunk), metadata)
+		})
+	}
+
+	var reader hec.EventReader
+	if opts.format == "ndjson" {
+		ndjson := hec.NewNDJSONReader(input)
+		ndjson.SetMetadata(metadata)
+		reader = ndjson
+	} else {
+		// Lines up to the max content length fit into the buffer of the
+		// scanner, longer ones could not be sent as events anyway
+		scanner := bufio.NewScanner(input)
+		scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxLength+1)
+		reader = &lineReader{scanner: scanner, metadata: metadata}
+	}
+	return hec.WriteAll(client, reader, opts.batch)
+}
+
+// follow tails files until ctx is cancelled
+func follow(ctx context.Context, client hec.HEC, paths []string, opts options) error {
+	var wg sync.WaitGroup
+	errs := make(chan error, len(paths))
+	for _, path := range paths {
+		metadata := opts.metadata
+		if metadata.Source == nil {
+			metadata.Source = hec.String(path)
+		}
+		tailer := tail.NewTailer(path, client)
+		tailer.SetMetadata(&metadata)
+		tailer.SetEventMode(opts.mode == "event")
+		wg.Add(1)
+		go func(path string) {
+			defer wg.Done()
+			if err := tailer.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				errs <- fmt.Errorf("%s: %v", path, err)
+			}
+		}(path)
+	}
+	wg.Wait()
+	close(errs)
+	return <-errs
+}
+
+// lineReader reads every line as an event
+type lineReader struct {
+	scanner  *bufio.Scanner
+	metadata *hec.EventMetadata
+
+	// Number of the last read line
+	line int
+}
+
+func (r *lineReader) ReadEvent() (*hec.Event, error) {
+	for r.scanner.Scan() {
+		r.line++
+		line := r.scanner.Text()
+		if line == "" {
+			continue
+		}
+		event := hec.NewEvent(line)
+		event.Host, event.Source, event.SourceType = r.metadata.Host, r.metadata.Source, r.metadata.SourceType
+		return event, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, fmt.Errorf("line %d: %w", r.line+1, err)
+	}
+	return nil, io.EOF
+}
+
+func optional(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
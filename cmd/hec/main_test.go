@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testToken = "00000000-0000-0000-0000-000000000000"
+
+// newSplunk returns the URL of a HEC endpoint recording the paths, sources
+// in the query and bodies of requests
+func newSplunk(t *testing.T, requests *[]string) string {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		path := r.URL.Path
+		if source := r.URL.Query().Get("source"); source != "" {
+			path += "?source=" + source
+		}
+		*requests = append(*requests, path+" "+string(body))
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	t.Cleanup(ts.Close)
+	return ts.URL
+}
+
+func TestRunEvents(t *testing.T) {
+	var requests []string
+	url := newSplunk(t, &requests)
+	err := run(context.Background(), []string{"-url", url, "-token", testToken, "-sourcetype", "cli", "-batch", "2"},
+		strings.NewReader("one\n\ntwo\nthree\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		`/services/collector {"sourcetype":"cli","event":"one"}{"sourcetype":"cli","event":"two"}`,
+		`/services/collector {"sourcetype":"cli","event":"three"}`,
+	}, requests)
+}
+
+func TestRunNDJSON(t *testing.T) {
+	var requests []string
+	url := newSplunk(t, &requests)
+	err := run(context.Background(), []string{"-url", url, "-token", testToken, "-format", "ndjson", "-index", "main"},
+		strings.NewReader(`{"a":1}`+"\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`/services/collector {"index":"main","event":{"a":1}}`}, requests)
+}
+
+func TestRunRawFiles(t *testing.T) {
+	var requests []string
+	url := newSplunk(t, &requests)
+	path := filepath.Join(t.TempDir(), "app.log")
+	assert.NoError(t, os.WriteFile(path, []byte("first\nsecond"), 0644))
+	err := run(context.Background(), []string{"-url", url, "-token", testToken, "-mode", "raw", path}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/services/collector/raw?source=" + path + " first\nsecond\n"}, requests)
+}
+
+func TestRunInvalid(t *testing.T) {
+	t.Setenv("SPLUNK_HEC_URL", "")
+	assert.Error(t, run(context.Background(), []string{"-token", testToken}, nil))
+	assert.Error(t, run(context.Background(), []string{"-url", "http://localhost:8088", "-token", testToken, "-mode", "json"}, nil))
+	assert.Error(t, run(context.Background(), []string{"-url", "http://localhost:8088", "-token", testToken, "-follow"}, nil))
+}
+
+func TestRunLongLines(t *testing.T) {
+	var requests []string
+	url := newSplunk(t, &requests)
+	long := strings.Repeat("x", 100*1024)
+	err := run(context.Background(), []string{"-url", url, "-token", testToken}, strings.NewReader("short\n"+long+"\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`/services/collector {"event":"short"}{"event":"` + long + `"}`}, requests)
+
+	requests = nil
+	err = run(context.Background(), []string{"-url", url, "-token", testToken, "-mode", "raw"}, strings.NewReader(long+"\nshort\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/services/collector/raw " + long + "\nshort\n"}, requests)
+
+	// Lines too long for an event fail with their number
+	tooLong := strings.Repeat("x", 2*1024*1024)
+	err = run(context.Background(), []string{"-url", url, "-token", testToken}, strings.NewReader("short\n"+tooLong+"\n"))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "line 2: bufio.Scanner: token too long")
+	}
+}
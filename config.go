@@ -0,0 +1,243 @@
+package hec
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Config holds the settings of a client, to be embedded in configuration
+// files of applications. Zero values mean defaults.
+type Config struct {
+	// Server URLs; more than one creates a Cluster
+	URLs []string `json:"urls" yaml:"urls"`
+
+	Token   string `json:"token" yaml:"token"`
+	Channel string `json:"channel,omitempty" yaml:"channel,omitempty"`
+
+	// "splunk", "bearer" or "basic", see AuthScheme (default: "splunk")
+	AuthScheme string `json:"auth_scheme,omitempty" yaml:"auth_scheme,omitempty"`
+
+	// Index of events and raw data that don't set their own
+	Index string `json:"index,omitempty" yaml:"index,omitempty"`
+
+	// Max retries, see SetMaxRetry
+	Retries *int `json:"retries,omitempty" yaml:"retries,omitempty"`
+
+	// Batching of requests and streaming writes
+	MaxContentLength int      `json:"max_content_length,omitempty" yaml:"max_content_length,omitempty"`
+	MaxBatchEvents   int      `json:"max_batch_events,omitempty" yaml:"max_batch_events,omitempty"`
+	FlushInterval    Duration `json:"flush_interval,omitempty" yaml:"flush_interval,omitempty"`
+
+	// Name of a built-in validation profile, e.g. "strict"
+	ValidationProfile string `json:"validation_profile,omitempty" yaml:"validation_profile,omitempty"`
+
+	// "" or "gzip"
+	Compression string `json:"compression,omitempty" yaml:"compression,omitempty"`
+
+	// Timeout of every HTTP request
+	Timeout Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+
+	TLS TLSConfig `json:"tls" yaml:"tls,omitempty"`
+
+	// URL of an HTTP proxy, otherwise the proxy is taken from the environment
+	ProxyURL string `json:"proxy_url,omitempty" yaml:"proxy_url,omitempty"`
+}
+
+// TLSConfig holds the TLS settings of Config
+type TLSConfig struct {
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty" yaml:"insecure_skip_verify,omitempty"`
+
+	// PEM file of CA certificates to verify servers with instead of the system pool
+	CAFile string `json:"ca_file,omitempty" yaml:"ca_file,omitempty"`
+
+	// PEM files of the client certificate and key
+	CertFile string `json:"cert_file,omitempty" yaml:"cert_file,omitempty"`
+	KeyFile  string `json:"key_file,omitempty" yaml:"key_file,omitempty"`
+
+	// Interval to check the certificate and key files for changes, see CertificateLoader
+	CertReloadInterval Duration `json:"cert_reload_interval,omitempty" yaml:"cert_reload_interval,omitempty"`
+
+	// Name to verify server certificates against, if not the host of the URL
+	ServerName string `json:"server_name,omitempty" yaml:"server_name,omitempty"`
+
+	// Name of a built-in TLS policy, e.g. "fips" (default: "default")
+	Policy string `json:"policy,omitempty" yaml:"policy,omitempty"`
+
+	// Min version like "1.3" and cipher suites like
+	// "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", overriding those of the policy
+	MinVersion   string   `json:"min_version,omitempty" yaml:"min_version,omitempty"`
+	CipherSuites []string `json:"cipher_suites,omitempty" yaml:"cipher_suites,omitempty"`
+
+	// Public key pins of server certificate chains, see WithPinnedKeys
+	Pins []string `json:"pins,omitempty" yaml:"pins,omitempty"`
+}
+
+// policy returns the TLS policy named by config, with its overrides
+func (t TLSConfig) policy() (TLSPolicy, error) {
+	policy := TLSPolicyDefault
+	if t.Policy != "" {
+		var ok bool
+		if policy, ok = LookupTLSPolicy(t.Policy); !ok {
+			return policy, &ConfigError{Param: "TLS policy", Reason: fmt.Sprintf("%q is unknown", t.Policy)}
+		}
+	}
+	if t.MinVersion != "" {
+		version, err := parseTLSVersion(t.MinVersion)
+		if err != nil {
+			return policy, &ConfigError{Param: "TLS min version", Reason: err.Error()}
+		}
+		policy.MinVersion = version
+	}
+	if t.CipherSuites != nil {
+		suites, err := parseCipherSuites(t.CipherSuites)
+		if err != nil {
+			return policy, &ConfigError{Param: "cipher suites", Reason: err.Error()}
+		}
+		policy.CipherSuites = suites
+	}
+	return policy, nil
+}
+
+// Duration is a time.Duration written as a string like "1m30s" in configuration files
+type Duration time.Duration
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	duration, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+// NewFromConfig creates a Client, or a Cluster if there are more than one URLs,
+// from config. The parameters are validated as by NewValidatedClient.
+func NewFromConfig(config Config) (HEC, error) {
+	compression := config.Compression
+	if compression != "" && compression != "gzip" {
+		return nil, &ConfigError{Param: "compression", Reason: "only gzip is supported"}
+	}
+	authScheme, ok := map[string]AuthScheme{"": AuthSplunk, "splunk": AuthSplunk, "bearer": AuthBearer, "basic": AuthBasic}[config.AuthScheme]
+	if !ok {
+		return nil, &ConfigError{Param: "auth scheme", Reason: fmt.Sprintf("%q is unknown", config.AuthScheme)}
+	}
+	var profile *ValidationProfile
+	if config.ValidationProfile != "" {
+		p, ok := LookupProfile(config.ValidationProfile)
+		if !ok {
+			return nil, &ConfigError{Param: "validation profile", Reason: fmt.Sprintf("%q is unknown", config.ValidationProfile)}
+		}
+		profile = &p
+	}
+	httpClient, err := config.httpClient()
+	if err != nil {
+		return nil, err
+	}
+
+	var client HEC
+	if len(config.URLs) == 1 {
+		client, err = newValidatedClient(config.URLs[0], config.Token, config.MaxContentLength, authScheme)
+	} else {
+		client, err = newValidatedCluster(config.URLs, config.Token, config.MaxContentLength, authScheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if httpClient != nil {
+		client.SetHTTPClient(httpClient)
+	}
+	if profile != nil {
+		client.SetValidationProfile(profile)
+	}
+	if authScheme != AuthSplunk {
+		client.SetAuthScheme(authScheme)
+	}
+	if config.Channel != "" {
+		client.SetChannel(config.Channel)
+	}
+	if config.Index != "" {
+		index := config.Index
+		client.SetDefaultMetadata(&EventMetadata{Index: &index})
+	}
+	if config.Retries != nil {
+		client.SetMaxRetry(*config.Retries)
+	}
+	if config.MaxBatchEvents > 0 {
+		client.SetMaxBatchEvents(config.MaxBatchEvents)
+	}
+	if config.FlushInterval > 0 {
+		client.SetFlushInterval(time.Duration(config.FlushInterval))
+	}
+	client.SetCompression(compression)
+	return client, nil
+}
+
+// httpClient returns the HTTP client for config, or nil if the default one will do
+func (config Config) httpClient() (*http.Client, error) {
+	t := config.TLS
+	if !t.InsecureSkipVerify && t.CAFile == "" && t.CertFile == "" && t.ServerName == "" &&
+		t.Policy == "" && t.MinVersion == "" && t.CipherSuites == nil && t.Pins == nil &&
+		config.ProxyURL == "" && config.Timeout == 0 {
+		return nil, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	tlsConfig := &tls.Config{InsecureSkipVerify: t.InsecureSkipVerify, ServerName: t.ServerName}
+	if t.CAFile != "" {
+		pem, err := ioutil.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, &ConfigError{Param: "CA file", Reason: err.Error()}
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, &ConfigError{Param: "CA file", Reason: fmt.Sprintf("%s has no PEM certificates", t.CAFile)}
+		}
+	}
+	if t.CertReloadInterval > 0 {
+		loader, err := NewCertificateLoader(t.CertFile, t.KeyFile, time.Duration(t.CertReloadInterval))
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.GetClientCertificate = loader.GetClientCertificate
+	} else if t.CertFile != "" || t.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, &ConfigError{Param: "client certificate", Reason: err.Error()}
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	policy, err := t.policy()
+	if err != nil {
+		return nil, err
+	}
+	policy.apply(tlsConfig)
+	if t.Pins != nil {
+		hashes, err := parsePins(t.Pins)
+		if err != nil {
+			return nil, &ConfigError{Param: "pins", Reason: err.Error()}
+		}
+		tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
+			return verifyPins(state, hashes)
+		}
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	if config.ProxyURL != "" {
+		proxyURL, err := url.Parse(config.ProxyURL)
+		if err != nil {
+			return nil, &ConfigError{Param: "proxy URL", Reason: "cannot be parsed"}
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	return &http.Client{Transport: transport, Timeout: time.Duration(config.Timeout)}, nil
+}
@@ -0,0 +1,75 @@
+package hec
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// CSVReader converts rows of CSV (or TSV) data into events. The first row is
+// the header, and every following row becomes an event object mapping the
+// header names to the values of the row.
+type CSVReader struct {
+	reader *csv.Reader
+	header []string
+
+	// Column holding the event time (optional)
+	timeField string
+
+	// Metadata of all events (optional)
+	metadata *EventMetadata
+}
+
+func NewCSVReader(reader io.Reader) *CSVReader {
+	r := csv.NewReader(reader)
+	r.ReuseRecord = true
+	return &CSVReader{reader: r}
+}
+
+// NewTSVReader returns a CSVReader for tab-separated values
+func NewTSVReader(reader io.Reader) *CSVReader {
+	r := NewCSVReader(reader)
+	r.reader.Comma = '\t'
+	r.reader.LazyQuotes = true
+	return r
+}
+
+// SetTimeField sets the column to take the event time from. Its value can be
+// epoch seconds or an RFC 3339 string; events with other values are sent
+// without time, so Splunk assigns the index time.
+func (r *CSVReader) SetTimeField(field string) {
+	r.timeField = field
+}
+
+func (r *CSVReader) SetMetadata(metadata *EventMetadata) {
+	r.metadata = metadata
+}
+
+func (r *CSVReader) ReadEvent() (*Event, error) {
+	if r.header == nil {
+		header, err := r.reader.Read()
+		if err != nil {
+			return nil, err
+		}
+		r.header = append([]string(nil), header...)
+	}
+
+	row, err := r.reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	data := make(map[string]string, len(r.header))
+	for i, value := range row {
+		if i < len(r.header) {
+			data[r.header[i]] = value
+		}
+	}
+
+	event := NewEvent(data)
+	applyMetadata(event, r.metadata)
+	if r.timeField != "" {
+		if t := parseTime(data[r.timeField]); t != nil {
+			event.Time = t
+		}
+	}
+	return event, nil
+}
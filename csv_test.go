@@ -0,0 +1,46 @@
+package hec
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readAll(t *testing.T, reader EventReader) []*Event {
+	var events []*Event
+	for {
+		event, err := reader.ReadEvent()
+		if err == io.EOF {
+			return events
+		}
+		assert.NoError(t, err)
+		events = append(events, event)
+	}
+}
+
+func TestCSVReader(t *testing.T) {
+	input := "time,user,action\n1485237827.123,alice,login\n2017-01-24T06:03:47Z,\"bob, jr\",logout\n"
+	r := NewCSVReader(strings.NewReader(input))
+	r.SetTimeField("time")
+	events := readAll(t, r)
+
+	assert.Len(t, events, 2)
+	assert.Equal(t, "1485237827.123", *events[0].Time)
+	assert.Equal(t, "1485237827.000", *events[1].Time)
+	data, _ := json.Marshal(events[1])
+	assert.Equal(t, `{"time":"1485237827.000","event":{"action":"logout","time":"2017-01-24T06:03:47Z","user":"bob, jr"}}`, string(data))
+}
+
+func TestTSVReader(t *testing.T) {
+	input := "user\taction\nalice\tlogin\n"
+	r := NewTSVReader(strings.NewReader(input))
+	r.SetMetadata(&EventMetadata{SourceType: String("tsv")})
+	events := readAll(t, r)
+
+	assert.Len(t, events, 1)
+	assert.Equal(t, map[string]string{"user": "alice", "action": "login"}, events[0].Event)
+	assert.Equal(t, "tsv", *events[0].SourceType)
+}
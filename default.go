@@ -0,0 +1,45 @@
+package hec
+
+import (
+	"errors"
+	"sync"
+)
+
+var ErrNoDefaultClient = errors.New("No default client is set")
+
+var (
+	defaultMtx    sync.RWMutex
+	defaultClient HEC
+)
+
+// SetDefault sets the client used by the package-level write functions
+func SetDefault(client HEC) {
+	defaultMtx.Lock()
+	defaultClient = client
+	defaultMtx.Unlock()
+}
+
+// Default returns the client set by SetDefault, or nil
+func Default() HEC {
+	defaultMtx.RLock()
+	defer defaultMtx.RUnlock()
+	return defaultClient
+}
+
+// WriteEvent writes single event with the default client
+func WriteEvent(event *Event) error {
+	client := Default()
+	if client == nil {
+		return ErrNoDefaultClient
+	}
+	return client.WriteEvent(event)
+}
+
+// WriteBatch writes multiple events with the default client
+func WriteBatch(events []*Event) error {
+	client := Default()
+	if client == nil {
+		return ErrNoDefaultClient
+	}
+	return client.WriteBatch(events)
+}
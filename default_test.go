@@ -0,0 +1,23 @@
+package hec
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefault(t *testing.T) {
+	defer SetDefault(nil)
+	assert.ErrorIs(t, WriteEvent(NewEvent("hello")), ErrNoDefaultClient)
+
+	ts := httptest.NewServer(jsonEndpoint(t, ""))
+	defer ts.Close()
+	c := NewClient(ts.URL, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+	SetDefault(c)
+
+	assert.Equal(t, c, Default())
+	assert.NoError(t, WriteEvent(NewEvent("hello")))
+	assert.NoError(t, WriteBatch([]*Event{NewEvent("one"), NewEvent("two")}))
+}
@@ -0,0 +1,328 @@
+// Package dirwatch forwards files dropped into a directory to Splunk HEC
+// and deletes or archives them once they are delivered.
+package dirwatch
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/fuyufjh/splunk-hec-go"
+)
+
+const (
+	defaultSettleTime = 1 * time.Second
+
+	// Min interval of checking for settled files
+	minSettleCheck = 10 * time.Millisecond
+
+	// Lines per batch in event mode, and per checkpoint
+	eventBatchSize = 1000
+)
+
+type Watcher struct {
+	// Watched directory (required)
+	dir string
+
+	// Glob matched against the names of files in dir (required)
+	pattern string
+
+	// HEC client to forward files to (required)
+	client hec.HEC
+
+	// Metadata of forwarded files; the source defaults to the file path (optional)
+	metadata *hec.EventMetadata
+
+	// Send every line as an event instead of using raw mode (optional, default: false)
+	eventMode bool
+
+	// File to persist the offsets of partly forwarded files into (optional)
+	checkpointFile string
+
+	// Directory to move forwarded files into instead of deleting them (optional)
+	archiveDir string
+
+	// Wait for indexer acknowledgement before deleting or archiving files (optional, default: true)
+	ack bool
+
+	// Time without writes after which a file is complete (optional, default: 1s)
+	settleTime time.Duration
+
+	// Called with files failed to be forwarded, which are retried once the
+	// settle time passed again (optional)
+	errorHandler func(path string, err error)
+
+	// Files to forward, by the time of their last change
+	pending map[string]time.Time
+
+	// Offsets of partly forwarded files
+	offsets map[string]int64
+}
+
+func NewWatcher(dir string, pattern string, client hec.HEC) *Watcher {
+	return &Watcher{
+		dir:        dir,
+		pattern:    pattern,
+		client:     client,
+		ack:        true,
+		settleTime: defaultSettleTime,
+		pending:    make(map[string]time.Time),
+		offsets:    make(map[string]int64),
+	}
+}
+
+func (w *Watcher) SetMetadata(metadata *hec.EventMetadata) {
+	w.metadata = metadata
+}
+
+func (w *Watcher) SetEventMode(enable bool) {
+	w.eventMode = enable
+}
+
+func (w *Watcher) SetCheckpointFile(path string) {
+	w.checkpointFile = path
+}
+
+func (w *Watcher) SetArchiveDir(dir string) {
+	w.archiveDir = dir
+}
+
+// SetAck sets whether files are deleted or archived only once the indexer
+// acknowledged their data (default: true), which requires indexer
+// acknowledgement enabled for the HEC token. Disabling it deletes files as
+// soon as HEC accepted them, so data lost by an indexer is lost for good.
+func (w *Watcher) SetAck(enable bool) {
+	w.ack = enable
+}
+
+func (w *Watcher) SetSettleTime(settleTime time.Duration) {
+	w.settleTime = settleTime
+}
+
+func (w *Watcher) SetErrorHandler(handler func(path string, err error)) {
+	w.errorHandler = handler
+}
+
+// Run forwards the matching files in the directory and files created in or
+// moved into it, once they have not changed for the settle time, until ctx
+// is done or the directory cannot be watched. Files failed to be forwarded
+// are reported to the error handler and retried, without holding up the
+// others. Forwarding resumes at the checkpoint of a file, so data accepted by
+// HEC is not sent again after a restart.
+func (w *Watcher) Run(ctx context.Context) error {
+	if _, err := filepath.Match(w.pattern, ""); err != nil {
+		return err
+	}
+	if err := w.loadCheckpoint(); err != nil {
+		return err
+	}
+	notify, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer notify.Close()
+	if err := notify.Add(w.dir); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			w.change(filepath.Join(w.dir, entry.Name()), time.Time{})
+		}
+	}
+
+	ticker := time.NewTicker(max(w.settleTime/2, minSettleCheck))
+	defer ticker.Stop()
+	for {
+		w.forwardSettled(ctx)
+		select {
+		case event := <-notify.Events:
+			if event.Has(fsnotify.Create) || event.Has(fsnotify.Write) {
+				w.change(event.Name, time.Now())
+			}
+		case err := <-notify.Errors:
+			return err
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// change marks a file as changed at time if its name matches the pattern
+func (w *Watcher) change(path string, time time.Time) {
+	if matched, _ := filepath.Match(w.pattern, filepath.Base(path)); matched {
+		w.pending[path] = time
+	}
+}
+
+func (w *Watcher) forwardSettled(ctx context.Context) {
+	for path, changed := range w.pending {
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(changed) < w.settleTime {
+			continue
+		}
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			delete(w.pending, path) // moved away or deleted before it settled
+			continue
+		}
+		if err == nil && !info.Mode().IsRegular() {
+			delete(w.pending, path)
+			continue
+		}
+		if err == nil {
+			err = w.forward(ctx, path)
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				w.fail(path, err)
+			}
+			continue
+		}
+		delete(w.pending, path)
+	}
+}
+
+// fail reports a file failed to be forwarded, and retries it after the settle time
+func (w *Watcher) fail(path string, err error) {
+	w.pending[path] = time.Now()
+	if w.errorHandler != nil {
+		w.errorHandler(path, err)
+	}
+}
+
+// forward sends a file from its checkpoint and deletes or archives it
+func (w *Watcher) forward(ctx context.Context, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	metadata := hec.EventMetadata{Source: hec.String(path)}
+	if w.metadata != nil {
+		metadata = *w.metadata
+		if metadata.Source == nil {
+			metadata.Source = hec.String(path)
+		}
+	}
+	checkpoint := func(offset int64) error {
+		w.offsets[path] = offset
+		return w.saveCheckpoint()
+	}
+	if w.eventMode {
+		err = w.forwardLines(ctx, file, &metadata, w.offsets[path], checkpoint)
+	} else {
+		err = w.client.WriteRawFromOffset(ctx, file, &metadata, w.offsets[path], checkpoint)
+	}
+	if err != nil {
+		return err
+	}
+	if w.ack {
+		if err := w.client.WaitForAcknowledgementWithContext(ctx); err != nil {
+			return err
+		}
+	}
+
+	file.Close()
+	if w.archiveDir != "" {
+		err = os.Rename(path, filepath.Join(w.archiveDir, filepath.Base(path)))
+	} else {
+		err = os.Remove(path)
+	}
+	if err != nil {
+		return err
+	}
+	delete(w.offsets, path)
+	return w.saveCheckpoint()
+}
+
+func (w *Watcher) forwardLines(ctx context.Context, file *os.File, metadata *hec.EventMetadata, offset int64, checkpoint func(offset int64) error) error {
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	reader := bufio.NewReader(file)
+	var events []*hec.Event
+	for {
+		line, err := reader.ReadString('\n')
+		if len(line) > 0 {
+			offset += int64(len(line))
+			event := hec.NewEvent(trimNewline(line))
+			event.Host = metadata.Host
+			event.Index = metadata.Index
+			event.Source = metadata.Source
+			event.SourceType = metadata.SourceType
+			events = append(events, event)
+		}
+		if len(events) > 0 && (len(events) >= eventBatchSize || err == io.EOF) {
+			if _, err := w.client.WriteBatchWithResponses(ctx, events); err != nil {
+				return err
+			}
+			if err := checkpoint(offset); err != nil {
+				return err
+			}
+			events = nil
+		}
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+func trimNewline(line string) string {
+	if n := len(line); n > 0 && line[n-1] == '\n' {
+		line = line[:n-1]
+		if n := len(line); n > 0 && line[n-1] == '\r' {
+			line = line[:n-1]
+		}
+	}
+	return line
+}
+
+func (w *Watcher) loadCheckpoint() error {
+	if w.checkpointFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(w.checkpointFile)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &w.offsets)
+}
+
+func (w *Watcher) saveCheckpoint() error {
+	if w.checkpointFile == "" {
+		return nil
+	}
+	data, _ := json.Marshal(w.offsets)
+	// Write atomically so a crash never leaves a corrupted checkpoint
+	tmp, err := os.CreateTemp(filepath.Dir(w.checkpointFile), ".checkpoint-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), w.checkpointFile)
+}
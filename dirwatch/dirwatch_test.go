@@ -0,0 +1,126 @@
+package dirwatch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fuyufjh/splunk-hec-go"
+	"github.com/fuyufjh/splunk-hec-go/hectest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testToken = "00000000-0000-0000-0000-000000000000"
+
+// run runs w until done returns true
+func run(t *testing.T, w *Watcher, done func() bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	errs := make(chan error, 1)
+	go func() { errs <- w.Run(ctx) }()
+	require.Eventually(t, done, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-errs, context.Canceled)
+}
+
+func TestRawMode(t *testing.T) {
+	server := hectest.NewServer(testToken)
+	defer server.Close()
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "a.log")
+	require.NoError(t, os.WriteFile(existing, []byte("line 1\nline 2\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "skipped.txt"), []byte("other\n"), 0o644))
+
+	w := NewWatcher(dir, "*.log", hec.NewClient(server.URL, testToken))
+	// Files are only deleted once acknowledged by default
+	assert.True(t, w.ack)
+	w.SetSettleTime(50 * time.Millisecond)
+	created := filepath.Join(dir, "b.log")
+	written := false
+	run(t, w, func() bool {
+		if len(server.Raw()) == 1 && !written {
+			os.WriteFile(created, []byte("line 3\n"), 0o644)
+			written = true
+		}
+		return len(server.Raw()) == 2
+	})
+
+	raw := server.Raw()
+	assert.Equal(t, "line 1\nline 2\n", string(raw[0].Data))
+	assert.Equal(t, existing, *raw[0].Metadata.Source)
+	assert.Equal(t, "line 3\n", string(raw[1].Data))
+	assert.NoFileExists(t, existing)
+	assert.NoFileExists(t, created)
+	assert.FileExists(t, filepath.Join(dir, "skipped.txt"))
+}
+
+func TestEventModeWithCheckpointAndArchive(t *testing.T) {
+	server := hectest.NewServer(testToken)
+	defer server.Close()
+	server.EnableAck(0)
+	dir, archive := t.TempDir(), t.TempDir()
+	path := filepath.Join(dir, "a.log")
+	require.NoError(t, os.WriteFile(path, []byte("line 1\nline 2\r\nline 3"), 0o644))
+	checkpointFile := filepath.Join(t.TempDir(), "checkpoint.json")
+	require.NoError(t, os.WriteFile(checkpointFile, []byte(`{"`+path+`":7}`), 0o644))
+
+	w := NewWatcher(dir, "*.log", hec.NewClient(server.URL, testToken))
+	w.SetEventMode(true)
+	w.SetAck(true)
+	w.SetArchiveDir(archive)
+	w.SetCheckpointFile(checkpointFile)
+	w.SetMetadata(&hec.EventMetadata{SourceType: hec.String("drop")})
+	run(t, w, func() bool { return len(server.Events()) == 2 })
+
+	events := server.Events()
+	assert.Equal(t, "line 2", events[0].Event.Event)
+	assert.Equal(t, "line 3", events[1].Event.Event)
+	assert.Equal(t, "drop", *events[0].SourceType)
+	assert.Equal(t, path, *events[0].Source)
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(filepath.Join(archive, "a.log"))
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	assert.NoFileExists(t, path)
+	data, err := os.ReadFile(checkpointFile)
+	require.NoError(t, err)
+	assert.Equal(t, "{}", string(data))
+}
+
+func TestFailingFile(t *testing.T) {
+	server := hectest.NewServer(testToken)
+	defer server.Close()
+	dir := t.TempDir()
+	bad, good := filepath.Join(dir, "a.log"), filepath.Join(dir, "b.log")
+	require.NoError(t, os.WriteFile(bad, []byte("too long for the client\n"), 0o644))
+	require.NoError(t, os.WriteFile(good, []byte("line 1\n"), 0o644))
+
+	client := hec.NewClient(server.URL, testToken)
+	client.SetMaxLineSize(10)
+	w := NewWatcher(dir, "*.log", client)
+	// Checked as often as possible
+	w.SetSettleTime(0)
+	var mtx sync.Mutex
+	var failed []string
+	w.SetErrorHandler(func(path string, err error) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		assert.ErrorIs(t, err, hec.ErrLineTooLong)
+		failed = append(failed, path)
+	})
+	run(t, w, func() bool {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return len(server.Raw()) == 1 && len(failed) >= 2
+	})
+
+	// The bad file is retried, and doesn't hold up the good one
+	assert.Equal(t, "line 1\n", string(server.Raw()[0].Data))
+	assert.NoFileExists(t, good)
+	assert.FileExists(t, bad)
+	assert.Equal(t, bad, failed[0])
+}
@@ -0,0 +1,17 @@
+module github.com/fuyufjh/splunk-hec-go/dirwatch
+
+go 1.23
+
+require (
+	github.com/fsnotify/fsnotify v1.10.1
+	github.com/fuyufjh/splunk-hec-go v0.0.0
+	github.com/stretchr/testify v1.12.1
+)
+
+require (
+	github.com/google/uuid v1.0.0 // indirect
+	go.yaml.in/yaml/v3 v3.0.5 // indirect
+	golang.org/x/sys v0.13.0 // indirect
+)
+
+replace github.com/fuyufjh/splunk-hec-go => ../
@@ -0,0 +1,27 @@
+package hec
+
+// Enricher adds fields or metadata to an event before it is sent. It gets a
+// copy of the event with a copy of its fields, so it may modify both, but not
+// the data of the event. It is called once per event written by a client,
+// again for a Cluster failing over and for audits of dropped events, and
+// from multiple goroutines.
+type Enricher func(event *Event)
+
+// WithEnrichers makes a client pass every event through enrichers, in order,
+// after the default metadata is applied
+func WithEnrichers(enrichers ...Enricher) Option {
+	return func(client *Client) {
+		client.enrichers = append(client.enrichers, enrichers...)
+	}
+}
+
+func (hec *Client) enrich(event *Event) {
+	fields := make(map[string]interface{}, len(event.Fields))
+	for key, value := range event.Fields {
+		fields[key] = value
+	}
+	event.Fields = fields
+	for _, enricher := range hec.enrichers {
+		enricher(event)
+	}
+}
@@ -0,0 +1,68 @@
+package hec
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithEnrichers(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	recorder := NewTestRecorder()
+	c := NewClient(ts.URL, testSplunkToken, WithTestMode(recorder), WithEnrichers(
+		func(event *Event) { event.SetField("pod", "web-1") },
+		func(event *Event) {
+			if event.Index == nil {
+				event.SetIndex("k8s")
+			}
+		},
+	))
+	c.SetHTTPClient(testHttpClient)
+	c.SetChannelMode(ChannelNone)
+	c.SetDefaultMetadata(&EventMetadata{SourceType: String("app")})
+
+	event := NewEvent("hello")
+	event.SetFields(map[string]interface{}{"level": "info"})
+	assert.NoError(t, c.WriteBatch([]*Event{event, NewEvent("world")}))
+	assert.Equal(t, `{"index":"k8s","sourcetype":"app","fields":{"level":"info","pod":"web-1"},"event":"hello"}`+
+		`{"index":"k8s","sourcetype":"app","fields":{"pod":"web-1"},"event":"world"}`, string(recorder.Requests()[0].Payload))
+	// The events of the caller are unchanged
+	assert.Nil(t, event.Index)
+	assert.Equal(t, map[string]interface{}{"level": "info"}, event.Fields)
+	assert.Equal(t, []Usage{{Index: "k8s", SourceType: "app", Events: 2, Bytes: int64(len(recorder.Requests()[0].Payload))}}, c.Stats().Usage)
+}
+
+func TestWithEnrichers_Once(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	// Every call routes the event to another index
+	calls := 0
+	recorder := NewTestRecorder()
+	c := NewClient(ts.URL, testSplunkToken, WithTestMode(recorder), WithEnrichers(func(event *Event) {
+		calls++
+		event.SetIndex(fmt.Sprintf("index-%d", calls))
+	}))
+	c.SetChannelMode(ChannelNone)
+	c.SetQuotas([]Quota{{Index: "index-1", Events: 10, Policy: QuotaError}})
+
+	assert.NoError(t, c.WriteEvent(NewEvent("event")))
+	assert.NoError(t, c.WriteBatch([]*Event{NewEvent("batch")}))
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, `{"index":"index-1","event":"event"}`, string(recorder.Requests()[0].Payload))
+	assert.Equal(t, `{"index":"index-2","event":"batch"}`, string(recorder.Requests()[1].Payload))
+	// The sent events are the ones accounted
+	usage := c.Stats().Usage
+	if assert.Len(t, usage, 2) {
+		assert.ElementsMatch(t, []string{"index-1", "index-2"}, []string{usage[0].Index, usage[1].Index})
+	}
+}
@@ -0,0 +1,51 @@
+package hec
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Environment variables read by NewClientFromEnv
+const (
+	EnvURL                = "SPLUNK_HEC_URL"
+	EnvURLs               = "SPLUNK_HEC_URLS" // Comma-separated
+	EnvToken              = "SPLUNK_HEC_TOKEN"
+	EnvIndex              = "SPLUNK_HEC_INDEX"
+	EnvCompression        = "SPLUNK_HEC_COMPRESSION"
+	EnvInsecureSkipVerify = "SPLUNK_HEC_INSECURE_SKIP_VERIFY"
+)
+
+// NewClientFromEnv creates a client configured by environment variables. It
+// returns a Cluster if SPLUNK_HEC_URLS has more than one URL, otherwise a
+// Client. The parameters are validated as by NewValidatedClient.
+func NewClientFromEnv() (HEC, error) {
+	var urls []string
+	for _, serverURL := range strings.Split(os.Getenv(EnvURLs), ",") {
+		if serverURL = strings.TrimSpace(serverURL); serverURL != "" {
+			urls = append(urls, serverURL)
+		}
+	}
+	if len(urls) == 0 {
+		if serverURL := os.Getenv(EnvURL); serverURL != "" {
+			urls = append(urls, serverURL)
+		}
+	}
+	if len(urls) == 0 {
+		return nil, &ConfigError{Param: EnvURL, Reason: "is not set"}
+	}
+
+	config := Config{
+		URLs:        urls,
+		Token:       os.Getenv(EnvToken),
+		Index:       os.Getenv(EnvIndex),
+		Compression: os.Getenv(EnvCompression),
+	}
+	if value := os.Getenv(EnvInsecureSkipVerify); value != "" {
+		var err error
+		if config.TLS.InsecureSkipVerify, err = strconv.ParseBool(value); err != nil {
+			return nil, &ConfigError{Param: EnvInsecureSkipVerify, Reason: "must be a boolean"}
+		}
+	}
+	return NewFromConfig(config)
+}
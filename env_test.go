@@ -0,0 +1,40 @@
+package hec
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewClientFromEnv(t *testing.T) {
+	t.Setenv(EnvURL, "https://splunk.example.com:8088")
+	t.Setenv(EnvToken, testSplunkToken)
+	t.Setenv(EnvIndex, "main")
+	t.Setenv(EnvCompression, "gzip")
+	t.Setenv(EnvInsecureSkipVerify, "true")
+
+	c, err := NewClientFromEnv()
+	if assert.NoError(t, err) {
+		client := c.(*Client)
+		assert.Equal(t, "https://splunk.example.com:8088", client.serverURL)
+		assert.Equal(t, "main", *client.defaults.Index)
+		assert.Equal(t, "gzip", client.current().Compression)
+		assert.True(t, client.httpClient.Transport.(*http.Transport).TLSClientConfig.InsecureSkipVerify)
+	}
+
+	t.Setenv(EnvURLs, "https://a:8088, https://b:8088")
+	c, err = NewClientFromEnv()
+	if assert.NoError(t, err) {
+		assert.Len(t, c.(*Cluster).clients, 2)
+	}
+
+	t.Setenv(EnvInsecureSkipVerify, "maybe")
+	_, err = NewClientFromEnv()
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+
+	t.Setenv(EnvInsecureSkipVerify, "")
+	t.Setenv(EnvToken, "")
+	_, err = NewClientFromEnv()
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+}
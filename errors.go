@@ -1,7 +1,13 @@
 package hec
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
 )
 
 // Response is response message from HEC. For example, `{"text":"Success","code":0}`.
@@ -10,29 +16,432 @@ type Response struct {
 	Code  int             `json:"code"`
 	AckID *int            `json:"ackId"` // Use a pointer so we can differentiate between a 0 and an ack ID not being specified
 	Acks  map[string]bool `json:"acks"`  // Splunk returns ack IDs as strings rather than ints
+
+	// Number of the rejected event. Errors of WriteBatch give the index of the event in the batch.
+	InvalidEventNumber *int `json:"invalid-event-number,omitempty"`
+
+	// HTTP status code and headers of the response
+	StatusCode int         `json:"-"`
+	Header     http.Header `json:"-"`
+
+	// Raw response body, including fields unknown to this package
+	Body []byte `json:"-"`
+
+	// Acknowledgement of a successful request, see Ack
+	ack *Ack
 }
 
 // Response status codes
 const (
-	StatusSuccess              = 0
-	StatusTokenDisabled        = 1
-	StatusTokenRequired        = 2
-	StatusInvalidAuthorization = 3
-	StatusInvalidToken         = 4
-	StatusNoData               = 5
-	StatusInvalidDataFormat    = 6
-	StatusIncorrectIndex       = 7
-	StatusInternalServerError  = 8
-	StatusServerBusy           = 9
-	StatusChannelMissing       = 10
-	StatusInvalidChannel       = 11
-	StatusEventFieldRequired   = 12
-	StatusEventFieldBlank      = 13
-	StatusAckDisabled          = 14
+	StatusSuccess                    = 0
+	StatusTokenDisabled              = 1
+	StatusTokenRequired              = 2
+	StatusInvalidAuthorization       = 3
+	StatusInvalidToken               = 4
+	StatusNoData                     = 5
+	StatusInvalidDataFormat          = 6
+	StatusIncorrectIndex             = 7
+	StatusInternalServerError        = 8
+	StatusServerBusy                 = 9
+	StatusChannelMissing             = 10
+	StatusInvalidChannel             = 11
+	StatusEventFieldRequired         = 12
+	StatusEventFieldBlank            = 13
+	StatusAckDisabled                = 14
+	StatusIndexedFieldsError         = 15
+	StatusQueryStringAuthDisabled    = 16
+	StatusHealthy                    = 17
+	StatusUnhealthyQueuesFull        = 18
+	StatusUnhealthyAckUnavailable    = 19
+	StatusUnhealthyQueuesFullAckDown = 20
+)
+
+// Errors for response status codes. A *Response returned as error wraps the
+// error of its code, so it can be checked with errors.Is, e.g.
+// errors.Is(err, hec.ErrServerBusy).
+var (
+	ErrTokenDisabled           = errors.New("Token disabled")
+	ErrTokenRequired           = errors.New("Token is required")
+	ErrInvalidAuthorization    = errors.New("Invalid authorization")
+	ErrInvalidToken            = errors.New("Invalid token")
+	ErrNoData                  = errors.New("No data")
+	ErrInvalidDataFormat       = errors.New("Invalid data format")
+	ErrIncorrectIndex          = errors.New("Incorrect index")
+	ErrInternalServerError     = errors.New("Internal server error")
+	ErrServerBusy              = errors.New("Server is busy")
+	ErrChannelMissing          = errors.New("Data channel is missing")
+	ErrInvalidChannel          = errors.New("Invalid data channel")
+	ErrEventFieldRequired      = errors.New("Event field is required")
+	ErrEventFieldBlank         = errors.New("Event field cannot be blank")
+	ErrAckDisabled             = errors.New("ACK is disabled")
+	ErrIndexedFieldsError      = errors.New("Error in handling indexed fields")
+	ErrQueryStringAuthDisabled = errors.New("Query string authorization is not enabled")
+	ErrUnhealthy               = errors.New("HEC is unhealthy")
+)
+
+// Category groups response status codes by the kind of failure
+type Category string
+
+// Categories of response status codes
+const (
+	CategoryNone       Category = ""            // Success or health check codes
+	CategoryAuth       Category = "auth"        // Token or authorization problems
+	CategoryDataFormat Category = "data-format" // Malformed or missing data
+	CategoryCapacity   Category = "capacity"    // Server overloaded or unhealthy
+	CategoryConfig     Category = "config"      // Server or client misconfiguration
+	CategoryUnknown    Category = "unknown"     // Codes unknown to this package
+)
+
+// statusInfo describes how the client handles a response status code
+type statusInfo struct {
+	// Error wrapped by responses with the code
+	err error
+
+	// Whether the request should be retried
+	retriable bool
+
+	category    Category
+	description string
+}
+
+var (
+	statusMtx sync.RWMutex
+
+	statusTable = map[int]statusInfo{
+		StatusSuccess:                    {description: "Success"},
+		StatusTokenDisabled:              {err: ErrTokenDisabled, category: CategoryAuth, description: "The token is disabled"},
+		StatusTokenRequired:              {err: ErrTokenRequired, category: CategoryAuth, description: "The request has no token"},
+		StatusInvalidAuthorization:       {err: ErrInvalidAuthorization, category: CategoryAuth, description: "The Authorization header is malformed"},
+		StatusInvalidToken:               {err: ErrInvalidToken, category: CategoryAuth, description: "The token is not valid"},
+		StatusNoData:                     {err: ErrNoData, category: CategoryDataFormat, description: "The request has no data"},
+		StatusInvalidDataFormat:          {err: ErrInvalidDataFormat, category: CategoryDataFormat, description: "The data is not valid JSON or not in HEC format"},
+		StatusIncorrectIndex:             {err: ErrIncorrectIndex, category: CategoryConfig, description: "The index does not exist or the token may not write to it"},
+		StatusInternalServerError:        {err: ErrInternalServerError, retriable: true, category: CategoryCapacity, description: "The server failed to handle the request"},
+		StatusServerBusy:                 {err: ErrServerBusy, retriable: true, category: CategoryCapacity, description: "The server is too busy to accept data"},
+		StatusChannelMissing:             {err: ErrChannelMissing, category: CategoryConfig, description: "Indexer acknowledgement requires a data channel"},
+		StatusInvalidChannel:             {err: ErrInvalidChannel, category: CategoryConfig, description: "The data channel is not a valid GUID"},
+		StatusEventFieldRequired:         {err: ErrEventFieldRequired, category: CategoryDataFormat, description: "An event has no event field"},
+		StatusEventFieldBlank:            {err: ErrEventFieldBlank, category: CategoryDataFormat, description: "An event has a blank event field"},
+		StatusAckDisabled:                {err: ErrAckDisabled, category: CategoryConfig, description: "Indexer acknowledgement is disabled for the token"},
+		StatusIndexedFieldsError:         {err: ErrIndexedFieldsError, category: CategoryDataFormat, description: "The indexed fields of an event are not valid"},
+		StatusQueryStringAuthDisabled:    {err: ErrQueryStringAuthDisabled, category: CategoryConfig, description: "Query string authorization is disabled for the token"},
+		StatusHealthy:                    {description: "HEC is healthy"},
+		StatusUnhealthyQueuesFull:        {err: ErrUnhealthy, retriable: true, category: CategoryCapacity, description: "HEC queues are full"},
+		StatusUnhealthyAckUnavailable:    {err: ErrUnhealthy, retriable: true, category: CategoryCapacity, description: "Indexer acknowledgement is unavailable"},
+		StatusUnhealthyQueuesFullAckDown: {err: ErrUnhealthy, retriable: true, category: CategoryCapacity, description: "HEC queues are full and indexer acknowledgement is unavailable"},
+	}
 )
 
+// RegisterStatus registers a response status code unknown to this package, or
+// overrides the handling of a known one. Responses with the code wrap err, and
+// are retried if retriable is true. Codes registered this way keep their
+// category and description if known, otherwise they are CategoryUnknown and
+// described by err.
+func RegisterStatus(code int, err error, retriable bool) {
+	statusMtx.Lock()
+	info, ok := statusTable[code]
+	if !ok {
+		info.category = CategoryUnknown
+		if err != nil {
+			info.description = err.Error()
+		}
+	}
+	info.err, info.retriable = err, retriable
+	statusTable[code] = info
+	statusMtx.Unlock()
+}
+
+// StatusCategory returns the category of a response status code
+func StatusCategory(code int) Category {
+	info, ok := lookupStatusOK(code)
+	if !ok {
+		return CategoryUnknown
+	}
+	return info.category
+}
+
+// StatusDescription returns an English description of a response status code,
+// independent of the text returned by the server
+func StatusDescription(code int) string {
+	info, ok := lookupStatusOK(code)
+	if !ok {
+		return fmt.Sprintf("Unknown status code %d", code)
+	}
+	return info.description
+}
+
+func lookupStatusOK(code int) (statusInfo, bool) {
+	statusMtx.RLock()
+	defer statusMtx.RUnlock()
+	info, ok := statusTable[code]
+	return info, ok
+}
+
+func lookupStatus(code int) statusInfo {
+	info, _ := lookupStatusOK(code)
+	return info
+}
+
+// Unwrap returns the error of the response status code, or nil for unknown codes
+func (res *Response) Unwrap() error {
+	return lookupStatus(res.Code).err
+}
+
 func retriable(code int) bool {
-	return code == StatusServerBusy || code == StatusInternalServerError
+	return lookupStatus(code).retriable
 }
 
 var ErrEventTooLong = errors.New("Event length is too long")
+
+// EventTooLongError is returned when events are longer than the max content
+// length. The other events of a batch are still sent. It matches
+// ErrEventTooLong with errors.Is.
+type EventTooLongError struct {
+	// Indexes of the too long events in the batch
+	Indexes []int
+
+	// Serialized sizes of the too long events
+	Sizes []int
+
+	// The too long events
+	Events []*Event
+
+	// Max content length
+	Limit int
+}
+
+func (e *EventTooLongError) add(index int, size int, event *Event) {
+	e.Indexes = append(e.Indexes, index)
+	e.Sizes = append(e.Sizes, size)
+	e.Events = append(e.Events, event)
+}
+
+func (e *EventTooLongError) Error() string {
+	return fmt.Sprintf("Event length is too long (%d events longer than %d bytes)", len(e.Indexes), e.Limit)
+}
+
+func (e *EventTooLongError) Is(target error) bool {
+	return target == ErrEventTooLong
+}
+
+var ErrLineTooLong = errors.New("Line length is too long")
+
+const maxLinePreview = 64
+
+// LineTooLongError is returned by raw mode when a line exceeds the max line size.
+// It matches ErrLineTooLong with errors.Is.
+type LineTooLongError struct {
+	// Number of the line in the stream, starting from 1
+	Line int
+
+	// Length of the line in bytes, or of the part read before it was found
+	// too long if it spans chunks
+	Length int
+
+	// Max line size
+	Limit int
+
+	// Beginning of the line
+	Preview string
+}
+
+func newLineTooLongError(number int, line []byte, limit int) *LineTooLongError {
+	preview := line
+	if len(preview) > maxLinePreview {
+		preview = preview[:maxLinePreview]
+	}
+	return &LineTooLongError{Line: number, Length: len(line), Limit: limit, Preview: string(preview)}
+}
+
+func (e *LineTooLongError) Error() string {
+	return fmt.Sprintf("Line %d length %d is too long (limit: %d): %q", e.Line, e.Length, e.Limit, e.Preview)
+}
+
+func (e *LineTooLongError) Is(target error) bool {
+	return target == ErrLineTooLong
+}
+
+// RequestError is returned when a request could not be sent to HEC or its
+// response could not be read. It never contains the HEC token.
+type RequestError struct {
+	// Server URL and endpoint of the request
+	URL string
+
+	// Attempt number of the request, starting from 1
+	Attempt int
+
+	// Size of the payload before compression
+	Size int
+
+	// Time elapsed until the request failed
+	Elapsed time.Duration
+
+	// Underlying error from net/http
+	Err error
+
+	// ErrCanceled, ErrDeadlineExceeded or ErrClientTimeout if the request failed
+	// because of one of them, otherwise nil
+	Reason error
+}
+
+func (e *RequestError) Error() string {
+	return fmt.Sprintf("Request to %s failed (attempt: %d, size: %d, elapsed: %v): %v", e.URL, e.Attempt, e.Size, e.Elapsed, e.Err)
+}
+
+func (e *RequestError) Is(target error) bool {
+	return e.Reason != nil && target == e.Reason
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+var ErrBadResponse = errors.New("Bad response from HEC")
+
+// BadResponseError is returned when the response body is not a HEC response,
+// e.g. an HTML error page from a proxy. It matches ErrBadResponse with errors.Is.
+type BadResponseError struct {
+	// HTTP status code of the response
+	StatusCode int
+
+	// Beginning of the response body
+	Body string
+
+	// Error from parsing the body
+	Err error
+}
+
+func (e *BadResponseError) Error() string {
+	return fmt.Sprintf("Bad response from HEC (status: %d): %q", e.StatusCode, e.Body)
+}
+
+func (e *BadResponseError) Is(target error) bool {
+	return target == ErrBadResponse || target == ErrServerTimeout && serverTimeout(e.StatusCode)
+}
+
+func (e *BadResponseError) Unwrap() error {
+	return e.Err
+}
+
+// PayloadInfo describes the payload of a failed write
+type PayloadInfo struct {
+	// Path and query of the HEC endpoint
+	Endpoint string
+
+	// Size of the payload before compression
+	Size int
+
+	// Number of events in the payload, 0 for raw data
+	Events int
+}
+
+var ErrRetriesExhausted = errors.New("Retries exhausted")
+
+// Attempt records a failed attempt to write to HEC
+type Attempt struct {
+	// Server URL the attempt was sent to
+	Server string
+
+	// Time the attempt was started
+	Time time.Time
+
+	// HTTP status code and HEC status code of the response, 0 if there is none
+	StatusCode int
+	Code       int
+
+	// Error of the attempt
+	Err error
+}
+
+func attemptOf(server string, startTime time.Time, err error) Attempt {
+	attempt := Attempt{Server: server, Time: startTime, Err: err}
+	var res *Response
+	if errors.As(err, &res) {
+		attempt.StatusCode = res.StatusCode
+		attempt.Code = res.Code
+	}
+	return attempt
+}
+
+// RetriesExhaustedError is returned when a write failed after all retries. It
+// wraps the error of the last attempt and matches ErrRetriesExhausted with
+// errors.Is.
+type RetriesExhaustedError struct {
+	// All failed attempts in order
+	Attempts []Attempt
+
+	// Error of the last attempt
+	Last error
+}
+
+func (e *RetriesExhaustedError) Error() string {
+	return fmt.Sprintf("Retries exhausted after %d attempts: %v", len(e.Attempts), e.Last)
+}
+
+func (e *RetriesExhaustedError) Is(target error) bool {
+	return target == ErrRetriesExhausted
+}
+
+func (e *RetriesExhaustedError) Unwrap() error {
+	return e.Last
+}
+
+// Reasons of failures caused by time limits, checked with errors.Is. Retry
+// logic usually gives up on ErrCanceled, but may retry on the others.
+var (
+	// The context of the caller was cancelled
+	ErrCanceled = errors.New("Canceled by caller")
+
+	// The deadline of the caller's context was exceeded
+	ErrDeadlineExceeded = errors.New("Deadline of caller exceeded")
+
+	// The timeout of the HTTP client was exceeded
+	ErrClientTimeout = errors.New("HTTP client timeout exceeded")
+
+	// The server or a proxy in front of it timed out (HTTP 408 or 504)
+	ErrServerTimeout = errors.New("Server timed out")
+)
+
+func failureReason(ctx context.Context, err error) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return ErrCanceled
+	case context.DeadlineExceeded:
+		return ErrDeadlineExceeded
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrClientTimeout
+	}
+	return nil
+}
+
+func serverTimeout(statusCode int) bool {
+	return statusCode == http.StatusRequestTimeout || statusCode == http.StatusGatewayTimeout
+}
+
+func (res *Response) Is(target error) bool {
+	return target == ErrServerTimeout && serverTimeout(res.StatusCode)
+}
+
+// ctxError is the error of a cancelled context, matching ErrCanceled or ErrDeadlineExceeded
+type ctxError struct {
+	reason error
+	err    error
+}
+
+func contextError(ctx context.Context) error {
+	return &ctxError{reason: failureReason(ctx, nil), err: ctx.Err()}
+}
+
+func (e *ctxError) Error() string {
+	return e.err.Error()
+}
+
+func (e *ctxError) Is(target error) bool {
+	return target == e.reason
+}
+
+func (e *ctxError) Unwrap() error {
+	return e.err
+}
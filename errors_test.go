@@ -0,0 +1,83 @@
+package hec
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponse_Unwrap(t *testing.T) {
+	assert.ErrorIs(t, &Response{Text: "Server is busy", Code: StatusServerBusy}, ErrServerBusy)
+	assert.ErrorIs(t, &Response{Code: StatusUnhealthyQueuesFull}, ErrUnhealthy)
+	assert.True(t, retriable(StatusUnhealthyAckUnavailable))
+	assert.False(t, retriable(StatusInvalidToken))
+	assert.Nil(t, (&Response{Code: 99}).Unwrap())
+}
+
+func TestRegisterStatus(t *testing.T) {
+	errThrottled := errors.New("Throttled by gateway")
+	RegisterStatus(429, errThrottled, true)
+	defer func() {
+		statusMtx.Lock()
+		delete(statusTable, 429)
+		statusMtx.Unlock()
+	}()
+
+	assert.ErrorIs(t, &Response{Code: 429}, errThrottled)
+	assert.True(t, retriable(429))
+	assert.Equal(t, CategoryUnknown, StatusCategory(429))
+	assert.Equal(t, "Throttled by gateway", StatusDescription(429))
+}
+
+func TestStatusCategory(t *testing.T) {
+	assert.Equal(t, CategoryNone, StatusCategory(StatusSuccess))
+	assert.Equal(t, CategoryAuth, StatusCategory(StatusInvalidToken))
+	assert.Equal(t, CategoryDataFormat, StatusCategory(StatusEventFieldBlank))
+	assert.Equal(t, CategoryCapacity, StatusCategory(StatusServerBusy))
+	assert.Equal(t, CategoryConfig, StatusCategory(StatusIncorrectIndex))
+	assert.Equal(t, CategoryUnknown, StatusCategory(99))
+
+	assert.Equal(t, "The token is not valid", StatusDescription(StatusInvalidToken))
+	assert.Equal(t, "Unknown status code 99", StatusDescription(99))
+}
+
+func TestFailureReasons(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(504)
+		w.Write([]byte(`<html>Gateway Timeout</html>`))
+	}))
+	event := NewEvent("hello, world")
+
+	c := NewClient(slow.URL, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+	err := c.WriteEvent(event)
+	assert.ErrorIs(t, err, ErrClientTimeout)
+	assert.NotErrorIs(t, err, ErrCanceled)
+
+	c.SetHTTPClient(http.DefaultClient)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = c.WriteBatchWithContext(ctx, []*Event{event})
+	assert.ErrorIs(t, err, ErrDeadlineExceeded)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	err = c.WriteBatchWithContext(ctx, []*Event{event})
+	assert.ErrorIs(t, err, ErrCanceled)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	c = NewClient(gateway.URL, testSplunkToken)
+	err = c.WriteEvent(event)
+	assert.ErrorIs(t, err, ErrServerTimeout)
+	assert.NotErrorIs(t, err, ErrClientTimeout)
+}
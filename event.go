@@ -78,3 +78,77 @@ func epochTime(t *time.Time) string {
 func String(str string) *string {
 	return &str
 }
+
+func Int(i int) *int {
+	return &i
+}
+
+func Float(f float64) *float64 {
+	return &f
+}
+
+// Time returns the epoch time string expected by Event.Time
+func Time(t time.Time) *string {
+	return String(epochTime(&t))
+}
+
+// Ptr returns a pointer to any value, e.g. hec.Ptr(time.Now()) for EventMetadata.Time
+func Ptr[T any](v T) *T {
+	return &v
+}
+
+// BatchBuilder builds batches of events that mostly share the same metadata.
+// Equal host, index, source, sourcetype and time strings are interned, so all
+// events in the batch point to a single copy of every distinct value.
+type BatchBuilder struct {
+	strings map[string]*string
+	events  []*Event
+}
+
+func NewBatchBuilder() *BatchBuilder {
+	return &BatchBuilder{strings: make(map[string]*string)}
+}
+
+// Add appends a new event with the given metadata to the batch and returns it
+func (b *BatchBuilder) Add(data interface{}, metadata *EventMetadata) *Event {
+	event := NewEvent(data)
+	if metadata != nil {
+		event.Host = b.intern(metadata.Host)
+		event.Index = b.intern(metadata.Index)
+		event.Source = b.intern(metadata.Source)
+		event.SourceType = b.intern(metadata.SourceType)
+		if metadata.Time != nil {
+			event.Time = b.intern(String(epochTime(metadata.Time)))
+		}
+	}
+	b.events = append(b.events, event)
+	return event
+}
+
+// Events returns all events added to the batch so far
+func (b *BatchBuilder) Events() []*Event {
+	return b.events
+}
+
+func (b *BatchBuilder) intern(str *string) *string {
+	if str == nil {
+		return nil
+	}
+	if interned, ok := b.strings[*str]; ok {
+		return interned
+	}
+	// A copy, so changing the metadata of the caller doesn't change the events
+	interned := String(*str)
+	b.strings[*str] = interned
+	return interned
+}
+
+// NewBatch builds a batch of events which all share the same metadata
+func NewBatch(metadata *EventMetadata, data ...interface{}) []*Event {
+	b := NewBatchBuilder()
+	b.events = make([]*Event, 0, len(data))
+	for _, d := range data {
+		b.Add(d, metadata)
+	}
+	return b.events
+}
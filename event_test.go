@@ -0,0 +1,60 @@
+package hec
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchBuilder(t *testing.T) {
+	b := NewBatchBuilder()
+	e1 := b.Add("event one", &EventMetadata{Host: String("localhost"), Source: String("test")})
+	e2 := b.Add("event two", &EventMetadata{Host: String("localhost"), Source: String("other")})
+	e3 := b.Add("event three", nil)
+
+	assert.Len(t, b.Events(), 3)
+	assert.Same(t, e1.Host, e2.Host)
+	assert.NotSame(t, e1.Source, e2.Source)
+	assert.Equal(t, "other", *e2.Source)
+	assert.Nil(t, e3.Host)
+
+	// Events keep the metadata they were added with
+	metadata := &EventMetadata{Host: String("changed"), Index: String("main")}
+	e4 := b.Add("event four", metadata)
+	*metadata.Host, *metadata.Index = "other", "other"
+	assert.Equal(t, "changed", *e4.Host)
+	assert.Equal(t, "main", *e4.Index)
+	assert.Equal(t, "other", *b.Add("event five", metadata).Host)
+}
+
+func TestNewBatch(t *testing.T) {
+	now := time.Unix(1485237827, 123000000)
+	metadata := &EventMetadata{
+		Index:      String("main"),
+		SourceType: String("manual"),
+		Time:       &now,
+	}
+	events := NewBatch(metadata, "event one", "event two")
+
+	assert.Len(t, events, 2)
+	assert.Equal(t, "event two", events[1].Event)
+	assert.Equal(t, "1485237827.123", *events[0].Time)
+	assert.Same(t, events[0].Index, events[1].Index)
+	assert.Same(t, events[0].Time, events[1].Time)
+}
+
+func TestPointerHelpers(t *testing.T) {
+	now := time.Unix(1485237827, 123000000)
+	event := &Event{
+		Host:  String("localhost"),
+		Time:  Time(now),
+		Event: "hello, world",
+	}
+	assert.Equal(t, "1485237827.123", *event.Time)
+	assert.Equal(t, 42, *Int(42))
+	assert.Equal(t, 4.2, *Float(4.2))
+
+	metadata := EventMetadata{Time: Ptr(now)}
+	assert.True(t, now.Equal(*metadata.Time))
+}
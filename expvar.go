@@ -0,0 +1,46 @@
+package hec
+
+import (
+	"expvar"
+	"sync"
+)
+
+// Guards looking up, publishing and initializing maps, since expvar panics on
+// publishing a name twice and clients with the same prefix share the counters
+var expvarMtx sync.Mutex
+
+// WithExpvar publishes counters of a client as an expvar map named prefix:
+//
+//	sent     requests written successfully
+//	failed   writes that failed, as reported to the error handler
+//	retried  requests retried after a retriable response
+//	dropped  events dropped by quotas
+//
+// Clients with the same prefix share the counters. If prefix is the name of
+// another kind of variable, the counters are kept but not published.
+func WithExpvar(prefix string) Option {
+	expvarMtx.Lock()
+	vars, ok := expvar.Get(prefix).(*expvar.Map)
+	if !ok {
+		if expvar.Get(prefix) == nil {
+			vars = expvar.NewMap(prefix)
+		} else {
+			vars = new(expvar.Map)
+		}
+	}
+	for _, key := range []string{"sent", "failed", "retried", "dropped"} {
+		if vars.Get(key) == nil {
+			vars.Set(key, new(expvar.Int))
+		}
+	}
+	expvarMtx.Unlock()
+	return func(client *Client) {
+		client.vars = vars
+	}
+}
+
+func (hec *Client) countVar(key string) {
+	if hec.vars != nil {
+		hec.vars.Add(key, 1)
+	}
+}
@@ -0,0 +1,88 @@
+package hec
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithExpvar(t *testing.T) {
+	busy := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if busy {
+			w.WriteHeader(503)
+			w.Write([]byte(`{"text":"Server is busy","code":9}`))
+			return
+		}
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL, testSplunkToken, WithExpvar("hec_test"), WithTestMode(NewTestRecorder()))
+	c.SetHTTPClient(testHttpClient)
+	c.SetQuotas([]Quota{{Index: "debug", Events: 1}})
+	c.SetMaxRetry(1)
+	debug := NewEvent("debug")
+	debug.SetIndex("debug")
+	assert.NoError(t, c.WriteBatch([]*Event{NewEvent("one"), debug, debug, debug}))
+	busy = true
+	assert.Error(t, c.WriteEvent(NewEvent("four")))
+
+	vars := expvar.Get("hec_test").(*expvar.Map)
+	assert.Equal(t, "1", vars.Get("sent").String())
+	assert.Equal(t, "1", vars.Get("failed").String())
+	assert.Equal(t, "1", vars.Get("retried").String())
+	assert.Equal(t, "2", vars.Get("dropped").String())
+
+	// Clients with the same prefix share the counters
+	NewClient(ts.URL, testSplunkToken, WithExpvar("hec_test"))
+	assert.Equal(t, "1", vars.Get("sent").String())
+}
+
+func TestWithExpvar_NameTaken(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	// Published by the application as another kind of variable
+	if expvar.Get("hec_taken") == nil {
+		expvar.NewString("hec_taken").Set("taken")
+	}
+	var c HEC
+	assert.NotPanics(t, func() {
+		c = NewClient(ts.URL, testSplunkToken, WithExpvar("hec_taken"))
+	})
+	c.SetHTTPClient(testHttpClient)
+	assert.NoError(t, c.WriteEvent(NewEvent("one")))
+	assert.Equal(t, "1", c.(*Client).vars.Get("sent").String())
+	assert.Equal(t, `"taken"`, expvar.Get("hec_taken").String())
+}
+
+func TestWithExpvar_Concurrent(t *testing.T) {
+	// Clients created at the same time share the same counters
+	sent := 0
+	if published, ok := expvar.Get("hec_concurrent").(*expvar.Map); ok {
+		sent = int(published.Get("sent").(*expvar.Int).Value())
+	}
+	var wg sync.WaitGroup
+	vars := make([]*expvar.Map, 10)
+	for i := range vars {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c := NewClient("http://localhost:8088", testSplunkToken, WithExpvar("hec_concurrent"))
+			vars[i] = c.(*Client).vars
+			c.(*Client).countVar("sent")
+		}(i)
+	}
+	wg.Wait()
+	for _, v := range vars {
+		assert.Same(t, vars[0], v)
+	}
+	assert.Equal(t, int64(sent+10), vars[0].Get("sent").(*expvar.Int).Value())
+}
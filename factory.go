@@ -0,0 +1,83 @@
+package hec
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+)
+
+// ClientFactory creates clients sharing one HTTP client, a pool of
+// compression buffers and a limit of concurrent requests, e.g. for the
+// tenants of a multi-tenant gateway
+type ClientFactory struct {
+	httpClient *http.Client
+	buffers    *sync.Pool
+	workers    chan struct{}
+	gauge      *slotGauge
+}
+
+// NewClientFactory creates a factory of clients sending requests with
+// httpClient (http.DefaultClient if nil), at most maxConcurrent at a time
+// across all clients (0 for no limit)
+func NewClientFactory(httpClient *http.Client, maxConcurrent int) *ClientFactory {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	f := &ClientFactory{
+		httpClient: httpClient,
+		buffers:    &sync.Pool{New: func() interface{} { return new(bytes.Buffer) }},
+	}
+	if maxConcurrent > 0 {
+		f.workers = make(chan struct{}, maxConcurrent)
+		f.gauge = &slotGauge{capacity: maxConcurrent}
+	}
+	return f
+}
+
+// NewClient creates a client like hec.NewClient sharing the resources of the
+// factory. Options configuring TLS, e.g. WithTLS or WithCAFile, apply to a
+// clone of the transport of the factory, which the client doesn't share.
+func (f *ClientFactory) NewClient(serverURL string, token string, options ...Option) HEC {
+	client := NewClient(serverURL, token, f.withOptions(options)...).(*Client)
+	f.share(client)
+	return client
+}
+
+// NewCluster creates a cluster like hec.NewCluster sharing the resources of
+// the factory, see NewClient
+func (f *ClientFactory) NewCluster(serverURLs []string, token string, options ...Option) HEC {
+	cluster := NewCluster(serverURLs, token, f.withOptions(options)...).(*Cluster)
+	for _, client := range cluster.clients {
+		f.share(client)
+	}
+	return cluster
+}
+
+// withOptions returns options after one setting the HTTP client of the
+// factory, so options configuring the transport update a copy of it
+func (f *ClientFactory) withOptions(options []Option) []Option {
+	return append([]Option{func(client *Client) {
+		client.httpClient = f.httpClient
+	}}, options...)
+}
+
+func (f *ClientFactory) share(client *Client) {
+	client.buffers = f.buffers
+	client.workers = f.workers
+	client.gauge = f.gauge
+}
+
+func (hec *Client) getBuffer() *bytes.Buffer {
+	if hec.buffers == nil {
+		return new(bytes.Buffer)
+	}
+	buffer := hec.buffers.Get().(*bytes.Buffer)
+	buffer.Reset()
+	return buffer
+}
+
+func (hec *Client) putBuffer(buffer *bytes.Buffer) {
+	if hec.buffers != nil && buffer != nil {
+		hec.buffers.Put(buffer)
+	}
+}
@@ -0,0 +1,68 @@
+package hec
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientFactory(t *testing.T) {
+	var active, maxActive int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&active, 1)
+		for {
+			max := atomic.LoadInt32(&maxActive)
+			if n <= max || atomic.CompareAndSwapInt32(&maxActive, max, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&active, -1)
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	factory := NewClientFactory(testHttpClient, 2)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		client := factory.NewClient(ts.URL, testSplunkToken)
+		client.SetCompression("gzip")
+		assert.Equal(t, testHttpClient, client.(*Client).httpClient)
+		for j := 0; j < 3; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, client.WriteEvent(NewEvent("hello, world")))
+			}()
+		}
+	}
+	wg.Wait()
+	assert.LessOrEqual(t, maxActive, int32(2))
+
+	cluster := factory.NewCluster([]string{ts.URL, ts.URL}, testSplunkToken)
+	assert.NoError(t, cluster.WriteEvent(NewEvent("hello, world")))
+}
+
+func TestClientFactory_TLS(t *testing.T) {
+	ca := newTestCA(t)
+	ts := ca.newServer(t)
+	defer ts.Close()
+
+	factory := NewClientFactory(nil, 0)
+	c := factory.NewClient(ts.URL, testSplunkToken)
+	c.SetMaxRetry(0)
+	assert.Error(t, c.WriteEvent(NewEvent("untrusted")))
+
+	// The TLS config of the client is kept
+	c = factory.NewClient(ts.URL, testSplunkToken, WithTLS(&tls.Config{RootCAs: ca.pool}))
+	assert.NoError(t, c.WriteEvent(NewEvent("trusted")))
+	cluster := factory.NewCluster([]string{ts.URL}, testSplunkToken, WithTLS(&tls.Config{RootCAs: ca.pool}))
+	assert.NoError(t, cluster.WriteEvent(NewEvent("trusted")))
+	assert.Equal(t, http.DefaultClient, factory.httpClient)
+}
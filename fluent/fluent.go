@@ -0,0 +1,232 @@
+// Package fluenthec relays records of Fluent Bit and Fluentd agents to HEC.
+// It accepts the Fluent forward protocol, so agents ship to it with their
+// forward output.
+package fluenthec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+)
+
+// Relay writes the records received as events. The event is the record,
+// with binary values as strings, at the time of the record; the tag is the
+// source unless metadata sets one, and an indexed field "tag".
+//
+// Records of a message are written in one batch. If the message asks for an
+// acknowledgement, it is sent once the batch is written; otherwise, or if the
+// write fails, the connection is closed so that the agent sends the message
+// again.
+type Relay struct {
+	client   hec.HEC
+	metadata *hec.EventMetadata
+	now      func() time.Time
+}
+
+// NewRelay creates a relay writing events with client, with the host, index,
+// source and sourcetype of metadata (optional)
+func NewRelay(client hec.HEC, metadata *hec.EventMetadata) *Relay {
+	return &Relay{client: client, metadata: metadata, now: time.Now}
+}
+
+// ServeTCP relays the messages of connections accepted on listener, until it
+// is closed
+func (r *Relay) ServeTCP(listener net.Listener) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer conn.Close()
+			r.serveConn(conn)
+		}()
+	}
+}
+
+func (r *Relay) serveConn(conn io.ReadWriter) error {
+	decoder := newDecoder(conn)
+	for {
+		value, err := decoder.decode()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		message, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("message is not an array but %T", value)
+		}
+		events, option, err := r.events(message)
+		if err != nil {
+			return err
+		}
+		if _, err := r.client.WriteBatchWithResponses(context.Background(), events); err != nil {
+			return err
+		}
+		if chunk, ok := option["chunk"].(string); ok {
+			if _, err := conn.Write(encodeAck(chunk)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// events returns the events of a message in Message, Forward or
+// PackedForward mode, and its option
+func (r *Relay) events(message []interface{}) ([]*hec.Event, map[string]interface{}, error) {
+	if len(message) < 2 {
+		return nil, nil, fmt.Errorf("message has %d elements", len(message))
+	}
+	tag, ok := message[0].(string)
+	if !ok {
+		return nil, nil, fmt.Errorf("tag is not a string but %T", message[0])
+	}
+	option := func(i int) map[string]interface{} {
+		if len(message) > i {
+			if option, ok := message[i].(map[string]interface{}); ok {
+				return option
+			}
+		}
+		return nil
+	}
+
+	switch entries := message[1].(type) {
+	case []interface{}:
+		// Forward: [tag, [[time, record], ...], option]
+		events, err := r.entryEvents(tag, entries)
+		return events, option(2), err
+	case string, []byte:
+		// PackedForward: [tag, concatenated [time, record] entries, option]
+		data := toBytes(entries)
+		opt := option(2)
+		if opt["compressed"] == "gzip" {
+			var err error
+			if data, err = gunzip(data); err != nil {
+				return nil, nil, err
+			}
+		}
+		decoder := newDecoder(bytes.NewReader(data))
+		var packed []interface{}
+		for {
+			entry, err := decoder.decode()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, nil, err
+			}
+			packed = append(packed, entry)
+		}
+		events, err := r.entryEvents(tag, packed)
+		return events, opt, err
+	default:
+		// Message: [tag, time, record, option]
+		if len(message) < 3 {
+			return nil, nil, fmt.Errorf("message has %d elements", len(message))
+		}
+		event, err := r.event(tag, message[1], message[2])
+		if err != nil {
+			return nil, nil, err
+		}
+		return []*hec.Event{event}, option(3), nil
+	}
+}
+
+func (r *Relay) entryEvents(tag string, entries []interface{}) ([]*hec.Event, error) {
+	events := make([]*hec.Event, 0, len(entries))
+	for _, entry := range entries {
+		pair, ok := entry.([]interface{})
+		if !ok || len(pair) < 2 {
+			return nil, fmt.Errorf("entry is not a [time, record] array")
+		}
+		event, err := r.event(tag, pair[0], pair[1])
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func (r *Relay) event(tag string, t interface{}, record interface{}) (*hec.Event, error) {
+	fields, ok := record.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("record is not a map but %T", record)
+	}
+	event := hec.NewEvent(stringify(fields))
+	if r.metadata != nil {
+		event.Host = r.metadata.Host
+		event.Index = r.metadata.Index
+		event.Source = r.metadata.Source
+		event.SourceType = r.metadata.SourceType
+	}
+	if event.Source == nil {
+		event.SetSource(tag)
+	}
+	event.SetField("tag", tag)
+	switch t := t.(type) {
+	case time.Time:
+		event.SetTime(t)
+	case int64:
+		event.SetTime(time.Unix(t, 0))
+	case uint64:
+		event.SetTime(time.Unix(int64(t), 0))
+	case float64:
+		event.SetTime(time.Unix(0, int64(t*float64(time.Second))))
+	default:
+		event.SetTime(r.now())
+	}
+	return event, nil
+}
+
+// stringify converts binary values to strings, as some agents send strings
+// as binary, and extension values to their bytes
+func stringify(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case ext:
+		return v.data
+	case []interface{}:
+		for i := range v {
+			v[i] = stringify(v[i])
+		}
+	case map[string]interface{}:
+		for key := range v {
+			v[key] = stringify(v[key])
+		}
+	}
+	return value
+}
+
+func toBytes(value interface{}) []byte {
+	if s, ok := value.(string); ok {
+		return []byte(s)
+	}
+	return value.([]byte)
+}
+
+func gunzip(data []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(io.LimitReader(reader, maxBytesLength))
+}
@@ -0,0 +1,197 @@
+package fluenthec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"sort"
+	"testing"
+	"time"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+	"github.com/fuyufjh/splunk-hec-go/hectest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testToken = "00000000-0000-0000-0000-000000000000"
+
+// eventTime is encoded as the EventTime extension
+type eventTime time.Time
+
+// pack encodes values as msgpack
+func pack(values ...interface{}) []byte {
+	var buffer bytes.Buffer
+	for _, value := range values {
+		packValue(&buffer, value)
+	}
+	return buffer.Bytes()
+}
+
+func packValue(buffer *bytes.Buffer, value interface{}) {
+	switch v := value.(type) {
+	case nil:
+		buffer.WriteByte(0xc0)
+	case bool:
+		if v {
+			buffer.WriteByte(0xc3)
+		} else {
+			buffer.WriteByte(0xc2)
+		}
+	case int:
+		buffer.WriteByte(0xd3)
+		binary.Write(buffer, binary.BigEndian, int64(v))
+	case float64:
+		buffer.WriteByte(0xcb)
+		binary.Write(buffer, binary.BigEndian, math.Float64bits(v))
+	case string:
+		buffer.WriteByte(0xdb)
+		binary.Write(buffer, binary.BigEndian, uint32(len(v)))
+		buffer.WriteString(v)
+	case []byte:
+		buffer.WriteByte(0xc6)
+		binary.Write(buffer, binary.BigEndian, uint32(len(v)))
+		buffer.Write(v)
+	case eventTime:
+		t := time.Time(v)
+		buffer.Write([]byte{0xd7, 0x00})
+		binary.Write(buffer, binary.BigEndian, uint32(t.Unix()))
+		binary.Write(buffer, binary.BigEndian, uint32(t.Nanosecond()))
+	case []interface{}:
+		buffer.WriteByte(0xdd)
+		binary.Write(buffer, binary.BigEndian, uint32(len(v)))
+		for _, item := range v {
+			packValue(buffer, item)
+		}
+	case map[string]interface{}:
+		buffer.WriteByte(0xdf)
+		binary.Write(buffer, binary.BigEndian, uint32(len(v)))
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			packValue(buffer, key)
+			packValue(buffer, v[key])
+		}
+	default:
+		panic(fmt.Sprintf("cannot pack %T", value))
+	}
+}
+
+type a = []interface{}
+type m = map[string]interface{}
+
+func TestDecoder(t *testing.T) {
+	data := []byte{
+		0x05, 0xff, 0xcc, 0xc8, 0xd1, 0xff, 0x38, // 5, -1, 200, -200
+		0x92, 0xa1, 'x', 0xc0, // ["x", nil]
+		0x81, 0x01, 0xc3, // {1: true}
+		0xca, 0x3f, 0xc0, 0x00, 0x00, // 1.5
+		0xc4, 0x02, 'h', 'i', // bin "hi"
+		0xd4, 0x05, 0x07, // fixext 1 of type 5
+	}
+	decoder := newDecoder(bytes.NewReader(data))
+	for _, expected := range []interface{}{int64(5), int64(-1), uint64(200), int64(-200), a{"x", nil}, m{"1": true}, 1.5, []byte("hi"), ext{typ: 5, data: []byte{7}}} {
+		value, err := decoder.decode()
+		require.NoError(t, err)
+		assert.Equal(t, expected, value)
+	}
+	_, err := decoder.decode()
+	assert.Equal(t, io.EOF, err)
+
+	_, err = newDecoder(bytes.NewReader([]byte{0xdb, 0xff, 0xff, 0xff, 0xff})).decode()
+	assert.Equal(t, errTooLarge, err)
+}
+
+func TestEncodeAck(t *testing.T) {
+	value, err := newDecoder(bytes.NewReader(encodeAck("chunk-id"))).decode()
+	require.NoError(t, err)
+	assert.Equal(t, m{"ack": "chunk-id"}, value)
+}
+
+// conn is a connection reading messages and recording responses
+type conn struct {
+	io.Reader
+	written bytes.Buffer
+}
+
+func (c *conn) Write(data []byte) (int, error) {
+	return c.written.Write(data)
+}
+
+func TestModes(t *testing.T) {
+	server := hectest.NewServer(testToken)
+	defer server.Close()
+	relay := NewRelay(hec.NewClient(server.URL, testToken), &hec.EventMetadata{Index: hec.String("main")})
+
+	at := time.Unix(1500000000, 123000000)
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write(pack(a{eventTime(at), m{"log": "packed 1"}}, a{eventTime(at), m{"log": "packed 2"}}))
+	gz.Close()
+
+	c := &conn{Reader: bytes.NewReader(pack(
+		a{"app.message", 1500000000, m{"log": []byte("message")}, m{"chunk": "c1"}},
+		a{"app.forward", a{a{eventTime(at), m{"log": "forward", "nested": m{"k": []byte("v")}}}}},
+		a{"app.packed", compressed.Bytes(), m{"compressed": "gzip", "chunk": "c2"}},
+	))}
+	require.NoError(t, relay.serveConn(c))
+
+	acks := newDecoder(&c.written)
+	for _, chunk := range []string{"c1", "c2"} {
+		ack, err := acks.decode()
+		require.NoError(t, err)
+		assert.Equal(t, m{"ack": chunk}, ack)
+	}
+
+	events := server.Events()
+	require.Len(t, events, 4)
+	assert.Equal(t, map[string]interface{}{"log": "message"}, events[0].Event.Event)
+	assert.Equal(t, "app.message", *events[0].Source)
+	assert.Equal(t, "main", *events[0].Index)
+	assert.Equal(t, "1500000000.000", *events[0].Time)
+	assert.Equal(t, "app.message", events[0].Fields["tag"])
+	assert.Equal(t, map[string]interface{}{"log": "forward", "nested": map[string]interface{}{"k": "v"}}, events[1].Event.Event)
+	assert.Equal(t, "1500000000.123", *events[1].Time)
+	assert.Equal(t, map[string]interface{}{"log": "packed 2"}, events[3].Event.Event)
+	assert.Equal(t, "app.packed", events[3].Fields["tag"])
+}
+
+func TestFailedWriteNotAcknowledged(t *testing.T) {
+	server := hectest.NewServer(testToken)
+	defer server.Close()
+	server.FailNext(hec.StatusIncorrectIndex)
+	relay := NewRelay(hec.NewClient(server.URL, testToken), nil)
+
+	c := &conn{Reader: bytes.NewReader(pack(a{"app", 0, m{"log": "x"}, m{"chunk": "c1"}}))}
+	assert.ErrorIs(t, relay.serveConn(c), hec.ErrIncorrectIndex)
+	assert.Zero(t, c.written.Len())
+}
+
+func TestServeTCP(t *testing.T) {
+	server := hectest.NewServer(testToken)
+	defer server.Close()
+	relay := NewRelay(hec.NewClient(server.URL, testToken), nil)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	done := make(chan error)
+	go func() { done <- relay.ServeTCP(listener) }()
+
+	client, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	client.Write(pack(a{"app", 0, m{"log": "over tcp"}, m{"chunk": "c1"}}))
+	ack, err := newDecoder(client).decode()
+	require.NoError(t, err)
+	assert.Equal(t, m{"ack": "c1"}, ack)
+	client.Close()
+	listener.Close()
+	assert.NoError(t, <-done)
+	assert.Len(t, server.Events(), 1)
+}
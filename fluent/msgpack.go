@@ -0,0 +1,215 @@
+package fluenthec
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// Limits of decoded values, to bound the memory taken by a bad peer
+const (
+	maxBytesLength = 64 << 20
+	maxItems       = 1 << 20
+)
+
+var errTooLarge = errors.New("msgpack value too large")
+
+// ext is a msgpack extension value
+type ext struct {
+	typ  int8
+	data []byte
+}
+
+// decoder decodes msgpack values into nil, bool, int64, uint64, float64,
+// string, []byte, []interface{}, map[string]interface{}, time.Time (for the
+// EventTime extension of Fluent) and ext
+type decoder struct {
+	reader *bufio.Reader
+}
+
+func newDecoder(reader io.Reader) *decoder {
+	return &decoder{reader: bufio.NewReader(reader)}
+}
+
+func (d *decoder) read(n int) ([]byte, error) {
+	if n > maxBytesLength {
+		return nil, errTooLarge
+	}
+	data := make([]byte, n)
+	_, err := io.ReadFull(d.reader, data)
+	return data, err
+}
+
+func (d *decoder) uint(n int) (uint64, error) {
+	data, err := d.read(n)
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, b := range data {
+		v = v<<8 | uint64(b)
+	}
+	return v, nil
+}
+
+func (d *decoder) decode() (interface{}, error) {
+	b, err := d.reader.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case b <= 0x7f:
+		return int64(b), nil
+	case b >= 0xe0:
+		return int64(int8(b)), nil
+	case b >= 0x80 && b <= 0x8f:
+		return d.decodeMap(int(b & 0x0f))
+	case b >= 0x90 && b <= 0x9f:
+		return d.decodeArray(int(b & 0x0f))
+	case b >= 0xa0 && b <= 0xbf:
+		data, err := d.read(int(b & 0x1f))
+		return string(data), err
+	}
+	switch b {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.uint(1 << (b - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		return d.readLength(n)
+	case 0xc7, 0xc8, 0xc9:
+		n, err := d.uint(1 << (b - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeExt(n)
+	case 0xca:
+		v, err := d.uint(4)
+		return float64(math.Float32frombits(uint32(v))), err
+	case 0xcb:
+		v, err := d.uint(8)
+		return math.Float64frombits(v), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return d.uint(1 << (b - 0xcc))
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (b - 0xd0)
+		v, err := d.uint(size)
+		// Sign-extend from size bytes
+		shift := 64 - 8*size
+		return int64(v<<shift) >> shift, err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.decodeExt(uint64(1) << (b - 0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.uint(1 << (b - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		data, err := d.readLength(n)
+		return string(data), err
+	case 0xdc, 0xdd:
+		n, err := d.uint(2 << (b - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		if n > maxItems {
+			return nil, errTooLarge
+		}
+		return d.decodeArray(int(n))
+	case 0xde, 0xdf:
+		n, err := d.uint(2 << (b - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		if n > maxItems {
+			return nil, errTooLarge
+		}
+		return d.decodeMap(int(n))
+	}
+	return nil, fmt.Errorf("invalid msgpack type 0x%02x", b)
+}
+
+func (d *decoder) readLength(n uint64) ([]byte, error) {
+	if n > maxBytesLength {
+		return nil, errTooLarge
+	}
+	return d.read(int(n))
+}
+
+func (d *decoder) decodeExt(n uint64) (interface{}, error) {
+	typ, err := d.reader.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	data, err := d.readLength(n)
+	if err != nil {
+		return nil, err
+	}
+	// EventTime: seconds and nanoseconds as big-endian 32-bit integers
+	if typ == 0 && len(data) == 8 {
+		return time.Unix(int64(binary.BigEndian.Uint32(data)), int64(binary.BigEndian.Uint32(data[4:]))), nil
+	}
+	return ext{typ: int8(typ), data: data}, nil
+}
+
+func (d *decoder) decodeArray(n int) ([]interface{}, error) {
+	array := make([]interface{}, 0, min(n, 1024))
+	for i := 0; i < n; i++ {
+		v, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		array = append(array, v)
+	}
+	return array, nil
+}
+
+// decodeMap decodes a map, formatting keys which are not strings
+func (d *decoder) decodeMap(n int) (map[string]interface{}, error) {
+	m := make(map[string]interface{}, min(n, 1024))
+	for i := 0; i < n; i++ {
+		key, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		value, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		switch k := key.(type) {
+		case string:
+			m[k] = value
+		case []byte:
+			m[string(k)] = value
+		default:
+			m[fmt.Sprint(k)] = value
+		}
+	}
+	return m, nil
+}
+
+// encodeAck encodes the response {"ack": chunk} to an option requesting an
+// acknowledgement
+func encodeAck(chunk string) []byte {
+	data := []byte{0x81, 0xa3, 'a', 'c', 'k'}
+	switch n := len(chunk); {
+	case n < 32:
+		data = append(data, 0xa0|byte(n))
+	case n < 1<<8:
+		data = append(data, 0xd9, byte(n))
+	case n < 1<<16:
+		data = append(data, 0xda, byte(n>>8), byte(n))
+	default:
+		data = append(data, 0xdb, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	return append(data, chunk...)
+}
@@ -0,0 +1,37 @@
+// Package gcpsecrets provides HEC tokens stored in Google Cloud Secret
+// Manager.
+package gcpsecrets
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	hec "github.com/fuyufjh/splunk-hec-go"
+	"github.com/googleapis/gax-go/v2"
+)
+
+// API accesses secret versions, e.g. a *secretmanager.Client
+type API interface {
+	AccessSecretVersion(ctx context.Context, request *secretmanagerpb.AccessSecretVersionRequest, options ...gax.CallOption) (*secretmanagerpb.AccessSecretVersionResponse, error)
+}
+
+// NewTokenProvider returns a provider reading the token from the secret
+// version name with api, like "projects/p/secrets/hec-token/versions/latest",
+// cached for ttl or until HEC rejects it (0 to cache until rejected).
+// Surrounding whitespace of the payload is trimmed.
+func NewTokenProvider(api API, name string, ttl time.Duration) hec.TokenProvider {
+	return hec.CachedToken(func(ctx context.Context) (string, error) {
+		response, err := api.AccessSecretVersion(ctx, &secretmanagerpb.AccessSecretVersionRequest{Name: name})
+		if err != nil {
+			return "", err
+		}
+		token := strings.TrimSpace(string(response.GetPayload().GetData()))
+		if token == "" {
+			return "", errors.New("secret version is empty")
+		}
+		return token, nil
+	}, ttl)
+}
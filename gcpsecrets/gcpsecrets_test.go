@@ -0,0 +1,50 @@
+package gcpsecrets
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	hec "github.com/fuyufjh/splunk-hec-go"
+	"github.com/googleapis/gax-go/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeAPI struct {
+	versions map[string]string
+	calls    int
+}
+
+func (f *fakeAPI) AccessSecretVersion(ctx context.Context, request *secretmanagerpb.AccessSecretVersionRequest, options ...gax.CallOption) (*secretmanagerpb.AccessSecretVersionResponse, error) {
+	f.calls++
+	data, ok := f.versions[request.Name]
+	if !ok {
+		return nil, errors.New("NotFound")
+	}
+	return &secretmanagerpb.AccessSecretVersionResponse{
+		Name:    request.Name,
+		Payload: &secretmanagerpb.SecretPayload{Data: []byte(data)},
+	}, nil
+}
+
+func TestNewTokenProvider(t *testing.T) {
+	const name = "projects/p/secrets/hec-token/versions/latest"
+	api := &fakeAPI{versions: map[string]string{name: "token\n", "projects/p/secrets/empty/versions/1": ""}}
+	ctx := context.Background()
+
+	provider := NewTokenProvider(api, name, 0)
+	token, err := provider.Token(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "token", token)
+	provider.Token(ctx)
+	assert.Equal(t, 1, api.calls)
+	provider.(hec.TokenInvalidator).InvalidateToken(token)
+	provider.Token(ctx)
+	assert.Equal(t, 2, api.calls)
+
+	_, err = NewTokenProvider(api, "projects/p/secrets/empty/versions/1", 0).Token(ctx)
+	assert.Error(t, err)
+	_, err = NewTokenProvider(api, "projects/p/secrets/missing/versions/1", 0).Token(ctx)
+	assert.Error(t, err)
+}
@@ -0,0 +1,208 @@
+// Package grpchec writes an event for every RPC of a gRPC server to HEC.
+package grpchec
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultQueueSize     = 1000
+	defaultBatchSize     = 100
+	defaultFlushInterval = time.Second
+)
+
+// Options configures a Logger
+type Options struct {
+	// Metadata of events, e.g. the index and sourcetype (optional)
+	Metadata *hec.EventMetadata
+
+	// Keys of the incoming gRPC metadata added to events as "metadata.<key>",
+	// e.g. "x-request-id" (optional)
+	MetadataKeys []string
+
+	// Share of successful RPCs logged, between 0 and 1 (default: 1). Failed
+	// RPCs are always logged. Events carry the rate as "sample_rate".
+	SampleRate float64
+
+	// Max number of events waiting to be written; further events are dropped
+	// (default: 1000)
+	QueueSize int
+
+	// Max number of events per batch (default: 100)
+	BatchSize int
+
+	// Max time an event waits for its batch (default: 1s)
+	FlushInterval time.Duration
+
+	// Called with errors of writes, from the goroutine of the logger (optional)
+	OnError func(err error)
+}
+
+// Logger provides interceptors writing an event for every RPC, with its
+// method, status code, duration in milliseconds and peer address. Events are
+// queued and written in batches by a goroutine, so RPCs never wait for HEC.
+type Logger struct {
+	client  hec.HEC
+	options Options
+
+	// Guards sends to queue against Close
+	mtx     sync.RWMutex
+	closed  bool
+	queue   chan *hec.Event
+	done    chan struct{}
+	dropped atomic.Int64
+}
+
+// New creates a logger writing with client and starts its goroutine
+func New(client hec.HEC, options *Options) *Logger {
+	l := &Logger{client: client}
+	if options != nil {
+		l.options = *options
+	}
+	if l.options.QueueSize <= 0 {
+		l.options.QueueSize = defaultQueueSize
+	}
+	if l.options.BatchSize <= 0 {
+		l.options.BatchSize = defaultBatchSize
+	}
+	if l.options.FlushInterval <= 0 {
+		l.options.FlushInterval = defaultFlushInterval
+	}
+	if l.options.SampleRate <= 0 || l.options.SampleRate > 1 {
+		l.options.SampleRate = 1
+	}
+	l.queue = make(chan *hec.Event, l.options.QueueSize)
+	l.done = make(chan struct{})
+	go l.run()
+	return l
+}
+
+// UnaryServerInterceptor logs unary RPCs
+func (l *Logger) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		response, err := handler(ctx, req)
+		l.log(ctx, "unary", info.FullMethod, start, err)
+		return response, err
+	}
+}
+
+// StreamServerInterceptor logs streaming RPCs when they end
+func (l *Logger) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, stream)
+		l.log(stream.Context(), "stream", info.FullMethod, start, err)
+		return err
+	}
+}
+
+func (l *Logger) log(ctx context.Context, kind string, method string, start time.Time, err error) {
+	duration := time.Since(start)
+	code := status.Code(err)
+	if code == codes.OK && l.options.SampleRate < 1 && rand.Float64() >= l.options.SampleRate {
+		return
+	}
+	fields := map[string]interface{}{
+		"type":        kind,
+		"method":      method,
+		"code":        code.String(),
+		"duration_ms": float64(duration.Microseconds()) / 1000,
+		"sample_rate": l.options.SampleRate,
+	}
+	// FullMethod is /package.Service/Method
+	if service, name, ok := strings.Cut(strings.TrimPrefix(method, "/"), "/"); ok {
+		fields["service"] = service
+		fields["rpc"] = name
+	}
+	if err != nil {
+		fields["error"] = status.Convert(err).Message()
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		fields["peer"] = p.Addr.String()
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, key := range l.options.MetadataKeys {
+			if values := md.Get(key); len(values) > 0 {
+				fields["metadata."+strings.ToLower(key)] = strings.Join(values, ",")
+			}
+		}
+	}
+	event := hec.NewEvent(fields)
+	if m := l.options.Metadata; m != nil {
+		event.Host, event.Index, event.Source, event.SourceType = m.Host, m.Index, m.Source, m.SourceType
+	}
+	event.SetTime(start)
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+	if l.closed {
+		l.dropped.Add(1)
+		return
+	}
+	select {
+	case l.queue <- event:
+	default:
+		l.dropped.Add(1)
+	}
+}
+
+// Dropped returns the number of events dropped because the queue was full or
+// the logger was closed
+func (l *Logger) Dropped() int64 {
+	return l.dropped.Load()
+}
+
+// Close writes the queued events and stops the goroutine. RPCs logged
+// afterwards are dropped, so stop the server first.
+func (l *Logger) Close() {
+	l.mtx.Lock()
+	if !l.closed {
+		l.closed = true
+		close(l.queue)
+	}
+	l.mtx.Unlock()
+	<-l.done
+}
+
+func (l *Logger) run() {
+	defer close(l.done)
+	ticker := time.NewTicker(l.options.FlushInterval)
+	defer ticker.Stop()
+	var batch []*hec.Event
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if _, err := l.client.WriteBatchWithResponses(context.Background(), batch); err != nil && l.options.OnError != nil {
+			l.options.OnError(err)
+		}
+		batch = nil
+	}
+	for {
+		select {
+		case event, ok := <-l.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, event)
+			if len(batch) >= l.options.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
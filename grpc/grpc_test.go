@@ -0,0 +1,101 @@
+package grpchec
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+	"github.com/fuyufjh/splunk-hec-go/hectest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+)
+
+const testToken = "00000000-0000-0000-0000-000000000000"
+
+// serve starts a gRPC server with the health service logged by logger
+func serve(t *testing.T, logger *Logger) healthpb.HealthClient {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(logger.UnaryServerInterceptor()),
+		grpc.StreamInterceptor(logger.StreamServerInterceptor()),
+	)
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("shop", healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return healthpb.NewHealthClient(conn)
+}
+
+func TestInterceptors(t *testing.T) {
+	server := hectest.NewServer(testToken)
+	defer server.Close()
+
+	logger := New(hec.NewClient(server.URL, testToken), &Options{MetadataKeys: []string{"X-Request-ID"}, FlushInterval: 10 * time.Millisecond})
+	client := serve(t, logger)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "abc")
+	_, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: "shop"})
+	require.NoError(t, err)
+	_, err = client.Check(ctx, &healthpb.HealthCheckRequest{Service: "unknown"})
+	require.Error(t, err)
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	stream, err := client.Watch(streamCtx, &healthpb.HealthCheckRequest{Service: "shop"})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.NoError(t, err)
+	cancel()
+	// The stream ends on the server once it sees the cancellation
+	require.Eventually(t, func() bool { return len(server.Events()) == 3 }, time.Second, 10*time.Millisecond)
+	logger.Close()
+
+	events := server.Events()
+	ok := events[0].Event.Event.(map[string]interface{})
+	assert.Equal(t, "unary", ok["type"])
+	assert.Equal(t, "/grpc.health.v1.Health/Check", ok["method"])
+	assert.Equal(t, "grpc.health.v1.Health", ok["service"])
+	assert.Equal(t, "Check", ok["rpc"])
+	assert.Equal(t, "OK", ok["code"])
+	assert.Equal(t, "abc", ok["metadata.x-request-id"])
+	assert.Contains(t, ok["peer"], "127.0.0.1:")
+
+	failed := events[1].Event.Event.(map[string]interface{})
+	assert.Equal(t, "NotFound", failed["code"])
+	assert.NotEmpty(t, failed["error"])
+
+	stream2 := events[2].Event.Event.(map[string]interface{})
+	assert.Equal(t, "stream", stream2["type"])
+	assert.Equal(t, "Canceled", stream2["code"])
+}
+
+func TestSampling(t *testing.T) {
+	server := hectest.NewServer(testToken)
+	defer server.Close()
+
+	logger := New(hec.NewClient(server.URL, testToken), &Options{SampleRate: 0.000001})
+	client := serve(t, logger)
+	for i := 0; i < 5; i++ {
+		client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "shop"})
+	}
+	client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "unknown"})
+	time.Sleep(10 * time.Millisecond)
+	logger.Close()
+
+	events := server.Events()
+	require.Len(t, events, 1)
+	assert.Equal(t, "NotFound", events[0].Event.Event.(map[string]interface{})["code"])
+	assert.Equal(t, 0.000001, events[0].Event.Event.(map[string]interface{})["sample_rate"])
+}
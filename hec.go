@@ -2,34 +2,183 @@ package hec
 
 import (
 	"context"
+	"crypto/tls"
 	"io"
 	"net/http"
+	"regexp"
+	"time"
 )
 
 type HEC interface {
 	SetHTTPClient(client *http.Client)
+
+	// SetTLSConfig makes the HTTP client connect with a copy of config, e.g. to
+	// set RootCAs or InsecureSkipVerify, keeping its other settings. Call it
+	// after SetHTTPClient, which replaces it.
+	SetTLSConfig(config *tls.Config)
+
 	SetKeepAlive(enable bool)
+
+	// SetChannel sets the channel of requests (default: a random GUID). Retries
+	// of a request reuse its channel and payload, and the acknowledgement of
+	// the attempt that succeeded is tracked, see WithIdempotencyKeys.
 	SetChannel(channel string)
+
+	// SetChannelMode sets how the channel is sent (default: ChannelInQuery)
+	SetChannelMode(mode ChannelMode)
+
 	SetMaxRetry(retries int)
 	SetMaxContentLength(size int)
+
+	// SetMaxBatchEvents sets the max number of events per request of a batch (default: 0, no limit)
+	SetMaxBatchEvents(n int)
+
 	SetCompression(compression string)
 
+	// SetCanonicalJSON makes events marshal with object keys in sorted order
+	SetCanonicalJSON(enable bool)
+
+	// SetRawDelimiter sets the delimiter of records in raw mode, e.g. "\r\n" (default: "\n")
+	SetRawDelimiter(delimiter string)
+
+	// SetRawRecordPattern makes raw mode treat every match of pattern as the start of a record.
+	// It takes precedence over the delimiter; set nil to disable it.
+	SetRawRecordPattern(pattern *regexp.Regexp)
+
+	// SetRawContinuationPattern joins delimited lines matching pattern (e.g. indented stack
+	// trace lines) with the previous record, so multi-line events are never split across
+	// chunks. The sourcetype in Splunk should be configured to merge such lines as well.
+	SetRawContinuationPattern(pattern *regexp.Regexp)
+
+	// SetMaxLineSize makes raw mode fail with a LineTooLongError instead of
+	// breaking lines longer than size bytes (default: 0, no limit)
+	SetMaxLineSize(size int)
+
+	// SetRawProgressHandler sets a handler called with the accumulated progress after
+	// every chunk sent by raw mode. Progress starts over if a Cluster fails over.
+	SetRawProgressHandler(handler func(progress RawProgress))
+
+	// SetRawOverhead reserves size bytes of every raw request for headers, so chunks stay
+	// within the content length limits of proxies which count the whole request. Once it
+	// is set, the length of the metadata query string is subtracted from chunks as well.
+	SetRawOverhead(size int)
+
+	// SetErrorHandler sets a handler called for every failed write, including each failed
+	// attempt on the nodes of a Cluster. It may be called from multiple goroutines.
+	SetErrorHandler(handler func(err error, payload PayloadInfo))
+
+	// SetRedactor sets how headers exposed in errors and wire captures are redacted, after
+	// the credentials (default: DefaultRedactor)
+	SetRedactor(redactor Redactor)
+
+	// SetFlushInterval sets the max time data of streaming writes is buffered;
+	// zero or less resets it (default: 1s)
+	SetFlushInterval(interval time.Duration)
+
+	// Settings returns the current settings adjustable at runtime
+	Settings() Settings
+
+	// UpdateSettings replaces all settings adjustable at runtime at once
+	UpdateSettings(settings Settings)
+
+	// SetValidationProfile makes events violating profile fail with an
+	// InvalidEventError instead of being sent (optional)
+	SetValidationProfile(profile *ValidationProfile)
+
+	// SetQuotas sets quotas of events per index or sourcetype (optional)
+	SetQuotas(quotas []Quota)
+
+	// SetClock sets the source of time for retries, flushes and acknowledgement polling (default: SystemClock)
+	SetClock(clock Clock)
+
+	// SetTokenProvider sets a provider of tokens replacing the static token (optional)
+	SetTokenProvider(provider TokenProvider)
+
+	// SetAuthScheme sets how the token is sent (default: AuthSplunk)
+	SetAuthScheme(scheme AuthScheme)
+
+	// SetSlowRequestThreshold makes requests taking at least threshold logged
+	// with a warning and their diagnostic context (default: 0, disabled)
+	SetSlowRequestThreshold(threshold time.Duration)
+
+	// Latencies returns latency percentiles of recent requests per node and endpoint
+	Latencies() []LatencyStats
+
+	// Stats returns the data written successfully by index and sourcetype
+	Stats() Stats
+
+	// Status returns the health of the client based on recent writes
+	Status() Status
+
+	// SetDefaultMetadata sets the host, index, source, sourcetype and time of
+	// events and raw data that don't set their own
+	SetDefaultMetadata(metadata *EventMetadata)
+
 	// WriteEvent writes single event via HEC json mode
 	WriteEvent(event *Event) error
 
+	// WriteEventWithContext writes single event via HEC json mode with a context for cancellation
+	WriteEventWithContext(ctx context.Context, event *Event) error
+
 	// WriteBatch writes multiple events via HCE batch mode
 	WriteBatch(events []*Event) error
 
 	// WriteBatchWithContext writes multiple events via HEC batch mode with a context for cancellation
 	WriteBatchWithContext(ctx context.Context, events []*Event) error
 
+	// WriteEventWithResponse writes single event via HEC json mode and returns
+	// the response of HEC, or nil if the event is empty. With indexer
+	// acknowledgement, Response.Ack waits for this event only.
+	WriteEventWithResponse(ctx context.Context, event *Event) (*Response, error)
+
+	// WriteBatchWithResponses writes multiple events via HEC batch mode and
+	// returns the responses of all requests sent, one per chunk of the batch
+	WriteBatchWithResponses(ctx context.Context, events []*Event) ([]*Response, error)
+
+	// WriteBatchWithMetadata writes multiple events via HEC batch mode, with
+	// the metadata they don't set, time included, taken from defaults before
+	// the default metadata of the client
+	WriteBatchWithMetadata(events []*Event, defaults *EventMetadata) error
+
+	// WriteBatchWithMetadataContext is WriteBatchWithMetadata with a context for cancellation
+	WriteBatchWithMetadataContext(ctx context.Context, events []*Event, defaults *EventMetadata) error
+
 	// WriteRaw writes raw data stream via HEC raw mode
 	WriteRaw(reader io.ReadSeeker, metadata *EventMetadata) error
 
 	// WriteRawWithContext writes raw data stream via HEC raw mode with a context for cancellation
 	WriteRawWithContext(ctx context.Context, reader io.ReadSeeker, metadata *EventMetadata) error
 
-	// WaitForAcknowledgement blocks until the Splunk indexer acknowledges data sent to it
+	// WriteRawWithMetadataFunc writes raw data stream via HEC raw mode with the metadata of
+	// every chunk (counted from 0) given by metadataFunc, e.g. to vary the source per chunk
+	WriteRawWithMetadataFunc(ctx context.Context, reader io.ReadSeeker, metadataFunc func(index int, chunk []byte) *EventMetadata) error
+
+	// WriteRawFromOffset writes raw data stream via HEC raw mode starting at offset, and calls
+	// checkpoint with the offset after every accepted chunk so an upload can be resumed
+	WriteRawFromOffset(ctx context.Context, reader io.ReadSeeker, metadata *EventMetadata, offset int64, checkpoint func(offset int64) error) error
+
+	// WriteRawWithTimeFunc writes raw data stream via HEC raw mode, sending consecutive lines
+	// with the same time extracted by timeFunc together with that time
+	WriteRawWithTimeFunc(ctx context.Context, reader io.ReadSeeker, metadata *EventMetadata, timeFunc func(line []byte) (time.Time, bool)) error
+
+	// WriteRawString writes raw data from a string via HEC raw mode
+	WriteRawString(data string, metadata *EventMetadata) error
+
+	// WriteRawLine writes a single line via HEC raw mode in one request, terminated by the
+	// line delimiter unless it ends with it
+	WriteRawLine(line string, metadata *EventMetadata) error
+
+	// WriteRawLineWithContext writes a single line via HEC raw mode in one request with a
+	// context for cancellation
+	WriteRawLineWithContext(ctx context.Context, line string, metadata *EventMetadata) error
+
+	// WriteRawChannel writes records received from a channel via HEC raw mode, batching them
+	// by max content length and flush interval. It returns once the channel is closed and
+	// the remaining records are sent, the context is cancelled, or a write fails.
+	WriteRawChannel(ctx context.Context, records <-chan []byte, metadata *EventMetadata) error
+
+	// WaitForAcknowledgement blocks until the Splunk indexer acknowledges data sent to it.
+	// To wait for the data of some writes only, see Response.Ack and WaitForAcks.
 	WaitForAcknowledgement() error
 
 	// WaitForAcknowledgementWithContext blocks until the Splunk indexer acknowledges data sent to it with a context for cancellation
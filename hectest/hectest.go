@@ -0,0 +1,323 @@
+// Package hectest provides an in-process HEC server for tests of code
+// writing to Splunk.
+package hectest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+)
+
+// Event is an event received in JSON mode
+type Event struct {
+	// Channel the event was sent with, empty without a channel
+	Channel string
+
+	hec.Event
+}
+
+// Raw is data received in raw mode
+type Raw struct {
+	Channel  string
+	Metadata hec.EventMetadata
+	Data     []byte
+}
+
+// Server is a HEC server checking requests like Splunk does and recording
+// the data of successful ones. It checks the token, the channel, gzip
+// compression and the format of events. A request is either recorded as a
+// whole or rejected as a whole.
+type Server struct {
+	*httptest.Server
+
+	token string
+
+	mtx       sync.Mutex
+	events    []Event
+	raw       []Raw
+	failures  []int
+	latency   time.Duration
+	ack       bool
+	ackPolls  int
+	nextAckID int
+	acks      map[int]int // Polls of pending acknowledgements until they are acknowledged
+}
+
+// NewServer starts a server accepting token
+func NewServer(token string) *Server {
+	s := &Server{token: token, acks: make(map[int]int)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Events returns the events received, in order
+func (s *Server) Events() []Event {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return append([]Event(nil), s.events...)
+}
+
+// Raw returns the raw data received, in order
+func (s *Server) Raw() []Raw {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return append([]Raw(nil), s.raw...)
+}
+
+// Reset forgets the received data
+func (s *Server) Reset() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.events = nil
+	s.raw = nil
+}
+
+// FailNext makes the next requests fail with the status codes, one request
+// per code, e.g. hec.StatusServerBusy
+func (s *Server) FailNext(codes ...int) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.failures = append(s.failures, codes...)
+}
+
+// SetLatency delays every response by latency
+func (s *Server) SetLatency(latency time.Duration) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.latency = latency
+}
+
+// EnableAck enables indexer acknowledgement: writes require a channel and
+// return an ack ID, which is acknowledged at the given number of polls of
+// the ack endpoint (0 for the first poll)
+func (s *Server) EnableAck(polls int) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.ack = true
+	s.ackPolls = polls
+}
+
+var guid = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// httpStatus is the HTTP status code of responses with a status code
+var httpStatus = map[int]int{
+	hec.StatusTokenDisabled:              http.StatusForbidden,
+	hec.StatusTokenRequired:              http.StatusUnauthorized,
+	hec.StatusInvalidAuthorization:       http.StatusUnauthorized,
+	hec.StatusInvalidToken:               http.StatusForbidden,
+	hec.StatusInternalServerError:        http.StatusInternalServerError,
+	hec.StatusServerBusy:                 http.StatusServiceUnavailable,
+	hec.StatusUnhealthyQueuesFull:        http.StatusServiceUnavailable,
+	hec.StatusUnhealthyAckUnavailable:    http.StatusServiceUnavailable,
+	hec.StatusUnhealthyQueuesFullAckDown: http.StatusServiceUnavailable,
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mtx.Lock()
+	latency := s.latency
+	s.mtx.Unlock()
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+
+	path := strings.TrimSuffix(r.URL.Path, "/1.0")
+	if path == "/services/collector/health" {
+		respond(w, &hec.Response{Code: hec.StatusHealthy})
+		return
+	}
+	if response := s.authorize(r); response != nil {
+		respond(w, response)
+		return
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if len(s.failures) > 0 {
+		code := s.failures[0]
+		s.failures = s.failures[1:]
+		respond(w, &hec.Response{Code: code})
+		return
+	}
+
+	channel := r.URL.Query().Get("channel")
+	if channel == "" {
+		channel = r.Header.Get("X-Splunk-Request-Channel")
+	}
+	raw := path == "/services/collector/raw"
+	if channel == "" && (s.ack || raw) {
+		respond(w, &hec.Response{Code: hec.StatusChannelMissing})
+		return
+	}
+	if channel != "" && !guid.MatchString(channel) {
+		respond(w, &hec.Response{Code: hec.StatusInvalidChannel})
+		return
+	}
+
+	body, err := readBody(r)
+	if err != nil {
+		respond(w, &hec.Response{Code: hec.StatusInvalidDataFormat})
+		return
+	}
+	switch path {
+	case "/services/collector", "/services/collector/event":
+		response := s.receiveEvents(channel, body)
+		respond(w, response)
+	case "/services/collector/raw":
+		if len(bytes.TrimSpace(body)) == 0 {
+			respond(w, &hec.Response{Code: hec.StatusNoData})
+			return
+		}
+		s.raw = append(s.raw, Raw{Channel: channel, Metadata: metadataOf(r), Data: body})
+		respond(w, s.success())
+	case "/services/collector/ack":
+		respond(w, s.acknowledge(body))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) authorize(r *http.Request) *hec.Response {
+	authorization := r.Header.Get("Authorization")
+	if authorization == "" {
+		return &hec.Response{Code: hec.StatusTokenRequired}
+	}
+	token := strings.TrimPrefix(authorization, "Splunk ")
+	if token == authorization {
+		// Splunk also takes the token as the password of basic authentication
+		_, password, ok := r.BasicAuth()
+		if !ok {
+			return &hec.Response{Code: hec.StatusInvalidAuthorization}
+		}
+		token = password
+	}
+	if token != s.token {
+		return &hec.Response{Code: hec.StatusInvalidToken}
+	}
+	return nil
+}
+
+func readBody(r *http.Request) ([]byte, error) {
+	reader := io.Reader(r.Body)
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gzipReader, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+	return io.ReadAll(reader)
+}
+
+// receiveEvents records the events of body, if they are all valid
+func (s *Server) receiveEvents(channel string, body []byte) *hec.Response {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	var events []Event
+	for number := 0; ; number++ {
+		var object map[string]json.RawMessage
+		if err := decoder.Decode(&object); err == io.EOF {
+			break
+		} else if err != nil {
+			return &hec.Response{Code: hec.StatusInvalidDataFormat, InvalidEventNumber: hec.Int(number)}
+		}
+		value, ok := object["event"]
+		if !ok || string(value) == "null" {
+			return &hec.Response{Code: hec.StatusEventFieldRequired, InvalidEventNumber: hec.Int(number)}
+		}
+		if string(value) == `""` {
+			return &hec.Response{Code: hec.StatusEventFieldBlank, InvalidEventNumber: hec.Int(number)}
+		}
+		if time, ok := object["time"]; ok && len(time) > 0 && time[0] != '"' && string(time) != "null" {
+			object["time"], _ = json.Marshal(string(time)) // Splunk takes numbers and strings
+		}
+		event := Event{Channel: channel}
+		raw, _ := json.Marshal(object)
+		if err := json.Unmarshal(raw, &event.Event); err != nil {
+			return &hec.Response{Code: hec.StatusInvalidDataFormat, InvalidEventNumber: hec.Int(number)}
+		}
+		events = append(events, event)
+	}
+	if len(events) == 0 {
+		return &hec.Response{Code: hec.StatusNoData}
+	}
+	s.events = append(s.events, events...)
+	return s.success()
+}
+
+func (s *Server) success() *hec.Response {
+	response := &hec.Response{Code: hec.StatusSuccess}
+	if s.ack {
+		response.AckID = hec.Int(s.nextAckID)
+		s.acks[s.nextAckID] = s.ackPolls
+		s.nextAckID++
+	}
+	return response
+}
+
+// acknowledge answers a poll of acknowledgements
+func (s *Server) acknowledge(body []byte) *hec.Response {
+	if !s.ack {
+		return &hec.Response{Code: hec.StatusAckDisabled}
+	}
+	var request struct {
+		Acks []int `json:"acks"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		return &hec.Response{Code: hec.StatusInvalidDataFormat}
+	}
+	acks := make(map[string]bool)
+	for _, id := range request.Acks {
+		polls, ok := s.acks[id]
+		acks[strconv.Itoa(id)] = ok && polls == 0
+		if ok && polls > 0 {
+			s.acks[id] = polls - 1
+		}
+	}
+	return &hec.Response{Acks: acks}
+}
+
+func metadataOf(r *http.Request) hec.EventMetadata {
+	var metadata hec.EventMetadata
+	query := r.URL.Query()
+	for name, field := range map[string]**string{
+		"host":       &metadata.Host,
+		"index":      &metadata.Index,
+		"source":     &metadata.Source,
+		"sourcetype": &metadata.SourceType,
+	} {
+		if query.Has(name) {
+			*field = hec.String(query.Get(name))
+		}
+	}
+	return metadata
+}
+
+func respond(w http.ResponseWriter, response *hec.Response) {
+	status := http.StatusOK
+	if response.Code != hec.StatusSuccess && response.Code != hec.StatusHealthy && response.Acks == nil {
+		status = http.StatusBadRequest
+		if s, ok := httpStatus[response.Code]; ok {
+			status = s
+		}
+	}
+	if response.Text == "" {
+		response.Text = "Success"
+		if err := response.Unwrap(); err != nil {
+			response.Text = err.Error()
+		}
+	}
+	body, _ := json.Marshal(response)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
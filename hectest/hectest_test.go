@@ -0,0 +1,183 @@
+package hectest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+)
+
+const testToken = "00000000-0000-0000-0000-000000000000"
+
+func TestEvents(t *testing.T) {
+	server := NewServer(testToken)
+	defer server.Close()
+
+	for _, compression := range []string{"", "gzip"} {
+		server.Reset()
+		client := hec.NewClient(server.URL, testToken)
+		client.SetCompression(compression)
+		event := hec.NewEvent("hello")
+		event.SetIndex("main")
+		event.SetTime(time.Unix(1500000000, 0))
+		event.SetFields(map[string]interface{}{"level": "info"})
+		if err := client.WriteBatch([]*hec.Event{event, hec.NewEvent(map[string]interface{}{"n": 1.0})}); err != nil {
+			t.Fatal(err)
+		}
+
+		events := server.Events()
+		if len(events) != 2 {
+			t.Fatalf("%q: got %d events", compression, len(events))
+		}
+		if events[0].Event.Event != "hello" || *events[0].Index != "main" || *events[0].Time != "1500000000.000" || events[0].Fields["level"] != "info" {
+			t.Errorf("%q: got %+v", compression, events[0].Event)
+		}
+		if events[1].Event.Event.(map[string]interface{})["n"] != 1.0 {
+			t.Errorf("%q: got %+v", compression, events[1].Event)
+		}
+		if events[0].Channel == "" {
+			t.Errorf("%q: no channel recorded", compression)
+		}
+	}
+}
+
+func TestRaw(t *testing.T) {
+	server := NewServer(testToken)
+	defer server.Close()
+
+	client := hec.NewClient(server.URL, testToken)
+	err := client.WriteRaw(strings.NewReader("line 1\nline 2\n"), &hec.EventMetadata{SourceType: hec.String("syslog")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := server.Raw()
+	if len(raw) != 1 || string(raw[0].Data) != "line 1\nline 2\n" || *raw[0].Metadata.SourceType != "syslog" {
+		t.Errorf("got %+v", raw)
+	}
+
+	client.SetChannelMode(hec.ChannelNone)
+	err = client.WriteRaw(strings.NewReader("line 3\n"), nil)
+	if !errors.Is(err, hec.ErrChannelMissing) {
+		t.Errorf("got %v, want %v", err, hec.ErrChannelMissing)
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	server := NewServer(testToken)
+	defer server.Close()
+
+	client := hec.NewClient(server.URL, "11111111-1111-1111-1111-111111111111")
+	err := client.WriteEvent(hec.NewEvent("hello"))
+	var response *hec.Response
+	if !errors.As(err, &response) || response.Code != hec.StatusInvalidToken || response.StatusCode != http.StatusForbidden {
+		t.Errorf("got %v", err)
+	}
+
+	request, _ := http.NewRequest(http.MethodPost, server.URL+"/services/collector", strings.NewReader(`{"event":"hello"}`))
+	resp, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("got HTTP %d without token", resp.StatusCode)
+	}
+	if len(server.Events()) != 0 {
+		t.Errorf("recorded unauthorized events")
+	}
+
+	client = hec.NewClient(server.URL, testToken)
+	client.SetAuthScheme(hec.AuthBasic)
+	if err := client.WriteEvent(hec.NewEvent("basic")); err != nil {
+		t.Errorf("basic authentication: %v", err)
+	}
+	client.SetAuthScheme(hec.AuthBearer)
+	if err := client.WriteEvent(hec.NewEvent("bearer")); !errors.As(err, &response) || response.Code != hec.StatusInvalidAuthorization {
+		t.Errorf("got %v with a bearer token", err)
+	}
+}
+
+func TestInvalidEvents(t *testing.T) {
+	server := NewServer(testToken)
+	defer server.Close()
+
+	for body, code := range map[string]int{
+		`{"event":"a"}{"time":1}`:   hec.StatusEventFieldRequired,
+		`{"event":"a"}{"event":""}`: hec.StatusEventFieldBlank,
+		`{"event":"a"}{"event":`:    hec.StatusInvalidDataFormat,
+		``:                          hec.StatusNoData,
+	} {
+		request, _ := http.NewRequest(http.MethodPost, server.URL+"/services/collector", strings.NewReader(body))
+		request.Header.Set("Authorization", "Splunk "+testToken)
+		resp, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var response hec.Response
+		json.NewDecoder(resp.Body).Decode(&response)
+		resp.Body.Close()
+		if response.Code != code {
+			t.Errorf("%q: got %+v, want code %d", body, response, code)
+		}
+		if code != hec.StatusNoData && (response.InvalidEventNumber == nil || *response.InvalidEventNumber != 1) {
+			t.Errorf("%q: got invalid event number %v", body, response.InvalidEventNumber)
+		}
+	}
+	if len(server.Events()) != 0 {
+		t.Errorf("recorded events of rejected requests")
+	}
+}
+
+func TestFailNext(t *testing.T) {
+	server := NewServer(testToken)
+	defer server.Close()
+
+	server.FailNext(hec.StatusIncorrectIndex)
+	client := hec.NewClient(server.URL, testToken)
+	if err := client.WriteEvent(hec.NewEvent("hello")); !errors.Is(err, hec.ErrIncorrectIndex) {
+		t.Errorf("got %v, want %v", err, hec.ErrIncorrectIndex)
+	}
+	if err := client.WriteEvent(hec.NewEvent("hello")); err != nil {
+		t.Error(err)
+	}
+	if len(server.Events()) != 1 {
+		t.Errorf("got %d events, want 1", len(server.Events()))
+	}
+}
+
+func TestLatency(t *testing.T) {
+	server := NewServer(testToken)
+	defer server.Close()
+
+	server.SetLatency(50 * time.Millisecond)
+	start := time.Now()
+	if err := hec.NewClient(server.URL, testToken).WriteEvent(hec.NewEvent("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("responded after %v", elapsed)
+	}
+}
+
+func TestAcknowledgement(t *testing.T) {
+	server := NewServer(testToken)
+	defer server.Close()
+
+	server.EnableAck(1)
+	client := hec.NewClient(server.URL, testToken)
+	if err := client.WriteEvent(hec.NewEvent("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.WaitForAcknowledgement(); err != nil {
+		t.Error(err)
+	}
+
+	client.SetChannelMode(hec.ChannelNone)
+	if err := client.WriteEvent(hec.NewEvent("hello")); !errors.Is(err, hec.ErrChannelMissing) {
+		t.Errorf("got %v, want %v", err, hec.ErrChannelMissing)
+	}
+}
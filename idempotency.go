@@ -0,0 +1,47 @@
+package hec
+
+import "github.com/google/uuid"
+
+// WithIdempotencyKeys makes a client set the field named field of every event
+// not having it to a random GUID before its first attempt, e.g. "event_id".
+//
+// A request is retried with the same channel and payload, and a Cluster
+// fails over to another node with them as well, since its clients share the
+// channel. An ambiguous failure such as a timeout after the indexer received
+// the data still makes HEC index the events twice; the GUID, which is the
+// same for every attempt, lets searches drop the duplicates with
+// "| dedup <field>". Events of the caller are not modified.
+func WithIdempotencyKeys(field string) Option {
+	return func(client *Client) {
+		client.idempotencyField = field
+	}
+}
+
+// withIdempotencyKey returns a copy of event with a GUID in the idempotency
+// field, or event itself if it has no need for one
+func (hec *Client) withIdempotencyKey(event *Event) *Event {
+	if hec.idempotencyField == "" || event == nil || event.empty() {
+		return event
+	}
+	if _, ok := event.Fields[hec.idempotencyField]; ok {
+		return event
+	}
+	copied := *event
+	copied.Fields = make(map[string]interface{}, len(event.Fields)+1)
+	for key, value := range event.Fields {
+		copied.Fields[key] = value
+	}
+	copied.Fields[hec.idempotencyField] = uuid.New().String()
+	return &copied
+}
+
+func (hec *Client) withIdempotencyKeys(events []*Event) []*Event {
+	if hec.idempotencyField == "" {
+		return events
+	}
+	result := make([]*Event, len(events))
+	for i, event := range events {
+		result[i] = hec.withIdempotencyKey(event)
+	}
+	return result
+}
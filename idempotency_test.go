@@ -0,0 +1,64 @@
+package hec
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithIdempotencyKeys(t *testing.T) {
+	var bodies, queries []string
+	fail := 1
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		queries = append(queries, r.URL.RawQuery)
+		if len(bodies) <= fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"text":"Server is busy","code":9}`))
+			return
+		}
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	c := NewClient(ts.URL, testSplunkToken, WithIdempotencyKeys("event_id"))
+	c.SetClock(NewManualClock(time.Unix(0, 0)))
+	event := NewEvent("hello")
+	keyed := NewEvent("keyed")
+	keyed.SetField("event_id", "mine")
+	require.NoError(t, c.WriteBatch([]*Event{event, keyed}))
+
+	// The retry sends the same channel and payload
+	require.Len(t, bodies, 2)
+	assert.Equal(t, bodies[0], bodies[1])
+	assert.Equal(t, queries[0], queries[1])
+	assert.Regexp(t, `^\{"fields":\{"event_id":"[0-9a-f-]{36}"\},"event":"hello"\}\{"fields":\{"event_id":"mine"\},"event":"keyed"\}$`, bodies[0])
+	// The events of the caller are unchanged
+	assert.Nil(t, event.Fields)
+
+	// Every event gets its own key
+	bodies, queries = nil, nil
+	fail = 0
+	require.NoError(t, c.WriteEvent(NewEvent("one")))
+	require.NoError(t, c.WriteEvent(NewEvent("one")))
+	assert.NotEqual(t, bodies[0], bodies[1])
+
+	// And keeps it when a Cluster fails over to another node
+	other := httptest.NewServer(handler)
+	defer other.Close()
+	bodies, queries = nil, nil
+	fail = 1
+	cluster := NewCluster([]string{ts.URL, other.URL}, testSplunkToken, WithIdempotencyKeys("event_id"))
+	require.NoError(t, cluster.WriteEvent(NewEvent("failover")))
+	if assert.Len(t, bodies, 2) {
+		assert.Equal(t, bodies[0], bodies[1])
+		assert.Equal(t, queries[0], queries[1])
+	}
+}
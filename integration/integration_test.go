@@ -0,0 +1,109 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+)
+
+var splunk *Splunk
+
+func TestMain(m *testing.M) {
+	var err error
+	splunk, err = Start(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	code := m.Run()
+	splunk.Stop()
+	os.Exit(code)
+}
+
+func newClient(token string) hec.HEC {
+	client := hec.NewClient(splunk.URL, token)
+	client.SetHTTPClient(splunk.HTTPClient)
+	return client
+}
+
+func TestWriteAndSearch(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	id := fmt.Sprintf("event-%d", time.Now().UnixNano())
+	client := newClient(splunk.Token)
+	client.SetCompression("gzip")
+	events := []*hec.Event{}
+	for i := 0; i < 10; i++ {
+		event := hec.NewEvent(fmt.Sprintf("%s %d", id, i))
+		event.SetSourceType("integration")
+		events = append(events, event)
+	}
+	if err := client.WriteBatch(events); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := splunk.WaitForSearch(ctx, "index=main sourcetype=integration "+id, len(events))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(raw) != len(events) {
+		t.Errorf("found %d events, want %d", len(raw), len(events))
+	}
+}
+
+func TestWriteRawAndSearch(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	id := fmt.Sprintf("raw-%d", time.Now().UnixNano())
+	client := newClient(splunk.Token)
+	data := fmt.Sprintf("%s 1\n%s 2\n%s 3\n", id, id, id)
+	err := client.WriteRaw(strings.NewReader(data), &hec.EventMetadata{SourceType: hec.String("integration_raw")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := splunk.WaitForSearch(ctx, "index=main sourcetype=integration_raw "+id, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(raw) != 3 {
+		t.Errorf("found %d events, want 3", len(raw))
+	}
+}
+
+func TestAcknowledgement(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	token, err := splunk.CreateToken(ctx, "integration-ack", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := fmt.Sprintf("ack-%d", time.Now().UnixNano())
+	client := newClient(token)
+	if err := client.WriteEvent(hec.NewEvent(id)); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.WaitForAcknowledgement(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := splunk.WaitForSearch(ctx, "index=main "+id, 1); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInvalidToken(t *testing.T) {
+	client := newClient("ffffffff-ffff-ffff-ffff-ffffffffffff")
+	client.SetMaxRetry(0)
+	if err := client.WriteEvent(hec.NewEvent("hello")); !errors.Is(err, hec.ErrInvalidToken) {
+		t.Errorf("got %v, want %v", err, hec.ErrInvalidToken)
+	}
+}
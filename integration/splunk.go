@@ -0,0 +1,219 @@
+//go:build integration
+
+// Package integration runs a Splunk container for end-to-end tests of the
+// client. It needs Docker and is only built with the integration build tag:
+//
+//	go test -tags integration ./integration/
+package integration
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultImage is the Splunk image started when SPLUNK_IMAGE is not set
+	DefaultImage = "splunk/splunk:latest"
+
+	// Password of the admin user of the container
+	Password = "integration-password"
+
+	startTimeout = 5 * time.Minute
+)
+
+// Splunk is a running Splunk container with HEC enabled
+type Splunk struct {
+	// ID of the container
+	ID string
+
+	// HEC URL and a token without indexer acknowledgement
+	URL   string
+	Token string
+
+	// Management URL for the REST API
+	ManagementURL string
+
+	// HTTP client accepting the self-signed certificates of the container
+	HTTPClient *http.Client
+}
+
+// Start starts a Splunk container, provisions a HEC token and waits until
+// HEC is healthy. The image is taken from SPLUNK_IMAGE.
+func Start(ctx context.Context) (*Splunk, error) {
+	image := os.Getenv("SPLUNK_IMAGE")
+	if image == "" {
+		image = DefaultImage
+	}
+	token := "00000000-0000-0000-0000-000000000001"
+	out, err := docker(ctx, "run", "-d", "-P",
+		"-e", "SPLUNK_START_ARGS=--accept-license",
+		"-e", "SPLUNK_GENERAL_TERMS=--accept-sgt-current-at-splunk-com",
+		"-e", "SPLUNK_PASSWORD="+Password,
+		"-e", "SPLUNK_HEC_TOKEN="+token,
+		image)
+	if err != nil {
+		return nil, err
+	}
+	s := &Splunk{
+		ID:    out,
+		Token: token,
+		HTTPClient: &http.Client{
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+			Timeout:   30 * time.Second,
+		},
+	}
+	if s.URL, err = s.address(ctx, "8088/tcp"); err != nil {
+		s.Stop()
+		return nil, err
+	}
+	if s.ManagementURL, err = s.address(ctx, "8089/tcp"); err != nil {
+		s.Stop()
+		return nil, err
+	}
+	if err := s.waitHealthy(ctx); err != nil {
+		s.Stop()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Stop removes the container
+func (s *Splunk) Stop() error {
+	_, err := docker(context.Background(), "rm", "-f", s.ID)
+	return err
+}
+
+// CreateToken provisions a HEC token, with indexer acknowledgement if ack
+func (s *Splunk) CreateToken(ctx context.Context, name string, ack bool) (string, error) {
+	form := url.Values{"name": {name}, "index": {"main"}, "useACK": {"0"}}
+	if ack {
+		form.Set("useACK", "1")
+	}
+	body, err := s.rest(ctx, "/servicesNS/nobody/splunk_httpinput/data/inputs/http", form)
+	if err != nil {
+		return "", err
+	}
+	match := regexp.MustCompile(`<s:key name="token">([^<]+)</s:key>`).FindSubmatch(body)
+	if match == nil {
+		return "", fmt.Errorf("no token in response: %s", body)
+	}
+	return string(match[1]), nil
+}
+
+// Search runs a search and returns the _raw field of the results
+func (s *Splunk) Search(ctx context.Context, query string) ([]string, error) {
+	body, err := s.rest(ctx, "/services/search/jobs/export", url.Values{
+		"search":        {"search " + query},
+		"output_mode":   {"json"},
+		"earliest_time": {"0"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var raw []string
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	for {
+		var line struct {
+			Result map[string]interface{} `json:"result"`
+		}
+		if err := decoder.Decode(&line); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if value, ok := line.Result["_raw"].(string); ok {
+			raw = append(raw, value)
+		}
+	}
+	return raw, nil
+}
+
+// WaitForSearch repeats a search until it returns count results, as events
+// become searchable shortly after they are indexed
+func (s *Splunk) WaitForSearch(ctx context.Context, query string, count int) ([]string, error) {
+	for {
+		raw, err := s.Search(ctx, query)
+		if err != nil || len(raw) >= count {
+			return raw, err
+		}
+		select {
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+			return raw, fmt.Errorf("got %d of %d results: %w", len(raw), count, ctx.Err())
+		}
+	}
+}
+
+func (s *Splunk) rest(ctx context.Context, path string, form url.Values) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, s.ManagementURL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	request.SetBasicAuth("admin", Password)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	response, err := s.HTTPClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s: HTTP %d: %s", path, response.StatusCode, body)
+	}
+	return body, nil
+}
+
+func (s *Splunk) address(ctx context.Context, port string) (string, error) {
+	out, err := docker(ctx, "port", s.ID, port)
+	if err != nil {
+		return "", err
+	}
+	// Take the first mapping, e.g. "0.0.0.0:32768"
+	address := strings.Fields(out)[0]
+	address = strings.Replace(address, "0.0.0.0", "127.0.0.1", 1)
+	return "https://" + address, nil
+}
+
+func (s *Splunk) waitHealthy(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, startTimeout)
+	defer cancel()
+	for {
+		request, _ := http.NewRequestWithContext(ctx, http.MethodGet, s.URL+"/services/collector/health", nil)
+		if response, err := s.HTTPClient.Do(request); err == nil {
+			response.Body.Close()
+			if response.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+		select {
+		case <-time.After(2 * time.Second):
+		case <-ctx.Done():
+			return fmt.Errorf("splunk did not become healthy: %w", ctx.Err())
+		}
+	}
+}
+
+func docker(ctx context.Context, args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("docker %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
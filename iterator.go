@@ -0,0 +1,106 @@
+package hec
+
+import "encoding/json"
+
+// BatchIterator splits a large number of events into batches of at most
+// MaxSize bytes of JSON and MaxEvents events, e.g. to backfill millions of
+// events with one batch in memory at a time. Events are marshaled one by one
+// to size the batches, and taken from their source only as batches are
+// requested:
+//
+//	it := hec.NewBatchIterator(events)
+//	for it.Next() {
+//		if err := client.WriteBatch(it.Batch()); err != nil {
+//			...
+//		}
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type BatchIterator struct {
+	// Max bytes of JSON per batch (default: the default max content length
+	// of clients). A larger event makes a batch on its own.
+	MaxSize int
+
+	// Max events per batch (default: 0, no limit)
+	MaxEvents int
+
+	next  func() (*Event, bool)
+	batch []*Event
+	size  int
+
+	// Event taken from the source which didn't fit into the last batch
+	pending     *Event
+	pendingSize int
+
+	err error
+}
+
+// NewBatchIterator creates an iterator over the batches of events
+func NewBatchIterator(events []*Event) *BatchIterator {
+	i := 0
+	return NewBatchIteratorFunc(func() (*Event, bool) {
+		if i == len(events) {
+			return nil, false
+		}
+		i++
+		return events[i-1], true
+	})
+}
+
+// NewBatchIteratorFunc creates an iterator over the batches of events
+// returned by next, until it returns false
+func NewBatchIteratorFunc(next func() (*Event, bool)) *BatchIterator {
+	return &BatchIterator{next: next}
+}
+
+// Next prepares the next batch, and returns false at the end of the events or
+// if an event cannot be marshaled
+func (it *BatchIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	maxSize := it.MaxSize
+	if maxSize <= 0 {
+		maxSize = defaultMaxContentLength
+	}
+	it.batch = nil
+	size := 0
+	if it.pending != nil {
+		it.batch = append(it.batch, it.pending)
+		size = it.pendingSize
+		it.pending = nil
+	}
+	for it.MaxEvents <= 0 || len(it.batch) < it.MaxEvents {
+		event, ok := it.next()
+		if !ok {
+			break
+		}
+		if event == nil || event.empty() {
+			continue
+		}
+		data, err := json.Marshal(event)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		if len(it.batch) > 0 && size+len(data) > maxSize {
+			it.pending, it.pendingSize = event, len(data)
+			break
+		}
+		it.batch = append(it.batch, event)
+		size += len(data)
+	}
+	it.size = size
+	return len(it.batch) > 0
+}
+
+// Batch returns the events of the batch prepared by Next
+func (it *BatchIterator) Batch() []*Event {
+	return it.batch
+}
+
+// Err returns the error of the event that cannot be marshaled, if any
+func (it *BatchIterator) Err() error {
+	return it.err
+}
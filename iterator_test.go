@@ -0,0 +1,63 @@
+package hec
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchIterator(t *testing.T) {
+	// Every event marshals to {"event":"xxxxxxxx"}, 20 bytes
+	events := make([]*Event, 10)
+	for i := range events {
+		events[i] = NewEvent("xxxxxxxx")
+	}
+	events[4] = NewEvent("")
+
+	it := NewBatchIterator(events)
+	it.MaxSize = 64
+	var sizes []int
+	for it.Next() {
+		sizes = append(sizes, len(it.Batch()))
+	}
+	assert.NoError(t, it.Err())
+	assert.Equal(t, []int{3, 3, 3}, sizes)
+
+	it = NewBatchIterator(events)
+	it.MaxEvents = 4
+	sizes = nil
+	for it.Next() {
+		sizes = append(sizes, len(it.Batch()))
+	}
+	assert.Equal(t, []int{4, 4, 1}, sizes)
+
+	// Events larger than MaxSize make batches on their own
+	it = NewBatchIterator(events[:2])
+	it.MaxSize = 10
+	sizes = nil
+	for it.Next() {
+		sizes = append(sizes, len(it.Batch()))
+	}
+	assert.Equal(t, []int{1, 1}, sizes)
+}
+
+func TestBatchIteratorFunc(t *testing.T) {
+	taken := 0
+	it := NewBatchIteratorFunc(func() (*Event, bool) {
+		taken++
+		switch {
+		case taken == 4:
+			return NewEvent(math.Inf(1)), true
+		case taken > 4:
+			t.Fatal("taken after the error")
+		}
+		return NewEvent("event"), true
+	})
+	it.MaxEvents = 2
+	assert.True(t, it.Next())
+	assert.Equal(t, 2, taken) // events are taken lazily
+	assert.False(t, it.Next())
+	assert.Error(t, it.Err())
+	assert.False(t, it.Next())
+}
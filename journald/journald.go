@@ -0,0 +1,299 @@
+//go:build linux
+
+// Package journald forwards the systemd journal to Splunk HEC. It reads the
+// journal with journalctl and persists the cursor of the last forwarded
+// entry, so the forwarder continues where it stopped after a restart.
+package journald
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fuyufjh/splunk-hec-go"
+)
+
+const (
+	defaultBatchSize     = 100
+	defaultFlushInterval = 1 * time.Second
+
+	maxEntrySize = 1 << 20
+)
+
+type Forwarder struct {
+	// HEC client to forward entries to (required)
+	client hec.HEC
+
+	// Metadata of forwarded entries; the host defaults to _HOSTNAME (optional)
+	metadata *hec.EventMetadata
+
+	// File to persist the cursor of the last forwarded entry into (optional)
+	cursorFile string
+
+	// Matches passed to journalctl, e.g. "_SYSTEMD_UNIT=sshd.service" (optional)
+	matches []string
+
+	// Forward the entire journal when there is no cursor instead of new entries (optional, default: false)
+	startAtBeginning bool
+
+	// Wait for indexer acknowledgement before saving the cursor (optional, default: false)
+	ack bool
+
+	// Max number of entries per batch (optional, default: 100)
+	batchSize int
+
+	// Max time an entry waits for its batch (optional, default: 1s)
+	flushInterval time.Duration
+}
+
+func NewForwarder(client hec.HEC) *Forwarder {
+	return &Forwarder{
+		client:        client,
+		batchSize:     defaultBatchSize,
+		flushInterval: defaultFlushInterval,
+	}
+}
+
+func (f *Forwarder) SetMetadata(metadata *hec.EventMetadata) {
+	f.metadata = metadata
+}
+
+func (f *Forwarder) SetCursorFile(path string) {
+	f.cursorFile = path
+}
+
+func (f *Forwarder) SetMatches(matches []string) {
+	f.matches = matches
+}
+
+func (f *Forwarder) SetStartAtBeginning(enable bool) {
+	f.startAtBeginning = enable
+}
+
+func (f *Forwarder) SetAck(enable bool) {
+	f.ack = enable
+}
+
+func (f *Forwarder) SetBatchSize(size int) {
+	f.batchSize = size
+}
+
+func (f *Forwarder) SetFlushInterval(interval time.Duration) {
+	f.flushInterval = interval
+}
+
+// Run follows the journal from the saved cursor until ctx is done or
+// forwarding fails. The cursor is saved after every batch accepted, or
+// acknowledged if enabled, so entries are forwarded at least once.
+func (f *Forwarder) Run(ctx context.Context) error {
+	cursor, err := f.loadCursor()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "journalctl", f.args(cursor)...)
+	cmd.Stderr = os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	err = f.forward(ctx, stdout)
+	cancel()
+	cmd.Wait()
+	return err
+}
+
+// args returns the arguments of journalctl to follow the journal after cursor
+func (f *Forwarder) args(cursor string) []string {
+	args := []string{"--output=json", "--follow", "--no-pager"}
+	switch {
+	case cursor != "":
+		args = append(args, "--after-cursor="+cursor)
+	case f.startAtBeginning:
+		args = append(args, "--lines=all")
+	default:
+		args = append(args, "--lines=0")
+	}
+	return append(args, f.matches...)
+}
+
+// forward forwards the entries read from the JSON output of journalctl
+func (f *Forwarder) forward(ctx context.Context, output io.Reader) error {
+	entries := make(chan map[string]json.RawMessage)
+	readErr := make(chan error, 1)
+	go func() {
+		readErr <- readEntries(ctx, output, entries)
+		close(entries)
+	}()
+
+	var batch []*hec.Event
+	var cursor string
+	timer := time.NewTimer(f.flushInterval)
+	defer timer.Stop()
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if _, err := f.client.WriteBatchWithResponses(ctx, batch); err != nil {
+			return err
+		}
+		if f.ack {
+			if err := f.client.WaitForAcknowledgementWithContext(ctx); err != nil {
+				return err
+			}
+		}
+		batch = nil
+		return f.saveCursor(cursor)
+	}
+	for {
+		select {
+		case entry, ok := <-entries:
+			if !ok {
+				if err := flush(); err != nil {
+					return err
+				}
+				if err := <-readErr; err != nil {
+					return err
+				}
+				return ctx.Err()
+			}
+			if len(batch) == 0 {
+				timer.Reset(f.flushInterval)
+			}
+			batch = append(batch, f.event(entry))
+			cursor = stringField(entry["__CURSOR"])
+			if len(batch) >= f.batchSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		case <-timer.C:
+			if err := flush(); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func readEntries(ctx context.Context, output io.Reader, entries chan<- map[string]json.RawMessage) error {
+	scanner := bufio.NewScanner(output)
+	scanner.Buffer(make([]byte, 64*1024), maxEntrySize)
+	for scanner.Scan() {
+		var entry map[string]json.RawMessage
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return err
+		}
+		select {
+		case entries <- entry:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, os.ErrClosed) {
+		return err
+	}
+	return nil
+}
+
+// event maps a journal entry to an event with MESSAGE as data, the time of
+// the entry and its other fields as indexed fields. Fields starting
+// with "__", like the cursor, are left out.
+func (f *Forwarder) event(entry map[string]json.RawMessage) *hec.Event {
+	event := hec.NewEvent(stringField(entry["MESSAGE"]))
+	if f.metadata != nil {
+		event.Host = f.metadata.Host
+		event.Index = f.metadata.Index
+		event.Source = f.metadata.Source
+		event.SourceType = f.metadata.SourceType
+	}
+	if event.Host == nil {
+		if host := stringField(entry["_HOSTNAME"]); host != "" {
+			event.SetHost(host)
+		}
+	}
+	if usec, err := strconv.ParseInt(stringField(entry["__REALTIME_TIMESTAMP"]), 10, 64); err == nil {
+		event.SetTime(time.UnixMicro(usec))
+	}
+	fields := make(map[string]interface{})
+	for name, value := range entry {
+		if name == "MESSAGE" || strings.HasPrefix(name, "__") {
+			continue
+		}
+		fields[name] = stringField(value)
+	}
+	event.SetFields(fields)
+	return event
+}
+
+// stringField returns the value of a field, which journalctl outputs as a
+// string, an array of bytes if it is not valid UTF-8, or null if it is too
+// large. Fields set more than once are arrays of those; their values are
+// joined with newlines.
+func stringField(value json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		return s
+	}
+	var data []byte
+	var numbers []int
+	if err := json.Unmarshal(value, &numbers); err == nil {
+		for _, n := range numbers {
+			data = append(data, byte(n))
+		}
+		return string(data)
+	}
+	var values []json.RawMessage
+	if err := json.Unmarshal(value, &values); err == nil {
+		var joined []string
+		for _, v := range values {
+			joined = append(joined, stringField(v))
+		}
+		return strings.Join(joined, "\n")
+	}
+	return ""
+}
+
+func (f *Forwarder) loadCursor() (string, error) {
+	if f.cursorFile == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(f.cursorFile)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	return strings.TrimSpace(string(data)), err
+}
+
+func (f *Forwarder) saveCursor(cursor string) error {
+	if f.cursorFile == "" || cursor == "" {
+		return nil
+	}
+	// Write atomically so a crash never leaves a corrupted cursor
+	tmp, err := os.CreateTemp(filepath.Dir(f.cursorFile), ".cursor-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.WriteString(cursor)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), f.cursorFile)
+}
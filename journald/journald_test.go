@@ -0,0 +1,97 @@
+//go:build linux
+
+package journald
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fuyufjh/splunk-hec-go"
+	"github.com/fuyufjh/splunk-hec-go/hectest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testToken = "00000000-0000-0000-0000-000000000000"
+
+const output = `{"__CURSOR":"s=1;i=1","__REALTIME_TIMESTAMP":"1500000000123456","_HOSTNAME":"web-1","_SYSTEMD_UNIT":"sshd.service","PRIORITY":"6","MESSAGE":"Accepted publickey"}
+{"__CURSOR":"s=1;i=2","__REALTIME_TIMESTAMP":"1500000001000000","_HOSTNAME":"web-1","MESSAGE":[104,105,255],"TAG":["a","b"]}
+{"__CURSOR":"s=1;i=3","__REALTIME_TIMESTAMP":"1500000002000000","_HOSTNAME":"web-1","MESSAGE":"third"}
+`
+
+func TestForward(t *testing.T) {
+	server := hectest.NewServer(testToken)
+	defer server.Close()
+	server.EnableAck(0)
+	cursorFile := filepath.Join(t.TempDir(), "cursor")
+
+	f := NewForwarder(hec.NewClient(server.URL, testToken))
+	f.SetCursorFile(cursorFile)
+	f.SetAck(true)
+	f.SetBatchSize(2)
+	f.SetMetadata(&hec.EventMetadata{SourceType: hec.String("journald")})
+	require.NoError(t, f.forward(context.Background(), strings.NewReader(output)))
+
+	events := server.Events()
+	require.Len(t, events, 3)
+	assert.Equal(t, "Accepted publickey", events[0].Event.Event)
+	assert.Equal(t, "web-1", *events[0].Host)
+	assert.Equal(t, "journald", *events[0].SourceType)
+	assert.Equal(t, "1500000000.123", *events[0].Time)
+	assert.Equal(t, map[string]interface{}{"_HOSTNAME": "web-1", "_SYSTEMD_UNIT": "sshd.service", "PRIORITY": "6"}, events[0].Fields)
+	// Invalid UTF-8 is replaced when the event is marshaled
+	assert.Equal(t, "hi\ufffd", events[1].Event.Event)
+	assert.Equal(t, "a\nb", events[1].Fields["TAG"])
+
+	cursor, err := os.ReadFile(cursorFile)
+	require.NoError(t, err)
+	assert.Equal(t, "s=1;i=3", string(cursor))
+	loaded, err := f.loadCursor()
+	require.NoError(t, err)
+	f.SetMatches([]string{"_SYSTEMD_UNIT=sshd.service"})
+	assert.Equal(t, []string{"--output=json", "--follow", "--no-pager", "--after-cursor=s=1;i=3", "_SYSTEMD_UNIT=sshd.service"}, f.args(loaded))
+}
+
+func TestFailedBatchKeepsCursor(t *testing.T) {
+	server := hectest.NewServer(testToken)
+	defer server.Close()
+	cursorFile := filepath.Join(t.TempDir(), "cursor")
+
+	f := NewForwarder(hec.NewClient(server.URL, testToken))
+	f.SetCursorFile(cursorFile)
+	f.SetBatchSize(2)
+	server.FailNext(hec.StatusIncorrectIndex)
+	err := f.forward(context.Background(), strings.NewReader(output))
+	assert.ErrorIs(t, err, hec.ErrIncorrectIndex)
+	assert.NoFileExists(t, cursorFile)
+}
+
+func TestFlushInterval(t *testing.T) {
+	server := hectest.NewServer(testToken)
+	defer server.Close()
+
+	f := NewForwarder(hec.NewClient(server.URL, testToken))
+	f.SetFlushInterval(10 * time.Millisecond)
+	reader, writer, err := os.Pipe()
+	require.NoError(t, err)
+	defer writer.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() { errs <- f.forward(ctx, reader) }()
+
+	writer.WriteString(strings.SplitAfter(output, "\n")[0])
+	require.Eventually(t, func() bool { return len(server.Events()) == 1 }, time.Second, 5*time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-errs, context.Canceled)
+}
+
+func TestArgs(t *testing.T) {
+	f := NewForwarder(nil)
+	assert.Equal(t, []string{"--output=json", "--follow", "--no-pager", "--lines=0"}, f.args(""))
+	f.SetStartAtBeginning(true)
+	assert.Equal(t, []string{"--output=json", "--follow", "--no-pager", "--lines=all"}, f.args(""))
+}
@@ -0,0 +1,18 @@
+module github.com/fuyufjh/splunk-hec-go/kafkabridge
+
+go 1.23
+
+require (
+	github.com/fuyufjh/splunk-hec-go v0.0.0
+	github.com/segmentio/kafka-go v0.4.51
+	github.com/stretchr/testify v1.12.1
+)
+
+require (
+	github.com/google/uuid v1.0.0 // indirect
+	github.com/klauspost/compress v1.15.9 // indirect
+	github.com/pierrec/lz4/v4 v4.1.15 // indirect
+	go.yaml.in/yaml/v3 v3.0.5 // indirect
+)
+
+replace github.com/fuyufjh/splunk-hec-go => ../
@@ -0,0 +1,206 @@
+// Package kafkabridge copies messages of Kafka topics to Splunk as HEC
+// events. Offsets are committed only after the events are written, and
+// acknowledged by the indexers if enabled, so messages are delivered at
+// least once.
+package kafkabridge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	defaultBatchSize     = 100
+	defaultFlushInterval = time.Second
+)
+
+// Reader fetches and commits messages, e.g. a *kafka.Reader of a consumer
+// group
+type Reader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, messages ...kafka.Message) error
+}
+
+// Metadata fields that keys and headers can be mapped to
+const (
+	FieldHost       = "host"
+	FieldIndex      = "index"
+	FieldSource     = "source"
+	FieldSourceType = "sourcetype"
+)
+
+// Config configures how messages are mapped to events
+type Config struct {
+	// Metadata of all events. The source defaults to "kafka:<topic>".
+	Metadata hec.EventMetadata
+
+	// Metadata field set to the message key, e.g. FieldHost. The key is
+	// ignored if empty.
+	KeyField string
+
+	// Metadata fields set to message headers, by header name
+	HeaderFields map[string]string
+
+	// Maximum number of messages per batch, defaults to 100
+	BatchSize int
+
+	// Maximum time a fetched message waits for its batch, defaults to 1s
+	FlushInterval time.Duration
+
+	// Wait for indexer acknowledgement before committing offsets. The HEC
+	// token must have indexer acknowledgement enabled.
+	Ack bool
+}
+
+// Bridge writes messages of a Reader to a HEC
+type Bridge struct {
+	client hec.HEC
+	reader Reader
+	config Config
+}
+
+// New creates a bridge from reader to client
+func New(client hec.HEC, reader Reader, config Config) (*Bridge, error) {
+	if config.KeyField != "" {
+		if err := checkField(config.KeyField); err != nil {
+			return nil, err
+		}
+	}
+	for _, field := range config.HeaderFields {
+		if err := checkField(field); err != nil {
+			return nil, err
+		}
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultBatchSize
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = defaultFlushInterval
+	}
+	return &Bridge{client: client, reader: reader, config: config}, nil
+}
+
+func checkField(field string) error {
+	switch field {
+	case FieldHost, FieldIndex, FieldSource, FieldSourceType:
+		return nil
+	}
+	return fmt.Errorf("kafkabridge: unknown metadata field %q", field)
+}
+
+// Run copies messages until ctx is done or an error occurs. Messages of a
+// batch that fails are not committed and are fetched again by the next
+// consumer of the partition.
+func (b *Bridge) Run(ctx context.Context) error {
+	for {
+		messages, err := b.fetch(ctx)
+		if len(messages) > 0 {
+			if err := b.deliver(ctx, messages); err != nil {
+				return err
+			}
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// fetch blocks for the first message of a batch, then fetches until the
+// batch is full or the flush interval is over
+func (b *Bridge) fetch(ctx context.Context) ([]kafka.Message, error) {
+	message, err := b.reader.FetchMessage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	messages := []kafka.Message{message}
+
+	batchCtx, cancel := context.WithTimeout(ctx, b.config.FlushInterval)
+	defer cancel()
+	for len(messages) < b.config.BatchSize {
+		message, err := b.reader.FetchMessage(batchCtx)
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			break
+		} else if err != nil {
+			return messages, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+func (b *Bridge) deliver(ctx context.Context, messages []kafka.Message) error {
+	events := make([]*hec.Event, len(messages))
+	for i := range messages {
+		events[i] = b.Event(&messages[i])
+	}
+	if _, err := b.client.WriteBatchWithResponses(ctx, events); err != nil {
+		return err
+	}
+	if b.config.Ack {
+		if err := b.client.WaitForAcknowledgementWithContext(ctx); err != nil {
+			return err
+		}
+	}
+	// Commit with a context of its own: the events are in Splunk, so the
+	// offsets should be committed even if ctx is done by now
+	return b.reader.CommitMessages(context.Background(), messages...)
+}
+
+// Event maps a message to an event. Values that are JSON objects are sent as
+// objects, others as strings. The topic, partition and offset are added as
+// fields.
+func (b *Bridge) Event(message *kafka.Message) *hec.Event {
+	var value interface{} = string(message.Value)
+	if json.Valid(message.Value) && len(message.Value) > 0 && message.Value[0] == '{' {
+		value = json.RawMessage(message.Value)
+	}
+	event := hec.NewEvent(value)
+	metadata := b.config.Metadata
+	if metadata.Source == nil {
+		metadata.Source = hec.String("kafka:" + message.Topic)
+	}
+	if b.config.KeyField != "" && len(message.Key) > 0 {
+		setField(&metadata, b.config.KeyField, string(message.Key))
+	}
+	for _, header := range message.Headers {
+		if field, ok := b.config.HeaderFields[header.Key]; ok {
+			setField(&metadata, field, string(header.Value))
+		}
+	}
+	event.Host = metadata.Host
+	event.Index = metadata.Index
+	event.Source = metadata.Source
+	event.SourceType = metadata.SourceType
+	if !message.Time.IsZero() {
+		event.SetTime(message.Time)
+	}
+	event.SetFields(map[string]interface{}{
+		"kafka_topic":     message.Topic,
+		"kafka_partition": strconv.Itoa(message.Partition),
+		"kafka_offset":    strconv.FormatInt(message.Offset, 10),
+	})
+	return event
+}
+
+func setField(metadata *hec.EventMetadata, field string, value string) {
+	switch field {
+	case FieldHost:
+		metadata.Host = hec.String(value)
+	case FieldIndex:
+		metadata.Index = hec.String(value)
+	case FieldSource:
+		metadata.Source = hec.String(value)
+	case FieldSourceType:
+		metadata.SourceType = hec.String(value)
+	}
+}
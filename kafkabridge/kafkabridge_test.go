@@ -0,0 +1,130 @@
+package kafkabridge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+	"github.com/fuyufjh/splunk-hec-go/hectest"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testToken = "00000000-0000-0000-0000-000000000000"
+
+// fakeReader returns its messages, then blocks until the context is done
+type fakeReader struct {
+	mtx       sync.Mutex
+	messages  []kafka.Message
+	committed []kafka.Message
+	cancel    context.CancelFunc
+}
+
+func (r *fakeReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	r.mtx.Lock()
+	if len(r.messages) > 0 {
+		message := r.messages[0]
+		r.messages = r.messages[1:]
+		r.mtx.Unlock()
+		return message, nil
+	}
+	r.mtx.Unlock()
+	<-ctx.Done()
+	return kafka.Message{}, ctx.Err()
+}
+
+func (r *fakeReader) CommitMessages(ctx context.Context, messages ...kafka.Message) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.committed = append(r.committed, messages...)
+	if len(r.messages) == 0 && r.cancel != nil {
+		r.cancel()
+	}
+	return nil
+}
+
+func messages(n int) []kafka.Message {
+	var messages []kafka.Message
+	for i := 0; i < n; i++ {
+		messages = append(messages, kafka.Message{Topic: "logs", Partition: 1, Offset: int64(i), Value: []byte("message")})
+	}
+	return messages
+}
+
+func run(t *testing.T, client hec.HEC, reader *fakeReader, config Config) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	reader.cancel = cancel
+	bridge, err := New(client, reader, config)
+	require.NoError(t, err)
+	return bridge.Run(ctx)
+}
+
+func TestRun(t *testing.T) {
+	server := hectest.NewServer(testToken)
+	defer server.Close()
+
+	reader := &fakeReader{messages: messages(5)}
+	err := run(t, hec.NewClient(server.URL, testToken), reader, Config{BatchSize: 2, FlushInterval: 10 * time.Millisecond})
+	assert.NoError(t, err)
+	assert.Len(t, reader.committed, 5)
+	events := server.Events()
+	require.Len(t, events, 5)
+	assert.Equal(t, "message", events[0].Event.Event)
+	assert.Equal(t, "kafka:logs", *events[0].Source)
+	assert.Equal(t, "4", events[4].Fields["kafka_offset"])
+}
+
+func TestAck(t *testing.T) {
+	server := hectest.NewServer(testToken)
+	defer server.Close()
+	server.EnableAck(0)
+
+	reader := &fakeReader{messages: messages(3)}
+	err := run(t, hec.NewClient(server.URL, testToken), reader, Config{Ack: true, FlushInterval: 10 * time.Millisecond})
+	assert.NoError(t, err)
+	assert.Len(t, reader.committed, 3)
+	assert.Len(t, server.Events(), 3)
+}
+
+func TestFailedBatchNotCommitted(t *testing.T) {
+	server := hectest.NewServer(testToken)
+	defer server.Close()
+	server.FailNext(hec.StatusIncorrectIndex)
+
+	reader := &fakeReader{messages: messages(3)}
+	err := run(t, hec.NewClient(server.URL, testToken), reader, Config{FlushInterval: 10 * time.Millisecond})
+	assert.True(t, errors.Is(err, hec.ErrIncorrectIndex), "got %v", err)
+	assert.Empty(t, reader.committed)
+	assert.Empty(t, server.Events())
+}
+
+func TestEvent(t *testing.T) {
+	bridge, err := New(nil, nil, Config{
+		Metadata:     hec.EventMetadata{Index: hec.String("main"), Source: hec.String("bridge")},
+		KeyField:     FieldHost,
+		HeaderFields: map[string]string{"type": FieldSourceType},
+	})
+	require.NoError(t, err)
+	event := bridge.Event(&kafka.Message{
+		Topic:   "logs",
+		Key:     []byte("web-1"),
+		Value:   []byte(`{"level":"info"}`),
+		Headers: []kafka.Header{{Key: "type", Value: []byte("access")}, {Key: "other", Value: []byte("x")}},
+		Time:    time.Unix(1500000000, 0),
+	})
+	assert.Equal(t, "web-1", *event.Host)
+	assert.Equal(t, "main", *event.Index)
+	assert.Equal(t, "bridge", *event.Source)
+	assert.Equal(t, "access", *event.SourceType)
+	assert.Equal(t, "1500000000.000", *event.Time)
+	assert.JSONEq(t, `{"level":"info"}`, string(event.Event.(json.RawMessage)))
+
+	_, err = New(nil, nil, Config{KeyField: "time"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,216 @@
+// Package k8shec adds the Kubernetes metadata of the pod an application runs
+// in to its HEC events.
+package k8shec
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+)
+
+// Field names of the metadata, following the OpenTelemetry conventions used
+// by the Splunk distribution of the OpenTelemetry Collector
+const (
+	FieldPodName       = "k8s.pod.name"
+	FieldPodUID        = "k8s.pod.uid"
+	FieldNamespace     = "k8s.namespace.name"
+	FieldNodeName      = "k8s.node.name"
+	FieldContainerName = "k8s.container.name"
+
+	// Prefix of the fields of pod labels
+	FieldLabelPrefix = "k8s.pod.labels."
+)
+
+const (
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	kubeletPort       = "10250"
+)
+
+// Metadata of a pod and container
+type Metadata struct {
+	PodName       string
+	PodUID        string
+	Namespace     string
+	NodeName      string
+	ContainerName string
+	Labels        map[string]string
+}
+
+// FromEnv reads the metadata from environment variables set with the
+// downward API, e.g.
+//
+//	env:
+//	- name: POD_NAME
+//	  valueFrom: {fieldRef: {fieldPath: metadata.name}}
+//
+// It reads POD_NAME, POD_UID, POD_NAMESPACE, NODE_NAME and CONTAINER_NAME.
+// The pod name defaults to HOSTNAME and the namespace to the namespace of the
+// service account.
+func FromEnv() *Metadata {
+	m := &Metadata{
+		PodName:       os.Getenv("POD_NAME"),
+		PodUID:        os.Getenv("POD_UID"),
+		Namespace:     os.Getenv("POD_NAMESPACE"),
+		NodeName:      os.Getenv("NODE_NAME"),
+		ContainerName: os.Getenv("CONTAINER_NAME"),
+	}
+	if m.PodName == "" {
+		m.PodName = os.Getenv("HOSTNAME")
+	}
+	if m.Namespace == "" {
+		if namespace, err := os.ReadFile(serviceAccountDir + "/namespace"); err == nil {
+			m.Namespace = strings.TrimSpace(string(namespace))
+		}
+	}
+	return m
+}
+
+// LoadLabels reads the pod labels from a file of a downward API volume with
+// the metadata.labels field, e.g. /etc/podinfo/labels
+func (m *Metadata) LoadLabels(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	labels := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// Lines are key="value", with the value quoted like a Go string
+		key, quoted, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+		value, err := strconv.Unquote(quoted)
+		if err != nil {
+			return fmt.Errorf("invalid label %s in %s: %w", key, path, err)
+		}
+		labels[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	m.Labels = labels
+	return nil
+}
+
+// FromKubelet completes the metadata with the UID, node and labels of the
+// pod from the kubelet API of the node at nodeIP, e.g. status.hostIP from the
+// downward API. It authenticates with the token of the service account,
+// which needs the nodes/proxy permission. The pod name and namespace must be
+// set.
+func (m *Metadata) FromKubelet(ctx context.Context, nodeIP string) error {
+	token, err := os.ReadFile(serviceAccountDir + "/token")
+	if err != nil {
+		return err
+	}
+	client := kubeletClient()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://"+nodeIP+":"+kubeletPort+"/pods", nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("kubelet returned HTTP %d", response.StatusCode)
+	}
+	var pods podList
+	if err := json.NewDecoder(response.Body).Decode(&pods); err != nil {
+		return err
+	}
+	return m.fromPods(&pods)
+}
+
+type podList struct {
+	Items []struct {
+		Metadata struct {
+			Name      string            `json:"name"`
+			Namespace string            `json:"namespace"`
+			UID       string            `json:"uid"`
+			Labels    map[string]string `json:"labels"`
+		} `json:"metadata"`
+		Spec struct {
+			NodeName   string `json:"nodeName"`
+			Containers []struct {
+				Name string `json:"name"`
+			} `json:"containers"`
+		} `json:"spec"`
+	} `json:"items"`
+}
+
+func (m *Metadata) fromPods(pods *podList) error {
+	for _, pod := range pods.Items {
+		if pod.Metadata.Name != m.PodName || pod.Metadata.Namespace != m.Namespace {
+			continue
+		}
+		m.PodUID = pod.Metadata.UID
+		m.NodeName = pod.Spec.NodeName
+		m.Labels = pod.Metadata.Labels
+		// The container is only known if the pod has one
+		if m.ContainerName == "" && len(pod.Spec.Containers) == 1 {
+			m.ContainerName = pod.Spec.Containers[0].Name
+		}
+		return nil
+	}
+	return fmt.Errorf("pod %s/%s not found on kubelet", m.Namespace, m.PodName)
+}
+
+// kubeletClient returns a client trusting the cluster CA, or any certificate
+// if the CA of the service account is not mounted
+func kubeletClient() *http.Client {
+	config := &tls.Config{}
+	if ca, err := os.ReadFile(serviceAccountDir + "/ca.crt"); err == nil {
+		pool := x509.NewCertPool()
+		pool.AppendCertsFromPEM(ca)
+		config.RootCAs = pool
+	} else {
+		config.InsecureSkipVerify = true
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+}
+
+// Fields returns the metadata as indexed fields, leaving out empty values
+func (m *Metadata) Fields() map[string]string {
+	fields := make(map[string]string)
+	for name, value := range map[string]string{
+		FieldPodName:       m.PodName,
+		FieldPodUID:        m.PodUID,
+		FieldNamespace:     m.Namespace,
+		FieldNodeName:      m.NodeName,
+		FieldContainerName: m.ContainerName,
+	} {
+		if value != "" {
+			fields[name] = value
+		}
+	}
+	for key, value := range m.Labels {
+		fields[FieldLabelPrefix+key] = value
+	}
+	return fields
+}
+
+// Enricher returns an enricher for hec.WithEnrichers adding the metadata as
+// fields of events which don't set them
+func (m *Metadata) Enricher() hec.Enricher {
+	fields := m.Fields()
+	return func(event *hec.Event) {
+		for name, value := range fields {
+			if _, ok := event.Fields[name]; !ok {
+				event.SetField(name, value)
+			}
+		}
+	}
+}
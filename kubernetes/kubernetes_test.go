@@ -0,0 +1,63 @@
+package k8shec
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("POD_NAME", "web-1")
+	t.Setenv("POD_NAMESPACE", "shop")
+	t.Setenv("NODE_NAME", "node-a")
+	t.Setenv("POD_UID", "")
+	t.Setenv("CONTAINER_NAME", "app")
+	assert.Equal(t, &Metadata{PodName: "web-1", Namespace: "shop", NodeName: "node-a", ContainerName: "app"}, FromEnv())
+
+	t.Setenv("POD_NAME", "")
+	t.Setenv("HOSTNAME", "web-2")
+	assert.Equal(t, "web-2", FromEnv().PodName)
+}
+
+func TestLoadLabels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "labels")
+	require.NoError(t, os.WriteFile(path, []byte("app=\"web\"\napp.kubernetes.io/version=\"1.2\\\"3\"\n"), 0o644))
+	m := &Metadata{}
+	require.NoError(t, m.LoadLabels(path))
+	assert.Equal(t, map[string]string{"app": "web", "app.kubernetes.io/version": `1.2"3`}, m.Labels)
+
+	require.NoError(t, os.WriteFile(path, []byte("app=web\n"), 0o644))
+	assert.Error(t, m.LoadLabels(path))
+}
+
+func TestFromPods(t *testing.T) {
+	var pods podList
+	require.NoError(t, json.Unmarshal([]byte(`{"items":[
+		{"metadata":{"name":"web-1","namespace":"other","uid":"x"}},
+		{"metadata":{"name":"web-1","namespace":"shop","uid":"1234","labels":{"app":"web"}},
+		 "spec":{"nodeName":"node-a","containers":[{"name":"app"}]}}
+	]}`), &pods))
+	m := &Metadata{PodName: "web-1", Namespace: "shop"}
+	require.NoError(t, m.fromPods(&pods))
+	assert.Equal(t, &Metadata{PodName: "web-1", PodUID: "1234", Namespace: "shop", NodeName: "node-a", ContainerName: "app", Labels: map[string]string{"app": "web"}}, m)
+
+	m = &Metadata{PodName: "web-2", Namespace: "shop"}
+	assert.Error(t, m.fromPods(&pods))
+}
+
+func TestEnricher(t *testing.T) {
+	m := &Metadata{PodName: "web-1", Namespace: "shop", Labels: map[string]string{"app": "web"}}
+	event := hec.NewEvent("hello")
+	event.SetField(FieldNamespace, "own")
+	m.Enricher()(event)
+	assert.Equal(t, map[string]interface{}{
+		FieldPodName:             "web-1",
+		FieldNamespace:           "own",
+		FieldLabelPrefix + "app": "web",
+	}, event.Fields)
+}
@@ -0,0 +1,189 @@
+// Package lambdahec buffers HEC events in AWS Lambda functions and writes
+// them before the execution environment is frozen or shut down.
+package lambdahec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+)
+
+const (
+	// DefaultBatchSize is the default number of events written at once. It
+	// is small so that invocations don't wait long for a flush.
+	DefaultBatchSize = 25
+
+	// Lambda gives the runtime 300ms after SIGTERM when an extension is
+	// registered, and the extension 500ms after SHUTDOWN
+	shutdownTimeout = 250 * time.Millisecond
+
+	extensionAPI = "2020-01-01/extension"
+)
+
+// Buffer collects events and writes them in batches. Events still buffered
+// at the end of an invocation are written by Flush.
+type Buffer struct {
+	client    hec.HEC
+	batchSize int
+
+	mtx    sync.Mutex
+	events []*hec.Event
+
+	// OnError is called with errors of flushes at the end of invocations and
+	// at shutdown. It defaults to printing to stderr, i.e. CloudWatch Logs.
+	OnError func(err error)
+}
+
+// New creates a buffer writing batches of batchSize events (DefaultBatchSize
+// if 0) to client
+func New(client hec.HEC, batchSize int) *Buffer {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	return &Buffer{
+		client:    client,
+		batchSize: batchSize,
+		OnError: func(err error) {
+			fmt.Fprintf(os.Stderr, "hec: could not flush events: %v\n", err)
+		},
+	}
+}
+
+// WriteEvent buffers an event, writing the buffer if it is full
+func (b *Buffer) WriteEvent(event *hec.Event) error {
+	b.mtx.Lock()
+	b.events = append(b.events, event)
+	full := len(b.events) >= b.batchSize
+	b.mtx.Unlock()
+	if full {
+		return b.Flush(context.Background())
+	}
+	return nil
+}
+
+// Flush writes the buffered events. Events of a failed write are dropped, as
+// the execution environment may be frozen before a retry.
+func (b *Buffer) Flush(ctx context.Context) error {
+	b.mtx.Lock()
+	events := b.events
+	b.events = nil
+	b.mtx.Unlock()
+	if len(events) == 0 {
+		return nil
+	}
+	_, err := b.client.WriteBatchWithResponses(ctx, events)
+	return err
+}
+
+// Wrap returns a handler flushing the buffer after every invocation of
+// handler, before the response is returned to Lambda
+func Wrap[In, Out any](b *Buffer, handler func(context.Context, In) (Out, error)) func(context.Context, In) (Out, error) {
+	return func(ctx context.Context, in In) (Out, error) {
+		out, err := handler(ctx, in)
+		// The invocation deadline may be close, but the events should go out
+		// before the environment is frozen
+		if flushErr := b.Flush(context.WithoutCancel(ctx)); flushErr != nil {
+			b.OnError(flushErr)
+		}
+		return out, err
+	}
+}
+
+// FlushOnSIGTERM flushes the buffer when the runtime receives SIGTERM, which
+// Lambda sends before shutting down an environment with a registered
+// extension, and exits
+func (b *Buffer) FlushOnSIGTERM() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM)
+	go func() {
+		<-signals
+		b.shutdown()
+		os.Exit(0)
+	}()
+}
+
+func (b *Buffer) shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := b.Flush(ctx); err != nil {
+		b.OnError(err)
+	}
+}
+
+// RunExtension registers an external extension named name for the SHUTDOWN
+// event with the Extensions API and blocks until the event, when it flushes
+// the buffer. Registering any extension makes Lambda send SIGTERM to the
+// runtime, so it also enables FlushOnSIGTERM. The API address is taken from
+// AWS_LAMBDA_RUNTIME_API.
+func (b *Buffer) RunExtension(ctx context.Context, name string) error {
+	api := os.Getenv("AWS_LAMBDA_RUNTIME_API")
+	if api == "" {
+		return fmt.Errorf("AWS_LAMBDA_RUNTIME_API is not set")
+	}
+	baseURL := "http://" + api + "/" + extensionAPI
+
+	body, _ := json.Marshal(map[string][]string{"events": {"SHUTDOWN"}})
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+"/register", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Lambda-Extension-Name", name)
+	response, err := extensionRequest(request)
+	if err != nil {
+		return fmt.Errorf("could not register extension: %w", err)
+	}
+	id := response.Header.Get("Lambda-Extension-Identifier")
+
+	for {
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/event/next", nil)
+		if err != nil {
+			return err
+		}
+		request.Header.Set("Lambda-Extension-Identifier", id)
+		response, err := extensionRequest(request)
+		if err != nil {
+			return fmt.Errorf("could not get next event: %w", err)
+		}
+		var event struct {
+			EventType string `json:"eventType"`
+		}
+		if err := json.Unmarshal(response.body, &event); err != nil {
+			return fmt.Errorf("could not decode event: %w", err)
+		}
+		if event.EventType == "SHUTDOWN" {
+			b.shutdown()
+			return nil
+		}
+	}
+}
+
+type extensionResponse struct {
+	*http.Response
+	body []byte
+}
+
+func extensionRequest(request *http.Request) (*extensionResponse, error) {
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d: %s", response.StatusCode, body)
+	}
+	return &extensionResponse{Response: response, body: body}, nil
+}
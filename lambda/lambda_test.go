@@ -0,0 +1,93 @@
+package lambdahec
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+	"github.com/fuyufjh/splunk-hec-go/hectest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testToken = "00000000-0000-0000-0000-000000000000"
+
+func TestBuffer(t *testing.T) {
+	server := hectest.NewServer(testToken)
+	defer server.Close()
+
+	buffer := New(hec.NewClient(server.URL, testToken), 2)
+	require.NoError(t, buffer.WriteEvent(hec.NewEvent("1")))
+	assert.Empty(t, server.Events())
+	require.NoError(t, buffer.WriteEvent(hec.NewEvent("2")))
+	assert.Len(t, server.Events(), 2)
+	require.NoError(t, buffer.WriteEvent(hec.NewEvent("3")))
+	require.NoError(t, buffer.Flush(context.Background()))
+	assert.Len(t, server.Events(), 3)
+	require.NoError(t, buffer.Flush(context.Background()))
+	assert.Len(t, server.Events(), 3)
+}
+
+func TestWrap(t *testing.T) {
+	server := hectest.NewServer(testToken)
+	defer server.Close()
+
+	buffer := New(hec.NewClient(server.URL, testToken), 0)
+	handler := Wrap(buffer, func(ctx context.Context, name string) (string, error) {
+		buffer.WriteEvent(hec.NewEvent("invoked by " + name))
+		return "hello " + name, nil
+	})
+	out, err := handler(context.Background(), "test")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello test", out)
+	require.Len(t, server.Events(), 1)
+	assert.Equal(t, "invoked by test", server.Events()[0].Event.Event)
+
+	var flushErr error
+	buffer.OnError = func(err error) { flushErr = err }
+	server.FailNext(hec.StatusIncorrectIndex)
+	_, err = handler(context.Background(), "test")
+	assert.NoError(t, err)
+	assert.True(t, errors.Is(flushErr, hec.ErrIncorrectIndex), "got %v", flushErr)
+}
+
+func TestRunExtension(t *testing.T) {
+	server := hectest.NewServer(testToken)
+	defer server.Close()
+	buffer := New(hec.NewClient(server.URL, testToken), 0)
+	buffer.WriteEvent(hec.NewEvent("last words"))
+
+	var polls int
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2020-01-01/extension/register":
+			var body map[string][]string
+			json.NewDecoder(r.Body).Decode(&body)
+			assert.Equal(t, []string{"SHUTDOWN"}, body["events"])
+			assert.Equal(t, "hec", r.Header.Get("Lambda-Extension-Name"))
+			w.Header().Set("Lambda-Extension-Identifier", "id-1")
+			w.Write([]byte(`{}`))
+		case "/2020-01-01/extension/event/next":
+			assert.Equal(t, "id-1", r.Header.Get("Lambda-Extension-Identifier"))
+			polls++
+			if polls == 1 {
+				w.Write([]byte(`{"eventType":"INVOKE"}`))
+			} else {
+				w.Write([]byte(`{"eventType":"SHUTDOWN","shutdownReason":"spindown"}`))
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer api.Close()
+	t.Setenv("AWS_LAMBDA_RUNTIME_API", strings.TrimPrefix(api.URL, "http://"))
+
+	require.NoError(t, buffer.RunExtension(context.Background(), "hec"))
+	assert.Equal(t, 2, polls)
+	assert.Len(t, server.Events(), 1)
+}
@@ -0,0 +1,135 @@
+package hec
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Number of recent requests per endpoint latencies are computed from
+const latencySamples = 1024
+
+// LatencyStats are latencies of recent requests to an endpoint of a server
+type LatencyStats struct {
+	// Server URL without password, and path of the endpoint
+	Server   string
+	Endpoint string
+
+	// Number of requests the percentiles are computed from
+	Count int
+
+	P50 time.Duration
+	P90 time.Duration
+	P99 time.Duration
+	Max time.Duration
+}
+
+// latencyTracker keeps the latencies of recent requests per endpoint
+type latencyTracker struct {
+	mtx       sync.Mutex
+	endpoints map[string]*latencyRing
+}
+
+type latencyRing struct {
+	samples []time.Duration
+	next    int
+}
+
+func (t *latencyTracker) observe(endpoint string, latency time.Duration) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if t.endpoints == nil {
+		t.endpoints = make(map[string]*latencyRing)
+	}
+	ring, ok := t.endpoints[endpoint]
+	if !ok {
+		ring = &latencyRing{}
+		t.endpoints[endpoint] = ring
+	}
+	if len(ring.samples) < latencySamples {
+		ring.samples = append(ring.samples, latency)
+	} else {
+		ring.samples[ring.next] = latency
+		ring.next = (ring.next + 1) % latencySamples
+	}
+}
+
+func (t *latencyTracker) stats(server string) []LatencyStats {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	result := make([]LatencyStats, 0, len(t.endpoints))
+	for endpoint, ring := range t.endpoints {
+		samples := append([]time.Duration(nil), ring.samples...)
+		sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+		result = append(result, LatencyStats{
+			Server:   server,
+			Endpoint: endpoint,
+			Count:    len(samples),
+			P50:      percentile(samples, 50),
+			P90:      percentile(samples, 90),
+			P99:      percentile(samples, 99),
+			Max:      samples[len(samples)-1],
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Endpoint < result[j].Endpoint })
+	return result
+}
+
+// percentile returns the nearest-rank percentile p of sorted samples
+func percentile(samples []time.Duration, p int) time.Duration {
+	rank := (len(samples)*p + 99) / 100
+	return samples[max(rank, 1)-1]
+}
+
+// Latencies returns latency percentiles of recent requests per endpoint
+func (hec *Client) Latencies() []LatencyStats {
+	return hec.latencies.stats(redactURL(hec.serverURL))
+}
+
+// Latencies returns latency percentiles of recent requests per node and endpoint
+func (c *Cluster) Latencies() []LatencyStats {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	var result []LatencyStats
+	for _, client := range c.clients {
+		result = append(result, client.Latencies()...)
+	}
+	return result
+}
+
+func (hec *Client) SetSlowRequestThreshold(threshold time.Duration) {
+	hec.slowThreshold = threshold
+}
+
+func (c *Cluster) SetSlowRequestThreshold(threshold time.Duration) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for _, client := range c.clients {
+		client.SetSlowRequestThreshold(threshold)
+	}
+}
+
+// observeRequest tracks the latency of a request and logs it if it is slow
+func (hec *Client) observeRequest(ctx context.Context, entry requestLog) {
+	endpoint := entry.endpoint
+	if i := strings.IndexByte(endpoint, '?'); i >= 0 {
+		endpoint = endpoint[:i]
+	}
+	hec.latencies.observe(endpoint, entry.duration)
+
+	if hec.slowThreshold <= 0 || entry.duration < hec.slowThreshold {
+		return
+	}
+	logger := hec.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	attrs := append([]slog.Attr{
+		slog.String("server", redactURL(hec.serverURL)),
+		slog.Duration("threshold", hec.slowThreshold),
+	}, entry.attrs()...)
+	logger.LogAttrs(ctx, slog.LevelWarn, "Slow HEC request", attrs...)
+}
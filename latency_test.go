@@ -0,0 +1,64 @@
+package hec
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatencies(t *testing.T) {
+	recorder := NewTestRecorder()
+	latency := time.Duration(0)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		latency += time.Millisecond
+		recorder.Clock.Advance(latency)
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	var output bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&output, nil))
+	c := NewClient(ts.URL, testSplunkToken, WithTestMode(recorder), WithLogger(logger))
+	c.SetHTTPClient(testHttpClient)
+	c.SetSlowRequestThreshold(100 * time.Millisecond)
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, c.WriteEvent(NewEvent(i)))
+	}
+	assert.NoError(t, c.WriteRawString("raw", nil))
+
+	stats := c.Latencies()
+	if assert.Len(t, stats, 2) {
+		assert.Equal(t, LatencyStats{
+			Server:   ts.URL,
+			Endpoint: "/services/collector",
+			Count:    100,
+			P50:      50 * time.Millisecond,
+			P90:      90 * time.Millisecond,
+			P99:      99 * time.Millisecond,
+			Max:      100 * time.Millisecond,
+		}, stats[0])
+		assert.Equal(t, "/services/collector/raw", stats[1].Endpoint)
+		assert.Equal(t, 101*time.Millisecond, stats[1].Max)
+	}
+
+	// Only the last two requests reach the threshold
+	assert.Equal(t, 2, bytes.Count(output.Bytes(), []byte("Slow HEC request")))
+	assert.Contains(t, output.String(), "level=WARN")
+	assert.Contains(t, output.String(), "server="+ts.URL)
+	assert.Contains(t, output.String(), "duration=101ms")
+}
+
+func TestLatencyRing(t *testing.T) {
+	var tracker latencyTracker
+	for i := 1; i <= latencySamples+10; i++ {
+		tracker.observe("/services/collector", time.Duration(i))
+	}
+	stats := tracker.stats("")
+	assert.Equal(t, latencySamples, stats[0].Count)
+	assert.Equal(t, time.Duration(latencySamples+10), stats[0].Max)
+}
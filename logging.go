@@ -0,0 +1,104 @@
+package hec
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// WithLogger makes a client log every request at debug level with its
+// endpoint, size, compression ratio, status and duration
+func WithLogger(logger *slog.Logger) Option {
+	return func(client *Client) {
+		client.logger = logger
+	}
+}
+
+// WithPayloadDump makes a client write the payload of every request rejected
+// for its data format to writer, to troubleshoot malformed events. Payloads
+// are passed through redact first if it is not nil, e.g. to mask personal
+// data.
+func WithPayloadDump(writer io.Writer, redact func(payload []byte) []byte) Option {
+	return func(client *Client) {
+		client.dump = &payloadDump{writer: writer, redact: redact}
+	}
+}
+
+// payloadDump writes rejected payloads, one request at a time
+type payloadDump struct {
+	mtx    sync.Mutex
+	writer io.Writer
+	redact func(payload []byte) []byte
+}
+
+// requestLog describes a request for the debug log and the payload dump
+type requestLog struct {
+	endpoint   string
+	attempt    int
+	size       int
+	wireSize   int
+	statusCode int
+	response   *Response
+	startTime  time.Time
+	duration   time.Duration
+	err        error
+}
+
+func (hec *Client) logRequest(ctx context.Context, data []byte, entry requestLog) {
+	hec.observeRequest(ctx, entry)
+
+	if hec.logger != nil && hec.logger.Enabled(ctx, slog.LevelDebug) {
+		hec.logger.LogAttrs(ctx, slog.LevelDebug, "HEC request", entry.attrs()...)
+	}
+
+	if hec.dump != nil && entry.response != nil && StatusCategory(entry.response.Code) == CategoryDataFormat {
+		hec.dump.write(entry, data)
+	}
+
+	if hec.requestAudit != nil {
+		hec.requestAudit(hec.requestAuditRecord(data, entry))
+	}
+}
+
+func (entry requestLog) attrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("endpoint", entry.endpoint),
+		slog.Int("attempt", entry.attempt),
+		slog.Int("size", entry.size),
+		slog.Int("wire_size", entry.wireSize),
+		slog.Float64("compression_ratio", compressionRatio(entry.size, entry.wireSize)),
+		slog.Duration("duration", entry.duration),
+	}
+	if entry.statusCode != 0 {
+		attrs = append(attrs, slog.Int("status", entry.statusCode))
+	}
+	if entry.response != nil {
+		attrs = append(attrs, slog.Int("code", entry.response.Code), slog.String("text", entry.response.Text))
+	}
+	if entry.err != nil {
+		attrs = append(attrs, slog.String("error", entry.err.Error()))
+	}
+	return attrs
+}
+
+func (d *payloadDump) write(entry requestLog, data []byte) {
+	if d.redact != nil {
+		data = d.redact(data)
+	}
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	fmt.Fprintf(d.writer, "# %s code=%d text=%q size=%d\n", entry.endpoint, entry.response.Code, entry.response.Text, len(data))
+	d.writer.Write(data)
+	fmt.Fprintln(d.writer)
+}
+
+// compressionRatio returns how many times smaller the payload is on the wire
+func compressionRatio(size int, wireSize int) float64 {
+	if wireSize == 0 {
+		return 1
+	}
+	return float64(size) / float64(wireSize)
+}
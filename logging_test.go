@@ -0,0 +1,69 @@
+package hec
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithLogger(t *testing.T) {
+	ts := httptest.NewServer(jsonEndpoint(t, "gzip"))
+	defer ts.Close()
+
+	var output bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&output, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	c := NewClient(ts.URL, testSplunkToken, WithLogger(logger))
+	c.SetHTTPClient(testHttpClient)
+	c.SetCompression("gzip")
+	assert.NoError(t, c.WriteEvent(NewEvent(strings.Repeat("compressible ", 100))))
+
+	var record map[string]interface{}
+	if !assert.NoError(t, json.Unmarshal(output.Bytes(), &record)) {
+		return
+	}
+	assert.Equal(t, "DEBUG", record["level"])
+	assert.Equal(t, "HEC request", record["msg"])
+	assert.Equal(t, float64(200), record["status"])
+	assert.Equal(t, float64(0), record["code"])
+	assert.Equal(t, float64(1), record["attempt"])
+	assert.Greater(t, record["compression_ratio"], float64(10))
+	assert.Contains(t, record["endpoint"], "/services/collector?channel=")
+}
+
+func TestWithPayloadDump(t *testing.T) {
+	valid := true
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if valid {
+			w.Write([]byte(`{"text":"Success","code":0}`))
+			return
+		}
+		w.WriteHeader(400)
+		w.Write([]byte(`{"text":"Invalid data format","code":6}`))
+	}))
+	defer ts.Close()
+
+	var dump bytes.Buffer
+	redact := func(payload []byte) []byte {
+		return bytes.ReplaceAll(payload, []byte("secret"), []byte("******"))
+	}
+	c := NewClient(ts.URL, testSplunkToken, WithPayloadDump(&dump, redact))
+	c.SetHTTPClient(testHttpClient)
+
+	assert.NoError(t, c.WriteEvent(NewEvent("fine")))
+	assert.Empty(t, dump.String())
+
+	valid = false
+	assert.Error(t, c.WriteEvent(NewEvent("secret")))
+	lines := strings.Split(strings.TrimSpace(dump.String()), "\n")
+	if assert.Len(t, lines, 2) {
+		assert.Contains(t, lines[0], `code=6 text="Invalid data format"`)
+		assert.Contains(t, lines[1], `"event":"******"`)
+		assert.NotContains(t, lines[1], "secret")
+	}
+}
@@ -0,0 +1,91 @@
+// Package logrushec ships logrus entries to HEC.
+package logrushec
+
+import (
+	hec "github.com/fuyufjh/splunk-hec-go"
+	"github.com/sirupsen/logrus"
+)
+
+// Hook is a logrus hook writing every entry as an event with its message,
+// level and fields, at the time of the entry
+type Hook struct {
+	client   hec.HEC
+	levels   []logrus.Level
+	fieldMap logrus.FieldMap
+	metadata *hec.EventMetadata
+}
+
+// Option configures a Hook
+type Option func(hook *Hook)
+
+// WithLevels sets the levels of entries written (default: all levels)
+func WithLevels(levels ...logrus.Level) Option {
+	return func(hook *Hook) {
+		hook.levels = levels
+	}
+}
+
+// WithFieldMap renames the message and level keys of events, like the
+// FieldMap of logrus.JSONFormatter (default: "msg" and "level")
+func WithFieldMap(fieldMap logrus.FieldMap) Option {
+	return func(hook *Hook) {
+		hook.fieldMap = fieldMap
+	}
+}
+
+// WithMetadata sets the host, index, source and sourcetype of events
+func WithMetadata(metadata *hec.EventMetadata) Option {
+	return func(hook *Hook) {
+		hook.metadata = metadata
+	}
+}
+
+// NewHook creates a hook writing entries with client. Entries are written as
+// they are logged; use a client with a short timeout, or the logging call
+// blocks while HEC is unreachable.
+func NewHook(client hec.HEC, options ...Option) *Hook {
+	hook := &Hook{client: client, levels: logrus.AllLevels}
+	for _, option := range options {
+		option(hook)
+	}
+	return hook
+}
+
+func (hook *Hook) Levels() []logrus.Level {
+	return hook.levels
+}
+
+func (hook *Hook) Fire(entry *logrus.Entry) error {
+	return hook.client.WriteEvent(hook.event(entry))
+}
+
+func (hook *Hook) event(entry *logrus.Entry) *hec.Event {
+	data := make(map[string]interface{}, len(entry.Data)+2)
+	for key, value := range entry.Data {
+		if err, ok := value.(error); ok {
+			value = err.Error() // errors marshal to {} otherwise
+		}
+		data[key] = value
+	}
+	msgKey, levelKey := "msg", "level"
+	if key, ok := hook.fieldMap[logrus.FieldKeyMsg]; ok {
+		msgKey = key
+	}
+	if key, ok := hook.fieldMap[logrus.FieldKeyLevel]; ok {
+		levelKey = key
+	}
+	for _, key := range []string{msgKey, levelKey} {
+		if value, ok := data[key]; ok {
+			data["fields."+key] = value
+		}
+	}
+	data[msgKey] = entry.Message
+	data[levelKey] = entry.Level.String()
+
+	event := hec.NewEvent(data)
+	event.SetTime(entry.Time)
+	if m := hook.metadata; m != nil {
+		event.Host, event.Index, event.Source, event.SourceType = m.Host, m.Index, m.Source, m.SourceType
+	}
+	return event
+}
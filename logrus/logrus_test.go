@@ -0,0 +1,55 @@
+package logrushec
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHook(t *testing.T) {
+	var events []map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		events = append(events, event)
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	client := hec.NewClient(ts.URL, "00000000-0000-0000-0000-000000000000")
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	logger.AddHook(NewHook(client,
+		WithLevels(logrus.ErrorLevel, logrus.WarnLevel),
+		WithFieldMap(logrus.FieldMap{logrus.FieldKeyMsg: "message"}),
+		WithMetadata(&hec.EventMetadata{Index: hec.String("app"), SourceType: hec.String("logrus")}),
+	))
+
+	logger.Info("not written")
+	logger.WithTime(time.Unix(1500000000, 0)).
+		WithError(errors.New("connection refused")).
+		WithField("message", "shadowed").
+		WithField("attempt", 3).
+		Warn("retrying")
+
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "app", events[0]["index"])
+		assert.Equal(t, "logrus", events[0]["sourcetype"])
+		assert.Equal(t, "1500000000.000", events[0]["time"])
+		assert.Equal(t, map[string]interface{}{
+			"message":        "retrying",
+			"fields.message": "shadowed",
+			"level":          "warning",
+			"error":          "connection refused",
+			"attempt":        float64(3),
+		}, events[0]["event"])
+	}
+}
@@ -0,0 +1,68 @@
+package hec
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// Names of the metrics reported to a MetricsHook. Every metric has the labels
+// "endpoint" (path of the endpoint) and "code" (HEC status code, or "error"
+// if there is no response).
+const (
+	// Count of requests, including retries
+	MetricRequests = "hec.requests"
+
+	// Duration of requests in seconds
+	MetricRequestDuration = "hec.request.duration"
+
+	// Size of request payloads in bytes before compression
+	MetricPayloadSize = "hec.payload.size"
+)
+
+// MetricsHook receives the metrics of a client, to be adapted to a metrics
+// library such as OpenTelemetry or Prometheus
+type MetricsHook interface {
+	// Count adds value to the counter name
+	Count(name string, value int64, labels map[string]string)
+
+	// Observe records value in the histogram name
+	Observe(name string, value float64, labels map[string]string)
+}
+
+// WithMetricsHook makes a client report its metrics to hook
+func WithMetricsHook(hook MetricsHook) Option {
+	return func(client *Client) {
+		client.metrics = hook
+	}
+}
+
+// metricsTracer reports metrics of requests, traced by other if not nil
+type metricsTracer struct {
+	client *Client
+	other  RequestTracer
+}
+
+func (t metricsTracer) StartRequest(ctx context.Context, info RequestInfo) (context.Context, func(result RequestResult)) {
+	finishOther := func(RequestResult) {}
+	if t.other != nil {
+		ctx, finishOther = t.other.StartRequest(ctx, info)
+	}
+	startTime := t.client.clock.Now()
+	return ctx, func(result RequestResult) {
+		finishOther(result)
+		endpoint := info.Endpoint
+		if i := strings.IndexByte(endpoint, '?'); i >= 0 {
+			endpoint = endpoint[:i]
+		}
+		code := "error"
+		if result.StatusCode != 0 {
+			code = strconv.Itoa(result.Code)
+		}
+		labels := map[string]string{"endpoint": endpoint, "code": code}
+		hook := t.client.metrics
+		hook.Count(MetricRequests, 1, labels)
+		hook.Observe(MetricRequestDuration, t.client.clock.Now().Sub(startTime).Seconds(), labels)
+		hook.Observe(MetricPayloadSize, float64(info.Size), labels)
+	}
+}
@@ -0,0 +1,50 @@
+package hec
+
+import (
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingHook struct {
+	mtx          sync.Mutex
+	counts       map[string]int64
+	observations map[string][]float64
+}
+
+func (h *recordingHook) Count(name string, value int64, labels map[string]string) {
+	h.mtx.Lock()
+	h.counts[name+" "+labels["endpoint"]+" "+labels["code"]] += value
+	h.mtx.Unlock()
+}
+
+func (h *recordingHook) Observe(name string, value float64, labels map[string]string) {
+	h.mtx.Lock()
+	h.observations[name] = append(h.observations[name], value)
+	h.mtx.Unlock()
+}
+
+func TestHEC_MetricsHook(t *testing.T) {
+	ts := httptest.NewServer(jsonEndpoint(t, ""))
+	defer ts.Close()
+
+	hook := &recordingHook{counts: map[string]int64{}, observations: map[string][]float64{}}
+	tracer := &recordingTracer{}
+	c := NewClient(ts.URL, testSplunkToken, WithMetricsHook(hook), WithRequestTracer(tracer))
+	c.SetHTTPClient(testHttpClient)
+	assert.NoError(t, c.WriteEvent(NewEvent("hello, world")))
+	assert.NoError(t, c.WriteEvent(NewEvent("hello, world")))
+
+	c.(*Client).serverURL = "http://127.0.0.1:1"
+	assert.Error(t, c.WriteEvent(NewEvent("hello, world")))
+
+	assert.Equal(t, map[string]int64{
+		MetricRequests + " /services/collector 0":     2,
+		MetricRequests + " /services/collector error": 1,
+	}, hook.counts)
+	assert.Equal(t, []float64{24, 24, 24}, hook.observations[MetricPayloadSize])
+	assert.Len(t, hook.observations[MetricRequestDuration], 3)
+	assert.Len(t, tracer.results, 3)
+}
@@ -0,0 +1,152 @@
+package hec
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+const defaultReadBatchSize = 1000
+
+// EventReader is a source of events, e.g. converted from a file.
+// ReadEvent returns io.EOF when there are no more events.
+type EventReader interface {
+	ReadEvent() (*Event, error)
+}
+
+// WriteAll reads all events from reader and writes them via client in batches
+// of at most batchSize events (default: 1000), so the whole input is never held
+// in memory at once.
+func WriteAll(client HEC, reader EventReader, batchSize int) error {
+	if batchSize <= 0 {
+		batchSize = defaultReadBatchSize
+	}
+	batch := make([]*Event, 0, batchSize)
+	for {
+		event, err := reader.ReadEvent()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		batch = append(batch, event)
+		if len(batch) == batchSize {
+			if err := client.WriteBatch(batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if len(batch) > 0 {
+		return client.WriteBatch(batch)
+	}
+	return nil
+}
+
+// NDJSONReader converts newline-delimited JSON into events. Every line is sent
+// as the event data unchanged, and blank lines are skipped.
+type NDJSONReader struct {
+	reader *bufio.Reader
+
+	// Number of the last read line
+	line int
+
+	// Field of objects holding the event time (optional)
+	timeField string
+
+	// Metadata of all events (optional)
+	metadata *EventMetadata
+}
+
+func NewNDJSONReader(reader io.Reader) *NDJSONReader {
+	return &NDJSONReader{reader: bufio.NewReader(reader)}
+}
+
+// SetTimeField sets the field to take the event time from. Its value can be
+// epoch seconds or an RFC 3339 string; events with other values are sent
+// without time, so Splunk assigns the index time.
+func (r *NDJSONReader) SetTimeField(field string) {
+	r.timeField = field
+}
+
+func (r *NDJSONReader) SetMetadata(metadata *EventMetadata) {
+	r.metadata = metadata
+}
+
+func (r *NDJSONReader) ReadEvent() (*Event, error) {
+	for {
+		line, err := r.reader.ReadBytes('\n')
+		if len(line) == 0 && err != nil {
+			return nil, err
+		} else if err != nil && err != io.EOF {
+			return nil, err
+		}
+		r.line++
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if !json.Valid(line) {
+			return nil, fmt.Errorf("Invalid JSON at line %d", r.line)
+		}
+		event := NewEvent(json.RawMessage(line))
+		applyMetadata(event, r.metadata)
+		if r.timeField != "" {
+			if t := extractTime(line, r.timeField); t != nil {
+				event.Time = t
+			}
+		}
+		return event, nil
+	}
+}
+
+// applyMetadata sets metadata of event, sharing the strings of metadata
+func applyMetadata(event *Event, metadata *EventMetadata) {
+	if metadata == nil {
+		return
+	}
+	event.Host = metadata.Host
+	event.Index = metadata.Index
+	event.Source = metadata.Source
+	event.SourceType = metadata.SourceType
+	if metadata.Time != nil {
+		event.SetTime(*metadata.Time)
+	}
+}
+
+// extractTime returns the epoch time in field of a JSON object, or nil if there is none
+func extractTime(object []byte, field string) *string {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(object, &fields); err != nil {
+		return nil
+	}
+	value, ok := fields[field]
+	if !ok {
+		return nil
+	}
+	var number json.Number
+	if err := json.Unmarshal(value, &number); err == nil {
+		return String(number.String())
+	}
+	var str string
+	if err := json.Unmarshal(value, &str); err != nil {
+		return nil
+	}
+	return parseTime(str)
+}
+
+// parseTime converts epoch seconds or an RFC 3339 string into epoch time
+func parseTime(str string) *string {
+	if _, err := strconv.ParseFloat(str, 64); err == nil {
+		return String(str)
+	}
+	if t, err := time.Parse(time.RFC3339Nano, str); err == nil {
+		return Time(t)
+	}
+	return nil
+}
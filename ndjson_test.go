@@ -0,0 +1,64 @@
+package hec
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNDJSONReader(t *testing.T) {
+	input := `{"msg":"one","ts":1485237827.123}
+
+{"msg":"two","ts":"2017-01-24T06:03:47.5Z"}
+{"msg":"three"}`
+	r := NewNDJSONReader(strings.NewReader(input))
+	r.SetTimeField("ts")
+	r.SetMetadata(&EventMetadata{SourceType: String("_json")})
+
+	var events []*Event
+	for {
+		event, err := r.ReadEvent()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		events = append(events, event)
+	}
+
+	assert.Len(t, events, 3)
+	assert.Equal(t, "1485237827.123", *events[0].Time)
+	assert.Equal(t, "1485237827.500", *events[1].Time)
+	assert.Nil(t, events[2].Time)
+	assert.Equal(t, "_json", *events[2].SourceType)
+
+	data, _ := json.Marshal(events[2])
+	assert.Equal(t, `{"sourcetype":"_json","event":{"msg":"three"}}`, string(data))
+}
+
+func TestNDJSONReader_Invalid(t *testing.T) {
+	r := NewNDJSONReader(strings.NewReader("{\"msg\":\"one\"}\nnot json\n"))
+	_, err := r.ReadEvent()
+	assert.NoError(t, err)
+	_, err = r.ReadEvent()
+	assert.EqualError(t, err, "Invalid JSON at line 2")
+}
+
+func TestWriteAll(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	c := NewClient(ts.URL, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+
+	input := strings.Repeat(`{"msg":"hello"}`+"\n", 5)
+	err := WriteAll(c, NewNDJSONReader(strings.NewReader(input)), 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, requests)
+}
@@ -0,0 +1,139 @@
+package otelhec
+
+import (
+	"context"
+	"sync/atomic"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+	"go.opentelemetry.io/otel/attribute"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+// Attributes setting the metadata of events, as used by the Splunk
+// distribution of the OpenTelemetry Collector
+const (
+	AttributeIndex      = "com.splunk.index"
+	AttributeSource     = "com.splunk.source"
+	AttributeSourceType = "com.splunk.sourcetype"
+	AttributeHost       = "host.name"
+)
+
+// LogExporter is an exporter of the OpenTelemetry logs SDK writing records
+// as events. The body of a record is the event, and the attributes of the
+// record and its resource are indexed fields, with nested maps flattened
+// into dotted keys. Severity, trace and span IDs are the fields
+// otel.log.severity.text, otel.log.severity.number, trace_id and span_id.
+type LogExporter struct {
+	client   hec.HEC
+	shutdown atomic.Bool
+}
+
+var _ sdklog.Exporter = (*LogExporter)(nil)
+
+// NewLogExporter creates an exporter writing records with client
+func NewLogExporter(client hec.HEC) *LogExporter {
+	return &LogExporter{client: client}
+}
+
+func (e *LogExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	if e.shutdown.Load() {
+		return nil
+	}
+	events := make([]*hec.Event, len(records))
+	for i := range records {
+		events[i] = eventOf(&records[i])
+	}
+	_, err := e.client.WriteBatchWithResponses(ctx, events)
+	return err
+}
+
+// Shutdown stops exporting, records are written as they are exported
+func (e *LogExporter) Shutdown(ctx context.Context) error {
+	e.shutdown.Store(true)
+	return nil
+}
+
+func (e *LogExporter) ForceFlush(ctx context.Context) error {
+	return nil
+}
+
+func eventOf(record *sdklog.Record) *hec.Event {
+	fields := make(map[string]interface{})
+	if resource := record.Resource(); resource != nil {
+		for _, kv := range resource.Attributes() {
+			addField(fields, string(kv.Key), kv.Value)
+		}
+	}
+	record.WalkAttributes(func(kv attribute.KeyValue) bool {
+		addField(fields, string(kv.Key), kv.Value)
+		return true
+	})
+	if text := record.SeverityText(); text != "" {
+		fields["otel.log.severity.text"] = text
+	}
+	if severity := record.Severity(); severity != 0 {
+		fields["otel.log.severity.number"] = int(severity)
+	}
+	if id := record.TraceID(); id.IsValid() {
+		fields["trace_id"] = id.String()
+	}
+	if id := record.SpanID(); id.IsValid() {
+		fields["span_id"] = id.String()
+	}
+
+	event := hec.NewEvent(valueOf(record.Body()))
+	if t := record.Timestamp(); !t.IsZero() {
+		event.SetTime(t)
+	} else if t := record.ObservedTimestamp(); !t.IsZero() {
+		event.SetTime(t)
+	}
+	event.Index = takeField(fields, AttributeIndex)
+	event.Source = takeField(fields, AttributeSource)
+	event.SourceType = takeField(fields, AttributeSourceType)
+	event.Host = takeField(fields, AttributeHost)
+	if len(fields) > 0 {
+		event.SetFields(fields)
+	}
+	return event
+}
+
+// takeField removes a string field used as metadata, and returns its value
+func takeField(fields map[string]interface{}, key string) *string {
+	value, ok := fields[key].(string)
+	if !ok {
+		return nil
+	}
+	delete(fields, key)
+	return &value
+}
+
+// addField adds a value as field key, flattening maps into dotted keys since
+// indexed fields cannot be nested
+func addField(fields map[string]interface{}, key string, value attribute.Value) {
+	if value.Type() != attribute.MAP {
+		fields[key] = valueOf(value)
+		return
+	}
+	for _, kv := range value.AsMap() {
+		addField(fields, key+"."+string(kv.Key), kv.Value)
+	}
+}
+
+// valueOf converts a value into one marshaled to plain JSON
+func valueOf(value attribute.Value) interface{} {
+	switch value.Type() {
+	case attribute.MAP:
+		m := make(map[string]interface{})
+		for _, kv := range value.AsMap() {
+			m[string(kv.Key)] = valueOf(kv.Value)
+		}
+		return m
+	case attribute.SLICE:
+		var s []interface{}
+		for _, v := range value.AsSlice() {
+			s = append(s, valueOf(v))
+		}
+		return s
+	}
+	return value.AsInterface()
+}
@@ -0,0 +1,73 @@
+package otelhec
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestLogExporter(t *testing.T) {
+	var events []map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		events = append(events, event)
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	client := hec.NewClient(ts.URL, "00000000-0000-0000-0000-000000000000")
+	provider := sdklog.NewLoggerProvider(
+		sdklog.WithProcessor(sdklog.NewSimpleProcessor(NewLogExporter(client))),
+		sdklog.WithResource(resource.NewSchemaless(
+			attribute.String(AttributeHost, "web-1"),
+			attribute.String("service.name", "api"),
+			attribute.String(AttributeIndex, "otel"),
+		)),
+	)
+	defer provider.Shutdown(context.Background())
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID}))
+
+	var record log.Record
+	record.SetTimestamp(time.Unix(1500000000, 0))
+	record.SetSeverity(log.SeverityWarn)
+	record.SetSeverityText("WARN")
+	record.SetBody(attribute.MapValue(attribute.String("msg", "slow request"), attribute.Int("ms", 250)))
+	record.AddAttributes(
+		attribute.String(AttributeSourceType, "otel:log"),
+		attribute.Map("http", attribute.String("method", "GET"), attribute.Int("status", 200)),
+	)
+	provider.Logger("test").Emit(ctx, record)
+
+	if assert.Len(t, events, 1) {
+		event := events[0]
+		assert.Equal(t, "web-1", event["host"])
+		assert.Equal(t, "otel", event["index"])
+		assert.Equal(t, "otel:log", event["sourcetype"])
+		assert.Equal(t, "1500000000.000", event["time"])
+		assert.Equal(t, map[string]interface{}{"msg": "slow request", "ms": float64(250)}, event["event"])
+		assert.Equal(t, map[string]interface{}{
+			"service.name":             "api",
+			"http.method":              "GET",
+			"http.status":              float64(200),
+			"otel.log.severity.text":   "WARN",
+			"otel.log.severity.number": float64(13),
+			"trace_id":                 "4bf92f3577b34da6a3ce929d0e0e4736",
+			"span_id":                  "00f067aa0ba902b7",
+		}, event["fields"])
+	}
+}
@@ -0,0 +1,68 @@
+package otelhec
+
+import (
+	"context"
+	"sync"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// MetricsHook records the metrics of clients as OpenTelemetry instruments
+type MetricsHook struct {
+	meter metric.Meter
+
+	mtx        sync.Mutex
+	counters   map[string]metric.Int64Counter
+	histograms map[string]metric.Float64Histogram
+}
+
+// NewMetricsHook creates a hook recording metrics with provider, or the global
+// meter provider if it is nil
+func NewMetricsHook(provider metric.MeterProvider) *MetricsHook {
+	if provider == nil {
+		provider = otel.GetMeterProvider()
+	}
+	return &MetricsHook{
+		meter:      provider.Meter(instrumentationName),
+		counters:   make(map[string]metric.Int64Counter),
+		histograms: make(map[string]metric.Float64Histogram),
+	}
+}
+
+// WithMetrics makes a client record its metrics with the global meter provider
+func WithMetrics() hec.Option {
+	return hec.WithMetricsHook(NewMetricsHook(nil))
+}
+
+func (h *MetricsHook) Count(name string, value int64, labels map[string]string) {
+	h.mtx.Lock()
+	counter, ok := h.counters[name]
+	if !ok {
+		counter, _ = h.meter.Int64Counter(name)
+		h.counters[name] = counter
+	}
+	h.mtx.Unlock()
+	counter.Add(context.Background(), value, metric.WithAttributes(attributes(labels)...))
+}
+
+func (h *MetricsHook) Observe(name string, value float64, labels map[string]string) {
+	h.mtx.Lock()
+	histogram, ok := h.histograms[name]
+	if !ok {
+		histogram, _ = h.meter.Float64Histogram(name)
+		h.histograms[name] = histogram
+	}
+	h.mtx.Unlock()
+	histogram.Record(context.Background(), value, metric.WithAttributes(attributes(labels)...))
+}
+
+func attributes(labels map[string]string) []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, 0, len(labels))
+	for key, value := range labels {
+		kvs = append(kvs, attribute.String(key, value))
+	}
+	return kvs
+}
@@ -0,0 +1,41 @@
+package otelhec
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+	"github.com/stretchr/testify/assert"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestMetricsHook(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	c := hec.NewClient(ts.URL, "00000000-0000-0000-0000-000000000000", hec.WithMetricsHook(NewMetricsHook(provider)))
+	assert.NoError(t, c.WriteEvent(hec.NewEvent("hello, world")))
+	assert.NoError(t, c.WriteEvent(hec.NewEvent("hello, world")))
+
+	var data metricdata.ResourceMetrics
+	assert.NoError(t, reader.Collect(context.Background(), &data))
+	metrics := make(map[string]metricdata.Aggregation)
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			metrics[m.Name] = m.Data
+		}
+	}
+	if sum, ok := metrics[hec.MetricRequests].(metricdata.Sum[int64]); assert.True(t, ok) {
+		assert.Equal(t, int64(2), sum.DataPoints[0].Value)
+	}
+	if histogram, ok := metrics[hec.MetricPayloadSize].(metricdata.Histogram[float64]); assert.True(t, ok) {
+		assert.Equal(t, uint64(2), histogram.DataPoints[0].Count)
+	}
+}
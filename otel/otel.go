@@ -0,0 +1,72 @@
+// Package otelhec traces HEC requests and records metrics of HEC clients with OpenTelemetry.
+package otelhec
+
+import (
+	"context"
+	"strings"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/fuyufjh/splunk-hec-go/otel"
+
+// Tracer creates a client span around each request to HEC, and propagates the
+// trace context to HEC in the request headers
+type Tracer struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// NewTracer creates a tracer with provider and propagator. The global ones are
+// used if they are nil.
+func NewTracer(provider trace.TracerProvider, propagator propagation.TextMapPropagator) *Tracer {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	if propagator == nil {
+		propagator = otel.GetTextMapPropagator()
+	}
+	return &Tracer{tracer: provider.Tracer(instrumentationName), propagator: propagator}
+}
+
+// WithTracing makes a client trace its requests with the global tracer provider and propagator
+func WithTracing() hec.Option {
+	return hec.WithRequestTracer(NewTracer(nil, nil))
+}
+
+func (t *Tracer) StartRequest(ctx context.Context, info hec.RequestInfo) (context.Context, func(result hec.RequestResult)) {
+	path := info.Endpoint
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	ctx, span := t.tracer.Start(ctx, "HEC POST "+path,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("server.address", info.Server),
+			attribute.String("url.path", path),
+			attribute.Int("hec.payload.size", info.Size),
+			attribute.Int("hec.attempt", info.Attempt),
+		),
+	)
+	t.propagator.Inject(ctx, propagation.HeaderCarrier(info.Header))
+	return ctx, func(result hec.RequestResult) {
+		if result.StatusCode != 0 {
+			span.SetAttributes(
+				attribute.Int("http.response.status_code", result.StatusCode),
+				attribute.Int("hec.code", result.Code),
+			)
+		}
+		if result.Err != nil {
+			span.RecordError(result.Err)
+			span.SetStatus(codes.Error, result.Err.Error())
+		} else if result.Code != hec.StatusSuccess {
+			span.SetStatus(codes.Error, hec.StatusDescription(result.Code))
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,42 @@
+package otelhec
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracer(t *testing.T) {
+	var traceparent string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("Traceparent")
+		w.WriteHeader(400)
+		w.Write([]byte(`{"text":"Incorrect index","code":7}`))
+	}))
+	defer ts.Close()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	tracer := NewTracer(provider, propagation.TraceContext{})
+
+	c := hec.NewClient(ts.URL, "00000000-0000-0000-0000-000000000000", hec.WithRequestTracer(tracer))
+	assert.ErrorIs(t, c.WriteEvent(hec.NewEvent("hello, world")), hec.ErrIncorrectIndex)
+
+	spans := exporter.GetSpans()
+	if assert.Len(t, spans, 1) {
+		span := spans[0]
+		assert.Equal(t, "HEC POST /services/collector", span.Name)
+		assert.Equal(t, codes.Error, span.Status.Code)
+		assert.Contains(t, span.Attributes, attribute.Int("hec.code", 7))
+		assert.Contains(t, span.Attributes, attribute.Int("hec.payload.size", 24))
+		assert.Contains(t, traceparent, span.SpanContext.TraceID().String())
+	}
+}
@@ -0,0 +1,87 @@
+package hec
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrPinMismatch = errors.New("Server certificate chain matches no pin")
+
+// SPKIPin returns the pin of cert for WithPinnedKeys: "sha256/" followed by
+// the base64 SHA-256 hash of its public key, also computed by
+//
+//	openssl x509 -pubkey -noout | openssl pkey -pubin -outform der | openssl dgst -sha256 -binary | base64
+func SPKIPin(cert *x509.Certificate) string {
+	hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return "sha256/" + base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// WithPinnedKeys makes a client connect only to servers with a certificate
+// chain containing a public key of pins, as returned by SPKIPin (the
+// "sha256/" prefix is optional), after the usual verification. Keys are
+// rotated by pinning both the current and the next key, or the key of a CA.
+// Pins that cannot be decoded never match. When servers are not verified
+// by the handshake, e.g. with InsecureSkipVerify, only the key of the server
+// certificate itself is checked.
+func WithPinnedKeys(pins ...string) Option {
+	hashes, _ := parsePins(pins)
+	return func(client *Client) {
+		client.httpClient = withTLSConfig(client.httpClient, func(config *tls.Config) *tls.Config {
+			if config == nil {
+				config = &tls.Config{}
+			}
+			next := config.VerifyConnection
+			config.VerifyConnection = func(state tls.ConnectionState) error {
+				if next != nil {
+					if err := next(state); err != nil {
+						return err
+					}
+				}
+				return verifyPins(state, hashes)
+			}
+			return config
+		})
+	}
+}
+
+// parsePins returns the hashes of pins, skipping and reporting invalid ones
+func parsePins(pins []string) ([][]byte, error) {
+	var hashes [][]byte
+	var err error
+	for _, pin := range pins {
+		hash, decodeErr := base64.StdEncoding.DecodeString(strings.TrimPrefix(pin, "sha256/"))
+		if decodeErr != nil || len(hash) != sha256.Size {
+			err = fmt.Errorf("%q is not a SHA-256 pin", pin)
+			continue
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, err
+}
+
+func verifyPins(state tls.ConnectionState, hashes [][]byte) error {
+	// Other certificates sent by the server are only trusted when they form
+	// a verified chain, as anyone can send them
+	var certs []*x509.Certificate
+	for _, chain := range state.VerifiedChains {
+		certs = append(certs, chain...)
+	}
+	if len(certs) == 0 && len(state.PeerCertificates) > 0 {
+		certs = state.PeerCertificates[:1]
+	}
+	for _, cert := range certs {
+		hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+		for _, pinned := range hashes {
+			if subtle.ConstantTimeCompare(hash[:], pinned) == 1 {
+				return nil
+			}
+		}
+	}
+	return ErrPinMismatch
+}
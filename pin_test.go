@@ -0,0 +1,49 @@
+package hec
+
+import (
+	"crypto/tls"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithPinnedKeys(t *testing.T) {
+	ca, other := newTestCA(t), newTestCA(t)
+	ts := ca.newServer(t)
+	defer ts.Close()
+	serverPin := SPKIPin(ts.TLS.Certificates[0].Leaf)
+	caPin, otherPin := SPKIPin(ca.cert), SPKIPin(other.cert)
+	tlsConfig := &tls.Config{RootCAs: ca.pool}
+
+	for name, pins := range map[string][]string{
+		"server": {serverPin},
+		"CA":     {otherPin, caPin},
+		"prefix": {serverPin[len("sha256/"):]},
+	} {
+		c := NewClient(ts.URL, testSplunkToken, WithTLS(tlsConfig), WithPinnedKeys(pins...))
+		assert.NoError(t, c.WriteEvent(NewEvent("pinned")), name)
+	}
+
+	c := NewClient(ts.URL, testSplunkToken, WithTLS(tlsConfig), WithPinnedKeys(otherPin, "invalid"))
+	c.SetMaxRetry(0)
+	assert.ErrorIs(t, c.WriteEvent(NewEvent("not pinned")), ErrPinMismatch)
+
+	// Servers are verified by the handshake with the roots of a CA file as well
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, ca.pem(), 0o600))
+	c = NewClient(ts.URL, testSplunkToken, WithCAFile(caFile), WithPinnedKeys(caPin))
+	assert.NoError(t, c.WriteEvent(NewEvent("CA pin")))
+
+	// Without verification by the handshake, CA keys cannot be trusted
+	c = NewClient(ts.URL, testSplunkToken, WithTLS(&tls.Config{InsecureSkipVerify: true}), WithPinnedKeys(caPin))
+	c.SetMaxRetry(0)
+	assert.ErrorIs(t, c.WriteEvent(NewEvent("CA pin")), ErrPinMismatch)
+	c = NewClient(ts.URL, testSplunkToken, WithTLS(&tls.Config{InsecureSkipVerify: true}), WithPinnedKeys(serverPin))
+	assert.NoError(t, c.WriteEvent(NewEvent("server pin")))
+
+	_, err := NewFromConfig(Config{URLs: []string{ts.URL}, Token: testSplunkToken, TLS: TLSConfig{Pins: []string{"sha256/short"}}})
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+}
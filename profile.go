@@ -0,0 +1,123 @@
+package hec
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// ValidationProfile bundles limits events are checked against before they are
+// sent. Zero values mean no limit.
+type ValidationProfile struct {
+	Name string
+
+	// Max serialized size of an event in bytes
+	MaxEventSize int
+
+	// Max number of indexed fields of an event
+	MaxFields int
+
+	// Max age of event timestamps, and max time they may be in the future
+	MaxPast   time.Duration
+	MaxFuture time.Duration
+}
+
+const day = 24 * time.Hour
+
+// Built-in validation profiles
+var (
+	// ProfilePermissive checks nothing
+	ProfilePermissive = ValidationProfile{Name: "permissive"}
+
+	// ProfileSplunkCloud follows the default limits of Splunk Cloud: events of
+	// at most 1MB, and timestamps at most 2000 days ago or 2 days ahead
+	ProfileSplunkCloud = ValidationProfile{
+		Name:         "splunk-cloud",
+		MaxEventSize: 1000000,
+		MaxFields:    1000,
+		MaxPast:      2000 * day,
+		MaxFuture:    2 * day,
+	}
+
+	// ProfileStrict only allows events not truncated by the default TRUNCATE
+	// of Splunk, with few indexed fields and recent timestamps
+	ProfileStrict = ValidationProfile{
+		Name:         "strict",
+		MaxEventSize: 10000,
+		MaxFields:    50,
+		MaxPast:      30 * day,
+		MaxFuture:    10 * time.Minute,
+	}
+)
+
+// LookupProfile returns the built-in validation profile with the name
+func LookupProfile(name string) (ValidationProfile, bool) {
+	for _, profile := range []ValidationProfile{ProfilePermissive, ProfileSplunkCloud, ProfileStrict} {
+		if profile.Name == name {
+			return profile, true
+		}
+	}
+	return ValidationProfile{}, false
+}
+
+// validate returns why an event of size bytes violates the profile, or ""
+func (p *ValidationProfile) validate(event *Event, size int, now time.Time) string {
+	if p.MaxEventSize > 0 && size > p.MaxEventSize {
+		return fmt.Sprintf("size %d exceeds %d", size, p.MaxEventSize)
+	}
+	if p.MaxFields > 0 && len(event.Fields) > p.MaxFields {
+		return fmt.Sprintf("%d fields exceed %d", len(event.Fields), p.MaxFields)
+	}
+	if event.Time != nil && (p.MaxPast > 0 || p.MaxFuture > 0) {
+		epoch, err := strconv.ParseFloat(*event.Time, 64)
+		if err != nil {
+			return fmt.Sprintf("time %q is not an epoch time", *event.Time)
+		}
+		sec, frac := math.Modf(epoch)
+		t := time.Unix(int64(sec), int64(frac*1e9))
+		if p.MaxPast > 0 && t.Before(now.Add(-p.MaxPast)) {
+			return fmt.Sprintf("time %s is more than %v ago", t.UTC().Format(time.RFC3339), p.MaxPast)
+		}
+		if p.MaxFuture > 0 && t.After(now.Add(p.MaxFuture)) {
+			return fmt.Sprintf("time %s is more than %v ahead", t.UTC().Format(time.RFC3339), p.MaxFuture)
+		}
+	}
+	return ""
+}
+
+// validate returns why an event of size bytes violates the validation profile of the client, or ""
+func (hec *Client) validate(event *Event, size int) string {
+	if hec.profile == nil {
+		return ""
+	}
+	return hec.profile.validate(event, size, hec.clock.Now())
+}
+
+var ErrInvalidEvent = errors.New("Event is invalid")
+
+// InvalidEventError is returned for events violating the validation profile.
+// The other events of a batch are still sent. It matches ErrInvalidEvent with
+// errors.Is.
+type InvalidEventError struct {
+	// Name of the validation profile
+	Profile string
+
+	// Indexes of the invalid events in the batch, and why they are invalid
+	Indexes []int
+	Reasons []string
+}
+
+func (e *InvalidEventError) add(index int, reason string) {
+	e.Indexes = append(e.Indexes, index)
+	e.Reasons = append(e.Reasons, reason)
+}
+
+func (e *InvalidEventError) Error() string {
+	return fmt.Sprintf("Event is invalid by profile %q (%d events, first: %s)", e.Profile, len(e.Indexes), e.Reasons[0])
+}
+
+func (e *InvalidEventError) Is(target error) bool {
+	return target == ErrInvalidEvent
+}
@@ -0,0 +1,65 @@
+package hec
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHEC_ValidationProfile(t *testing.T) {
+	ts := httptest.NewServer(jsonEndpoint(t, ""))
+	defer ts.Close()
+
+	clock := NewManualClock(time.Unix(1485237827, 0))
+	c := NewClient(ts.URL, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+	c.SetClock(clock)
+	profile, ok := LookupProfile("strict")
+	assert.True(t, ok)
+	c.SetValidationProfile(&profile)
+
+	recent := NewEvent("recent")
+	recent.SetTime(clock.Now().Add(-time.Hour))
+	old := NewEvent("old")
+	old.SetTime(clock.Now().Add(-60 * day))
+	big := NewEvent(strings.Repeat("x", 20000))
+	future := NewEvent("future")
+	future.SetTime(clock.Now().Add(time.Hour))
+
+	err := c.WriteBatch([]*Event{recent, old, big, future})
+	assert.ErrorIs(t, err, ErrInvalidEvent)
+	invalid := err.(*InvalidEventError)
+	assert.Equal(t, "strict", invalid.Profile)
+	assert.Equal(t, []int{1, 2, 3}, invalid.Indexes)
+	assert.Contains(t, invalid.Reasons[0], "ago")
+	assert.Contains(t, invalid.Reasons[1], "size 20012 exceeds 10000")
+	assert.Contains(t, invalid.Reasons[2], "ahead")
+
+	assert.ErrorIs(t, c.WriteEvent(old), ErrInvalidEvent)
+	assert.NoError(t, c.WriteEvent(recent))
+
+	_, ok = LookupProfile("lenient")
+	assert.False(t, ok)
+}
+
+func TestHEC_ValidationProfile_Enriched(t *testing.T) {
+	ts := httptest.NewServer(jsonEndpoint(t, ""))
+	defer ts.Close()
+
+	// The fields added by enrichers count as well
+	c := NewClient(ts.URL, testSplunkToken, WithEnrichers(func(event *Event) {
+		event.SetField("region", "eu")
+		event.SetField("zone", "a")
+	}))
+	c.SetHTTPClient(testHttpClient)
+	c.SetValidationProfile(&ValidationProfile{Name: "fields", MaxFields: 2})
+
+	event := NewEvent("enriched")
+	assert.NoError(t, c.WriteEvent(event))
+	event.SetField("user", "admin")
+	assert.ErrorIs(t, c.WriteEvent(event), ErrInvalidEvent)
+	assert.ErrorIs(t, c.WriteBatch([]*Event{event}), ErrInvalidEvent)
+}
@@ -0,0 +1,97 @@
+// Package promhec exposes the metrics of HEC clients to Prometheus.
+package promhec
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MetricsHook exposes the metrics of clients as Prometheus counters and
+// histograms, named after the hec.Metric constants with dots replaced by
+// underscores, e.g. hec_requests_total
+type MetricsHook struct {
+	registerer prometheus.Registerer
+
+	mtx        sync.Mutex
+	counters   map[string]*prometheus.CounterVec
+	histograms map[string]*prometheus.HistogramVec
+}
+
+// NewMetricsHook creates a hook registering its metrics with registerer, or
+// the default registerer if it is nil
+func NewMetricsHook(registerer prometheus.Registerer) *MetricsHook {
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+	return &MetricsHook{
+		registerer: registerer,
+		counters:   make(map[string]*prometheus.CounterVec),
+		histograms: make(map[string]*prometheus.HistogramVec),
+	}
+}
+
+// WithMetrics makes a client expose its metrics with the default registerer
+func WithMetrics() hec.Option {
+	return hec.WithMetricsHook(NewMetricsHook(nil))
+}
+
+func (h *MetricsHook) Count(name string, value int64, labels map[string]string) {
+	h.mtx.Lock()
+	counter, ok := h.counters[name]
+	if !ok {
+		counter = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: promName(name) + "_total",
+		}, labelNames(labels))
+		counter = register(h.registerer, counter).(*prometheus.CounterVec)
+		h.counters[name] = counter
+	}
+	h.mtx.Unlock()
+	counter.With(labels).Add(float64(value))
+}
+
+func (h *MetricsHook) Observe(name string, value float64, labels map[string]string) {
+	h.mtx.Lock()
+	histogram, ok := h.histograms[name]
+	if !ok {
+		opts := prometheus.HistogramOpts{Name: promName(name)}
+		if name == hec.MetricPayloadSize {
+			opts.Name += "_bytes"
+			opts.Buckets = prometheus.ExponentialBuckets(256, 4, 8)
+		} else if name == hec.MetricRequestDuration {
+			opts.Name += "_seconds"
+		}
+		histogram = prometheus.NewHistogramVec(opts, labelNames(labels))
+		histogram = register(h.registerer, histogram).(*prometheus.HistogramVec)
+		h.histograms[name] = histogram
+	}
+	h.mtx.Unlock()
+	histogram.With(labels).Observe(value)
+}
+
+// register registers collector, or returns the one registered before, e.g. by
+// another hook of the same registerer
+func register(registerer prometheus.Registerer, collector prometheus.Collector) prometheus.Collector {
+	if err := registerer.Register(collector); err != nil {
+		if registered, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			return registered.ExistingCollector
+		}
+	}
+	return collector
+}
+
+func promName(name string) string {
+	return strings.ReplaceAll(name, ".", "_")
+}
+
+func labelNames(labels map[string]string) []string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
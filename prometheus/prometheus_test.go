@@ -0,0 +1,40 @@
+package promhec
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsHook(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	registry := prometheus.NewRegistry()
+	c := hec.NewClient(ts.URL, "00000000-0000-0000-0000-000000000000", hec.WithMetricsHook(NewMetricsHook(registry)))
+	assert.NoError(t, c.WriteEvent(hec.NewEvent("hello, world")))
+	assert.NoError(t, c.WriteEvent(hec.NewEvent("hello, world")))
+
+	expected := `
+# HELP hec_requests_total 
+# TYPE hec_requests_total counter
+hec_requests_total{code="0",endpoint="/services/collector"} 2
+`
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected), "hec_requests_total"))
+	count, err := testutil.GatherAndCount(registry, "hec_payload_size_bytes", "hec_request_duration_seconds")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	// Hooks of other clients share the metrics of the registerer
+	other := hec.NewClient(ts.URL, "00000000-0000-0000-0000-000000000000", hec.WithMetricsHook(NewMetricsHook(registry)))
+	assert.NoError(t, other.WriteEvent(hec.NewEvent("hello, world")))
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(strings.Replace(expected, "} 2", "} 3", 1)), "hec_requests_total"))
+}
@@ -0,0 +1,245 @@
+package promhec
+
+import (
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Max size of a decompressed remote write request
+const maxRemoteWriteSize = 32 << 20
+
+// RemoteWriteHandler receives Prometheus remote write requests and writes
+// their samples as multi-metric events to a metrics index: samples of a
+// series with the same labels apart from the metric name and the same
+// timestamp become one event, with the values as "metric_name:<name>" fields
+// and the labels as dimensions. NaN and infinite values, e.g. staleness
+// markers, are skipped. Failed writes are answered with 5xx, so Prometheus
+// retries them.
+type RemoteWriteHandler struct {
+	client   hec.HEC
+	metadata *hec.EventMetadata
+}
+
+// NewRemoteWriteHandler creates a handler writing with client. Metadata sets
+// the index and other metadata of events (optional).
+func NewRemoteWriteHandler(client hec.HEC, metadata *hec.EventMetadata) *RemoteWriteHandler {
+	return &RemoteWriteHandler{client: client, metadata: metadata}
+}
+
+func (h *RemoteWriteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	compressed, err := io.ReadAll(io.LimitReader(r.Body, maxRemoteWriteSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if n, err := snappy.DecodedLen(compressed); err != nil || n > maxRemoteWriteSize {
+		http.Error(w, "invalid snappy payload", http.StatusBadRequest)
+		return
+	}
+	data, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	series, err := decodeWriteRequest(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	events := h.events(series)
+	if _, err := h.client.WriteBatchWithResponses(r.Context(), events); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type label struct {
+	name, value string
+}
+
+type sample struct {
+	value     float64
+	timestamp int64 // in milliseconds
+}
+
+type timeSeries struct {
+	labels  []label
+	samples []sample
+}
+
+// events groups the samples of series into multi-metric events
+func (h *RemoteWriteHandler) events(series []timeSeries) []*hec.Event {
+	type key struct {
+		dimensions string
+		timestamp  int64
+	}
+	var events []*hec.Event
+	byKey := make(map[key]*hec.Event)
+	for _, ts := range series {
+		var name string
+		dimensions := make(map[string]interface{})
+		for _, l := range ts.labels {
+			if l.name == "__name__" {
+				name = l.value
+			} else {
+				dimensions[l.name] = l.value
+			}
+		}
+		if name == "" {
+			continue
+		}
+		id := dimensionsID(ts.labels)
+		for _, s := range ts.samples {
+			if math.IsNaN(s.value) || math.IsInf(s.value, 0) {
+				continue
+			}
+			k := key{dimensions: id, timestamp: s.timestamp}
+			event, ok := byKey[k]
+			if !ok {
+				event = hec.NewEvent("metric")
+				if h.metadata != nil {
+					event.Host = h.metadata.Host
+					event.Index = h.metadata.Index
+					event.Source = h.metadata.Source
+					event.SourceType = h.metadata.SourceType
+				}
+				event.SetTime(time.UnixMilli(s.timestamp))
+				fields := make(map[string]interface{}, len(dimensions)+1)
+				for name, value := range dimensions {
+					fields[name] = value
+				}
+				event.SetFields(fields)
+				byKey[k] = event
+				events = append(events, event)
+			}
+			event.SetField("metric_name:"+name, s.value)
+		}
+	}
+	return events
+}
+
+// dimensionsID identifies the labels apart from the metric name
+func dimensionsID(labels []label) string {
+	var parts []string
+	for _, l := range labels {
+		if l.name != "__name__" {
+			parts = append(parts, l.name+"\xff"+l.value)
+		}
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "\xfe")
+}
+
+// decodeWriteRequest decodes the time series of a prometheus.WriteRequest
+// protobuf message, ignoring the fields of metadata, exemplars and native
+// histograms
+func decodeWriteRequest(data []byte) ([]timeSeries, error) {
+	var series []timeSeries
+	err := decodeMessage(data, func(number protowire.Number, value []byte) error {
+		if number != 1 {
+			return nil
+		}
+		var ts timeSeries
+		err := decodeMessage(value, func(number protowire.Number, value []byte) error {
+			switch number {
+			case 1:
+				var l label
+				err := decodeMessage(value, func(number protowire.Number, value []byte) error {
+					switch number {
+					case 1:
+						l.name = string(value)
+					case 2:
+						l.value = string(value)
+					}
+					return nil
+				})
+				ts.labels = append(ts.labels, l)
+				return err
+			case 2:
+				s, err := decodeSample(value)
+				ts.samples = append(ts.samples, s)
+				return err
+			}
+			return nil
+		})
+		series = append(series, ts)
+		return err
+	})
+	return series, err
+}
+
+// decodeMessage calls field with the number and contents of every
+// length-delimited field of a message, skipping fields of other types
+func decodeMessage(data []byte, field func(number protowire.Number, value []byte) error) error {
+	for len(data) > 0 {
+		number, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+		if typ == protowire.BytesType {
+			value, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			if err := field(number, value); err != nil {
+				return err
+			}
+			data = data[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(number, typ, data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+func decodeSample(data []byte) (sample, error) {
+	var s sample
+	for len(data) > 0 {
+		number, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return s, protowire.ParseError(n)
+		}
+		data = data[n:]
+		switch {
+		case number == 1 && typ == protowire.Fixed64Type:
+			bits, n := protowire.ConsumeFixed64(data)
+			if n < 0 {
+				return s, protowire.ParseError(n)
+			}
+			s.value = math.Float64frombits(bits)
+			data = data[n:]
+		case number == 2 && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return s, protowire.ParseError(n)
+			}
+			s.timestamp = int64(v)
+			data = data[n:]
+		default:
+			n := protowire.ConsumeFieldValue(number, typ, data)
+			if n < 0 {
+				return s, protowire.ParseError(n)
+			}
+			data = data[n:]
+		}
+	}
+	return s, nil
+}
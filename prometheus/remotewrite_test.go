@@ -0,0 +1,106 @@
+package promhec
+
+import (
+	"bytes"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+	"github.com/fuyufjh/splunk-hec-go/hectest"
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const testToken = "00000000-0000-0000-0000-000000000000"
+
+// encodeWriteRequest encodes series as a prometheus.WriteRequest
+func encodeWriteRequest(series []timeSeries) []byte {
+	var request []byte
+	for _, ts := range series {
+		var message []byte
+		for _, l := range ts.labels {
+			var labelMessage []byte
+			labelMessage = protowire.AppendTag(labelMessage, 1, protowire.BytesType)
+			labelMessage = protowire.AppendString(labelMessage, l.name)
+			labelMessage = protowire.AppendTag(labelMessage, 2, protowire.BytesType)
+			labelMessage = protowire.AppendString(labelMessage, l.value)
+			message = protowire.AppendTag(message, 1, protowire.BytesType)
+			message = protowire.AppendBytes(message, labelMessage)
+		}
+		for _, s := range ts.samples {
+			var sampleMessage []byte
+			sampleMessage = protowire.AppendTag(sampleMessage, 1, protowire.Fixed64Type)
+			sampleMessage = protowire.AppendFixed64(sampleMessage, math.Float64bits(s.value))
+			sampleMessage = protowire.AppendTag(sampleMessage, 2, protowire.VarintType)
+			sampleMessage = protowire.AppendVarint(sampleMessage, uint64(s.timestamp))
+			message = protowire.AppendTag(message, 2, protowire.BytesType)
+			message = protowire.AppendBytes(message, sampleMessage)
+		}
+		request = protowire.AppendTag(request, 1, protowire.BytesType)
+		request = protowire.AppendBytes(request, message)
+	}
+	return snappy.Encode(nil, request)
+}
+
+func TestRemoteWriteHandler(t *testing.T) {
+	server := hectest.NewServer(testToken)
+	defer server.Close()
+	handler := NewRemoteWriteHandler(hec.NewClient(server.URL, testToken), &hec.EventMetadata{Index: hec.String("metrics")})
+
+	body := encodeWriteRequest([]timeSeries{
+		{
+			labels:  []label{{"__name__", "cpu_seconds"}, {"instance", "a"}, {"job", "node"}},
+			samples: []sample{{1.5, 1500000000123}, {2.5, 1500000015123}},
+		},
+		{
+			labels:  []label{{"job", "node"}, {"instance", "a"}, {"__name__", "memory_bytes"}},
+			samples: []sample{{1024, 1500000000123}, {math.NaN(), 1500000015123}},
+		},
+		{
+			labels:  []label{{"__name__", "up"}, {"instance", "b"}, {"job", "node"}},
+			samples: []sample{{1, 1500000000123}},
+		},
+	})
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/write", bytes.NewReader(body)))
+	require.Equal(t, http.StatusNoContent, recorder.Code, recorder.Body.String())
+
+	events := server.Events()
+	require.Len(t, events, 3)
+	assert.Equal(t, "metric", events[0].Event.Event)
+	assert.Equal(t, "metrics", *events[0].Index)
+	assert.Equal(t, "1500000000.123", *events[0].Time)
+	assert.Equal(t, map[string]interface{}{
+		"instance":                 "a",
+		"job":                      "node",
+		"metric_name:cpu_seconds":  1.5,
+		"metric_name:memory_bytes": 1024.0,
+	}, events[0].Fields)
+	assert.Equal(t, "1500000015.123", *events[1].Time)
+	assert.Equal(t, map[string]interface{}{"instance": "a", "job": "node", "metric_name:cpu_seconds": 2.5}, events[1].Fields)
+	assert.Equal(t, map[string]interface{}{"instance": "b", "job": "node", "metric_name:up": 1.0}, events[2].Fields)
+}
+
+func TestRemoteWriteHandlerErrors(t *testing.T) {
+	server := hectest.NewServer(testToken)
+	defer server.Close()
+	handler := NewRemoteWriteHandler(hec.NewClient(server.URL, testToken), nil)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/write", bytes.NewReader([]byte("not snappy"))))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/write", bytes.NewReader(snappy.Encode(nil, []byte{0x0a, 0x05}))))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	server.FailNext(hec.StatusIncorrectIndex)
+	body := encodeWriteRequest([]timeSeries{{labels: []label{{"__name__", "up"}}, samples: []sample{{1, 0}}}})
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/write", bytes.NewReader(body)))
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+}
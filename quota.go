@@ -0,0 +1,146 @@
+package hec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// QuotaPolicy is what happens to events exceeding a quota
+type QuotaPolicy int
+
+const (
+	// Drop the events silently
+	QuotaDrop QuotaPolicy = iota
+
+	// Wait until the quota is available again
+	QuotaThrottle
+
+	// Skip the events and return a QuotaExceededError
+	QuotaError
+)
+
+const defaultQuotaPeriod = time.Minute
+
+// Quota limits the events and bytes written per period for an index and/or a
+// sourcetype. A quota with neither applies to all events.
+type Quota struct {
+	Index      string
+	SourceType string
+
+	// Max events and serialized bytes per period, 0 for no limit
+	Events int
+	Bytes  int
+
+	// Length of the period (default: 1 minute)
+	Period time.Duration
+
+	Policy QuotaPolicy
+}
+
+func (q *Quota) matches(event *Event) bool {
+	return (q.Index == "" || event.Index != nil && *event.Index == q.Index) &&
+		(q.SourceType == "" || event.SourceType != nil && *event.SourceType == q.SourceType)
+}
+
+var ErrQuotaExceeded = errors.New("Quota exceeded")
+
+// QuotaExceededError is returned for events skipped by a quota with the
+// QuotaError policy. The other events of a batch are still sent. It matches
+// ErrQuotaExceeded with errors.Is.
+type QuotaExceededError struct {
+	// Indexes of the skipped events in the batch
+	Indexes []int
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("Quota exceeded (%d events skipped)", len(e.Indexes))
+}
+
+func (e *QuotaExceededError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
+// errQuotaDropped tells that an event was dropped by a quota
+var errQuotaDropped = errors.New("Dropped by quota")
+
+// quotaUsage is the usage of a quota in its current period
+type quotaUsage struct {
+	start  time.Time
+	events int
+	bytes  int
+}
+
+// quotaTracker enforces quotas, shared by the clients of a Cluster
+type quotaTracker struct {
+	mtx    sync.Mutex
+	quotas []Quota
+	usages []quotaUsage
+}
+
+func newQuotaTracker(quotas []Quota) *quotaTracker {
+	if len(quotas) == 0 {
+		return nil
+	}
+	tracker := &quotaTracker{quotas: make([]Quota, len(quotas)), usages: make([]quotaUsage, len(quotas))}
+	copy(tracker.quotas, quotas)
+	for i := range tracker.quotas {
+		if tracker.quotas[i].Period <= 0 {
+			tracker.quotas[i].Period = defaultQuotaPeriod
+		}
+	}
+	return tracker
+}
+
+// admit accounts an event of size bytes against the matching quotas. It
+// returns errQuotaDropped or ErrQuotaExceeded if the event must not be sent.
+func (t *quotaTracker) admit(ctx context.Context, clock Clock, event *Event, size int) error {
+	if t == nil {
+		return nil
+	}
+	for {
+		wait, err := t.tryAdmit(clock.Now(), event, size)
+		if wait == 0 {
+			return err
+		}
+		select {
+		case <-clock.After(wait):
+		case <-ctx.Done():
+			return contextError(ctx)
+		}
+	}
+}
+
+// tryAdmit returns how long to wait for a throttling quota, otherwise the result of admit
+func (t *quotaTracker) tryAdmit(now time.Time, event *Event, size int) (time.Duration, error) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	for i := range t.quotas {
+		quota, usage := &t.quotas[i], &t.usages[i]
+		if !quota.matches(event) {
+			continue
+		}
+		if !now.Before(usage.start.Add(quota.Period)) {
+			*usage = quotaUsage{start: now}
+		}
+		if quota.Events > 0 && usage.events+1 > quota.Events || quota.Bytes > 0 && usage.bytes+size > quota.Bytes {
+			switch quota.Policy {
+			case QuotaThrottle:
+				return usage.start.Add(quota.Period).Sub(now), nil
+			case QuotaError:
+				return 0, ErrQuotaExceeded
+			default:
+				return 0, errQuotaDropped
+			}
+		}
+	}
+	for i := range t.quotas {
+		if t.quotas[i].matches(event) {
+			t.usages[i].events++
+			t.usages[i].bytes += size
+		}
+	}
+	return 0, nil
+}
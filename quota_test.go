@@ -0,0 +1,49 @@
+package hec
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHEC_Quotas(t *testing.T) {
+	var events int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		events += strings.Count(string(body), `"event"`)
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	newEvent := func(index string) *Event {
+		event := NewEvent("debug line")
+		event.SetIndex(index)
+		return event
+	}
+	clock := NewManualClock(time.Unix(1485237827, 0))
+	c := NewClient(ts.URL, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+	c.SetClock(clock)
+
+	c.SetQuotas([]Quota{{Index: "debug", Events: 2, Policy: QuotaDrop}})
+	assert.NoError(t, c.WriteBatch([]*Event{newEvent("debug"), newEvent("debug"), newEvent("debug"), newEvent("main")}))
+	assert.Equal(t, 3, events)
+
+	c.SetQuotas([]Quota{{Index: "debug", Events: 1, Policy: QuotaError}})
+	err := c.WriteBatch([]*Event{newEvent("debug"), newEvent("main"), newEvent("debug")})
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	assert.Equal(t, []int{2}, err.(*QuotaExceededError).Indexes)
+	assert.Equal(t, 5, events)
+
+	c.SetQuotas([]Quota{{Bytes: 100, Period: time.Second, Policy: QuotaThrottle}})
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, c.WriteEvent(newEvent("debug")))
+	}
+	assert.Equal(t, 10, events)
+	assert.Equal(t, time.Second, clock.Slept()[0])
+}
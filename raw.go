@@ -0,0 +1,343 @@
+package hec
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"regexp"
+	"time"
+)
+
+var lineDelimiter = []byte{'\n'}
+
+// splitter finds the boundaries of records in raw data. Records end with a
+// delimiter ("\n" by default), or start with a match of pattern if it is set.
+// Delimited lines matching continuation are joined with the previous record.
+type splitter struct {
+	delimiter    []byte
+	pattern      *regexp.Regexp
+	continuation *regexp.Regexp
+
+	// Max length of a line or record, 0 for unlimited
+	maxLine int
+}
+
+// terminator returns the bytes appended to the final record if it is not terminated
+func (s splitter) terminator() []byte {
+	if s.pattern != nil {
+		return nil
+	}
+	if len(s.delimiter) == 0 {
+		return lineDelimiter
+	}
+	return s.delimiter
+}
+
+// cut returns the length of the complete records at the beginning of data, or 0 if there is none
+func (s splitter) cut(data []byte) int {
+	if s.pattern != nil {
+		matches := s.pattern.FindAllIndex(data, -1)
+		for i := len(matches) - 1; i >= 0; i-- {
+			if matches[i][0] > 0 {
+				return matches[i][0]
+			}
+		}
+		return 0
+	}
+	terminator := s.terminator()
+	end := len(data)
+	for {
+		i := bytes.LastIndex(data[:end], terminator)
+		if i < 0 {
+			return 0
+		}
+		cut := i + len(terminator)
+		if s.continuation == nil {
+			return cut
+		}
+		// Only cut before a line known to start a new record
+		if cut < len(data) && !s.continuation.Match(s.firstLine(data[cut:])) {
+			return cut
+		}
+		end = i
+	}
+}
+
+func (s splitter) firstLine(data []byte) []byte {
+	if i := bytes.Index(data, s.terminator()); i >= 0 {
+		return data[:i]
+	}
+	return data
+}
+
+// forEachLine calls fn with every delimited line (or record in pattern mode) in data
+func (s splitter) forEachLine(data []byte, fn func(line []byte) error) error {
+	if s.pattern != nil {
+		start := 0
+		for _, match := range s.pattern.FindAllIndex(data, -1) {
+			if match[0] > start {
+				if err := fn(data[start:match[0]]); err != nil {
+					return err
+				}
+				start = match[0]
+			}
+		}
+		if start < len(data) {
+			return fn(data[start:])
+		}
+		return nil
+	}
+	terminator := s.terminator()
+	for len(data) > 0 {
+		line := s.firstLine(data)
+		if err := fn(line); err != nil {
+			return err
+		}
+		if len(line) == len(data) {
+			break
+		}
+		data = data[len(line)+len(terminator):]
+	}
+	return nil
+}
+
+// countLines returns the number of complete lines (or records) in chunk
+func (s splitter) countLines(chunk []byte) int {
+	if s.pattern == nil {
+		return bytes.Count(chunk, s.terminator())
+	}
+	count := 0
+	s.forEachLine(chunk, func([]byte) error {
+		count++
+		return nil
+	})
+	return count
+}
+
+// breakStream breaks text from reader into chunks, with every chunk less than max.
+// Unless a single record is longer than max, it always cuts at record boundaries,
+// and unless a single line is, multi-line records are cut at line boundaries.
+func breakStream(reader io.Reader, max int, split splitter, callback func(chunk []byte) error) error {
+	terminator := split.terminator()
+	buf := make([]byte, max+len(terminator))
+	var writeAt int
+	var lines lineTracker
+	for {
+		n, err := io.ReadFull(reader, buf[writeAt:max])
+		atEOF := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !atEOF {
+			return err
+		}
+
+		data := buf[0 : writeAt+n]
+		if len(data) == 0 {
+			return nil
+		}
+
+		// If last record is not terminated, add a terminator for it
+		if atEOF && !bytes.HasSuffix(data, terminator) {
+			data = append(data, terminator...)
+		}
+
+		// Cut after the last complete record. At EOF all the data is complete,
+		// but the added terminator may have pushed it beyond max.
+		for atEOF && len(data) > max {
+			cut, complete := split.cutWithin(data, max)
+			if err := split.emit(data[:cut], complete, &lines, callback); err != nil {
+				return err
+			}
+			data = data[cut:]
+		}
+		if atEOF {
+			return split.emit(data, true, &lines, callback)
+		}
+
+		cut, complete := split.cutWithin(data, max)
+		if err := split.emit(data[:cut], complete, &lines, callback); err != nil {
+			return err
+		}
+		writeAt = copy(buf, data[cut:])
+	}
+}
+
+// cutWithin returns where to cut data so the first chunk is at most max
+// bytes, and whether the chunk ends at a record boundary
+func (s splitter) cutWithin(data []byte, max int) (int, bool) {
+	if len(data) > max {
+		data = data[:max]
+	}
+	if cut := s.cut(data); cut > 0 {
+		return cut, true
+	}
+	// This record is too long. A multi-line record breaks after its last line
+	// that fits, so lines are not split; pieces of a record in pattern mode
+	// are not complete records.
+	if s.pattern != nil || s.continuation != nil {
+		delimiter := s.delimiter
+		if len(delimiter) == 0 {
+			delimiter = lineDelimiter
+		}
+		if i := bytes.LastIndex(data, delimiter); i >= 0 {
+			return i + len(delimiter), s.pattern == nil
+		}
+	}
+	return len(data), false
+}
+
+// lineTracker numbers the lines of consecutive chunks, keeping the length and
+// beginning of a line broken by the end of a chunk
+type lineTracker struct {
+	lines   int
+	partial int
+	preview []byte
+}
+
+// emit checks and sends a chunk, which is complete if it ends at a record
+// boundary. Lines broken across chunks are checked as a whole.
+func (s splitter) emit(chunk []byte, complete bool, lines *lineTracker, callback func(chunk []byte) error) error {
+	if s.maxLine > 0 {
+		if err := s.checkLines(chunk, complete, lines); err != nil {
+			return err
+		}
+	}
+	return callback(chunk)
+}
+
+// checkLines checks the length of every line in chunk, continuing the line
+// broken by the previous chunk
+func (s splitter) checkLines(chunk []byte, complete bool, lines *lineTracker) error {
+	var pieces [][]byte
+	s.forEachLine(chunk, func(line []byte) error {
+		pieces = append(pieces, line)
+		return nil
+	})
+	for i, line := range pieces {
+		length, start := len(line), line
+		if i == 0 && lines.partial > 0 {
+			length += lines.partial
+			start = append(lines.preview, line...)
+		}
+		if length > s.maxLine {
+			err := newLineTooLongError(lines.lines+1, start, s.maxLine)
+			err.Length = length
+			return err
+		}
+		if i == len(pieces)-1 && !complete {
+			lines.partial = length
+			lines.preview = append([]byte(nil), start[:min(len(start), maxLinePreview)]...)
+		} else {
+			lines.lines++
+			lines.partial, lines.preview = 0, nil
+		}
+	}
+	return nil
+}
+
+// streamRaw consumes records from a channel and sends them in chunks of at
+// most max bytes. Buffered records are sent at least every flushInterval.
+func streamRaw(ctx context.Context, records <-chan []byte, max int, flushInterval time.Duration, clock Clock, split splitter, send func(chunk []byte) error) error {
+	terminator := split.terminator()
+	var buffer bytes.Buffer
+	flush := func() error {
+		if buffer.Len() == 0 {
+			return nil
+		}
+		err := send(buffer.Bytes())
+		buffer.Reset()
+		return err
+	}
+
+	ticker := clock.NewTicker(flushInterval)
+	defer ticker.Stop()
+	var number int
+	for {
+		select {
+		case record, ok := <-records:
+			if !ok {
+				return flush()
+			}
+			number++
+			length := len(record)
+			if !bytes.HasSuffix(record, terminator) {
+				length += len(terminator)
+			}
+			if length > max {
+				return newLineTooLongError(number, record, max)
+			}
+			if buffer.Len()+length > max {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+			buffer.Write(record)
+			if length > len(record) {
+				buffer.Write(terminator)
+			}
+		case <-ticker.C():
+			if err := flush(); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return contextError(ctx)
+		}
+	}
+}
+
+// RawProgress describes how much of a raw stream has been sent so far
+type RawProgress struct {
+	Bytes  int64
+	Chunks int
+	Lines  int
+}
+
+func (p *RawProgress) add(chunk []byte, split splitter) {
+	p.Bytes += int64(len(chunk))
+	p.Chunks++
+	split.forEachLine(chunk, func([]byte) error {
+		p.Lines++
+		return nil
+	})
+}
+
+// ChunkOption configures how ChunkLines finds record boundaries
+type ChunkOption func(*splitter)
+
+// ChunkDelimiter sets the delimiter of records (default: "\n")
+func ChunkDelimiter(delimiter string) ChunkOption {
+	return func(s *splitter) {
+		s.delimiter = []byte(delimiter)
+	}
+}
+
+// ChunkRecordPattern treats every match of pattern as the start of a record
+func ChunkRecordPattern(pattern *regexp.Regexp) ChunkOption {
+	return func(s *splitter) {
+		s.pattern = pattern
+	}
+}
+
+// ChunkContinuationPattern joins lines matching pattern with the previous record
+func ChunkContinuationPattern(pattern *regexp.Regexp) ChunkOption {
+	return func(s *splitter) {
+		s.continuation = pattern
+	}
+}
+
+// ChunkMaxLineSize makes ChunkLines fail with ErrLineTooLong if any line is longer than size
+func ChunkMaxLineSize(size int) ChunkOption {
+	return func(s *splitter) {
+		s.maxLine = size
+	}
+}
+
+// ChunkLines breaks text from reader into chunks no longer than max bytes and
+// calls fn for each of them, the same way WriteRaw does. Unless a single line
+// is longer than max, chunks always end at a line boundary. The chunk passed to
+// fn is only valid until fn returns.
+func ChunkLines(reader io.Reader, max int, fn func(chunk []byte) error, options ...ChunkOption) error {
+	var split splitter
+	for _, option := range options {
+		option(&split)
+	}
+	return breakStream(reader, max, split, fn)
+}
@@ -0,0 +1,142 @@
+package hec
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreakStream(t *testing.T) {
+	text := "This is line A\nThis is line B" // length of every line is 14
+
+	getCountFunc := func(counter *int) func(chunk []byte) error {
+		// returned function adds count of all character except "\n"
+		return func(chunk []byte) error {
+			for _, b := range chunk {
+				if b != '\n' {
+					*counter++
+				}
+			}
+			return nil
+		}
+	}
+
+	for _, max := range []int{13, 14, 15, 28, 5, 30} {
+		var counter int = 0
+		err := breakStream(strings.NewReader(text), max, splitter{}, getCountFunc(&counter))
+		assert.NoError(t, err)
+		assert.Equal(t, 28, counter)
+	}
+}
+
+func collectChunks(t *testing.T, text string, max int, split splitter) []string {
+	var chunks []string
+	err := breakStream(strings.NewReader(text), max, split, func(chunk []byte) error {
+		chunks = append(chunks, string(chunk))
+		return nil
+	})
+	assert.NoError(t, err)
+	return chunks
+}
+
+func TestBreakStreamDelimiter(t *testing.T) {
+	text := "line A\r\nline B\r\nline C"
+	chunks := collectChunks(t, text, 18, splitter{delimiter: []byte("\r\n")})
+	assert.Equal(t, []string{"line A\r\nline B\r\n", "line C\r\n"}, chunks)
+
+	text = "record A\x00record B\x00"
+	chunks = collectChunks(t, text, 12, splitter{delimiter: []byte{0}})
+	assert.Equal(t, []string{"record A\x00", "record B\x00"}, chunks)
+}
+
+func TestBreakStreamRecordPattern(t *testing.T) {
+	text := "2017-01-24 event one\n  detail\n2017-01-25 event two\n"
+	pattern := regexp.MustCompile(`(?m)^\d{4}-\d{2}-\d{2}`)
+	chunks := collectChunks(t, text, 40, splitter{pattern: pattern})
+	assert.Equal(t, []string{"2017-01-24 event one\n  detail\n", "2017-01-25 event two\n"}, chunks)
+}
+
+func TestBreakStreamContinuation(t *testing.T) {
+	text := "panic: oops\n\tat main.go:1\n\tat main.go:2\nnext event\nlast event\n"
+	split := splitter{continuation: regexp.MustCompile(`^\s`)}
+	chunks := collectChunks(t, text, 45, split)
+	assert.Equal(t, []string{"panic: oops\n\tat main.go:1\n\tat main.go:2\n", "next event\nlast event\n"}, chunks)
+
+	// Trailing line might be continued in the next read, so it is held back
+	chunks = collectChunks(t, "event one\nevent two\n\tdetail\n", 20, split)
+	assert.Equal(t, []string{"event one\n", "event two\n\tdetail\n"}, chunks)
+}
+
+func TestBreakStreamLongMultilineRecord(t *testing.T) {
+	// Records longer than max break after their last line that fits
+	text := "panic: oops\n\tat main.go:1\n\tat main.go:2\nnext event\n"
+	split := splitter{continuation: regexp.MustCompile(`^\s`)}
+	chunks := collectChunks(t, text, 30, split)
+	assert.Equal(t, []string{"panic: oops\n\tat main.go:1\n", "\tat main.go:2\nnext event\n"}, chunks)
+
+	text = "2017-01-24 event one\n  detail one\n  detail two\n2017-01-25 event two\n"
+	split = splitter{pattern: regexp.MustCompile(`(?m)^\d{4}-\d{2}-\d{2}`), maxLine: 50}
+	chunks = collectChunks(t, text, 40, split)
+	assert.Equal(t, []string{"2017-01-24 event one\n  detail one\n", "  detail two\n2017-01-25 event two\n"}, chunks)
+
+	// A single line longer than max is still cut within it
+	chunks = collectChunks(t, "panic: "+strings.Repeat("x", 30)+"\n", 20, splitter{continuation: regexp.MustCompile(`^\s`)})
+	assert.Equal(t, "panic: "+strings.Repeat("x", 13), chunks[0])
+}
+
+func TestChunkLines(t *testing.T) {
+	var chunks []string
+	collect := func(chunk []byte) error {
+		chunks = append(chunks, string(chunk))
+		return nil
+	}
+
+	err := ChunkLines(strings.NewReader("line A\nline B\nline C"), 14, collect)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"line A\nline B\n", "line C\n"}, chunks)
+
+	chunks = nil
+	err = ChunkLines(strings.NewReader("a;bb;ccc"), 5, collect, ChunkDelimiter(";"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a;bb;", "ccc;"}, chunks)
+
+	err = ChunkLines(strings.NewReader("short\nvery long line\n"), 100, collect, ChunkMaxLineSize(10))
+	assert.ErrorIs(t, err, ErrLineTooLong)
+}
+
+func TestBreakStreamTerminatorWithinMax(t *testing.T) {
+	// The terminator added to the last line must not push the chunk beyond max
+	for _, max := range []int{13, 14} {
+		chunks := collectChunks(t, "line A\nline B", max, splitter{})
+		for _, chunk := range chunks {
+			assert.LessOrEqual(t, len(chunk), max)
+		}
+		assert.Equal(t, "line A\nline B\n", strings.Join(chunks, ""))
+	}
+}
+
+func TestBreakStreamLineNumbers(t *testing.T) {
+	text := "line 1\nline 2\nline 3\n" + strings.Repeat("x", 100) + "\nline 5\n"
+	split := splitter{maxLine: 80}
+	// Lines longer than a chunk are checked as a whole, as soon as they are too long
+	for _, max := range []int{10, 20, 200} {
+		var sent string
+		err := breakStream(strings.NewReader(text), max, split, func(chunk []byte) error {
+			sent += string(chunk)
+			return nil
+		})
+		var lineErr *LineTooLongError
+		if assert.ErrorAs(t, err, &lineErr, max) {
+			assert.Equal(t, 4, lineErr.Line, max)
+			assert.Greater(t, lineErr.Length, 80, max)
+			assert.LessOrEqual(t, lineErr.Length, 100, max)
+			assert.Equal(t, strings.Repeat("x", 64), lineErr.Preview, max)
+		}
+		assert.NotContains(t, sent, "line 5", max)
+	}
+
+	text = "line 1\n" + strings.Repeat("x", 80) + "\nline 3\n"
+	assert.NoError(t, breakStream(strings.NewReader(text), 10, split, func([]byte) error { return nil }))
+}
@@ -0,0 +1,98 @@
+package hec
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const redacted = "<redacted>"
+
+// Redactor returns the value of a header to expose in errors, logs and wire
+// captures, e.g. redacted if it is sensitive. It is only called for headers
+// not redacted already: Authorization and Proxy-Authorization, and values
+// containing the HEC token as is or in base64, are always redacted first.
+type Redactor func(name string, value string) string
+
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+// DefaultRedactor redacts credentials and cookies
+func DefaultRedactor(name string, value string) string {
+	if sensitiveHeaders[http.CanonicalHeaderKey(name)] {
+		return redacted
+	}
+	return value
+}
+
+// redactHeader returns a copy of header with values redacted by redactor.
+// Credential headers and values containing the token, as is or in base64,
+// are redacted before, so redactor never sees them.
+func redactHeader(header http.Header, redactor Redactor, token string) http.Header {
+	if header == nil {
+		return nil
+	}
+	result := make(http.Header, len(header))
+	for name, values := range header {
+		redactedValues := make([]string, len(values))
+		for i, value := range values {
+			if credentialHeaders[http.CanonicalHeaderKey(name)] || containsToken(value, token) {
+				value = redacted
+			} else if redactor != nil {
+				value = redactor(name, value)
+			}
+			redactedValues[i] = value
+		}
+		result[name] = redactedValues
+	}
+	return result
+}
+
+// Headers carrying the credentials of the client, whatever the auth scheme
+var credentialHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+}
+
+// containsToken returns whether value contains token, including in basic
+// credentials or other base64
+func containsToken(value string, token string) bool {
+	if token == "" {
+		return false
+	}
+	if strings.Contains(value, token) ||
+		strings.Contains(value, base64.StdEncoding.EncodeToString([]byte(token))) ||
+		strings.Contains(value, base64.RawURLEncoding.EncodeToString([]byte(token))) {
+		return true
+	}
+	// Basic credentials are the base64 of "user:token"
+	for _, field := range strings.Fields(value) {
+		if decoded, err := base64.StdEncoding.DecodeString(field); err == nil && strings.Contains(string(decoded), token) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactURL removes the password of the user info in a URL
+func redactURL(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil {
+		return u.Redacted()
+	}
+	return rawURL
+}
+
+// String describes the client without its token
+func (hec *Client) String() string {
+	return fmt.Sprintf("hec.Client{serverURL: %q, channel: %q, token: %q}", redactURL(hec.serverURL), hec.channel, redacted)
+}
+
+func (hec *Client) GoString() string {
+	return hec.String()
+}
@@ -0,0 +1,101 @@
+package hec
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const secretToken = "11111111-2222-3333-4444-555555555555"
+
+func assertNoSecrets(t *testing.T, values ...interface{}) {
+	for _, value := range values {
+		for _, format := range []string{"%v", "%+v", "%#v", "%s"} {
+			output := fmt.Sprintf(format, value)
+			assert.NotContains(t, output, secretToken)
+			assert.NotContains(t, output, "hunter2")
+		}
+	}
+}
+
+func TestRedaction(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=hunter2")
+		w.Header().Set("X-Echo", strings.TrimPrefix(r.Header.Get("Authorization"), "Splunk "))
+		switch r.URL.Query().Get("host") {
+		case "busy":
+			w.WriteHeader(503)
+			w.Write([]byte(`{"text":"Server is busy","code":9}`))
+		case "html":
+			w.WriteHeader(502)
+			w.Write([]byte(`<html>Bad Gateway</html>`))
+		default:
+			w.WriteHeader(403)
+			w.Write([]byte(`{"text":"Invalid token","code":4}`))
+		}
+	}))
+	serverURL := strings.Replace(ts.URL, "http://", "http://admin:hunter2@", 1)
+
+	c := NewClient(serverURL, secretToken)
+	c.SetHTTPClient(testHttpClient)
+	c.SetClock(NewManualClock(time.Unix(1485237827, 0)))
+	c.SetMaxRetry(1)
+	assertNoSecrets(t, c)
+
+	errs := []error{
+		c.WriteEvent(NewEvent("hello, world")),
+		c.WriteRawLine("busy", &EventMetadata{Host: String("busy")}),
+		c.WriteRawLine("html", &EventMetadata{Host: String("html")}),
+	}
+
+	slow := NewClient(strings.Replace(serverURL, "127.0.0.1", "localhost", 1), secretToken)
+	slow.SetHTTPClient(&http.Client{Timeout: time.Nanosecond})
+	errs = append(errs, slow.WriteEvent(NewEvent("hello, world")))
+
+	for _, err := range errs {
+		if assert.Error(t, err) {
+			assertNoSecrets(t, err, err.Error())
+		}
+		var res *Response
+		if errors.As(err, &res) {
+			assertNoSecrets(t, res.Header)
+			assert.Equal(t, redacted, res.Header.Get("Set-Cookie"))
+			assert.Equal(t, redacted, res.Header.Get("X-Echo"))
+		}
+	}
+}
+
+func TestRedactHeader(t *testing.T) {
+	basic := base64.StdEncoding.EncodeToString([]byte("x:" + secretToken))
+	header := http.Header{
+		"Authorization":       {"Splunk " + secretToken},
+		"Proxy-Authorization": {"Basic " + basic},
+		"X-Basic":             {"Basic " + basic},
+		"X-Base64":            {base64.StdEncoding.EncodeToString([]byte(secretToken))},
+		"X-Token":             {"token=" + secretToken},
+		"X-Other":             {"value"},
+	}
+	// A redactor exposing everything sees no credentials
+	seen := make(http.Header)
+	redactedHeader := redactHeader(header, func(name string, value string) string {
+		seen.Add(name, value)
+		return value
+	}, secretToken)
+
+	assertNoSecrets(t, redactedHeader, seen)
+	for name, values := range redactedHeader {
+		for _, value := range values {
+			assert.NotContains(t, value, basic, name)
+		}
+	}
+	assert.Equal(t, http.Header{"X-Other": {"value"}}, seen)
+	assert.Equal(t, "value", redactedHeader.Get("X-Other"))
+	assert.Equal(t, redacted, redactedHeader.Get("Authorization"))
+}
@@ -0,0 +1,108 @@
+package hec
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RequestAuditRecord records a request sent to HEC, without its payload
+type RequestAuditRecord struct {
+	// Time the request was sent
+	Time time.Time `json:"time"`
+
+	// Server URL without password, and path and query of the endpoint
+	Server   string `json:"server"`
+	Endpoint string `json:"endpoint"`
+
+	// Attempt number of the request, starting from 1
+	Attempt int `json:"attempt"`
+
+	// Size of the payload before and after compression
+	Bytes     int `json:"bytes"`
+	WireBytes int `json:"wire_bytes"`
+
+	// Number of events, or lines for raw data
+	Events int `json:"events"`
+
+	// HTTP status code and HEC status code, nil if there is no response
+	StatusCode int  `json:"status_code,omitempty"`
+	Code       *int `json:"code,omitempty"`
+
+	// Duration of the request in milliseconds
+	DurationMS float64 `json:"duration_ms"`
+
+	// Error of the request or from parsing its response
+	Error string `json:"error,omitempty"`
+}
+
+// WithRequestAudit makes a client write an audit record as a line of JSON to
+// writer for every request sent, including retries and acknowledgement
+// polls, e.g. to keep a compliance trail of forwarded data
+func WithRequestAudit(writer io.Writer) Option {
+	var mtx sync.Mutex
+	return WithRequestAuditFunc(func(record RequestAuditRecord) {
+		line, _ := json.Marshal(record)
+		mtx.Lock()
+		defer mtx.Unlock()
+		writer.Write(append(line, '\n'))
+	})
+}
+
+// WithRequestAuditFunc makes a client call audit with the record of every
+// request sent, see WithRequestAudit. It is called from the goroutine of the
+// request, so it should not block.
+func WithRequestAuditFunc(audit func(record RequestAuditRecord)) Option {
+	return func(client *Client) {
+		client.requestAudit = audit
+	}
+}
+
+func (hec *Client) requestAuditRecord(data []byte, entry requestLog) RequestAuditRecord {
+	record := RequestAuditRecord{
+		Time:       entry.startTime,
+		Server:     redactURL(hec.serverURL),
+		Endpoint:   entry.endpoint,
+		Attempt:    entry.attempt,
+		Bytes:      entry.size,
+		WireBytes:  entry.wireSize,
+		Events:     countEvents(entry.endpoint, data),
+		StatusCode: entry.statusCode,
+		DurationMS: float64(entry.duration) / float64(time.Millisecond),
+	}
+	if entry.response != nil {
+		record.Code = Int(entry.response.Code)
+	}
+	if entry.err != nil {
+		record.Error = entry.err.Error()
+	}
+	return record
+}
+
+// countEvents returns the number of events in the payload of a request to
+// endpoint, or of lines for raw data
+func countEvents(endpoint string, data []byte) int {
+	path, _, _ := strings.Cut(endpoint, "?")
+	switch path {
+	case "/services/collector", "/services/collector/event":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		n := 0
+		for {
+			var event json.RawMessage
+			if decoder.Decode(&event) != nil {
+				return n
+			}
+			n++
+		}
+	case "/services/collector/raw":
+		n := bytes.Count(data, []byte{'\n'})
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			n++
+		}
+		return n
+	}
+	return 0
+}
@@ -0,0 +1,52 @@
+package hec
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithRequestAudit(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("index") == "forbidden" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"text":"Incorrect index","code":7}`))
+			return
+		}
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	var buffer bytes.Buffer
+	c := NewClient(ts.URL, testSplunkToken, WithRequestAudit(&buffer))
+	c.SetChannelMode(ChannelNone)
+	assert.NoError(t, c.WriteBatch([]*Event{NewEvent("a"), NewEvent("b")}))
+	assert.NoError(t, c.WriteRawString("one\ntwo\nthree", nil))
+	assert.Error(t, c.WriteRawString("secret payload\n", &EventMetadata{Index: String("forbidden")}))
+
+	var records []RequestAuditRecord
+	for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+		var record RequestAuditRecord
+		assert.NoError(t, json.Unmarshal([]byte(line), &record))
+		assert.False(t, record.Time.IsZero())
+		assert.Equal(t, ts.URL, record.Server)
+		records = append(records, record)
+	}
+	if assert.Len(t, records, 3) {
+		assert.Equal(t, "/services/collector", records[0].Endpoint)
+		assert.Equal(t, 2, records[0].Events)
+		assert.Equal(t, 200, records[0].StatusCode)
+		assert.Equal(t, Int(0), records[0].Code)
+		assert.Equal(t, 3, records[1].Events)
+		assert.Equal(t, 1, records[2].Events)
+		assert.Equal(t, 1, records[2].Attempt)
+		assert.Equal(t, 15, records[2].Bytes)
+		assert.Equal(t, Int(StatusIncorrectIndex), records[2].Code)
+	}
+	assert.NotContains(t, buffer.String(), "secret payload")
+}
@@ -0,0 +1,123 @@
+package hec
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+var ErrNoRoute = errors.New("No route for event")
+
+// Router writes events and raw data to different destinations, e.g. clients
+// with other tokens, channels or clusters, by the first matching rule
+type Router struct {
+	mtx      sync.RWMutex
+	routes   []route
+	fallback HEC
+}
+
+type route struct {
+	match  func(event *Event) bool
+	client HEC
+}
+
+// NewRouter creates a router writing events matching no rule to fallback.
+// If fallback is nil, such events fail with ErrNoRoute.
+func NewRouter(fallback HEC) *Router {
+	return &Router{fallback: fallback}
+}
+
+// AddRoute adds a rule writing events matched by match to client. Rules are
+// evaluated in the order they are added.
+func (r *Router) AddRoute(match func(event *Event) bool, client HEC) {
+	r.mtx.Lock()
+	r.routes = append(r.routes, route{match: match, client: client})
+	r.mtx.Unlock()
+}
+
+// MatchIndex matches events with the index
+func MatchIndex(index string) func(event *Event) bool {
+	return func(event *Event) bool {
+		return event.Index != nil && *event.Index == index
+	}
+}
+
+// MatchSourceType matches events with the sourcetype
+func MatchSourceType(sourcetype string) func(event *Event) bool {
+	return func(event *Event) bool {
+		return event.SourceType != nil && *event.SourceType == sourcetype
+	}
+}
+
+func (r *Router) destination(event *Event) HEC {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	for _, route := range r.routes {
+		if route.match(event) {
+			return route.client
+		}
+	}
+	return r.fallback
+}
+
+func (r *Router) WriteEvent(event *Event) error {
+	return r.WriteEventWithContext(context.Background(), event)
+}
+
+func (r *Router) WriteEventWithContext(ctx context.Context, event *Event) error {
+	client := r.destination(event)
+	if client == nil {
+		return ErrNoRoute
+	}
+	return client.WriteEventWithContext(ctx, event)
+}
+
+// WriteBatch splits events into one batch per destination, keeping their
+// order. All batches are written even if some fail; the first error is returned.
+func (r *Router) WriteBatch(events []*Event) error {
+	return r.WriteBatchWithContext(context.Background(), events)
+}
+
+func (r *Router) WriteBatchWithContext(ctx context.Context, events []*Event) error {
+	var clients []HEC
+	batches := make(map[HEC][]*Event)
+	var err error
+	for _, event := range events {
+		client := r.destination(event)
+		if client == nil {
+			if err == nil {
+				err = ErrNoRoute
+			}
+			continue
+		}
+		if _, ok := batches[client]; !ok {
+			clients = append(clients, client)
+		}
+		batches[client] = append(batches[client], event)
+	}
+	for _, client := range clients {
+		if e := client.WriteBatchWithContext(ctx, batches[client]); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// WriteRaw writes raw data to the destination of its metadata, matched by the
+// rules as an event with the metadata
+func (r *Router) WriteRaw(reader io.ReadSeeker, metadata *EventMetadata) error {
+	return r.WriteRawWithContext(context.Background(), reader, metadata)
+}
+
+func (r *Router) WriteRawWithContext(ctx context.Context, reader io.ReadSeeker, metadata *EventMetadata) error {
+	event := &Event{}
+	if metadata != nil {
+		event.Host, event.Index, event.Source, event.SourceType = metadata.Host, metadata.Index, metadata.Source, metadata.SourceType
+	}
+	client := r.destination(event)
+	if client == nil {
+		return ErrNoRoute
+	}
+	return client.WriteRawWithContext(ctx, reader, metadata)
+}
@@ -0,0 +1,59 @@
+package hec
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouter(t *testing.T) {
+	received := make(map[string][]string)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Splunk ")
+		received[token] = append(received[token], string(body))
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	newClient := func(token string) HEC {
+		c := NewClient(ts.URL, token)
+		c.SetHTTPClient(testHttpClient)
+		return c
+	}
+	router := NewRouter(nil)
+	router.AddRoute(MatchIndex("audit"), newClient("audit-token"))
+	router.AddRoute(MatchSourceType("metrics"), newClient("metrics-token"))
+
+	audit := NewEvent("login")
+	audit.SetIndex("audit")
+	metric := NewEvent("cpu")
+	metric.SetSourceType("metrics")
+	other := NewEvent("other")
+
+	err := router.WriteBatch([]*Event{audit, metric, other, audit})
+	assert.ErrorIs(t, err, ErrNoRoute)
+	assert.Equal(t, []string{`{"index":"audit","event":"login"}{"index":"audit","event":"login"}`}, received["audit-token"])
+	assert.Equal(t, []string{`{"sourcetype":"metrics","event":"cpu"}`}, received["metrics-token"])
+
+	// Raw data is routed by its metadata
+	ctx := context.Background()
+	assert.NoError(t, router.WriteRawWithContext(ctx, strings.NewReader("raw\n"), &EventMetadata{Index: String("audit")}))
+	assert.Equal(t, "raw\n", received["audit-token"][1])
+	assert.ErrorIs(t, router.WriteRaw(strings.NewReader("raw\n"), nil), ErrNoRoute)
+
+	router = NewRouter(newClient("default-token"))
+	assert.NoError(t, router.WriteEvent(other))
+	assert.Len(t, received["default-token"], 1)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, router.WriteEventWithContext(cancelled, other), ErrCanceled)
+	assert.ErrorIs(t, router.WriteBatchWithContext(cancelled, []*Event{other}), ErrCanceled)
+	assert.Len(t, received["default-token"], 1)
+}
@@ -0,0 +1,72 @@
+package hec
+
+import "time"
+
+// Settings are the parameters of a client adjustable at runtime. A write uses
+// the settings current when it starts; every request uses one consistent set.
+type Settings struct {
+	// Max retrying times, see SetMaxRetry
+	MaxRetries int
+
+	// Max content length, see SetMaxContentLength
+	MaxContentLength int
+
+	// Max events per request of a batch, see SetMaxBatchEvents
+	MaxBatchEvents int
+
+	// Compression type, see SetCompression
+	Compression string
+
+	// Max time to buffer data of streaming writes, see SetFlushInterval. Zero
+	// or less means the default.
+	FlushInterval time.Duration
+}
+
+func (hec *Client) current() *Settings {
+	return hec.settings.Load().(*Settings)
+}
+
+// updateSettings replaces the settings by a copy changed by update
+func (hec *Client) updateSettings(update func(settings *Settings)) {
+	hec.settingsMtx.Lock()
+	defer hec.settingsMtx.Unlock()
+	settings := *hec.current()
+	update(&settings)
+	if settings.FlushInterval <= 0 {
+		settings.FlushInterval = defaultFlushInterval // tickers need a positive interval
+	}
+	hec.settings.Store(&settings)
+}
+
+func (hec *Client) Settings() Settings {
+	return *hec.current()
+}
+
+func (hec *Client) UpdateSettings(settings Settings) {
+	hec.updateSettings(func(current *Settings) { *current = settings })
+}
+
+// Settings returns the settings of the clients. MaxRetries is the max number
+// of clients tried, as set by SetMaxRetry of Cluster.
+func (c *Cluster) Settings() Settings {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	settings := c.clients[0].Settings()
+	settings.MaxRetries = c.maxRetries
+	return settings
+}
+
+// UpdateSettings updates the settings of all clients. MaxRetries is the max
+// number of clients tried, as set by SetMaxRetry of Cluster.
+func (c *Cluster) UpdateSettings(settings Settings) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.maxRetries = settings.MaxRetries
+	for _, client := range c.clients {
+		client.updateSettings(func(current *Settings) {
+			retries := current.MaxRetries
+			*current = settings
+			current.MaxRetries = retries
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package hec
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHEC_UpdateSettings(t *testing.T) {
+	ts := httptest.NewServer(jsonEndpoint(t, "gzip"))
+	defer ts.Close()
+
+	c := NewClient(ts.URL, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+	c.UpdateSettings(Settings{MaxRetries: 1, MaxContentLength: 2048, Compression: "gzip", FlushInterval: time.Second})
+	assert.Equal(t, Settings{MaxRetries: 1, MaxContentLength: 2048, Compression: "gzip", FlushInterval: time.Second}, c.Settings())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, c.WriteEvent(NewEvent("hello, world")))
+		}()
+		go func(i int) {
+			defer wg.Done()
+			c.SetMaxContentLength(2048 + i)
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, "gzip", c.Settings().Compression)
+}
+
+func TestCluster_UpdateSettings(t *testing.T) {
+	c := NewCluster([]string{"http://a:8088", "http://b:8088"}, testSplunkToken)
+	c.UpdateSettings(Settings{MaxRetries: 1, MaxContentLength: 2048})
+	assert.Equal(t, 1, c.Settings().MaxRetries)
+	for _, client := range c.(*Cluster).clients {
+		assert.Equal(t, 0, client.Settings().MaxRetries)
+		assert.Equal(t, 2048, client.Settings().MaxContentLength)
+	}
+}
+
+func TestHEC_UpdateSettings_FlushInterval(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+	c.SetFlushInterval(0)
+	assert.Equal(t, defaultFlushInterval, c.Settings().FlushInterval)
+	c.UpdateSettings(Settings{MaxContentLength: 2048})
+	assert.Equal(t, defaultFlushInterval, c.Settings().FlushInterval)
+
+	// Streaming writes don't panic on a non-positive ticker interval
+	records := make(chan []byte, 1)
+	records <- []byte("raw event")
+	close(records)
+	assert.NoError(t, c.WriteRawChannel(context.Background(), records, nil))
+
+	cluster := NewCluster([]string{ts.URL, ts.URL}, testSplunkToken)
+	cluster.UpdateSettings(Settings{FlushInterval: -time.Second})
+	assert.Equal(t, defaultFlushInterval, cluster.Settings().FlushInterval)
+}
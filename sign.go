@@ -0,0 +1,25 @@
+package hec
+
+import "net/http"
+
+// RequestSigner signs requests to HEC, e.g. with an HMAC or AWS SigV4 for
+// API gateways in front of HEC. SignRequest is called before every request,
+// including retries, once all other headers are set; the body can be read
+// with req.GetBody. Requests failing to be signed are not sent.
+type RequestSigner interface {
+	SignRequest(req *http.Request) error
+}
+
+// RequestSignerFunc is a function signing requests
+type RequestSignerFunc func(req *http.Request) error
+
+func (f RequestSignerFunc) SignRequest(req *http.Request) error {
+	return f(req)
+}
+
+// WithRequestSigner makes a client sign its requests with signer
+func WithRequestSigner(signer RequestSigner) Option {
+	return func(client *Client) {
+		client.signer = signer
+	}
+}
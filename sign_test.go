@@ -0,0 +1,54 @@
+package hec
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithRequestSigner(t *testing.T) {
+	key := []byte("secret")
+	sign := func(body []byte) string {
+		mac := hmac.New(sha256.New, key)
+		mac.Write(body)
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, sign(body), r.Header.Get("X-Signature"))
+		assert.Equal(t, "Splunk "+testSplunkToken, r.Header.Get("X-Signed-Authorization"))
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL, testSplunkToken, WithRequestSigner(RequestSignerFunc(func(req *http.Request) error {
+		body, err := req.GetBody()
+		if err != nil {
+			return err
+		}
+		data, _ := io.ReadAll(body)
+		req.Header.Set("X-Signature", sign(data))
+		req.Header.Set("X-Signed-Authorization", req.Header.Get("Authorization"))
+		return nil
+	})))
+	assert.NoError(t, c.WriteEvent(NewEvent("signed")))
+	c.SetCompression("gzip")
+	assert.NoError(t, c.WriteEvent(NewEvent("compressed")))
+	assert.Equal(t, 2, requests)
+
+	errSigner := errors.New("no key")
+	c = NewClient(ts.URL, testSplunkToken, WithRequestSigner(RequestSignerFunc(func(req *http.Request) error {
+		return errSigner
+	})))
+	assert.ErrorIs(t, c.WriteEvent(NewEvent("unsigned")), errSigner)
+	assert.Equal(t, 2, requests)
+}
@@ -0,0 +1,139 @@
+// Package sloghec ships log/slog records to HEC.
+package sloghec
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+)
+
+// HandlerOptions configures a Handler
+type HandlerOptions struct {
+	// Min level of records written (default: slog.LevelInfo)
+	Level slog.Leveler
+
+	// Write the source file and line of records as "source"
+	AddSource bool
+
+	// Metadata of events, e.g. the index and sourcetype (optional)
+	Metadata *hec.EventMetadata
+}
+
+// Handler is a slog.Handler writing every record as an event with its
+// message as "msg", level as "level" and attributes, nesting groups as
+// objects, at the time of the record. Records are written as they are
+// logged; use a client with a short timeout, or logging blocks while HEC is
+// unreachable.
+type Handler struct {
+	client  hec.HEC
+	options HandlerOptions
+
+	// Attributes added by WithAttrs, each in the groups open at the time
+	attrs  []groupedAttr
+	groups []string
+}
+
+type groupedAttr struct {
+	groups []string
+	attr   slog.Attr
+}
+
+// NewHandler creates a handler writing records with client
+func NewHandler(client hec.HEC, options *HandlerOptions) *Handler {
+	h := &Handler{client: client}
+	if options != nil {
+		h.options = *options
+	}
+	if h.options.Level == nil {
+		h.options.Level = slog.LevelInfo
+	}
+	return h
+}
+
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.options.Level.Level()
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	clone := *h
+	clone.attrs = append([]groupedAttr(nil), h.attrs...)
+	for _, attr := range attrs {
+		clone.attrs = append(clone.attrs, groupedAttr{groups: h.groups, attr: attr})
+	}
+	return &clone
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.groups = append(append([]string(nil), h.groups...), name)
+	return &clone
+}
+
+func (h *Handler) Handle(ctx context.Context, record slog.Record) error {
+	data := map[string]interface{}{
+		slog.MessageKey: record.Message,
+		slog.LevelKey:   record.Level.String(),
+	}
+	if h.options.AddSource && record.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{record.PC}).Next()
+		data[slog.SourceKey] = fmt.Sprintf("%s:%d", frame.File, frame.Line)
+	}
+	for _, grouped := range h.attrs {
+		addAttr(data, grouped.groups, grouped.attr)
+	}
+	record.Attrs(func(attr slog.Attr) bool {
+		addAttr(data, h.groups, attr)
+		return true
+	})
+
+	event := hec.NewEvent(data)
+	if !record.Time.IsZero() {
+		event.SetTime(record.Time)
+	}
+	if m := h.options.Metadata; m != nil {
+		event.Host, event.Index, event.Source, event.SourceType = m.Host, m.Index, m.Source, m.SourceType
+	}
+	_, err := h.client.WriteEventWithResponse(ctx, event)
+	return err
+}
+
+// addAttr adds attr to data, in the object of the group path groups
+func addAttr(data map[string]interface{}, groups []string, attr slog.Attr) {
+	value := attr.Value.Resolve()
+	if attr.Key == "" && value.Kind() != slog.KindGroup || value.Kind() == slog.KindGroup && len(value.Group()) == 0 {
+		return // ignored like by the handlers of slog
+	}
+	for _, group := range groups {
+		nested, ok := data[group].(map[string]interface{})
+		if !ok {
+			nested = make(map[string]interface{})
+			data[group] = nested
+		}
+		data = nested
+	}
+	if value.Kind() == slog.KindGroup {
+		path := []string{attr.Key}
+		if attr.Key == "" {
+			path = nil // inlined
+		}
+		for _, member := range value.Group() {
+			addAttr(data, path, member)
+		}
+		return
+	}
+	switch v := value.Any().(type) {
+	case error:
+		data[attr.Key] = v.Error()
+	default:
+		data[attr.Key] = v
+	}
+}
@@ -0,0 +1,77 @@
+package sloghec
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/slogtest"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+	"github.com/stretchr/testify/assert"
+)
+
+const testToken = "00000000-0000-0000-0000-000000000000"
+
+// newSplunk returns the URL of a HEC endpoint recording events
+func newSplunk(t *testing.T, events *[]map[string]interface{}) string {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		*events = append(*events, event)
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	t.Cleanup(ts.Close)
+	return ts.URL
+}
+
+func TestHandler(t *testing.T) {
+	var events []map[string]interface{}
+	client := hec.NewClient(newSplunk(t, &events), testToken)
+	logger := slog.New(NewHandler(client, &HandlerOptions{
+		AddSource: true,
+		Metadata:  &hec.EventMetadata{Index: hec.String("app")},
+	}))
+
+	logger.Debug("not written")
+	logger.With("service", "api").WithGroup("request").With("id", 7).
+		Warn("slow", "path", "/", slog.Group("timing", "ms", 250), "err", errors.New("timeout"))
+
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "app", events[0]["index"])
+		assert.NotEmpty(t, events[0]["time"])
+		data := events[0]["event"].(map[string]interface{})
+		assert.True(t, strings.Contains(data["source"].(string), "slog_test.go:"))
+		delete(data, "source")
+		assert.Equal(t, map[string]interface{}{
+			"msg":     "slow",
+			"level":   "WARN",
+			"service": "api",
+			"request": map[string]interface{}{
+				"id":     float64(7),
+				"path":   "/",
+				"timing": map[string]interface{}{"ms": float64(250)},
+				"err":    "timeout",
+			},
+		}, data)
+	}
+}
+
+func TestSlogtest(t *testing.T) {
+	var events []map[string]interface{}
+	client := hec.NewClient(newSplunk(t, &events), testToken)
+	err := slogtest.TestHandler(NewHandler(client, nil), func() []map[string]any {
+		results := make([]map[string]any, len(events))
+		for i, event := range events {
+			results[i] = event["event"].(map[string]interface{})
+			if time, ok := event["time"]; ok {
+				results[i][slog.TimeKey] = time
+			}
+		}
+		return results
+	})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,169 @@
+package hec
+
+import (
+	"bytes"
+	"net/url"
+	"sort"
+	"sync"
+)
+
+// Max number of index and sourcetype pairs accounted separately, the events of
+// further pairs are accounted to OtherUsage
+const maxUsageKeys = 1000
+
+// OtherUsage is the index and sourcetype of events beyond maxUsageKeys pairs
+const OtherUsage = "(other)"
+
+// Usage is the amount of data written successfully to an index with a sourcetype.
+// Index and SourceType are empty if they are set neither by the data nor by
+// the default metadata, which leaves them to the token.
+type Usage struct {
+	Index      string
+	SourceType string
+
+	// Events in JSON mode, and lines in raw mode
+	Events int64
+
+	// Bytes of the payloads before compression
+	Bytes int64
+}
+
+// Stats are statistics of the data written by a client
+type Stats struct {
+	// Counters as published by WithExpvar: requests written successfully,
+	// writes failed, requests retried and events dropped by quotas
+	Sent    int64
+	Failed  int64
+	Retried int64
+	Dropped int64
+
+	// Usage by index and sourcetype, ordered by index and sourcetype
+	Usage []Usage
+}
+
+type usageKey struct {
+	index      string
+	sourceType string
+}
+
+// usageSet accumulates usage by index and sourcetype
+type usageSet map[usageKey]*Usage
+
+func (s usageSet) add(index *string, sourceType *string, events int64, bytes int64) {
+	key := usageKey{index: stringValue(index), sourceType: stringValue(sourceType)}
+	if _, ok := s[key]; !ok && len(s) >= maxUsageKeys {
+		key = usageKey{index: OtherUsage, sourceType: OtherUsage}
+	}
+	usage, ok := s[key]
+	if !ok {
+		usage = &Usage{Index: key.index, SourceType: key.sourceType}
+		s[key] = usage
+	}
+	usage.Events += events
+	usage.Bytes += bytes
+}
+
+func (s usageSet) usage() []Usage {
+	result := make([]Usage, 0, len(s))
+	for _, usage := range s {
+		result = append(result, *usage)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Index != result[j].Index {
+			return result[i].Index < result[j].Index
+		}
+		return result[i].SourceType < result[j].SourceType
+	})
+	return result
+}
+
+// accounting is the usage and counters of a client, safe for concurrent use
+type accounting struct {
+	mtx      sync.Mutex
+	total    usageSet
+	counters map[string]int64
+}
+
+// count increments the counter key, also published with expvar
+func (hec *Client) count(key string) {
+	hec.usage.mtx.Lock()
+	if hec.usage.counters == nil {
+		hec.usage.counters = make(map[string]int64)
+	}
+	hec.usage.counters[key]++
+	hec.usage.mtx.Unlock()
+	hec.countVar(key)
+}
+
+func (a *accounting) merge(usage usageSet) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	if a.total == nil {
+		a.total = make(usageSet)
+	}
+	for _, u := range usage {
+		a.total.add(&u.Index, &u.SourceType, u.Events, u.Bytes)
+	}
+}
+
+// accountRaw accounts a chunk of raw data written to endpoint
+func (hec *Client) accountRaw(endpoint string, chunk []byte) {
+	var index, sourceType *string
+	if u, err := url.Parse(endpoint); err == nil {
+		query := u.Query()
+		if query.Has("index") {
+			index = String(query.Get("index"))
+		}
+		if query.Has("sourcetype") {
+			sourceType = String(query.Get("sourcetype"))
+		}
+	}
+	lines := hec.rawSplitter.countLines(chunk)
+	if hec.rawSplitter.pattern == nil && len(chunk) > 0 && !bytes.HasSuffix(chunk, hec.rawSplitter.terminator()) {
+		lines++ // the last line of data without a trailing delimiter
+	}
+	usage := make(usageSet)
+	usage.add(index, sourceType, int64(lines), int64(len(chunk)))
+	hec.usage.merge(usage)
+}
+
+// Stats returns the counters and the data written successfully since the client was created
+func (hec *Client) Stats() Stats {
+	hec.usage.mtx.Lock()
+	defer hec.usage.mtx.Unlock()
+	counters := hec.usage.counters
+	return Stats{
+		Sent:    counters["sent"],
+		Failed:  counters["failed"],
+		Retried: counters["retried"],
+		Dropped: counters["dropped"],
+		Usage:   hec.usage.total.usage(),
+	}
+}
+
+// Stats returns the data written successfully by all nodes since the cluster was created
+func (c *Cluster) Stats() Stats {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	var stats Stats
+	total := make(usageSet)
+	for _, client := range c.clients {
+		node := client.Stats()
+		stats.Sent += node.Sent
+		stats.Failed += node.Failed
+		stats.Retried += node.Retried
+		stats.Dropped += node.Dropped
+		for _, u := range node.Usage {
+			total.add(&u.Index, &u.SourceType, u.Events, u.Bytes)
+		}
+	}
+	stats.Usage = total.usage()
+	return stats
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
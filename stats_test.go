@@ -0,0 +1,51 @@
+package hec
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStats(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+	c.SetMaxContentLength(60)
+	c.SetDefaultMetadata(&EventMetadata{Index: String("main")})
+
+	access := NewEvent("access")
+	access.SetSourceType("access_log")
+	audit := NewEvent("audit")
+	audit.SetIndex("audit")
+	assert.NoError(t, c.WriteEvent(access))
+	assert.NoError(t, c.WriteBatch([]*Event{access, audit, access}))
+	assert.NoError(t, c.WriteRawString("one\ntwo\nthree", &EventMetadata{SourceType: String("syslog")}))
+
+	accessSize := int64(len(`{"index":"main","sourcetype":"access_log","event":"access"}`))
+	stats := c.Stats()
+	assert.Equal(t, []Usage{
+		{Index: "audit", SourceType: "", Events: 1, Bytes: int64(len(`{"index":"audit","event":"audit"}`))},
+		{Index: "main", SourceType: "access_log", Events: 3, Bytes: 3 * accessSize},
+		{Index: "main", SourceType: "syslog", Events: 3, Bytes: int64(len("one\ntwo\nthree\n"))},
+	}, stats.Usage)
+	assert.Equal(t, int64(5), stats.Sent)
+	assert.Equal(t, int64(0), stats.Failed)
+}
+
+func TestStatsCardinality(t *testing.T) {
+	usage := make(usageSet)
+	for i := 0; i < maxUsageKeys+5; i++ {
+		usage.add(String(fmt.Sprint("index", i)), nil, 1, 10)
+	}
+	assert.Len(t, usage, maxUsageKeys+1)
+	other := usage[usageKey{index: OtherUsage, sourceType: OtherUsage}]
+	assert.Equal(t, int64(5), other.Events)
+	assert.Equal(t, int64(50), other.Bytes)
+}
@@ -0,0 +1,104 @@
+// Package statsdhec sends the metrics of HEC clients to StatsD or DogStatsD.
+package statsdhec
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+)
+
+// Emitter implements hec.MetricsHook by writing one StatsD line per metric.
+// DogStatsD lines carry the labels as tags and histograms as such; plain
+// StatsD lines append the label values to the metric name and send
+// histograms as timers.
+type Emitter struct {
+	mtx       sync.Mutex
+	writer    io.Writer
+	prefix    string
+	dogstatsd bool
+}
+
+// New creates an emitter writing to writer, with metric names prefixed by prefix
+func New(writer io.Writer, prefix string, dogstatsd bool) *Emitter {
+	return &Emitter{writer: writer, prefix: prefix, dogstatsd: dogstatsd}
+}
+
+// Dial creates an emitter sending UDP packets to addr, e.g. "localhost:8125"
+func Dial(addr string, prefix string, dogstatsd bool) (*Emitter, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return New(conn, prefix, dogstatsd), nil
+}
+
+// WithMetrics makes a client send its metrics with emitter
+func WithMetrics(emitter *Emitter) hec.Option {
+	return hec.WithMetricsHook(emitter)
+}
+
+func (e *Emitter) Count(name string, value int64, labels map[string]string) {
+	e.emit(name, fmt.Sprint(value), "c", labels)
+}
+
+func (e *Emitter) Observe(name string, value float64, labels map[string]string) {
+	if e.dogstatsd {
+		e.emit(name, fmt.Sprint(value), "h", labels)
+		return
+	}
+	if name == hec.MetricRequestDuration {
+		value *= 1000 // timers are in milliseconds
+	}
+	e.emit(name, fmt.Sprint(value), "ms", labels)
+}
+
+// emit writes a line, ignoring errors like StatsD clients usually do
+func (e *Emitter) emit(name string, value string, kind string, labels map[string]string) {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var line strings.Builder
+	if e.prefix != "" {
+		line.WriteString(e.prefix + ".")
+	}
+	line.WriteString(name)
+	if !e.dogstatsd {
+		for _, key := range keys {
+			line.WriteString("." + sanitize(labels[key]))
+		}
+	}
+	line.WriteString(":" + value + "|" + kind)
+	if e.dogstatsd && len(keys) > 0 {
+		for i, key := range keys {
+			separator := ","
+			if i == 0 {
+				separator = "|#"
+			}
+			line.WriteString(separator + key + ":" + sanitize(labels[key]))
+		}
+	}
+	line.WriteString("\n")
+
+	e.mtx.Lock()
+	e.writer.Write([]byte(line.String()))
+	e.mtx.Unlock()
+}
+
+// sanitize replaces characters with a meaning in StatsD lines
+func sanitize(value string) string {
+	return strings.Trim(strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', ',', '#', '@', '.', '/', ' ', '\n':
+			return '_'
+		}
+		return r
+	}, value), "_")
+}
@@ -0,0 +1,50 @@
+package statsdhec
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+	"github.com/stretchr/testify/assert"
+)
+
+var labels = map[string]string{"endpoint": "/services/collector", "code": "0"}
+
+func TestEmitter(t *testing.T) {
+	var buffer bytes.Buffer
+	dogstatsd := New(&buffer, "app", true)
+	dogstatsd.Count(hec.MetricRequests, 1, labels)
+	dogstatsd.Observe(hec.MetricRequestDuration, 0.25, labels)
+	assert.Equal(t, "app.hec.requests:1|c|#code:0,endpoint:services_collector\n"+
+		"app.hec.request.duration:0.25|h|#code:0,endpoint:services_collector\n", buffer.String())
+
+	buffer.Reset()
+	statsd := New(&buffer, "", false)
+	statsd.Count(hec.MetricRequests, 2, labels)
+	statsd.Observe(hec.MetricRequestDuration, 0.25, labels)
+	assert.Equal(t, "hec.requests.0.services_collector:2|c\n"+
+		"hec.request.duration.0.services_collector:250|ms\n", buffer.String())
+}
+
+func TestDial(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	emitter, err := Dial(conn.LocalAddr().String(), "app", true)
+	if !assert.NoError(t, err) {
+		return
+	}
+	emitter.Count(hec.MetricRequests, 1, nil)
+
+	packet := make([]byte, 512)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(packet)
+	assert.NoError(t, err)
+	assert.Equal(t, "app.hec.requests:1|c", strings.TrimSpace(string(packet[:n])))
+}
@@ -0,0 +1,78 @@
+package hec
+
+import (
+	"sync"
+	"time"
+)
+
+// Status is the health of a client, to be embedded in health checks of
+// applications. Only requests to HEC count, events skipped before sending
+// (e.g. too long or over quota) don't.
+type Status struct {
+	// Server URL without password, empty for a Cluster
+	Server string `json:"server,omitempty"`
+
+	// Times of the last successful and failed writes, zero if there was none
+	LastSuccess time.Time `json:"last_success"`
+	LastFailure time.Time `json:"last_failure"`
+
+	// Error of the last failed write
+	LastError string `json:"last_error,omitempty"`
+
+	// Failed writes since the last successful one. For a Cluster it is the
+	// least of its nodes, so it is 0 as long as one node works.
+	ConsecutiveFailures int `json:"consecutive_failures"`
+
+	// Status of every node of a Cluster
+	Nodes []Status `json:"nodes,omitempty"`
+}
+
+// health tracks the status of a client, safe for concurrent use
+type health struct {
+	mtx    sync.Mutex
+	status Status
+}
+
+func (h *health) succeeded(now time.Time) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	h.status.LastSuccess = now
+	h.status.ConsecutiveFailures = 0
+}
+
+func (h *health) failed(now time.Time, err error) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	h.status.LastFailure = now
+	h.status.LastError = err.Error()
+	h.status.ConsecutiveFailures++
+}
+
+func (hec *Client) Status() Status {
+	hec.health.mtx.Lock()
+	defer hec.health.mtx.Unlock()
+	status := hec.health.status
+	status.Server = redactURL(hec.serverURL)
+	return status
+}
+
+func (c *Cluster) Status() Status {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	var status Status
+	for i, client := range c.clients {
+		node := client.Status()
+		status.Nodes = append(status.Nodes, node)
+		if node.LastSuccess.After(status.LastSuccess) {
+			status.LastSuccess = node.LastSuccess
+		}
+		if node.LastFailure.After(status.LastFailure) {
+			status.LastFailure = node.LastFailure
+			status.LastError = node.LastError
+		}
+		if i == 0 || node.ConsecutiveFailures < status.ConsecutiveFailures {
+			status.ConsecutiveFailures = node.ConsecutiveFailures
+		}
+	}
+	return status
+}
@@ -0,0 +1,75 @@
+package hec
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatus(t *testing.T) {
+	busy := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if busy {
+			w.WriteHeader(503)
+			w.Write([]byte(`{"text":"Server is busy","code":9}`))
+			return
+		}
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	recorder := NewTestRecorder()
+	c := NewClient(ts.URL, testSplunkToken, WithTestMode(recorder))
+	c.SetHTTPClient(testHttpClient)
+	assert.Equal(t, Status{Server: ts.URL}, c.Status())
+
+	recorder.Clock.Advance(time.Second)
+	assert.NoError(t, c.WriteEvent(NewEvent("one")))
+	busy = true
+	recorder.Clock.Advance(time.Second)
+	assert.Error(t, c.WriteEvent(NewEvent("two")))
+	assert.Error(t, c.WriteEvent(NewEvent("three")))
+
+	status := c.Status()
+	assert.Equal(t, time.Unix(1, 0), status.LastSuccess)
+	assert.Equal(t, time.Unix(2, 0), status.LastFailure)
+	assert.Equal(t, "Server is busy", status.LastError)
+	assert.Equal(t, 2, status.ConsecutiveFailures)
+
+	busy = false
+	assert.NoError(t, c.WriteEvent(NewEvent("four")))
+	assert.Equal(t, 0, c.Status().ConsecutiveFailures)
+	assert.Equal(t, "Server is busy", c.Status().LastError)
+
+	output, err := json.Marshal(c.Status())
+	assert.NoError(t, err)
+	assert.Contains(t, string(output), `"last_error":"Server is busy","consecutive_failures":0`)
+}
+
+func TestClusterStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(503)
+		w.Write([]byte(`{"text":"Server is busy","code":9}`))
+	}))
+	defer down.Close()
+
+	c := NewCluster([]string{down.URL, ts.URL}, testSplunkToken, WithTestMode(NewTestRecorder()))
+	c.SetHTTPClient(testHttpClient)
+	c.SetMaxRetry(2)
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, c.WriteEvent(NewEvent(i)))
+	}
+
+	status := c.Status()
+	assert.Len(t, status.Nodes, 2)
+	assert.False(t, status.LastSuccess.IsZero())
+	assert.Equal(t, 0, status.ConsecutiveFailures)
+}
@@ -0,0 +1,259 @@
+// Package stdloghec writes the lines of the standard library logger, or any
+// logger writing lines to an io.Writer, as HEC events.
+package stdloghec
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+)
+
+const (
+	defaultQueueSize     = 1000
+	defaultBatchSize     = 100
+	defaultFlushInterval = time.Second
+)
+
+// Options configures a Writer
+type Options struct {
+	// Metadata of events, e.g. the index and sourcetype (optional)
+	Metadata *hec.EventMetadata
+
+	// Prefix of the logger, stripped from lines (optional)
+	Prefix string
+
+	// Severity of lines without a level (default: "info")
+	DefaultSeverity string
+
+	// Location of timestamps without a zone (default: time.Local, as log
+	// writes local time unless log.LUTC is set)
+	Location *time.Location
+
+	// Max number of events waiting to be written; further events are dropped
+	// (default: 1000)
+	QueueSize int
+
+	// Max number of events per batch (default: 100)
+	BatchSize int
+
+	// Max time an event waits for its batch (default: 1s)
+	FlushInterval time.Duration
+
+	// Called with errors of writes, from the goroutine of the writer (optional)
+	OnError func(err error)
+}
+
+// Writer is an io.Writer turning every line into an event with the message
+// as "message" and its severity as "severity", at the time of the line.
+// Timestamps of the log package and RFC 3339 at the start of lines are
+// parsed, as well as levels like "[WARN]", "ERROR:" or "level=debug". Events
+// are queued and written in batches by a goroutine, so logging never waits
+// for HEC.
+//
+//	log.SetOutput(io.MultiWriter(os.Stderr, writer))
+type Writer struct {
+	client  hec.HEC
+	options Options
+	now     func() time.Time
+
+	// Incomplete last line of the writes so far
+	partialMtx sync.Mutex
+	partial    []byte
+
+	// Guards sends to queue against Close
+	mtx     sync.RWMutex
+	closed  bool
+	queue   chan *hec.Event
+	done    chan struct{}
+	dropped atomic.Int64
+}
+
+// New creates a writer writing with client and starts its goroutine
+func New(client hec.HEC, options *Options) *Writer {
+	w := &Writer{client: client, now: time.Now}
+	if options != nil {
+		w.options = *options
+	}
+	if w.options.DefaultSeverity == "" {
+		w.options.DefaultSeverity = "info"
+	}
+	if w.options.Location == nil {
+		w.options.Location = time.Local
+	}
+	if w.options.QueueSize <= 0 {
+		w.options.QueueSize = defaultQueueSize
+	}
+	if w.options.BatchSize <= 0 {
+		w.options.BatchSize = defaultBatchSize
+	}
+	if w.options.FlushInterval <= 0 {
+		w.options.FlushInterval = defaultFlushInterval
+	}
+	w.queue = make(chan *hec.Event, w.options.QueueSize)
+	w.done = make(chan struct{})
+	go w.run()
+	return w
+}
+
+// Write queues an event per complete line of data. It never fails.
+func (w *Writer) Write(data []byte) (int, error) {
+	w.partialMtx.Lock()
+	buffered := append(w.partial, data...)
+	cut := bytes.LastIndexByte(buffered, '\n') + 1
+	lines := buffered[:cut]
+	w.partial = append([]byte(nil), buffered[cut:]...)
+	w.partialMtx.Unlock()
+
+	for len(lines) > 0 {
+		end := bytes.IndexByte(lines, '\n')
+		line := strings.TrimRight(string(lines[:end]), "\r")
+		lines = lines[end+1:]
+		if strings.TrimSpace(line) != "" {
+			w.enqueue(w.event(line))
+		}
+	}
+	return len(data), nil
+}
+
+var (
+	timestampPattern = regexp.MustCompile(`^(\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}(?:\.\d{1,9})?|\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:\.\d{1,9})?(?:Z|[+-]\d{2}:\d{2})?)\s+`)
+
+	// Levels at the start of lines in brackets, followed by a colon, or in
+	// upper case, so that words like "error" starting a message are not taken
+	// for levels
+	levelPrefixPattern = regexp.MustCompile(`^(?:\[(?i:(trace|debug|info|warn|warning|error|err|fatal|panic|crit|critical))\]:?|(?i:(trace|debug|info|warn|warning|error|err|fatal|panic|crit|critical)):|(TRACE|DEBUG|INFO|WARN|WARNING|ERROR|ERR|FATAL|PANIC|CRIT|CRITICAL))\s+`)
+
+	levelFieldPattern = regexp.MustCompile(`(?i)\blevel=["']?(trace|debug|info|warn|warning|error|err|fatal|panic|crit|critical)\b`)
+)
+
+// Severities by the lower case levels found in lines
+var severities = map[string]string{
+	"warning": "warn",
+	"err":     "error",
+	"crit":    "critical",
+	"panic":   "fatal",
+}
+
+func (w *Writer) event(line string) *hec.Event {
+	at := w.now()
+	// The prefix is at the start, or after the timestamp with log.Lmsgprefix
+	line = strings.TrimPrefix(line, w.options.Prefix)
+	if match := timestampPattern.FindStringSubmatch(line); match != nil {
+		if t, ok := parseTimestamp(match[1], w.options.Location); ok {
+			at = t
+		}
+		line = strings.TrimPrefix(line[len(match[0]):], w.options.Prefix)
+	}
+	message := line
+	severity := w.options.DefaultSeverity
+	if match := levelPrefixPattern.FindStringSubmatch(line); match != nil {
+		severity = normalize(match[1] + match[2] + match[3])
+		message = line[len(match[0]):]
+	} else if match := levelFieldPattern.FindStringSubmatch(line); match != nil {
+		severity = normalize(match[1])
+	}
+
+	event := hec.NewEvent(map[string]interface{}{
+		"message":  message,
+		"severity": severity,
+	})
+	if m := w.options.Metadata; m != nil {
+		event.Host, event.Index, event.Source, event.SourceType = m.Host, m.Index, m.Source, m.SourceType
+	}
+	event.SetTime(at)
+	return event
+}
+
+func normalize(level string) string {
+	level = strings.ToLower(level)
+	if severity, ok := severities[level]; ok {
+		return severity
+	}
+	return level
+}
+
+func parseTimestamp(timestamp string, location *time.Location) (time.Time, bool) {
+	for _, layout := range []string{"2006/01/02 15:04:05.999999999", time.RFC3339Nano, "2006-01-02 15:04:05.999999999Z07:00", "2006-01-02T15:04:05.999999999", "2006-01-02 15:04:05.999999999"} {
+		if t, err := time.ParseInLocation(layout, timestamp, location); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func (w *Writer) enqueue(event *hec.Event) {
+	w.mtx.RLock()
+	defer w.mtx.RUnlock()
+	if w.closed {
+		w.dropped.Add(1)
+		return
+	}
+	select {
+	case w.queue <- event:
+	default:
+		w.dropped.Add(1)
+	}
+}
+
+// Dropped returns the number of events dropped because the queue was full or
+// the writer was closed
+func (w *Writer) Dropped() int64 {
+	return w.dropped.Load()
+}
+
+// Close writes the queued events, and an incomplete last line, and stops the
+// goroutine. Lines written afterwards are dropped.
+func (w *Writer) Close() {
+	w.partialMtx.Lock()
+	partial := string(w.partial)
+	w.partial = nil
+	w.partialMtx.Unlock()
+	if strings.TrimSpace(partial) != "" {
+		w.enqueue(w.event(partial))
+	}
+
+	w.mtx.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.mtx.Unlock()
+	<-w.done
+}
+
+func (w *Writer) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.options.FlushInterval)
+	defer ticker.Stop()
+	var batch []*hec.Event
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if _, err := w.client.WriteBatchWithResponses(context.Background(), batch); err != nil && w.options.OnError != nil {
+			w.options.OnError(err)
+		}
+		batch = nil
+	}
+	for {
+		select {
+		case event, ok := <-w.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, event)
+			if len(batch) >= w.options.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package stdloghec
+
+import (
+	"log"
+	"testing"
+	"time"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+	"github.com/fuyufjh/splunk-hec-go/hectest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testToken = "00000000-0000-0000-0000-000000000000"
+
+func TestEvent(t *testing.T) {
+	w := &Writer{options: Options{DefaultSeverity: "info", Location: time.UTC, Prefix: "app: "}, now: func() time.Time { return time.Unix(0, 0) }}
+	for line, expected := range map[string]struct {
+		message, severity, time string
+	}{
+		"app: 2017/07/14 02:40:00 started":                 {"started", "info", "1500000000.000"},
+		"2017/07/14 02:40:00.123456 app: [WARN] disk full": {"disk full", "warn", "1500000000.123"},
+		"2017-07-14T04:40:00+02:00 ERROR: failed":          {"failed", "error", "1500000000.000"},
+		"2017-07-14T02:40:00.5Z WARNING low memory":        {"low memory", "warn", "1500000000.500"},
+		"level=debug msg=\"connecting\"":                   {"level=debug msg=\"connecting\"", "debug", "0.000"},
+		"[crit] out of cheese":                             {"out of cheese", "critical", "0.000"},
+		"error handling is not a level":                    {"error handling is not a level", "info", "0.000"},
+		"information is not a level":                       {"information is not a level", "info", "0.000"},
+	} {
+		event := w.event(line)
+		assert.Equal(t, map[string]interface{}{"message": expected.message, "severity": expected.severity}, event.Event, line)
+		assert.Equal(t, expected.time, *event.Time, line)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	server := hectest.NewServer(testToken)
+	defer server.Close()
+
+	w := New(hec.NewClient(server.URL, testToken), &Options{
+		Metadata: &hec.EventMetadata{SourceType: hec.String("golang")},
+		Location: time.UTC,
+	})
+	logger := log.New(w, "", log.LstdFlags|log.LUTC)
+	logger.Print("ERROR: first")
+	logger.Print("second\nwith two lines")
+	w.Write([]byte("partial"))
+	w.Write([]byte(" line\nlast"))
+	w.Close()
+
+	events := server.Events()
+	require.Len(t, events, 5)
+	assert.Equal(t, map[string]interface{}{"message": "first", "severity": "error"}, events[0].Event.Event)
+	assert.Equal(t, "golang", *events[0].SourceType)
+	assert.Equal(t, "with two lines", events[2].Event.Event.(map[string]interface{})["message"])
+	assert.Equal(t, "partial line", events[3].Event.Event.(map[string]interface{})["message"])
+	assert.Equal(t, "last", events[4].Event.Event.(map[string]interface{})["message"])
+
+	logger.Print("after close")
+	assert.Equal(t, int64(1), w.Dropped())
+}
@@ -0,0 +1,158 @@
+// Package sysloghec relays syslog messages to HEC.
+package sysloghec
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidMessage is returned by Parse for messages without a priority
+var ErrInvalidMessage = errors.New("Invalid syslog message")
+
+// Message is a syslog message in RFC 5424 or RFC 3164 format. Fields missing
+// in the message, or nil ("-") in RFC 5424, are empty.
+type Message struct {
+	// Priority, and the facility and severity it consists of
+	Priority int
+	Facility int
+	Severity int
+
+	// Version is 1 for RFC 5424 messages and 0 for RFC 3164 messages
+	Version int
+
+	Time     time.Time
+	Hostname string
+	AppName  string
+	ProcID   string
+	MsgID    string
+
+	// Parameters of structured data elements by their IDs
+	StructuredData map[string]map[string]string
+
+	Message string
+}
+
+// Parse parses a message in RFC 5424 or RFC 3164 format. RFC 3164 timestamps
+// have no year and time zone, they are taken in the year and location of now.
+func Parse(data []byte, now time.Time) (*Message, error) {
+	data = bytes.TrimRight(data, "\r\n")
+	if len(data) < 3 || data[0] != '<' {
+		return nil, ErrInvalidMessage
+	}
+	end := bytes.IndexByte(data, '>')
+	if end < 2 || end > 4 {
+		return nil, ErrInvalidMessage
+	}
+	priority, err := strconv.Atoi(string(data[1:end]))
+	if err != nil || priority > 191 {
+		return nil, ErrInvalidMessage
+	}
+	m := &Message{Priority: priority, Facility: priority / 8, Severity: priority % 8}
+	rest := string(data[end+1:])
+	if strings.HasPrefix(rest, "1 ") {
+		m.Version = 1
+		m.parse5424(rest[2:])
+	} else {
+		m.parse3164(rest, now)
+	}
+	return m, nil
+}
+
+// parse5424 parses TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD [MSG]
+func (m *Message) parse5424(rest string) {
+	var fields [5]string
+	for i := range fields {
+		fields[i], rest = nextField(rest)
+		if fields[i] == "-" {
+			fields[i] = ""
+		}
+	}
+	if t, err := time.Parse(time.RFC3339Nano, fields[0]); err == nil {
+		m.Time = t
+	}
+	m.Hostname, m.AppName, m.ProcID, m.MsgID = fields[1], fields[2], fields[3], fields[4]
+
+	if strings.HasPrefix(rest, "-") {
+		rest = rest[1:]
+	} else {
+		m.StructuredData, rest = parseStructuredData(rest)
+	}
+	rest = strings.TrimPrefix(rest, " ")
+	m.Message = strings.TrimPrefix(rest, "\ufeff")
+}
+
+// parse3164 parses Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG, leniently
+func (m *Message) parse3164(rest string, now time.Time) {
+	const stamp = "Jan _2 15:04:05"
+	if len(rest) > len(stamp) {
+		if t, err := time.ParseInLocation(stamp, rest[:len(stamp)], now.Location()); err == nil {
+			m.Time = t.AddDate(now.Year(), 0, 0)
+			rest = strings.TrimPrefix(rest[len(stamp):], " ")
+			m.Hostname, rest = nextField(rest)
+		}
+	}
+	// The tag is alphanumeric, up to 32 characters, and ends with ':' or '['
+	if i := strings.IndexAny(rest, ":["); i > 0 && i <= 32 && !strings.ContainsAny(rest[:i], " ") {
+		m.AppName = rest[:i]
+		rest = rest[i:]
+		if strings.HasPrefix(rest, "[") {
+			if j := strings.Index(rest, "]"); j > 0 {
+				m.ProcID = rest[1:j]
+				rest = rest[j+1:]
+			}
+		}
+		rest = strings.TrimPrefix(rest, ":")
+		rest = strings.TrimPrefix(rest, " ")
+	}
+	m.Message = rest
+}
+
+func nextField(s string) (string, string) {
+	if i := strings.IndexByte(s, ' '); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+	return s, ""
+}
+
+// parseStructuredData parses elements like [id name="value" ...] until the
+// first character outside of an element
+func parseStructuredData(s string) (map[string]map[string]string, string) {
+	data := make(map[string]map[string]string)
+	for strings.HasPrefix(s, "[") {
+		s = s[1:]
+		var id string
+		end := strings.IndexAny(s, " ]")
+		if end < 0 {
+			return data, ""
+		}
+		id, s = s[:end], s[end:]
+		params := make(map[string]string)
+		data[id] = params
+		for strings.HasPrefix(s, " ") {
+			s = strings.TrimLeft(s, " ")
+			eq := strings.Index(s, `="`)
+			if eq < 0 {
+				return data, ""
+			}
+			name := s[:eq]
+			var value strings.Builder
+			i := eq + 2
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(`"\]`, s[i+1]) >= 0 {
+					i++
+				}
+				value.WriteByte(s[i])
+			}
+			params[name] = value.String()
+			if i >= len(s) {
+				return data, ""
+			}
+			s = s[i+1:]
+		}
+		s = strings.TrimPrefix(s, "]")
+	}
+	return data, s
+}
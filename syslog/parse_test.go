@@ -0,0 +1,67 @@
+package sysloghec
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var now = time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+func TestParse5424(t *testing.T) {
+	m, err := Parse([]byte(`<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3" eventSource="App\"lication"][origin ip="192.0.2.1"] An application event`+"\n"), now)
+	assert.NoError(t, err)
+	assert.Equal(t, &Message{
+		Priority: 165,
+		Facility: 20,
+		Severity: 5,
+		Version:  1,
+		Time:     time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC),
+		Hostname: "mymachine.example.com",
+		AppName:  "evntslog",
+		MsgID:    "ID47",
+		StructuredData: map[string]map[string]string{
+			"exampleSDID@32473": {"iut": "3", "eventSource": `App"lication`},
+			"origin":            {"ip": "192.0.2.1"},
+		},
+		Message: "An application event",
+	}, m)
+
+	m, err = Parse([]byte("<34>1 - - su - - - \ufeff'su root' failed"), now)
+	assert.NoError(t, err)
+	assert.True(t, m.Time.IsZero())
+	assert.Empty(t, m.Hostname)
+	assert.Equal(t, "su", m.AppName)
+	assert.Nil(t, m.StructuredData)
+	assert.Equal(t, "'su root' failed", m.Message)
+}
+
+func TestParse3164(t *testing.T) {
+	m, err := Parse([]byte("<34>Oct 11 22:14:15 mymachine su[123]: 'su root' failed for lonvick on /dev/pts/8"), now)
+	assert.NoError(t, err)
+	assert.Equal(t, &Message{
+		Priority: 34,
+		Facility: 4,
+		Severity: 2,
+		Time:     time.Date(2024, 10, 11, 22, 14, 15, 0, time.UTC),
+		Hostname: "mymachine",
+		AppName:  "su",
+		ProcID:   "123",
+		Message:  "'su root' failed for lonvick on /dev/pts/8",
+	}, m)
+
+	// Without timestamp and tag
+	m, err = Parse([]byte("<13>just a message"), now)
+	assert.NoError(t, err)
+	assert.Equal(t, 13, m.Priority)
+	assert.Empty(t, m.AppName)
+	assert.Equal(t, "just a message", m.Message)
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, message := range []string{"", "no priority", "<>1 -", "<999>message", "<ab>message"} {
+		_, err := Parse([]byte(message), now)
+		assert.ErrorIs(t, err, ErrInvalidMessage, message)
+	}
+}
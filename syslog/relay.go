@@ -0,0 +1,156 @@
+package sysloghec
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+)
+
+// Max size of a message, larger ones are truncated (UDP) or dropped (TCP)
+const maxMessageSize = 64 * 1024
+
+// Names of severities, as fields of events
+var severities = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+// Relay forwards syslog messages as events. The event is the text of the
+// message, host is the hostname, and priority, facility, severity, app_name,
+// proc_id, msg_id and the structured data as sd.<id>.<name> are indexed
+// fields. Messages which cannot be parsed are forwarded as they are.
+type Relay struct {
+	client   hec.HEC
+	metadata *hec.EventMetadata
+	now      func() time.Time
+}
+
+// NewRelay creates a relay writing events with client, with the index,
+// source and sourcetype of metadata (optional). Its host is used for
+// messages without a hostname.
+func NewRelay(client hec.HEC, metadata *hec.EventMetadata) *Relay {
+	return &Relay{client: client, metadata: metadata, now: time.Now}
+}
+
+// ServeUDP forwards a message per packet received on conn, until conn is closed
+func (r *Relay) ServeUDP(conn net.PacketConn) error {
+	packet := make([]byte, maxMessageSize)
+	for {
+		n, _, err := conn.ReadFrom(packet)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		r.forward(packet[:n])
+	}
+}
+
+// ServeTCP forwards the messages of connections accepted on listener, until
+// it is closed. Messages are framed by octet counting or by newlines as in
+// RFC 6587.
+func (r *Relay) ServeTCP(listener net.Listener) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer conn.Close()
+			r.serveConn(conn)
+		}()
+	}
+}
+
+func (r *Relay) serveConn(conn io.Reader) {
+	reader := bufio.NewReaderSize(conn, maxMessageSize)
+	for {
+		first, err := reader.Peek(1)
+		if err != nil {
+			return
+		}
+		var message []byte
+		if first[0] >= '1' && first[0] <= '9' {
+			// Octet counting: MSG-LEN SP MSG
+			length, err := reader.ReadString(' ')
+			if err != nil {
+				return
+			}
+			n, err := strconv.Atoi(length[:len(length)-1])
+			if err != nil || n > maxMessageSize {
+				return
+			}
+			message = make([]byte, n)
+			if _, err := io.ReadFull(reader, message); err != nil {
+				return
+			}
+		} else {
+			line, err := reader.ReadSlice('\n')
+			if err != nil && (err != io.EOF || len(line) == 0) {
+				return
+			}
+			message = line
+		}
+		r.forward(message)
+	}
+}
+
+// forward writes a message as an event, failures are reported to the error
+// handler of the client
+func (r *Relay) forward(data []byte) {
+	r.client.WriteEvent(r.event(data))
+}
+
+func (r *Relay) event(data []byte) *hec.Event {
+	var event *hec.Event
+	m, err := Parse(data, r.now())
+	if err != nil {
+		event = hec.NewEvent(string(data))
+	} else {
+		event = hec.NewEvent(m.Message)
+		if !m.Time.IsZero() {
+			event.SetTime(m.Time)
+		}
+		if m.Hostname != "" {
+			event.SetHost(m.Hostname)
+		}
+		event.SetFields(m.fields())
+	}
+	if r.metadata != nil {
+		if r.metadata.Host != nil && event.Host == nil {
+			event.Host = r.metadata.Host
+		}
+		event.Index, event.Source, event.SourceType = r.metadata.Index, r.metadata.Source, r.metadata.SourceType
+	}
+	return event
+}
+
+func (m *Message) fields() map[string]interface{} {
+	fields := map[string]interface{}{
+		"priority": m.Priority,
+		"facility": m.Facility,
+		"severity": severities[m.Severity],
+	}
+	for name, value := range map[string]string{"app_name": m.AppName, "proc_id": m.ProcID, "msg_id": m.MsgID} {
+		if value != "" {
+			fields[name] = value
+		}
+	}
+	for id, params := range m.StructuredData {
+		for name, value := range params {
+			fields["sd."+id+"."+name] = value
+		}
+	}
+	return fields
+}
@@ -0,0 +1,111 @@
+package sysloghec
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+	"github.com/stretchr/testify/assert"
+)
+
+type splunk struct {
+	mtx    sync.Mutex
+	events []map[string]interface{}
+}
+
+func (s *splunk) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var event map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&event)
+	s.mtx.Lock()
+	s.events = append(s.events, event)
+	s.mtx.Unlock()
+	w.Write([]byte(`{"text":"Success","code":0}`))
+}
+
+// wait returns the received events once there are n
+func (s *splunk) wait(t *testing.T, n int) []map[string]interface{} {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s.mtx.Lock()
+		events := append([]map[string]interface{}(nil), s.events...)
+		s.mtx.Unlock()
+		if len(events) >= n || time.Now().After(deadline) {
+			return events
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func newRelay(t *testing.T) (*Relay, *splunk) {
+	s := &splunk{}
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	client := hec.NewClient(ts.URL, "00000000-0000-0000-0000-000000000000")
+	return NewRelay(client, &hec.EventMetadata{Index: hec.String("syslog"), Host: hec.String("relay")}), s
+}
+
+func TestServeUDP(t *testing.T) {
+	relay, s := newRelay(t)
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	done := make(chan error)
+	go func() { done <- relay.ServeUDP(conn) }()
+
+	sender, err := net.Dial("udp", conn.LocalAddr().String())
+	assert.NoError(t, err)
+	sender.Write([]byte(`<165>1 2003-10-11T22:14:15.003Z web-1 app 42 ID47 [origin ip="192.0.2.1"] started`))
+	sender.Write([]byte("not syslog"))
+	sender.Close()
+
+	events := s.wait(t, 2)
+	conn.Close()
+	assert.NoError(t, <-done)
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, "started", events[0]["event"])
+		assert.Equal(t, "web-1", events[0]["host"])
+		assert.Equal(t, "syslog", events[0]["index"])
+		assert.Equal(t, map[string]interface{}{
+			"priority":     float64(165),
+			"facility":     float64(20),
+			"severity":     "notice",
+			"app_name":     "app",
+			"proc_id":      "42",
+			"msg_id":       "ID47",
+			"sd.origin.ip": "192.0.2.1",
+		}, events[0]["fields"])
+		assert.Equal(t, "not syslog", events[1]["event"])
+		assert.Equal(t, "relay", events[1]["host"])
+	}
+}
+
+func TestServeTCP(t *testing.T) {
+	relay, s := newRelay(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	done := make(chan error)
+	go func() { done <- relay.ServeTCP(listener) }()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	assert.NoError(t, err)
+	conn.Write([]byte("<13>first\n<13>second\n"))
+	conn.Write([]byte("21 <13>counted\nmultiline"))
+	conn.Close()
+
+	events := s.wait(t, 3)
+	listener.Close()
+	assert.NoError(t, <-done)
+	if assert.Len(t, events, 3) {
+		assert.Equal(t, "first", events[0]["event"])
+		assert.Equal(t, "second", events[1]["event"])
+		assert.Equal(t, "counted\nmultiline", events[2]["event"])
+	}
+}
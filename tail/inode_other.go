@@ -0,0 +1,10 @@
+//go:build windows || plan9
+
+package tail
+
+import "os"
+
+// inode is not available on this platform, so checkpoints are matched by path and size only
+func inode(info os.FileInfo) uint64 {
+	return 0
+}
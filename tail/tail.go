@@ -0,0 +1,293 @@
+// Package tail follows a log file and forwards appended lines to Splunk HEC.
+// It is a lightweight forwarder for hosts where a Universal Forwarder cannot
+// be installed.
+package tail
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fuyufjh/splunk-hec-go"
+)
+
+const (
+	defaultPollInterval = 1 * time.Second
+
+	readBufferSize = 1 << 20
+)
+
+type Tailer struct {
+	// Path of the followed file (required)
+	path string
+
+	// HEC client to forward lines to (required)
+	client hec.HEC
+
+	// Metadata of forwarded lines (optional)
+	metadata *hec.EventMetadata
+
+	// Send every line as an event instead of using raw mode (optional, default: false)
+	eventMode bool
+
+	// File to persist the read offset into (optional)
+	checkpointFile string
+
+	// Interval to check for new data and rotation (optional, default: 1s)
+	pollInterval time.Duration
+
+	// Skip existing content when there is no checkpoint (optional, default: false)
+	startAtEnd bool
+
+	file *os.File
+	info os.FileInfo
+
+	// Offset of the end of the last forwarded line
+	offset int64
+
+	// Incomplete last line read from the file after offset
+	partial []byte
+}
+
+// checkpoint is the persisted read position in a file
+type checkpoint struct {
+	Path   string `json:"path"`
+	Inode  uint64 `json:"inode"`
+	Offset int64  `json:"offset"`
+}
+
+func NewTailer(path string, client hec.HEC) *Tailer {
+	return &Tailer{
+		path:         path,
+		client:       client,
+		pollInterval: defaultPollInterval,
+	}
+}
+
+func (t *Tailer) SetMetadata(metadata *hec.EventMetadata) {
+	t.metadata = metadata
+}
+
+func (t *Tailer) SetEventMode(enable bool) {
+	t.eventMode = enable
+}
+
+func (t *Tailer) SetCheckpointFile(path string) {
+	t.checkpointFile = path
+}
+
+func (t *Tailer) SetPollInterval(interval time.Duration) {
+	t.pollInterval = interval
+}
+
+func (t *Tailer) SetStartAtEnd(enable bool) {
+	t.startAtEnd = enable
+}
+
+// Run follows the file until ctx is cancelled. Lines are forwarded once they
+// are complete, and the offset is checkpointed after every successful write.
+// When the file is rotated, the rest of the old file is forwarded before
+// following the new one from its beginning.
+func (t *Tailer) Run(ctx context.Context) error {
+	defer t.close()
+	for {
+		if err := t.poll(); err != nil {
+			return err
+		}
+		select {
+		case <-time.After(t.pollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (t *Tailer) poll() error {
+	if t.file == nil {
+		if err := t.open(); err != nil {
+			if os.IsNotExist(err) {
+				return nil // wait for the file to be created
+			}
+			return err
+		}
+	}
+	if err := t.forward(); err != nil {
+		return err
+	}
+
+	info, err := os.Stat(t.path)
+	if os.IsNotExist(err) {
+		return nil // rotated but not recreated yet
+	} else if err != nil {
+		return err
+	}
+	if !os.SameFile(info, t.info) {
+		// Rotated: drain lines appended to the old file since the last read,
+		// after which its incomplete last line is complete
+		if err := t.forward(); err != nil {
+			return err
+		}
+		if len(t.partial) > 0 {
+			if err := t.send(append(t.partial, '\n')); err != nil {
+				return err
+			}
+			t.partial = nil
+		}
+		t.close()
+		if err := t.openAt(0); err != nil {
+			return err
+		}
+		return t.forward()
+	}
+	if info.Size() < t.offset+int64(len(t.partial)) {
+		// Truncated: start over from the beginning
+		t.partial = nil
+		if _, err := t.file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		t.offset = 0
+		return t.forward()
+	}
+	return nil
+}
+
+func (t *Tailer) open() error {
+	var offset int64
+	if t.startAtEnd {
+		offset = -1
+	}
+	if cp, err := t.loadCheckpoint(); err != nil {
+		return err
+	} else if cp != nil {
+		offset = cp.Offset
+	}
+	return t.openAt(offset)
+}
+
+// openAt opens the file at offset, or at its end if offset is negative
+func (t *Tailer) openAt(offset int64) error {
+	file, err := os.Open(t.path)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	if offset < 0 || offset > info.Size() {
+		offset = info.Size()
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return err
+	}
+	t.file, t.info, t.offset = file, info, offset
+	return nil
+}
+
+func (t *Tailer) close() {
+	if t.file != nil {
+		t.file.Close()
+		t.file = nil
+	}
+}
+
+// forward sends all complete lines from the current position to the end of file
+func (t *Tailer) forward() error {
+	buf := make([]byte, readBufferSize)
+	for {
+		n, err := t.file.Read(buf)
+		if n > 0 {
+			data := append(t.partial, buf[:n]...)
+			cut := bytes.LastIndexByte(data, '\n') + 1
+			if cut > 0 {
+				if err := t.send(data[:cut]); err != nil {
+					return err
+				}
+				t.offset += int64(cut)
+				if err := t.saveCheckpoint(); err != nil {
+					return err
+				}
+			}
+			t.partial = append([]byte(nil), data[cut:]...)
+		}
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+func (t *Tailer) send(lines []byte) error {
+	if !t.eventMode {
+		return t.client.WriteRaw(bytes.NewReader(lines), t.metadata)
+	}
+	var events []*hec.Event
+	for _, line := range bytes.Split(bytes.TrimSuffix(lines, []byte{'\n'}), []byte{'\n'}) {
+		event := hec.NewEvent(string(line))
+		if t.metadata != nil {
+			event.Host = t.metadata.Host
+			event.Index = t.metadata.Index
+			event.Source = t.metadata.Source
+			event.SourceType = t.metadata.SourceType
+		}
+		events = append(events, event)
+	}
+	return t.client.WriteBatch(events)
+}
+
+func (t *Tailer) loadCheckpoint() (*checkpoint, error) {
+	if t.checkpointFile == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(t.checkpointFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var cp checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, err
+	}
+	// Discard the checkpoint if it belongs to another (rotated) file
+	info, err := os.Stat(t.path)
+	if err != nil {
+		return nil, err
+	}
+	if cp.Path != t.path || cp.Inode != inode(info) || cp.Offset > info.Size() {
+		return &checkpoint{}, nil
+	}
+	return &cp, nil
+}
+
+func (t *Tailer) saveCheckpoint() error {
+	if t.checkpointFile == "" {
+		return nil
+	}
+	data, _ := json.Marshal(checkpoint{
+		Path:   t.path,
+		Inode:  inode(t.info),
+		Offset: t.offset,
+	})
+	// Write atomically so a crash never leaves a corrupted checkpoint
+	tmp, err := os.CreateTemp(filepath.Dir(t.checkpointFile), ".checkpoint-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), t.checkpointFile)
+}
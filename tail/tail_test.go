@@ -0,0 +1,137 @@
+package tail
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fuyufjh/splunk-hec-go"
+	"github.com/stretchr/testify/assert"
+)
+
+type recorder struct {
+	mtx    sync.Mutex
+	bodies []string
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mtx.Lock()
+	r.bodies = append(r.bodies, string(body))
+	r.mtx.Unlock()
+	w.Write([]byte(`{"text":"Success","code":0}`))
+}
+
+func (r *recorder) received() string {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return strings.Join(r.bodies, "")
+}
+
+func appendFile(t *testing.T, path, text string) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	assert.NoError(t, err)
+	f.WriteString(text)
+	f.Close()
+}
+
+func TestTailer_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	checkpointFile := filepath.Join(dir, "checkpoint.json")
+
+	rec := &recorder{}
+	ts := httptest.NewServer(rec)
+	defer ts.Close()
+
+	appendFile(t, path, "line one\nline two\npartial")
+
+	tailer := NewTailer(path, hec.NewClient(ts.URL, "00000000-0000-0000-0000-000000000000"))
+	tailer.SetCheckpointFile(checkpointFile)
+	tailer.SetPollInterval(10 * time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- tailer.Run(ctx) }()
+
+	assert.Eventually(t, func() bool {
+		return rec.received() == "line one\nline two\n"
+	}, time.Second, 10*time.Millisecond)
+
+	appendFile(t, path, " line\n")
+	assert.Eventually(t, func() bool {
+		return rec.received() == "line one\nline two\npartial line\n"
+	}, time.Second, 10*time.Millisecond)
+
+	os.Rename(path, path+".1")
+	appendFile(t, path, "rotated\n")
+	assert.Eventually(t, func() bool {
+		return strings.HasSuffix(rec.received(), "partial line\nrotated\n")
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+
+	var cp checkpoint
+	data, err := os.ReadFile(checkpointFile)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(data, &cp))
+	assert.Equal(t, int64(len("rotated\n")), cp.Offset)
+}
+
+func TestTailer_ResumeFromCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	checkpointFile := filepath.Join(dir, "checkpoint.json")
+	appendFile(t, path, "old line\n")
+
+	rec := &recorder{}
+	ts := httptest.NewServer(rec)
+	defer ts.Close()
+	client := hec.NewClient(ts.URL, "00000000-0000-0000-0000-000000000000")
+
+	tailer := NewTailer(path, client)
+	tailer.SetCheckpointFile(checkpointFile)
+	assert.NoError(t, tailer.poll())
+	tailer.close()
+
+	appendFile(t, path, "new line\n")
+	tailer = NewTailer(path, client)
+	tailer.SetCheckpointFile(checkpointFile)
+	tailer.SetEventMode(true)
+	assert.NoError(t, tailer.poll())
+	tailer.close()
+
+	assert.Equal(t, `old line
+{"event":"new line"}`, rec.received())
+}
+
+func TestTailer_RotationWithinPollInterval(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	rec := &recorder{}
+	ts := httptest.NewServer(rec)
+	defer ts.Close()
+
+	appendFile(t, path, "line one\n")
+	tailer := NewTailer(path, hec.NewClient(ts.URL, "00000000-0000-0000-0000-000000000000"))
+	defer tailer.close()
+	assert.NoError(t, tailer.poll())
+	assert.Equal(t, "line one\n", rec.received())
+
+	// Appended and rotated between two polls
+	appendFile(t, path, "line two\nlast")
+	assert.NoError(t, os.Rename(path, path+".1"))
+	appendFile(t, path, "rotated\n")
+	assert.NoError(t, tailer.poll())
+	assert.Equal(t, "line one\nline two\nlast\nrotated\n", rec.received())
+}
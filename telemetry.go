@@ -0,0 +1,107 @@
+package hec
+
+import (
+	"context"
+	"time"
+)
+
+// Default sourcetype of telemetry events
+const TelemetrySourceType = "hec:client:telemetry"
+
+// TelemetryConfig configures the telemetry reported by ReportTelemetry
+type TelemetryConfig struct {
+	// Interval between telemetry events (default: 1m)
+	Interval time.Duration
+
+	// Metadata of telemetry events, e.g. an internal index. The sourcetype
+	// defaults to TelemetrySourceType.
+	Metadata *EventMetadata
+
+	// Source of time for the interval (default: SystemClock)
+	Clock Clock
+}
+
+// ReportTelemetry writes the operational metrics of client as an event to
+// client itself at every interval, until ctx is cancelled. Every event has
+// the counters of Stats and the events and bytes written during the
+// interval, the Status of the client and its latency percentiles in
+// milliseconds. Failed writes of telemetry are reported to the error handler
+// like any other and don't stop the reporting.
+func ReportTelemetry(ctx context.Context, client HEC, config TelemetryConfig) error {
+	interval := config.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	clock := config.Clock
+	if clock == nil {
+		clock = SystemClock
+	}
+	ticker := clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := client.Stats()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C():
+		}
+		stats := client.Stats()
+		event := NewEvent(telemetryOf(last, stats, client.Status(), client.Latencies()))
+		event.SetSourceType(TelemetrySourceType)
+		if m := config.Metadata; m != nil {
+			event.Host = firstNonNil(m.Host, event.Host)
+			event.Index = firstNonNil(m.Index, event.Index)
+			event.Source = firstNonNil(m.Source, event.Source)
+			event.SourceType = firstNonNil(m.SourceType, event.SourceType)
+		}
+		client.WriteEvent(event)
+		last = stats
+	}
+}
+
+// telemetryOf returns the data of a telemetry event, with the differences of
+// the counters since last
+func telemetryOf(last Stats, stats Stats, status Status, latencies []LatencyStats) map[string]interface{} {
+	events, bytes := stats.totalUsage()
+	lastEvents, lastBytes := last.totalUsage()
+	data := map[string]interface{}{
+		"sent":                 stats.Sent - last.Sent,
+		"failed":               stats.Failed - last.Failed,
+		"retried":              stats.Retried - last.Retried,
+		"dropped":              stats.Dropped - last.Dropped,
+		"events":               events - lastEvents,
+		"bytes":                bytes - lastBytes,
+		"consecutive_failures": status.ConsecutiveFailures,
+	}
+	if status.LastError != "" {
+		data["last_error"] = status.LastError
+	}
+	var latency []map[string]interface{}
+	for _, l := range latencies {
+		latency = append(latency, map[string]interface{}{
+			"server":   l.Server,
+			"endpoint": l.Endpoint,
+			"p50":      milliseconds(l.P50),
+			"p90":      milliseconds(l.P90),
+			"p99":      milliseconds(l.P99),
+			"max":      milliseconds(l.Max),
+		})
+	}
+	if latency != nil {
+		data["latency"] = latency
+	}
+	return data
+}
+
+func (s Stats) totalUsage() (events int64, bytes int64) {
+	for _, u := range s.Usage {
+		events += u.Events
+		bytes += u.Bytes
+	}
+	return events, bytes
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
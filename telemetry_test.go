@@ -0,0 +1,89 @@
+package hec
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// tickClock is a clock with a ticker fired by the test
+type tickClock struct {
+	Clock
+	ticks chan time.Time
+}
+
+type tickTicker struct{ ticks chan time.Time }
+
+func (c tickClock) NewTicker(time.Duration) Ticker { return tickTicker{ticks: c.ticks} }
+func (t tickTicker) C() <-chan time.Time           { return t.ticks }
+func (t tickTicker) Stop()                         {}
+
+func TestReportTelemetry(t *testing.T) {
+	var mtx sync.Mutex
+	var telemetry []map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event struct {
+			Index      string                 `json:"index"`
+			SourceType string                 `json:"sourcetype"`
+			Event      map[string]interface{} `json:"event"`
+		}
+		if json.NewDecoder(r.Body).Decode(&event) == nil && event.SourceType == TelemetrySourceType {
+			assert.Equal(t, "_internal_hec", event.Index)
+			mtx.Lock()
+			telemetry = append(telemetry, event.Event)
+			mtx.Unlock()
+		}
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+	clock := tickClock{Clock: SystemClock, ticks: make(chan time.Time)}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- ReportTelemetry(ctx, c, TelemetryConfig{Metadata: &EventMetadata{Index: String("_internal_hec")}, Clock: clock})
+	}()
+
+	assert.NoError(t, c.WriteEvent(NewEvent("one")))
+	assert.NoError(t, c.WriteEvent(NewEvent("two")))
+	clock.ticks <- time.Now()
+	clock.ticks <- time.Now()
+	cancel() // the second event is still written
+	assert.ErrorIs(t, <-done, context.Canceled)
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	if assert.Len(t, telemetry, 2) {
+		assert.Equal(t, float64(2), telemetry[0]["sent"])
+		assert.Equal(t, float64(2), telemetry[0]["events"])
+		assert.Equal(t, float64(0), telemetry[0]["failed"])
+		assert.Len(t, telemetry[0]["latency"], 1)
+
+		// The second event accounts for the first one
+		assert.Equal(t, float64(1), telemetry[1]["sent"])
+	}
+}
+
+func TestTelemetryOf(t *testing.T) {
+	last := Stats{Sent: 1, Usage: []Usage{{Events: 1, Bytes: 10}}}
+	stats := Stats{Sent: 4, Failed: 1, Usage: []Usage{{Events: 3, Bytes: 30}, {Index: "main", Events: 2, Bytes: 5}}}
+	data := telemetryOf(last, stats, Status{ConsecutiveFailures: 1, LastError: "Server is busy"}, nil)
+	assert.Equal(t, map[string]interface{}{
+		"sent":                 int64(3),
+		"failed":               int64(1),
+		"retried":              int64(0),
+		"dropped":              int64(0),
+		"events":               int64(4),
+		"bytes":                int64(25),
+		"consecutive_failures": 1,
+		"last_error":           "Server is busy",
+	}, data)
+}
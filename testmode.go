@@ -0,0 +1,97 @@
+package hec
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RecordedRequest is a request recorded in test mode
+type RecordedRequest struct {
+	// Path and query of the HEC endpoint
+	Endpoint string
+
+	// Payload before compression
+	Payload []byte
+}
+
+// TestRecorder records the requests of clients in test mode
+type TestRecorder struct {
+	// Clock of the clients, starting at the Unix epoch
+	Clock *ManualClock
+
+	mtx      sync.Mutex
+	requests []RecordedRequest
+}
+
+func NewTestRecorder() *TestRecorder {
+	return &TestRecorder{Clock: NewManualClock(time.Unix(0, 0))}
+}
+
+// WithTestMode makes a client deterministic for unit tests of applications:
+// requests are not retried, the clock of recorder is used, and every request
+// is recorded by recorder
+func WithTestMode(recorder *TestRecorder) Option {
+	return func(client *Client) {
+		client.SetMaxRetry(0)
+		client.SetClock(recorder.Clock)
+		client.recorder = recorder
+	}
+}
+
+func (r *TestRecorder) record(endpoint string, payload []byte) {
+	if r == nil {
+		return
+	}
+	r.mtx.Lock()
+	r.requests = append(r.requests, RecordedRequest{Endpoint: endpoint, Payload: append([]byte(nil), payload...)})
+	r.mtx.Unlock()
+}
+
+// Requests returns the recorded requests in order, including retries
+func (r *TestRecorder) Requests() []RecordedRequest {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return append([]RecordedRequest(nil), r.requests...)
+}
+
+// Reset forgets the recorded requests
+func (r *TestRecorder) Reset() {
+	r.mtx.Lock()
+	r.requests = nil
+	r.mtx.Unlock()
+}
+
+// VerifyNoLeaks returns an error if goroutines other than the calling one are
+// still running code of this package after timeout, e.g. a streaming write
+// never finished by closing its channel
+func (r *TestRecorder) VerifyNoLeaks(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		leaked := leakedGoroutines()
+		if len(leaked) == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%d goroutines leaked:\n\n%s", len(leaked), strings.Join(leaked, "\n\n"))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// leakedGoroutines returns the stacks of other goroutines in functions of this package
+func leakedGoroutines() []string {
+	buf := make([]byte, 1<<20)
+	buf = buf[:runtime.Stack(buf, true)]
+	stacks := strings.Split(string(buf), "\n\n")
+	var leaked []string
+	for _, stack := range stacks[1:] { // The first one is the calling goroutine
+		if strings.Contains(stack, "github.com/fuyufjh/splunk-hec-go.(*") ||
+			strings.Contains(stack, "github.com/fuyufjh/splunk-hec-go.streamRaw") {
+			leaked = append(leaked, stack)
+		}
+	}
+	return leaked
+}
@@ -0,0 +1,46 @@
+package hec
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithTestMode(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(503)
+		w.Write([]byte(`{"text":"Server is busy","code":9}`))
+	}))
+	defer ts.Close()
+
+	recorder := NewTestRecorder()
+	c := NewClient(ts.URL, testSplunkToken, WithTestMode(recorder))
+	c.SetHTTPClient(testHttpClient)
+	c.SetChannelMode(ChannelNone)
+	c.SetCompression("gzip")
+
+	assert.ErrorIs(t, c.WriteEvent(NewEvent("hello, world")), ErrServerBusy)
+	assert.Equal(t, []RecordedRequest{{
+		Endpoint: "/services/collector",
+		Payload:  []byte(`{"event":"hello, world"}`),
+	}}, recorder.Requests())
+	assert.Empty(t, recorder.Clock.Slept())
+	assert.NoError(t, recorder.VerifyNoLeaks(time.Second))
+
+	records := make(chan []byte)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.WriteRawChannel(ctx, records, nil)
+		close(done)
+	}()
+	records <- []byte("pending")
+	assert.Error(t, recorder.VerifyNoLeaks(50*time.Millisecond))
+	cancel()
+	<-done
+	assert.NoError(t, recorder.VerifyNoLeaks(time.Second))
+}
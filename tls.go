@@ -0,0 +1,56 @@
+package hec
+
+import (
+	"crypto/tls"
+	"net/http"
+)
+
+// WithTLS makes a client connect with config, see SetTLSConfig
+func WithTLS(config *tls.Config) Option {
+	return func(client *Client) {
+		client.SetTLSConfig(config)
+	}
+}
+
+// WithServerName makes a client send name as SNI and verify the server
+// certificate against it instead of the host of the URL, e.g. to connect to
+// indexers by IP address behind a load balancer. Apply it after WithTLS.
+func WithServerName(name string) Option {
+	return func(client *Client) {
+		client.httpClient = withTLSConfig(client.httpClient, func(config *tls.Config) *tls.Config {
+			if config == nil {
+				config = &tls.Config{}
+			}
+			config.ServerName = name
+			return config
+		})
+	}
+}
+
+func (hec *Client) SetTLSConfig(config *tls.Config) {
+	hec.httpClient = withTLSConfig(hec.httpClient, func(*tls.Config) *tls.Config { return config.Clone() })
+}
+
+// withTLSConfig returns a copy of client with a copy of its transport using the
+// TLS config returned by update, which gets a copy of the current one, or nil.
+// A transport other than *http.Transport is replaced by a clone of
+// http.DefaultTransport, as there is no way to set its TLS configuration;
+// the transport of WithCAFile keeps its roots.
+func withTLSConfig(client *http.Client, update func(config *tls.Config) *tls.Config) *http.Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	copied := *client
+	if transport, ok := client.Transport.(*caTransport); ok {
+		copied.Transport = transport.withBase(update)
+		return &copied
+	}
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok {
+		transport = http.DefaultTransport.(*http.Transport)
+	}
+	transport = transport.Clone()
+	transport.TLSClientConfig = update(transport.TLSClientConfig.Clone())
+	copied.Transport = transport
+	return &copied
+}
@@ -0,0 +1,69 @@
+package hec
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetTLSConfig(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+
+	c := NewClient(ts.URL, testSplunkToken)
+	c.SetMaxRetry(0)
+	assert.Error(t, c.WriteEvent(NewEvent("untrusted")))
+
+	c = NewClient(ts.URL, testSplunkToken, WithTLS(&tls.Config{RootCAs: pool}))
+	assert.NoError(t, c.WriteEvent(NewEvent("trusted")))
+	// The default transport is not changed
+	if config := http.DefaultTransport.(*http.Transport).TLSClientConfig; config != nil {
+		assert.Nil(t, config.RootCAs)
+	}
+
+	// The other settings of the HTTP client are kept
+	c = NewClient(ts.URL, testSplunkToken)
+	c.SetHTTPClient(&http.Client{Timeout: time.Minute})
+	c.SetTLSConfig(&tls.Config{InsecureSkipVerify: true})
+	assert.Equal(t, time.Minute, c.(*Client).httpClient.Timeout)
+	assert.NoError(t, c.WriteEvent(NewEvent("insecure")))
+
+	cluster := NewCluster([]string{ts.URL, ts.URL}, testSplunkToken)
+	cluster.SetTLSConfig(&tls.Config{RootCAs: pool})
+	assert.NoError(t, cluster.WriteEvent(NewEvent("cluster")))
+}
+
+func TestWithServerName(t *testing.T) {
+	ca := newTestCA(t)
+	ts := ca.newServer(t)
+	defer ts.Close()
+
+	c := NewClient(ts.URL, testSplunkToken, WithTLS(&tls.Config{RootCAs: ca.pool}), WithServerName("server"))
+	assert.NoError(t, c.WriteEvent(NewEvent("server name")))
+	assert.Equal(t, ca.pool, c.(*Client).httpClient.Transport.(*http.Transport).TLSClientConfig.RootCAs)
+
+	c = NewClient(ts.URL, testSplunkToken, WithTLS(&tls.Config{RootCAs: ca.pool}), WithServerName("indexer"))
+	c.SetMaxRetry(0)
+	assert.Error(t, c.WriteEvent(NewEvent("wrong name")))
+
+	// Also verified against with a CA file
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, ca.pem(), 0o600))
+	c = NewClient(ts.URL, testSplunkToken, WithCAFile(caFile), WithServerName("indexer"))
+	c.SetMaxRetry(0)
+	assert.Error(t, c.WriteEvent(NewEvent("wrong name")))
+	c = NewClient(ts.URL, testSplunkToken, WithCAFile(caFile), WithServerName("server"))
+	assert.NoError(t, c.WriteEvent(NewEvent("server name")))
+}
@@ -0,0 +1,116 @@
+package hec
+
+import (
+	"crypto/tls"
+	"fmt"
+)
+
+// TLSPolicy restricts the TLS connections of a client, to enforce the
+// versions and cipher suites approved by a security policy. Nil lists mean
+// the defaults of crypto/tls.
+type TLSPolicy struct {
+	Name string
+
+	// Min TLS version (default: tls.VersionTLS12)
+	MinVersion uint16
+
+	// Cipher suites allowed up to TLS 1.2, see tls.CipherSuites. The suites
+	// of TLS 1.3 cannot be configured with crypto/tls.
+	CipherSuites []uint16
+
+	// Curves allowed for key exchange
+	CurvePreferences []tls.CurveID
+}
+
+// Built-in TLS policies
+var (
+	// TLSPolicyDefault requires TLS 1.2, with the defaults of crypto/tls
+	TLSPolicyDefault = TLSPolicy{Name: "default", MinVersion: tls.VersionTLS12}
+
+	// TLSPolicyModern only allows TLS 1.3
+	TLSPolicyModern = TLSPolicy{Name: "modern", MinVersion: tls.VersionTLS13}
+
+	// TLSPolicyFIPS only allows the AES-GCM suites with ECDHE on the NIST
+	// curves approved by FIPS 140. It restricts the configuration only; for a
+	// validated module run with GODEBUG=fips140=on.
+	TLSPolicyFIPS = TLSPolicy{
+		Name:       "fips",
+		MinVersion: tls.VersionTLS12,
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+		},
+		CurvePreferences: []tls.CurveID{tls.CurveP256, tls.CurveP384},
+	}
+)
+
+// LookupTLSPolicy returns the built-in TLS policy with the name
+func LookupTLSPolicy(name string) (TLSPolicy, bool) {
+	for _, policy := range []TLSPolicy{TLSPolicyDefault, TLSPolicyModern, TLSPolicyFIPS} {
+		if policy.Name == name {
+			return policy, true
+		}
+	}
+	return TLSPolicy{}, false
+}
+
+// WithTLSPolicy makes a client connect only as allowed by policy. It keeps
+// the rest of the TLS config, so it goes after WithTLS.
+func WithTLSPolicy(policy TLSPolicy) Option {
+	return func(client *Client) {
+		client.httpClient = withTLSConfig(client.httpClient, func(config *tls.Config) *tls.Config {
+			if config == nil {
+				config = &tls.Config{}
+			}
+			policy.apply(config)
+			return config
+		})
+	}
+}
+
+func (policy TLSPolicy) apply(config *tls.Config) {
+	config.MinVersion = policy.MinVersion
+	if config.MinVersion == 0 {
+		config.MinVersion = tls.VersionTLS12
+	}
+	if config.MaxVersion != 0 && config.MaxVersion < config.MinVersion {
+		config.MaxVersion = 0
+	}
+	config.CipherSuites = append([]uint16(nil), policy.CipherSuites...)
+	config.CurvePreferences = append([]tls.CurveID(nil), policy.CurvePreferences...)
+}
+
+// parseTLSVersion returns the version of a name like "1.2"
+func parseTLSVersion(name string) (uint16, error) {
+	switch name {
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("%q is not a TLS version", name)
+}
+
+// parseCipherSuites returns the IDs of cipher suites named as by
+// tls.CipherSuiteName. Only the secure suites of tls.CipherSuites are known.
+func parseCipherSuites(names []string) ([]uint16, error) {
+	known := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("%q is not a secure cipher suite", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,66 @@
+package hec
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithTLSPolicy(t *testing.T) {
+	var version, suite atomic.Uint32
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		version.Store(uint32(r.TLS.Version))
+		suite.Store(uint32(r.TLS.CipherSuite))
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	ts.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	ts.StartTLS()
+	defer ts.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+
+	c := NewClient(ts.URL, testSplunkToken, WithTLS(&tls.Config{RootCAs: pool}), WithTLSPolicy(TLSPolicyModern))
+	c.SetMaxRetry(0)
+	assert.Error(t, c.WriteEvent(NewEvent("TLS 1.2 server")))
+
+	c = NewClient(ts.URL, testSplunkToken, WithTLS(&tls.Config{RootCAs: pool}), WithTLSPolicy(TLSPolicyFIPS))
+	assert.NoError(t, c.WriteEvent(NewEvent("FIPS")))
+	assert.EqualValues(t, tls.VersionTLS12, version.Load())
+	assert.Contains(t, TLSPolicyFIPS.CipherSuites, uint16(suite.Load()))
+
+	// The min version defaults to TLS 1.2
+	c = NewClient(ts.URL, testSplunkToken, WithTLS(&tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS10}),
+		WithTLSPolicy(TLSPolicy{CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384, tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384}}))
+	config := c.(*Client).httpClient.Transport.(*http.Transport).TLSClientConfig
+	assert.EqualValues(t, tls.VersionTLS12, config.MinVersion)
+	assert.Equal(t, pool, config.RootCAs)
+	assert.NoError(t, c.WriteEvent(NewEvent("AES-256")))
+	assert.Contains(t, []uint32{uint32(tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384), uint32(tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384)}, suite.Load())
+}
+
+func TestTLSConfigPolicy(t *testing.T) {
+	policy, err := TLSConfig{Policy: "fips", MinVersion: "1.3"}.policy()
+	if assert.NoError(t, err) {
+		assert.EqualValues(t, tls.VersionTLS13, policy.MinVersion)
+		assert.Equal(t, TLSPolicyFIPS.CipherSuites, policy.CipherSuites)
+	}
+	policy, err = TLSConfig{CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"}}.policy()
+	if assert.NoError(t, err) {
+		assert.EqualValues(t, tls.VersionTLS12, policy.MinVersion)
+		assert.Equal(t, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}, policy.CipherSuites)
+	}
+
+	for _, config := range []TLSConfig{
+		{Policy: "lax"},
+		{MinVersion: "1.4"},
+		{CipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}},
+	} {
+		_, err = NewFromConfig(Config{URLs: []string{"https://a:8088"}, Token: testSplunkToken, TLS: config})
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+	}
+}
@@ -0,0 +1,135 @@
+package hec
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenProvider provides HEC tokens at runtime, e.g. fetched from a secret
+// store, so that they can be rotated without recreating the client
+type TokenProvider interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// TokenInvalidator is implemented by token providers caching their tokens.
+// InvalidateToken is called when HEC rejects token, before a new one is
+// requested and the request is retried.
+type TokenInvalidator interface {
+	InvalidateToken(token string)
+}
+
+// CachedToken returns a TokenProvider caching the tokens returned by fetch for
+// ttl, or until HEC rejects them. A ttl of 0 caches until rejection.
+func CachedToken(fetch func(ctx context.Context) (string, error), ttl time.Duration) TokenProvider {
+	return &cachedToken{fetch: fetch, ttl: ttl}
+}
+
+type cachedToken struct {
+	fetch func(ctx context.Context) (string, error)
+	ttl   time.Duration
+
+	mtx       sync.Mutex
+	token     string
+	fetchedAt time.Time
+}
+
+func (c *cachedToken) Token(ctx context.Context) (string, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.token != "" && (c.ttl == 0 || time.Since(c.fetchedAt) < c.ttl) {
+		return c.token, nil
+	}
+	token, err := c.fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+	c.token, c.fetchedAt = token, time.Now()
+	return token, nil
+}
+
+func (c *cachedToken) InvalidateToken(token string) {
+	c.mtx.Lock()
+	if c.token == token {
+		c.token = ""
+	}
+	c.mtx.Unlock()
+}
+
+// WithTokenFile makes a client read its token from the file at path, see TokenFile
+func WithTokenFile(path string) Option {
+	provider := TokenFile(path)
+	return func(client *Client) {
+		client.tokenProvider = provider
+	}
+}
+
+// TokenFile returns a TokenProvider reading the token from the file at path,
+// e.g. a mounted Kubernetes secret, with surrounding whitespace trimmed. The
+// file is read again when its modification time changes, or when HEC rejects
+// the token. If it cannot be read, the last token is kept.
+func TokenFile(path string) TokenProvider {
+	return &tokenFile{path: path}
+}
+
+type tokenFile struct {
+	path string
+
+	mtx     sync.Mutex
+	token   string
+	modTime time.Time
+}
+
+func (f *tokenFile) Token(ctx context.Context) (string, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	info, err := os.Stat(f.path)
+	if err == nil && (f.token == "" || !info.ModTime().Equal(f.modTime)) {
+		var data []byte
+		if data, err = os.ReadFile(f.path); err == nil {
+			if token := strings.TrimSpace(string(data)); token != "" {
+				f.token, f.modTime = token, info.ModTime()
+			} else {
+				err = fmt.Errorf("%s is empty", f.path)
+			}
+		}
+	}
+	if f.token == "" {
+		return "", err
+	}
+	return f.token, nil
+}
+
+func (f *tokenFile) InvalidateToken(token string) {
+	f.mtx.Lock()
+	if f.token == token {
+		// Read again even if a rotation kept the modification time
+		f.modTime = time.Time{}
+	}
+	f.mtx.Unlock()
+}
+
+// currentToken returns the token for the next request
+func (hec *Client) currentToken(ctx context.Context) (string, error) {
+	if hec.tokenProvider == nil {
+		return hec.token, nil
+	}
+	token, err := hec.tokenProvider.Token(ctx)
+	if err != nil {
+		return "", fmt.Errorf("Failed to get HEC token: %w", err)
+	}
+	return token, nil
+}
+
+// tokenRejected tells whether HEC rejected the token of a request
+func tokenRejected(statusCode int, code int) bool {
+	switch code {
+	case StatusTokenDisabled, StatusInvalidAuthorization, StatusInvalidToken:
+		return true
+	}
+	return statusCode == http.StatusUnauthorized
+}
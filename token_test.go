@@ -0,0 +1,78 @@
+package hec
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHEC_TokenProvider(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Splunk new-token" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"text":"Invalid token","code":4}`))
+			return
+		}
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	tokens := []string{"old-token", "new-token"}
+	fetches := 0
+	provider := CachedToken(func(ctx context.Context) (string, error) {
+		token := tokens[fetches]
+		fetches++
+		return token, nil
+	}, 0)
+
+	c := NewClient(ts.URL, "")
+	c.SetHTTPClient(testHttpClient)
+	c.SetTokenProvider(provider)
+	assert.NoError(t, c.WriteEvent(NewEvent("rotated")))
+	assert.NoError(t, c.WriteEvent(NewEvent("cached")))
+	assert.Equal(t, 2, fetches)
+
+	errVault := errors.New("vault sealed")
+	c.SetTokenProvider(CachedToken(func(ctx context.Context) (string, error) {
+		return "", errVault
+	}, 0))
+	assert.ErrorIs(t, c.WriteEvent(NewEvent("failed")), errVault)
+}
+
+func TestTokenFile(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Splunk new-token" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"text":"Invalid token","code":4}`))
+			return
+		}
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	path := filepath.Join(t.TempDir(), "token")
+	c := NewClient(ts.URL, "", WithTokenFile(path))
+	c.SetMaxRetry(0)
+	assert.Error(t, c.WriteEvent(NewEvent("missing")))
+
+	// The file is read again when the token is rejected, even if its
+	// modification time is the same
+	modTime := time.Now().Add(-time.Hour)
+	assert.NoError(t, os.WriteFile(path, []byte("old-token\n"), 0o600))
+	os.Chtimes(path, modTime, modTime)
+	assert.Error(t, c.WriteEvent(NewEvent("old")))
+	assert.NoError(t, os.WriteFile(path, []byte("new-token\n"), 0o600))
+	os.Chtimes(path, modTime, modTime)
+	assert.NoError(t, c.WriteEvent(NewEvent("rejected")))
+
+	// The last token is kept if the file cannot be read
+	assert.NoError(t, os.Remove(path))
+	assert.NoError(t, c.WriteEvent(NewEvent("removed")))
+}
@@ -0,0 +1,58 @@
+package hec
+
+import (
+	"context"
+	"net/http"
+)
+
+// RequestInfo describes an HTTP request to HEC
+type RequestInfo struct {
+	// Server URL without password, and path and query of the endpoint
+	Server   string
+	Endpoint string
+
+	// Size of the payload before compression
+	Size int
+
+	// Attempt number of the request, starting from 1
+	Attempt int
+
+	// Headers of the request, e.g. to propagate trace context. The
+	// Authorization header is not set yet.
+	Header http.Header
+}
+
+// RequestResult is the result of an HTTP request to HEC
+type RequestResult struct {
+	// HTTP status code and HEC status code, 0 if there is no response
+	StatusCode int
+	Code       int
+
+	// Error of the request or from parsing its response
+	Err error
+}
+
+// RequestTracer traces HTTP requests to HEC, e.g. as spans of distributed
+// traces. StartRequest is called before each request, including retries, with
+// the context of the caller; the request is sent with the returned context,
+// and finish is called with its result.
+type RequestTracer interface {
+	StartRequest(ctx context.Context, info RequestInfo) (_ context.Context, finish func(result RequestResult))
+}
+
+// WithRequestTracer makes a client trace its requests with tracer
+func WithRequestTracer(tracer RequestTracer) Option {
+	return func(client *Client) {
+		client.tracer = tracer
+	}
+}
+
+func (hec *Client) startRequest(ctx context.Context, info RequestInfo) (context.Context, func(result RequestResult)) {
+	if hec.metrics != nil {
+		return metricsTracer{client: hec, other: hec.tracer}.StartRequest(ctx, info)
+	}
+	if hec.tracer == nil {
+		return ctx, func(RequestResult) {}
+	}
+	return hec.tracer.StartRequest(ctx, info)
+}
@@ -0,0 +1,48 @@
+package hec
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingTracer struct {
+	infos   []RequestInfo
+	results []RequestResult
+}
+
+func (r *recordingTracer) StartRequest(ctx context.Context, info RequestInfo) (context.Context, func(RequestResult)) {
+	info.Header.Set("Traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	r.infos = append(r.infos, info)
+	return ctx, func(result RequestResult) {
+		r.results = append(r.results, result)
+	}
+}
+
+func TestHEC_RequestTracer(t *testing.T) {
+	var traceparents []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents = append(traceparents, r.Header.Get("Traceparent"))
+		w.WriteHeader(503)
+		w.Write([]byte(`{"text":"Server is busy","code":9}`))
+	}))
+	defer ts.Close()
+
+	tracer := &recordingTracer{}
+	c := NewClient(ts.URL, testSplunkToken, WithRequestTracer(tracer), WithTestMode(NewTestRecorder()))
+	c.SetHTTPClient(testHttpClient)
+	c.SetMaxRetry(1)
+	c.SetChannelMode(ChannelNone)
+	assert.Error(t, c.WriteEvent(NewEvent("hello, world")))
+
+	if assert.Len(t, tracer.infos, 2) && assert.Len(t, tracer.results, 2) {
+		assert.Equal(t, "/services/collector", tracer.infos[1].Endpoint)
+		assert.Equal(t, 24, tracer.infos[1].Size)
+		assert.Equal(t, 2, tracer.infos[1].Attempt)
+		assert.Equal(t, RequestResult{StatusCode: 503, Code: StatusServerBusy}, tracer.results[1])
+	}
+	assert.NotEmpty(t, traceparents[0])
+}
@@ -0,0 +1,61 @@
+package hec
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+)
+
+// W3C trace context of a caller, see https://www.w3.org/TR/trace-context/
+type traceContext struct {
+	traceParent string
+	traceState  string
+}
+
+type traceContextKey struct{}
+
+type requestIDKey struct{}
+
+// Format of the traceparent header: version-traceid-parentid-flags
+var traceParentPattern = regexp.MustCompile(`^[0-9a-f]{2}-[0-9a-f]{32}-[0-9a-f]{16}-[0-9a-f]{2}$`)
+
+// ContextWithTraceContext returns a context carrying the traceparent and
+// tracestate headers of a W3C trace context, e.g. from an incoming request, to
+// be sent with requests to HEC by clients created WithTraceContext
+func ContextWithTraceContext(ctx context.Context, traceParent string, traceState string) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, traceContext{traceParent: traceParent, traceState: traceState})
+}
+
+// ContextWithRequestID returns a context carrying a request ID to be sent with
+// requests to HEC by clients created WithTraceContext
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// WithTraceContext makes a client send the W3C trace context and the request
+// ID of the context of every write, so the access logs of Splunk can be
+// correlated with the traces of the application. The request ID is sent in
+// requestIDHeader, or not at all if it is empty. An invalid traceparent is
+// not sent, together with its tracestate.
+func WithTraceContext(requestIDHeader string) Option {
+	return func(client *Client) {
+		client.traceContext = true
+		client.requestIDHeader = requestIDHeader
+	}
+}
+
+// injectTraceContext sets the headers of the trace context and request ID of ctx
+func (hec *Client) injectTraceContext(ctx context.Context, header http.Header) {
+	if !hec.traceContext {
+		return
+	}
+	if tc, ok := ctx.Value(traceContextKey{}).(traceContext); ok && traceParentPattern.MatchString(tc.traceParent) {
+		header.Set("traceparent", tc.traceParent)
+		if tc.traceState != "" {
+			header.Set("tracestate", tc.traceState)
+		}
+	}
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok && id != "" && hec.requestIDHeader != "" {
+		header.Set(hec.requestIDHeader, id)
+	}
+}
@@ -0,0 +1,46 @@
+package hec
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithTraceContext(t *testing.T) {
+	var header http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	traceParent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	ctx := ContextWithTraceContext(context.Background(), traceParent, "vendor=value")
+	ctx = ContextWithRequestID(ctx, "request-1")
+
+	c := NewClient(ts.URL, testSplunkToken, WithTraceContext("X-Request-ID"))
+	c.SetHTTPClient(testHttpClient)
+	_, err := c.WriteEventWithResponse(ctx, NewEvent("traced"))
+	assert.NoError(t, err)
+	assert.Equal(t, traceParent, header.Get("traceparent"))
+	assert.Equal(t, "vendor=value", header.Get("tracestate"))
+	assert.Equal(t, "request-1", header.Get("X-Request-ID"))
+
+	// Invalid trace contexts are not sent
+	ctx = ContextWithTraceContext(context.Background(), "00-invalid-01", "vendor=value")
+	_, err = c.WriteEventWithResponse(ctx, NewEvent("traced"))
+	assert.NoError(t, err)
+	assert.Empty(t, header.Get("traceparent"))
+	assert.Empty(t, header.Get("tracestate"))
+	assert.Empty(t, header.Get("X-Request-ID"))
+
+	// Nothing is sent unless enabled
+	c = NewClient(ts.URL, testSplunkToken)
+	c.SetHTTPClient(testHttpClient)
+	_, err = c.WriteEventWithResponse(ContextWithTraceContext(context.Background(), traceParent, ""), NewEvent("traced"))
+	assert.NoError(t, err)
+	assert.Empty(t, header.Get("traceparent"))
+}
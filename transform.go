@@ -0,0 +1,204 @@
+package hec
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// Transformer transforms an event before it is validated and sent, e.g. to
+// redact sensitive data. It gets a copy of the event with a copy of its
+// fields, so it may modify both, but must replace the data of the event
+// rather than modify it. It returns the event to send, nil to drop it, or an
+// error to fail it.
+type Transformer func(event *Event) (*Event, error)
+
+// WithTransformers makes a client pass every event through transformers, in
+// order, before it is written. The events in errors and drop audits are the
+// transformed ones.
+func WithTransformers(transformers ...Transformer) Option {
+	return func(client *Client) {
+		client.transformers = append(client.transformers, transformers...)
+	}
+}
+
+var ErrTransformFailed = errors.New("Event transformation failed")
+
+// TransformError is returned for events a transformer failed. The other
+// events of a batch are still sent. It matches ErrTransformFailed with
+// errors.Is.
+type TransformError struct {
+	// Indexes of the failed events in the batch, and the errors of the
+	// transformers
+	Indexes []int
+	Errs    []error
+}
+
+func (e *TransformError) add(index int, err error) {
+	e.Indexes = append(e.Indexes, index)
+	e.Errs = append(e.Errs, err)
+}
+
+func (e *TransformError) Error() string {
+	return fmt.Sprintf("Event transformation failed (%d events, first: %v)", len(e.Indexes), e.Errs[0])
+}
+
+func (e *TransformError) Is(target error) bool {
+	return target == ErrTransformFailed
+}
+
+func (e *TransformError) Unwrap() []error {
+	return e.Errs
+}
+
+// transform returns the event to write, nil if it is dropped
+func (hec *Client) transform(event *Event) (*Event, error) {
+	copied := *event
+	if event.Fields != nil {
+		copied.Fields = make(map[string]interface{}, len(event.Fields))
+		for key, value := range event.Fields {
+			copied.Fields[key] = value
+		}
+	}
+	result := &copied
+	for _, transformer := range hec.transformers {
+		var err error
+		if result, err = transformer(result); err != nil || result == nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// transformAll transforms events, keeping their indexes; dropped and failed
+// events are nil
+func (hec *Client) transformAll(events []*Event) ([]*Event, *TransformError) {
+	transformed := make([]*Event, len(events))
+	failed := &TransformError{}
+	for index, event := range events {
+		if event == nil {
+			continue
+		}
+		var err error
+		if transformed[index], err = hec.transform(event); err != nil {
+			failed.add(index, err)
+		}
+	}
+	return transformed, failed
+}
+
+// Patterns of the built-in redactions
+var (
+	emailPattern      = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	creditCardPattern = regexp.MustCompile(`\b\d(?:[ \-]?\d){12,18}\b`)
+)
+
+// RedactEmails replaces email addresses in the data and fields of events
+// with replacement
+func RedactEmails(replacement string) Transformer {
+	return RedactPattern(emailPattern, replacement)
+}
+
+// RedactCreditCards replaces payment card numbers of 13 to 19 digits,
+// optionally grouped by spaces or dashes and with a valid Luhn checksum, in
+// the data and fields of events with replacement
+func RedactCreditCards(replacement string) Transformer {
+	return redactStrings(func(s string) string {
+		return creditCardPattern.ReplaceAllStringFunc(s, func(number string) string {
+			if !luhn(number) {
+				return number
+			}
+			return replacement
+		})
+	})
+}
+
+// RedactPattern replaces the matches of pattern in the data and fields of
+// events with replacement, which may refer to submatches like
+// regexp.Regexp.ReplaceAllString
+func RedactPattern(pattern *regexp.Regexp, replacement string) Transformer {
+	return redactStrings(func(s string) string {
+		return pattern.ReplaceAllString(s, replacement)
+	})
+}
+
+// redactStrings returns a transformer replacing every string of the data and
+// fields of events by redact. Data other than strings, maps and slices is
+// converted to its JSON form first.
+func redactStrings(redact func(s string) string) Transformer {
+	return func(event *Event) (*Event, error) {
+		data := event.Event
+		switch data.(type) {
+		case string, *string, map[string]interface{}, []interface{}:
+		default:
+			encoded, err := json.Marshal(data)
+			if err != nil {
+				return nil, err
+			}
+			decoder := json.NewDecoder(bytes.NewReader(encoded))
+			decoder.UseNumber()
+			if err := decoder.Decode(&data); err != nil {
+				return nil, err
+			}
+		}
+		event.Event = redactValue(data, redact)
+		for key, value := range event.Fields {
+			event.Fields[key] = redactValue(value, redact)
+		}
+		return event, nil
+	}
+}
+
+// redactValue returns a copy of value with its strings replaced by redact
+func redactValue(value interface{}, redact func(s string) string) interface{} {
+	switch v := value.(type) {
+	case string:
+		return redact(v)
+	case *string:
+		if v == nil {
+			return v
+		}
+		return redact(*v)
+	case []string:
+		result := make([]string, len(v))
+		for i, s := range v {
+			result[i] = redact(s)
+		}
+		return result
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, element := range v {
+			result[key] = redactValue(element, redact)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, element := range v {
+			result[i] = redactValue(element, redact)
+		}
+		return result
+	}
+	return value
+}
+
+// luhn tells whether the digits of number have a valid Luhn checksum
+func luhn(number string) bool {
+	sum, double := 0, false
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		digit := int(c - '0')
+		if double {
+			if digit *= 2; digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return sum%10 == 0
+}
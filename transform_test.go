@@ -0,0 +1,76 @@
+package hec
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithTransformers(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	errSecret := errors.New("secret event")
+	recorder := NewTestRecorder()
+	c := NewClient(ts.URL, testSplunkToken, WithTestMode(recorder), WithTransformers(
+		RedactEmails("[email]"),
+		func(event *Event) (*Event, error) {
+			switch event.Event {
+			case "debug":
+				return nil, nil
+			case "secret":
+				return nil, errSecret
+			}
+			return event, nil
+		},
+	))
+	c.SetHTTPClient(testHttpClient)
+	c.SetChannelMode(ChannelNone)
+
+	event := NewEvent("mail from jane.doe@example.com")
+	event.SetFields(map[string]interface{}{"user": "jane@example.org"})
+	err := c.WriteBatch([]*Event{NewEvent("secret"), event, NewEvent("debug")})
+	var transformErr *TransformError
+	if assert.ErrorAs(t, err, &transformErr) {
+		assert.Equal(t, []int{0}, transformErr.Indexes)
+		assert.ErrorIs(t, err, ErrTransformFailed)
+		assert.ErrorIs(t, err, errSecret)
+	}
+	assert.Equal(t, `{"fields":{"user":"[email]"},"event":"mail from [email]"}`, string(recorder.Requests()[0].Payload))
+	// The events of the caller are unchanged
+	assert.Equal(t, "mail from jane.doe@example.com", event.Event)
+	assert.Equal(t, map[string]interface{}{"user": "jane@example.org"}, event.Fields)
+
+	assert.ErrorIs(t, c.WriteEvent(NewEvent("secret")), ErrTransformFailed)
+	assert.NoError(t, c.WriteEvent(NewEvent("debug")))
+	assert.Len(t, recorder.Requests(), 1)
+}
+
+func TestRedactors(t *testing.T) {
+	transform := func(transformer Transformer, data interface{}) interface{} {
+		event, err := transformer(NewEvent(data))
+		assert.NoError(t, err)
+		return event.Event
+	}
+
+	cards := RedactCreditCards("[card]")
+	assert.Equal(t, "paid with [card] and [card]", transform(cards, "paid with 4111 1111 1111 1111 and 5500-0000-0000-0004"))
+	assert.Equal(t, "order 1234567890123", transform(cards, "order 1234567890123")) // invalid checksum
+
+	type login struct {
+		User  string `json:"user"`
+		Count int    `json:"count"`
+	}
+	ips := RedactPattern(regexp.MustCompile(`(\d+)\.\d+\.\d+\.\d+`), "$1.x.x.x")
+	assert.Equal(t, map[string]interface{}{"user": "from 10.x.x.x", "count": json.Number("3")},
+		transform(ips, login{User: "from 10.1.2.3", Count: 3}))
+	assert.Equal(t, map[string]interface{}{"hosts": []interface{}{"192.x.x.x"}},
+		transform(ips, map[string]interface{}{"hosts": []interface{}{"192.168.0.1"}}))
+}
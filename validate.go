@@ -0,0 +1,121 @@
+package hec
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Bounds of the max content length accepted by the validating constructors
+const (
+	lowestContentLength  = 1024
+	highestContentLength = 800 * 1024 * 1024 // Default max_content_length of Splunk
+)
+
+var ErrInvalidConfig = errors.New("Invalid configuration")
+
+// ConfigError is returned by the validating constructors when a parameter is
+// not valid. It never contains the HEC token, and matches ErrInvalidConfig
+// with errors.Is.
+type ConfigError struct {
+	// Name of the parameter
+	Param string
+
+	// Why the parameter is not valid
+	Reason string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("Invalid %s: %s", e.Param, e.Reason)
+}
+
+func (e *ConfigError) Is(target error) bool {
+	return target == ErrInvalidConfig
+}
+
+// NewValidatedClient is like NewClient, but fails if the server URL or token
+// is malformed, or the max content length is out of range. Pass 0 as
+// maxContentLength to use the default.
+func NewValidatedClient(serverURL string, token string, maxContentLength int) (HEC, error) {
+	return newValidatedClient(serverURL, token, maxContentLength, AuthSplunk)
+}
+
+// newValidatedClient is NewValidatedClient for a token sent with scheme
+func newValidatedClient(serverURL string, token string, maxContentLength int, scheme AuthScheme) (HEC, error) {
+	serverURL, err := validateServerURL(serverURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateParams(token, maxContentLength, scheme); err != nil {
+		return nil, err
+	}
+	client := NewClient(serverURL, token)
+	if maxContentLength > 0 {
+		client.SetMaxContentLength(maxContentLength)
+	}
+	return client, nil
+}
+
+// NewValidatedCluster is like NewCluster, but validates its parameters as
+// NewValidatedClient does
+func NewValidatedCluster(serverURLs []string, token string, maxContentLength int) (HEC, error) {
+	return newValidatedCluster(serverURLs, token, maxContentLength, AuthSplunk)
+}
+
+// newValidatedCluster is NewValidatedCluster for a token sent with scheme
+func newValidatedCluster(serverURLs []string, token string, maxContentLength int, scheme AuthScheme) (HEC, error) {
+	if len(serverURLs) == 0 {
+		return nil, &ConfigError{Param: "server URLs", Reason: "at least one is required"}
+	}
+	urls := make([]string, len(serverURLs))
+	for i, serverURL := range serverURLs {
+		var err error
+		if urls[i], err = validateServerURL(serverURL); err != nil {
+			return nil, err
+		}
+	}
+	if err := validateParams(token, maxContentLength, scheme); err != nil {
+		return nil, err
+	}
+	cluster := NewCluster(urls, token)
+	if maxContentLength > 0 {
+		cluster.SetMaxContentLength(maxContentLength)
+	}
+	return cluster, nil
+}
+
+// validateServerURL checks a server URL and returns it without trailing slash
+func validateServerURL(serverURL string) (string, error) {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return "", &ConfigError{Param: "server URL", Reason: "cannot be parsed"}
+	}
+	redactedURL := u.Redacted()
+	switch {
+	case u.Scheme != "http" && u.Scheme != "https":
+		return "", &ConfigError{Param: "server URL", Reason: fmt.Sprintf("%q must use http or https", redactedURL)}
+	case u.Host == "":
+		return "", &ConfigError{Param: "server URL", Reason: fmt.Sprintf("%q has no host", redactedURL)}
+	case strings.Trim(u.Path, "/") != "" || u.RawQuery != "" || u.Fragment != "":
+		return "", &ConfigError{Param: "server URL", Reason: fmt.Sprintf("%q must not have a path, query or fragment", redactedURL)}
+	}
+	return strings.TrimRight(serverURL, "/"), nil
+}
+
+// validateParams checks the parameters of a client. Only HEC tokens sent
+// with AuthSplunk must be GUIDs, other schemes may carry e.g. OAuth tokens.
+func validateParams(token string, maxContentLength int, scheme AuthScheme) error {
+	if token == "" {
+		return &ConfigError{Param: "token", Reason: "is required"}
+	}
+	if _, err := uuid.Parse(token); scheme == AuthSplunk && (err != nil || len(token) != 36) {
+		return &ConfigError{Param: "token", Reason: "must be a GUID like 12345678-1234-1234-1234-123456789012"}
+	}
+	if maxContentLength != 0 && (maxContentLength < lowestContentLength || maxContentLength > highestContentLength) {
+		return &ConfigError{Param: "max content length", Reason: fmt.Sprintf("%d is not between %d and %d", maxContentLength, lowestContentLength, highestContentLength)}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package hec
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewValidatedClient(t *testing.T) {
+	c, err := NewValidatedClient("https://splunk.example.com:8088/", testSplunkToken, 0)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "https://splunk.example.com:8088", c.(*Client).serverURL)
+		assert.Equal(t, defaultMaxContentLength, c.(*Client).current().MaxContentLength)
+	}
+
+	for _, test := range []struct {
+		url, token string
+		length     int
+		param      string
+	}{
+		{"splunk.example.com:8088", testSplunkToken, 0, "server URL"},
+		{"ftp://splunk.example.com", testSplunkToken, 0, "server URL"},
+		{"https://", testSplunkToken, 0, "server URL"},
+		{"https://splunk.example.com:8088/services/collector", testSplunkToken, 0, "server URL"},
+		{"https://splunk.example.com:8088?x=1", testSplunkToken, 0, "server URL"},
+		{"https://splunk.example.com:8088", "", 0, "token"},
+		{"https://splunk.example.com:8088", "Splunk 00000000", 0, "token"},
+		{"https://splunk.example.com:8088", testSplunkToken, 10, "max content length"},
+	} {
+		_, err := NewValidatedClient(test.url, test.token, test.length)
+		var configErr *ConfigError
+		if assert.True(t, errors.As(err, &configErr), test.url) {
+			assert.Equal(t, test.param, configErr.Param)
+			assert.ErrorIs(t, err, ErrInvalidConfig)
+		}
+	}
+}
+
+func TestNewValidatedCluster(t *testing.T) {
+	c, err := NewValidatedCluster([]string{"http://a:8088", "http://b:8088"}, testSplunkToken, 2048)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2048, c.(*Cluster).clients[1].current().MaxContentLength)
+	}
+
+	_, err = NewValidatedCluster(nil, testSplunkToken, 0)
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+	_, err = NewValidatedCluster([]string{"http://a:8088", "b:8088"}, testSplunkToken, 0)
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+}
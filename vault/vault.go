@@ -0,0 +1,56 @@
+// Package vaulthec provides HEC tokens stored in HashiCorp Vault.
+package vaulthec
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+	"github.com/hashicorp/vault/api"
+)
+
+// DefaultKey is the key of the token in a secret, if not set
+const DefaultKey = "token"
+
+// Secret locates a token in a KV secrets engine
+type Secret struct {
+	// Mount path of the secrets engine, e.g. "secret"
+	Mount string
+
+	// Path of the secret in the engine, e.g. "splunk/hec"
+	Path string
+
+	// Key of the token in the secret (default: DefaultKey)
+	Key string
+
+	// Read with version 1 of the KV engine instead of version 2
+	KVv1 bool
+}
+
+// NewTokenProvider returns a provider reading the token of secret with
+// client, cached for ttl or until HEC rejects it (0 to cache until rejected).
+// Tokens are rotated by writing a new version of the secret.
+func NewTokenProvider(client *api.Client, secret Secret, ttl time.Duration) hec.TokenProvider {
+	key := secret.Key
+	if key == "" {
+		key = DefaultKey
+	}
+	return hec.CachedToken(func(ctx context.Context) (string, error) {
+		var kv *api.KVSecret
+		var err error
+		if secret.KVv1 {
+			kv, err = client.KVv1(secret.Mount).Get(ctx, secret.Path)
+		} else {
+			kv, err = client.KVv2(secret.Mount).Get(ctx, secret.Path)
+		}
+		if err != nil {
+			return "", err
+		}
+		token, ok := kv.Data[key].(string)
+		if !ok || token == "" {
+			return "", fmt.Errorf("secret %s/%s has no %q", secret.Mount, secret.Path, key)
+		}
+		return token, nil
+	}, ttl)
+}
@@ -0,0 +1,61 @@
+package vaulthec
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+	"github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTokenProvider(t *testing.T) {
+	tokens := []string{"old-token", "new-token"}
+	version := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/secret/data/splunk/hec":
+			assert.Equal(t, "vault-token", r.Header.Get("X-Vault-Token"))
+			w.Write([]byte(`{"data":{"data":{"token":"` + tokens[version] + `"},"metadata":{"version":1}}}`))
+			version++
+		case "/v1/kv/splunk":
+			w.Write([]byte(`{"data":{"hec":"kv1-token"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":[]}`))
+		}
+	}))
+	defer ts.Close()
+
+	config := api.DefaultConfig()
+	config.Address = ts.URL
+	client, err := api.NewClient(config)
+	require.NoError(t, err)
+	client.SetToken("vault-token")
+	ctx := context.Background()
+
+	provider := NewTokenProvider(client, Secret{Mount: "secret", Path: "splunk/hec"}, 0)
+	token, err := provider.Token(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "old-token", token)
+	token, _ = provider.Token(ctx)
+	assert.Equal(t, "old-token", token) // cached
+	provider.(hec.TokenInvalidator).InvalidateToken(token)
+	token, _ = provider.Token(ctx)
+	assert.Equal(t, "new-token", token)
+
+	provider = NewTokenProvider(client, Secret{Mount: "kv", Path: "splunk", Key: "hec", KVv1: true}, 0)
+	token, err = provider.Token(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "kv1-token", token)
+
+	provider = NewTokenProvider(client, Secret{Mount: "kv", Path: "splunk", KVv1: true}, 0)
+	_, err = provider.Token(ctx)
+	assert.Error(t, err)
+	provider = NewTokenProvider(client, Secret{Mount: "secret", Path: "missing"}, 0)
+	_, err = provider.Token(ctx)
+	assert.Error(t, err)
+}
@@ -0,0 +1,97 @@
+package hec
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kinds of warnings
+const (
+	// HEC or a proxy in front of it throttles requests: HTTP 429, or HEC is busy
+	WarningThrottled = "throttled"
+
+	// HEC queues are full or indexer acknowledgement is unavailable
+	WarningUnhealthy = "unhealthy"
+
+	// The token is disabled
+	WarningTokenDisabled = "token-disabled"
+
+	// The response mentions the license, e.g. a license violation
+	WarningLicense = "license"
+)
+
+// Warning is an early signal from HEC that ingestion may be blocked soon
+type Warning struct {
+	// One of the Warning constants
+	Kind string
+
+	// Server URL without password, and path and query of the endpoint
+	Server   string
+	Endpoint string
+
+	// HTTP status code, and HEC status code and text if the response has them
+	StatusCode int
+	Code       int
+	Text       string
+
+	// Delay asked for by the Retry-After header, 0 without it
+	RetryAfter time.Duration
+}
+
+// WithWarningHandler makes a client call handler with the warnings of every
+// response, including of retries. It is called from the goroutine of the
+// request, so it should not block.
+func WithWarningHandler(handler func(warning Warning)) Option {
+	return func(client *Client) {
+		client.warningHandler = handler
+	}
+}
+
+// checkWarnings reports the warnings of a response, which is nil if the body
+// is not a HEC response
+func (hec *Client) checkWarnings(endpoint string, res *http.Response, response *Response) {
+	if hec.warningHandler == nil {
+		return
+	}
+	warning := Warning{
+		Server:     redactURL(hec.serverURL),
+		Endpoint:   endpoint,
+		StatusCode: res.StatusCode,
+		RetryAfter: retryAfter(res.Header.Get("Retry-After"), hec.clock.Now()),
+	}
+	if response != nil {
+		warning.Code, warning.Text = response.Code, response.Text
+	}
+	var kinds []string
+	switch {
+	case res.StatusCode == http.StatusTooManyRequests || response != nil && response.Code == StatusServerBusy:
+		kinds = append(kinds, WarningThrottled)
+	case response != nil && response.Code >= StatusUnhealthyQueuesFull && response.Code <= StatusUnhealthyQueuesFullAckDown:
+		kinds = append(kinds, WarningUnhealthy)
+	case response != nil && response.Code == StatusTokenDisabled:
+		kinds = append(kinds, WarningTokenDisabled)
+	}
+	if response != nil && strings.Contains(strings.ToLower(response.Text), "license") {
+		kinds = append(kinds, WarningLicense)
+	}
+	for _, kind := range kinds {
+		warning.Kind = kind
+		hec.warningHandler(warning)
+	}
+}
+
+// retryAfter parses a Retry-After header, in seconds or as an HTTP date
+func retryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,60 @@
+package hec
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithWarningHandler(t *testing.T) {
+	responses := map[string]func(w http.ResponseWriter){
+		"/busy": func(w http.ResponseWriter) {
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"text":"Server is busy","code":9}`))
+		},
+		"/proxy": func(w http.ResponseWriter) {
+			w.Header().Set("Retry-After", "Wed, 21 Oct 2015 07:29:00 GMT")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`<html>Too Many Requests</html>`))
+		},
+		"/license": func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"text":"Token disabled, license expired","code":1}`))
+		},
+		"/ok": func(w http.ResponseWriter) {
+			w.Write([]byte(`{"text":"Success","code":0}`))
+		},
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		responses[strings.TrimSuffix(r.URL.Path, "/services/collector")](w)
+	}))
+	defer ts.Close()
+
+	var warnings []Warning
+	handler := WithWarningHandler(func(warning Warning) {
+		warnings = append(warnings, warning)
+	})
+	write := func(path string) {
+		c := NewClient(ts.URL+path, testSplunkToken, handler)
+		c.SetMaxRetry(0)
+		c.SetChannelMode(ChannelNone)
+		c.SetClock(NewManualClock(time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC)))
+		c.WriteEvent(NewEvent("hello"))
+	}
+
+	write("/busy")
+	write("/proxy")
+	write("/license")
+	write("/ok")
+	assert.Equal(t, []Warning{
+		{Kind: WarningThrottled, Server: ts.URL + "/busy", Endpoint: "/services/collector", StatusCode: 503, Code: 9, Text: "Server is busy", RetryAfter: 30 * time.Second},
+		{Kind: WarningThrottled, Server: ts.URL + "/proxy", Endpoint: "/services/collector", StatusCode: 429, RetryAfter: time.Minute},
+		{Kind: WarningTokenDisabled, Server: ts.URL + "/license", Endpoint: "/services/collector", StatusCode: 403, Code: 1, Text: "Token disabled, license expired"},
+		{Kind: WarningLicense, Server: ts.URL + "/license", Endpoint: "/services/collector", StatusCode: 403, Code: 1, Text: "Token disabled, license expired"},
+	}, warnings)
+}
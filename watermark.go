@@ -0,0 +1,87 @@
+package hec
+
+import (
+	"sort"
+	"sync"
+)
+
+// Watermark reports that the utilization of the request slots of a
+// ClientFactory crossed a watermark
+type Watermark struct {
+	// Watermark crossed, from 0 to 1
+	Level float64
+
+	// Fraction of slots in use after crossing
+	Utilization float64
+
+	// Whether the utilization rose above Level, or fell below it
+	Rising bool
+}
+
+// slotGauge tracks the utilization of request slots for watermark callbacks
+type slotGauge struct {
+	mtx        sync.Mutex
+	capacity   int
+	inUse      int
+	watermarks []float64
+	level      int // watermarks reached by the utilization
+	callback   func(watermark Watermark)
+}
+
+// SetWatermarks makes the factory call callback whenever the fraction of
+// its request slots in use rises to or above one of watermarks (e.g. 0.5,
+// 0.8 and 0.95), or falls below it again, so applications can shed load
+// before requests pile up. Callbacks are called in order and must not block.
+// It has no effect on a factory without a limit of concurrent requests.
+func (f *ClientFactory) SetWatermarks(watermarks []float64, callback func(watermark Watermark)) {
+	if f.gauge == nil {
+		return
+	}
+	sorted := append([]float64(nil), watermarks...)
+	sort.Float64s(sorted)
+	f.gauge.mtx.Lock()
+	defer f.gauge.mtx.Unlock()
+	f.gauge.watermarks = sorted
+	f.gauge.callback = callback
+	f.gauge.level = f.gauge.reached()
+}
+
+func (g *slotGauge) acquire() {
+	g.update(1)
+}
+
+func (g *slotGauge) release() {
+	g.update(-1)
+}
+
+func (g *slotGauge) update(delta int) {
+	if g == nil {
+		return
+	}
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	g.inUse += delta
+	level := g.reached()
+	for ; g.level < level; g.level++ {
+		g.notify(g.watermarks[g.level], true)
+	}
+	for ; g.level > level; g.level-- {
+		g.notify(g.watermarks[g.level-1], false)
+	}
+}
+
+// reached returns the number of watermarks at or below the utilization
+func (g *slotGauge) reached() int {
+	utilization := g.utilization()
+	return sort.Search(len(g.watermarks), func(i int) bool { return g.watermarks[i] > utilization })
+}
+
+func (g *slotGauge) utilization() float64 {
+	return float64(g.inUse) / float64(g.capacity)
+}
+
+func (g *slotGauge) notify(level float64, rising bool) {
+	if g.callback != nil {
+		g.callback(Watermark{Level: level, Utilization: g.utilization(), Rising: rising})
+	}
+}
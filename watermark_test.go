@@ -0,0 +1,63 @@
+package hec
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlotGauge(t *testing.T) {
+	f := NewClientFactory(nil, 4)
+	var crossed []Watermark
+	f.SetWatermarks([]float64{0.8, 0.5}, func(watermark Watermark) {
+		crossed = append(crossed, watermark)
+	})
+
+	for i := 0; i < 4; i++ {
+		f.gauge.acquire()
+	}
+	for i := 0; i < 4; i++ {
+		f.gauge.release()
+	}
+	assert.Equal(t, []Watermark{
+		{Level: 0.5, Utilization: 0.5, Rising: true},
+		{Level: 0.8, Utilization: 1, Rising: true},
+		{Level: 0.8, Utilization: 0.75, Rising: false},
+		{Level: 0.5, Utilization: 0.25, Rising: false},
+	}, crossed)
+
+	// No limit, no watermarks
+	NewClientFactory(nil, 0).SetWatermarks([]float64{0.5}, func(Watermark) { t.Fail() })
+}
+
+func TestClientFactoryWatermarks(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	f := NewClientFactory(testHttpClient, 2)
+	reached := make(chan Watermark, 2)
+	f.SetWatermarks([]float64{1}, func(watermark Watermark) {
+		reached <- watermark
+	})
+	c := f.NewClient(ts.URL, testSplunkToken)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, c.WriteEvent(NewEvent("event")))
+		}()
+	}
+	assert.Equal(t, Watermark{Level: 1, Utilization: 1, Rising: true}, <-reached)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, Watermark{Level: 1, Utilization: 0.5, Rising: false}, <-reached)
+}
@@ -0,0 +1,17 @@
+module github.com/fuyufjh/splunk-hec-go/zap
+
+go 1.21
+
+require (
+	github.com/fuyufjh/splunk-hec-go v0.0.0
+	github.com/stretchr/testify v1.12.1
+	go.uber.org/zap v1.28.0
+)
+
+require (
+	github.com/google/uuid v1.0.0 // indirect
+	go.uber.org/multierr v1.10.0 // indirect
+	go.yaml.in/yaml/v3 v3.0.5 // indirect
+)
+
+replace github.com/fuyufjh/splunk-hec-go => ../
@@ -0,0 +1,77 @@
+package zaphec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+	"go.uber.org/zap"
+)
+
+// Scheme of the URLs of HEC sinks
+const Scheme = "hec"
+
+// RegisterSink registers the "hec" scheme with zap, so output paths like
+//
+//	hec://TOKEN@splunk.example.com:8088?index=main&sourcetype=app
+//
+// write the encoded entries as events to HEC. The query may set host,
+// index, source, sourcetype and batch (the batch size), and "insecure=true"
+// to use http instead of https.
+func RegisterSink() error {
+	return zap.RegisterSink(Scheme, func(u *url.URL) (zap.Sink, error) {
+		return newSink(u)
+	})
+}
+
+// sink writes every line encoded by zap as an event
+type sink struct {
+	batcher *batcher
+}
+
+func newSink(u *url.URL) (*sink, error) {
+	if u.User == nil || u.User.Username() == "" {
+		return nil, fmt.Errorf("HEC sink URL %q has no token", u.Redacted())
+	}
+	query := u.Query()
+	scheme := "https"
+	if query.Get("insecure") == "true" {
+		scheme = "http"
+	}
+	config := Config{
+		Host:       query.Get("host"),
+		Index:      query.Get("index"),
+		Source:     query.Get("source"),
+		SourceType: query.Get("sourcetype"),
+	}
+	if batch := query.Get("batch"); batch != "" {
+		size, err := strconv.Atoi(batch)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid batch size %q of HEC sink", batch)
+		}
+		config.BatchSize = size
+	}
+	client := hec.NewClient(scheme+"://"+u.Host, u.User.Username())
+	return &sink{batcher: newBatcher(client, config)}, nil
+}
+
+// Write writes an encoded entry, as an object if it is JSON or as text otherwise
+func (s *sink) Write(p []byte) (int, error) {
+	line := bytes.TrimSpace(p)
+	var data interface{} = string(line)
+	if json.Valid(line) {
+		data = json.RawMessage(append([]byte(nil), line...))
+	}
+	return len(p), s.batcher.add(hec.NewEvent(data), false)
+}
+
+func (s *sink) Sync() error {
+	return s.batcher.sync()
+}
+
+func (s *sink) Close() error {
+	return s.batcher.sync()
+}
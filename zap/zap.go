@@ -0,0 +1,154 @@
+// Package zaphec ships zap logs to HEC, as a zapcore.Core or as a zap.Sink.
+package zaphec
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+	"go.uber.org/zap/zapcore"
+)
+
+// Default number of events written in one batch
+const defaultBatchSize = 100
+
+// Config configures where and how logs are written
+type Config struct {
+	// Metadata of events, empty values are left to the token
+	Host       string
+	Index      string
+	Source     string
+	SourceType string
+
+	// Number of entries written in one batch (default: 100). Entries are
+	// also written by Sync, and at once from level error on.
+	BatchSize int
+
+	// Writer of the entries of failed batches as JSON lines (default: os.Stderr)
+	Fallback io.Writer
+}
+
+// batcher buffers events and writes them in batches, falling back to a
+// local writer when a batch fails
+type batcher struct {
+	client hec.HEC
+	config Config
+
+	mtx    sync.Mutex
+	events []*hec.Event
+}
+
+func newBatcher(client hec.HEC, config Config) *batcher {
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultBatchSize
+	}
+	if config.Fallback == nil {
+		config.Fallback = os.Stderr
+	}
+	return &batcher{client: client, config: config}
+}
+
+// add buffers event, and writes the batch if it is full or flush is set
+func (b *batcher) add(event *hec.Event, flush bool) error {
+	c := b.config
+	event.Host, event.Index, event.Source, event.SourceType = optional(c.Host), optional(c.Index), optional(c.Source), optional(c.SourceType)
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.events = append(b.events, event)
+	if flush || len(b.events) >= b.config.BatchSize {
+		return b.flush()
+	}
+	return nil
+}
+
+func (b *batcher) sync() error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.flush()
+}
+
+func (b *batcher) flush() error {
+	if len(b.events) == 0 {
+		return nil
+	}
+	events := b.events
+	b.events = nil
+	err := b.client.WriteBatch(events)
+	if err == nil {
+		return nil
+	}
+	for _, event := range events {
+		line, _ := json.Marshal(event)
+		b.config.Fallback.Write(append(line, '\n'))
+	}
+	return fmt.Errorf("Failed to write %d log entries to HEC: %v", len(events), err)
+}
+
+func optional(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// Core is a zapcore.Core writing entries as events with their message,
+// level, logger, caller, stack and fields, at the time of the entry
+type Core struct {
+	zapcore.LevelEnabler
+	batcher *batcher
+	fields  []zapcore.Field
+}
+
+// NewCore creates a core writing entries enabled by enabler with client
+func NewCore(client hec.HEC, enabler zapcore.LevelEnabler, config Config) *Core {
+	return &Core{LevelEnabler: enabler, batcher: newBatcher(client, config)}
+}
+
+func (c *Core) With(fields []zapcore.Field) zapcore.Core {
+	return &Core{
+		LevelEnabler: c.LevelEnabler,
+		batcher:      c.batcher,
+		fields:       append(append([]zapcore.Field(nil), c.fields...), fields...),
+	}
+}
+
+func (c *Core) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *Core) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	encoder := zapcore.NewMapObjectEncoder()
+	for _, field := range c.fields {
+		field.AddTo(encoder)
+	}
+	for _, field := range fields {
+		field.AddTo(encoder)
+	}
+	data := encoder.Fields
+	data["msg"] = entry.Message
+	data["level"] = entry.Level.String()
+	if entry.LoggerName != "" {
+		data["logger"] = entry.LoggerName
+	}
+	if entry.Caller.Defined {
+		data["caller"] = entry.Caller.TrimmedPath()
+	}
+	if entry.Stack != "" {
+		data["stack"] = entry.Stack
+	}
+
+	event := hec.NewEvent(data)
+	event.SetTime(entry.Time)
+	return c.batcher.add(event, entry.Level >= zapcore.ErrorLevel)
+}
+
+func (c *Core) Sync() error {
+	return c.batcher.sync()
+}
@@ -0,0 +1,117 @@
+package zaphec
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	hec "github.com/fuyufjh/splunk-hec-go"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const testToken = "00000000-0000-0000-0000-000000000000"
+
+// splunk is a HEC endpoint recording the events of every request
+type splunk struct {
+	mtx      sync.Mutex
+	requests [][]map[string]interface{}
+	fail     bool
+}
+
+func (s *splunk) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.fail {
+		w.WriteHeader(400)
+		w.Write([]byte(`{"text":"Invalid data format","code":6}`))
+		return
+	}
+	var events []map[string]interface{}
+	decoder := json.NewDecoder(r.Body)
+	for {
+		var event map[string]interface{}
+		if err := decoder.Decode(&event); err == io.EOF {
+			break
+		} else if err != nil {
+			w.WriteHeader(400)
+			return
+		}
+		events = append(events, event)
+	}
+	s.requests = append(s.requests, events)
+	w.Write([]byte(`{"text":"Success","code":0}`))
+}
+
+func TestCore(t *testing.T) {
+	s := &splunk{}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	client := hec.NewClient(ts.URL, testToken)
+	client.SetMaxRetry(0)
+	var fallback bytes.Buffer
+	core := NewCore(client, zapcore.InfoLevel, Config{Index: "app", SourceType: "zap", BatchSize: 3, Fallback: &fallback})
+	logger := zap.New(core).Named("test").With(zap.String("service", "api"))
+
+	logger.Debug("not written")
+	logger.Info("first", zap.Int("attempt", 1))
+	logger.Info("second")
+	assert.Empty(t, s.requests)
+	logger.Error("third", zap.Error(io.EOF)) // errors are written at once
+	if assert.Len(t, s.requests, 1) && assert.Len(t, s.requests[0], 3) {
+		first := s.requests[0][0]
+		assert.Equal(t, "app", first["index"])
+		assert.Equal(t, "zap", first["sourcetype"])
+		assert.Equal(t, map[string]interface{}{
+			"msg":     "first",
+			"level":   "info",
+			"logger":  "test",
+			"service": "api",
+			"attempt": float64(1),
+		}, first["event"])
+		assert.Equal(t, "EOF", s.requests[0][2]["event"].(map[string]interface{})["error"])
+	}
+
+	// Entries of failed batches are written to the fallback writer
+	s.fail = true
+	logger.Info("lost")
+	assert.Error(t, logger.Sync())
+	assert.Contains(t, fallback.String(), `"msg":"lost"`)
+	assert.Equal(t, 1, strings.Count(fallback.String(), "\n"))
+}
+
+func TestSink(t *testing.T) {
+	s := &splunk{}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	assert.NoError(t, RegisterSink())
+	config := zap.NewProductionConfig()
+	config.OutputPaths = []string{"hec://" + testToken + "@" + strings.TrimPrefix(ts.URL, "http://") + "?insecure=true&index=app&batch=2"}
+	logger, err := config.Build()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	logger.Info("first")
+	logger.Info("second")
+	logger.Info("third")
+	assert.NoError(t, logger.Sync())
+	if assert.Len(t, s.requests, 2) {
+		assert.Len(t, s.requests[0], 2)
+		assert.Equal(t, "app", s.requests[0][0]["index"])
+		assert.Equal(t, "first", s.requests[0][0]["event"].(map[string]interface{})["msg"])
+		assert.Equal(t, "third", s.requests[1][0]["event"].(map[string]interface{})["msg"])
+	}
+
+	_, err = newSink(&url.URL{Scheme: Scheme, Host: "localhost:8088"})
+	assert.Error(t, err)
+}